@@ -50,3 +50,65 @@ CREATE INDEX idx_stock_movements_stock ON stock_movements(stock_id);
 CREATE INDEX idx_stock_movements_created ON stock_movements(created_at);
 CREATE INDEX idx_stock_reservations_order ON stock_reservations(order_id);
 CREATE INDEX idx_stock_reservations_status ON stock_reservations(status);
+
+-- Stock is kept per product variant. Rows that predate variants belong to the
+-- product's default variant, whose ID equals the product ID.
+ALTER TABLE stock ADD COLUMN IF NOT EXISTS variant_id UUID;  -- references product service (external ID)
+UPDATE stock SET variant_id = product_id WHERE variant_id IS NULL;
+ALTER TABLE stock ALTER COLUMN variant_id SET NOT NULL;
+ALTER TABLE stock DROP CONSTRAINT IF EXISTS stock_product_id_warehouse_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_variant_warehouse ON stock(variant_id, warehouse_id);
+
+-- Warehouse addresses are structured. Free-form addresses from before are
+-- kept as the first address line.
+DO $$
+BEGIN
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'warehouses' AND column_name = 'address') = 'text' THEN
+        ALTER TABLE warehouses ALTER COLUMN address TYPE JSONB
+            USING CASE WHEN address IS NULL THEN NULL ELSE jsonb_build_object('line1', address) END;
+    END IF;
+END $$;
+
+-- Reservations are routed to warehouses in the requested region first, then
+-- by ascending priority. Capacity caps the units a warehouse can hold.
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS region VARCHAR(50);
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS priority INT NOT NULL DEFAULT 100;
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS capacity INT CHECK (capacity >= 0);
+
+-- Transfers move stock between warehouses. Shipping books a negative
+-- "transfer" movement at the source and receiving a positive one at the
+-- destination, both referencing the transfer. Units shipped but neither
+-- received nor written off as a discrepancy are in transit.
+CREATE TABLE IF NOT EXISTS stock_transfers (
+    id                       UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    source_warehouse_id      UUID NOT NULL REFERENCES warehouses(id),
+    destination_warehouse_id UUID NOT NULL REFERENCES warehouses(id),
+    status                   VARCHAR(20) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'in_transit', 'partially_received', 'received', 'cancelled')),
+    notes                    TEXT,
+    shipped_at               TIMESTAMPTZ,
+    received_at              TIMESTAMPTZ,
+    cancelled_at             TIMESTAMPTZ,
+    created_at               TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at               TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (source_warehouse_id <> destination_warehouse_id)
+);
+
+CREATE TABLE IF NOT EXISTS stock_transfer_items (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transfer_id UUID NOT NULL REFERENCES stock_transfers(id) ON DELETE CASCADE,
+    product_id  UUID NOT NULL,  -- references product service (external ID)
+    variant_id  UUID NOT NULL,  -- references product service (external ID)
+    quantity    INT NOT NULL CHECK (quantity > 0),
+    shipped     INT NOT NULL DEFAULT 0 CHECK (shipped >= 0),
+    received    INT NOT NULL DEFAULT 0 CHECK (received >= 0),
+    discrepancy INT NOT NULL DEFAULT 0 CHECK (discrepancy >= 0),
+    UNIQUE(transfer_id, variant_id),
+    CHECK (received + discrepancy <= shipped)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_transfers_source ON stock_transfers(source_warehouse_id);
+CREATE INDEX IF NOT EXISTS idx_stock_transfers_destination ON stock_transfers(destination_warehouse_id);
+CREATE INDEX IF NOT EXISTS idx_stock_transfers_status ON stock_transfers(status);
+CREATE INDEX IF NOT EXISTS idx_stock_transfer_items_variant ON stock_transfer_items(variant_id);
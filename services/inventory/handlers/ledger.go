@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"main.go/services/inventory/service"
+)
+
+// LedgerHandler exposes the stock ledger consistency checker over HTTP
+type LedgerHandler struct {
+	ledger *service.LedgerService
+}
+
+// NewLedgerHandler creates a new LedgerHandler
+func NewLedgerHandler(ledger *service.LedgerService) *LedgerHandler {
+	return &LedgerHandler{ledger: ledger}
+}
+
+// RegisterRoutes registers the ledger routes on mux
+func (h *LedgerHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /stock/ledger/drift", h.listDrift)
+	mux.HandleFunc("POST /stock/ledger/check", h.check)
+	mux.HandleFunc("POST /stock/ledger/repair", h.repair)
+}
+
+// listDrift pages through drifted stock rows with ?after_id= and ?limit=
+func (h *LedgerHandler) listDrift(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	warehouseID, err := queryUUID(r, "warehouse_id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var afterID uuid.UUID
+	if v := q.Get("after_id"); v != "" {
+		if afterID, err = uuid.Parse(v); err != nil {
+			writeError(w, fmt.Errorf("%w: after_id must be a UUID", service.ErrValidation))
+			return
+		}
+	}
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, fmt.Errorf("%w: limit must be an integer", service.ErrValidation))
+			return
+		}
+	}
+	drift, err := h.ledger.ListDrift(r.Context(), warehouseID, afterID, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, drift)
+}
+
+func (h *LedgerHandler) check(w http.ResponseWriter, r *http.Request) {
+	report, err := h.ledger.Check(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (h *LedgerHandler) repair(w http.ResponseWriter, r *http.Request) {
+	var in service.LedgerRepairInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	repairs, err := h.ledger.Repair(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, repairs)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"main.go/services/inventory/service"
+)
+
+// StockHandler exposes stock levels and movements over HTTP
+type StockHandler struct {
+	stock *service.StockService
+}
+
+// NewStockHandler creates a new StockHandler
+func NewStockHandler(stock *service.StockService) *StockHandler {
+	return &StockHandler{stock: stock}
+}
+
+// RegisterRoutes registers the stock routes on mux
+func (h *StockHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /products/{id}/stock", h.listByProduct)
+	mux.HandleFunc("GET /variants/{id}/stock", h.listByVariant)
+	mux.HandleFunc("POST /stock/movements", h.recordMovement)
+	mux.HandleFunc("GET /stock/availability", h.availabilityChanges)
+	mux.HandleFunc("GET /reservations", h.listReservations)
+	mux.HandleFunc("PUT /reservations", h.setReservation)
+	mux.HandleFunc("POST /reservations/release", h.releaseReservations)
+}
+
+func (h *StockHandler) listByProduct(w http.ResponseWriter, r *http.Request) {
+	productID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	stock, err := h.stock.ListByProduct(r.Context(), productID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stock)
+}
+
+// availabilityChanges pages through variants whose stock changed after the
+// RFC 3339 ?since= and, for ties on that timestamp, the ?after= variant ID
+func (h *StockHandler) availabilityChanges(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var (
+		since   time.Time
+		afterID uuid.UUID
+		limit   int
+		err     error
+	)
+	if v := q.Get("since"); v != "" {
+		if since, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			writeError(w, fmt.Errorf("%w: since must be an RFC 3339 timestamp", service.ErrValidation))
+			return
+		}
+	}
+	if v := q.Get("after"); v != "" {
+		if afterID, err = uuid.Parse(v); err != nil {
+			writeError(w, fmt.Errorf("%w: after must be a UUID", service.ErrValidation))
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, fmt.Errorf("%w: limit must be an integer", service.ErrValidation))
+			return
+		}
+	}
+	changes, err := h.stock.AvailabilityChanges(r.Context(), since, afterID, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, changes)
+}
+
+func (h *StockHandler) listByVariant(w http.ResponseWriter, r *http.Request) {
+	variantID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	stock, err := h.stock.ListByVariant(r.Context(), variantID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stock)
+}
+
+func (h *StockHandler) recordMovement(w http.ResponseWriter, r *http.Request) {
+	var in service.MovementInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := h.stock.RecordMovement(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *StockHandler) listReservations(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(r.URL.Query().Get("order_id"))
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: order_id is required", service.ErrValidation))
+		return
+	}
+	reservations, err := h.stock.ListReservations(r.Context(), orderID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reservations)
+}
+
+func (h *StockHandler) releaseReservations(w http.ResponseWriter, r *http.Request) {
+	var in service.ReleaseReservationsInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	released, err := h.stock.ReleaseReservations(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, released)
+}
+
+func (h *StockHandler) setReservation(w http.ResponseWriter, r *http.Request) {
+	var in service.SetReservationInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	reservations, err := h.stock.SetReservation(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reservations)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"main.go/services/inventory/service"
+)
+
+// TransferHandler exposes stock transfers between warehouses over HTTP
+type TransferHandler struct {
+	transfers *service.TransferService
+}
+
+// NewTransferHandler creates a new TransferHandler
+func NewTransferHandler(transfers *service.TransferService) *TransferHandler {
+	return &TransferHandler{transfers: transfers}
+}
+
+// RegisterRoutes registers the transfer routes on mux
+func (h *TransferHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /transfers", h.list)
+	mux.HandleFunc("POST /transfers", h.create)
+	mux.HandleFunc("GET /transfers/{id}", h.get)
+	mux.HandleFunc("POST /transfers/{id}/ship", h.ship)
+	mux.HandleFunc("POST /transfers/{id}/receive", h.receive)
+	mux.HandleFunc("POST /transfers/{id}/cancel", h.cancel)
+	mux.HandleFunc("GET /stock/in-transit", h.inTransit)
+}
+
+func (h *TransferHandler) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	in := service.ListTransfersInput{Status: q.Get("status")}
+	var err error
+	if in.WarehouseID, err = queryUUID(r, "warehouse_id"); err != nil {
+		writeError(w, err)
+		return
+	}
+	if v := q.Get("discrepancies"); v != "" {
+		if in.Discrepancies, err = strconv.ParseBool(v); err != nil {
+			writeError(w, fmt.Errorf("%w: discrepancies must be true or false", service.ErrValidation))
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if in.Limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, fmt.Errorf("%w: limit must be an integer", service.ErrValidation))
+			return
+		}
+	}
+	transfers, err := h.transfers.List(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, transfers)
+}
+
+func (h *TransferHandler) create(w http.ResponseWriter, r *http.Request) {
+	var in service.CreateTransferInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	transfer, err := h.transfers.Create(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, transfer)
+}
+
+func (h *TransferHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	transfer, err := h.transfers.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, transfer)
+}
+
+func (h *TransferHandler) ship(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	transfer, err := h.transfers.Ship(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, transfer)
+}
+
+func (h *TransferHandler) receive(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.ReceiveTransferInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	transfer, err := h.transfers.Receive(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, transfer)
+}
+
+func (h *TransferHandler) cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	transfer, err := h.transfers.Cancel(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, transfer)
+}
+
+func (h *TransferHandler) inTransit(w http.ResponseWriter, r *http.Request) {
+	variantID, err := queryUUID(r, "variant_id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	warehouseID, err := queryUUID(r, "warehouse_id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	stock, err := h.transfers.ListInTransit(r.Context(), variantID, warehouseID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stock)
+}
+
+// queryUUID parses an optional UUID query parameter
+func queryUUID(r *http.Request, name string) (*uuid.UUID, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be a UUID", service.ErrValidation, name)
+	}
+	return &id, nil
+}
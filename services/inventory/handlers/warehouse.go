@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"main.go/services/inventory/service"
+)
+
+// WarehouseHandler exposes warehouse management over HTTP
+type WarehouseHandler struct {
+	warehouses *service.WarehouseService
+}
+
+// NewWarehouseHandler creates a new WarehouseHandler
+func NewWarehouseHandler(warehouses *service.WarehouseService) *WarehouseHandler {
+	return &WarehouseHandler{warehouses: warehouses}
+}
+
+// RegisterRoutes registers the warehouse routes on mux
+func (h *WarehouseHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /warehouses", h.list)
+	mux.HandleFunc("POST /warehouses", h.create)
+	mux.HandleFunc("GET /warehouses/{id}", h.get)
+	mux.HandleFunc("PUT /warehouses/{id}", h.update)
+	mux.HandleFunc("DELETE /warehouses/{id}", h.delete)
+	mux.HandleFunc("POST /warehouses/{id}/activate", h.activate)
+	mux.HandleFunc("POST /warehouses/{id}/deactivate", h.deactivate)
+	mux.HandleFunc("GET /warehouses/{id}/stock", h.listStock)
+	mux.HandleFunc("GET /warehouses/{id}/stock/summary", h.stockSummary)
+}
+
+func (h *WarehouseHandler) list(w http.ResponseWriter, r *http.Request) {
+	var active *bool
+	if v := r.URL.Query().Get("active"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, fmt.Errorf("%w: active must be true or false", service.ErrValidation))
+			return
+		}
+		active = &b
+	}
+	warehouses, err := h.warehouses.List(r.Context(), active)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, warehouses)
+}
+
+func (h *WarehouseHandler) create(w http.ResponseWriter, r *http.Request) {
+	var in service.WarehouseInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	warehouse, err := h.warehouses.Create(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, warehouse)
+}
+
+func (h *WarehouseHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	warehouse, err := h.warehouses.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, warehouse)
+}
+
+func (h *WarehouseHandler) update(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.WarehouseInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	warehouse, err := h.warehouses.Update(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, warehouse)
+}
+
+func (h *WarehouseHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.warehouses.Delete(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WarehouseHandler) activate(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	status, err := h.warehouses.Activate(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (h *WarehouseHandler) deactivate(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	status, err := h.warehouses.Deactivate(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (h *WarehouseHandler) listStock(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	stock, err := h.warehouses.ListStock(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stock)
+}
+
+func (h *WarehouseHandler) stockSummary(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	summary, err := h.warehouses.StockSummary(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"main.go/services/inventory/db"
+	"main.go/services/inventory/handlers"
+	"main.go/services/inventory/service"
+)
+
+func main() {
+	// Load database configuration from environment variables
+	config := db.LoadConfig()
+
+	// Connect to the database
+	if err := db.Connect(config); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := db.GetDB()
+
+	autoRepair, _ := strconv.ParseBool(getEnv("INVENTORY_LEDGER_AUTO_REPAIR", "false"))
+	ledger := service.NewLedgerService(pool, autoRepair)
+	go ledger.RunLedgerCheck(ctx, time.Hour)
+
+	mux := http.NewServeMux()
+	handlers.NewStockHandler(service.NewStockService(pool)).RegisterRoutes(mux)
+	handlers.NewWarehouseHandler(service.NewWarehouseService(pool)).RegisterRoutes(mux)
+	handlers.NewTransferHandler(service.NewTransferService(pool)).RegisterRoutes(mux)
+	handlers.NewLedgerHandler(ledger).RegisterRoutes(mux)
+
+	addr := getEnv("INVENTORY_HTTP_ADDR", ":8082")
+	log.Printf("Inventory service listening on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Fatalf("Inventory service stopped: %v", err)
+	}
+}
+
+// getEnv gets an environment variable or returns a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
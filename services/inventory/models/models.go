@@ -6,21 +6,51 @@ import (
 	"github.com/google/uuid"
 )
 
-// Warehouse represents a warehouse location
+// Warehouse represents a warehouse location. Reservations prefer warehouses
+// in the region they ask for, then the lowest Priority. A nil Capacity does
+// not limit the units the warehouse can hold.
 type Warehouse struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name" validate:"required,min=1,max=255"`
-	Code      string    `json:"code" db:"code" validate:"required,min=1,max=50"`
-	Address   *string   `json:"address,omitempty" db:"address"`
-	IsActive  bool      `json:"is_active" db:"is_active"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID        uuid.UUID         `json:"id" db:"id"`
+	Name      string            `json:"name" db:"name" validate:"required,min=1,max=255"`
+	Code      string            `json:"code" db:"code" validate:"required,min=1,max=50"`
+	Address   *WarehouseAddress `json:"address,omitempty" db:"address"`
+	Region    *string           `json:"region,omitempty" db:"region" validate:"omitempty,max=50"`
+	Priority  int               `json:"priority" db:"priority" validate:"min=0"`
+	Capacity  *int              `json:"capacity,omitempty" db:"capacity" validate:"omitempty,min=0"`
+	IsActive  bool              `json:"is_active" db:"is_active"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// WarehouseAddress is the postal address of a warehouse
+type WarehouseAddress struct {
+	Line1      string  `json:"line1" validate:"required"`
+	Line2      *string `json:"line2,omitempty"`
+	City       string  `json:"city" validate:"required"`
+	State      string  `json:"state,omitempty"`
+	PostalCode string  `json:"postal_code,omitempty"`
+	Country    string  `json:"country" validate:"required,len=2"`
 }
 
-// Stock represents inventory stock for a product in a warehouse
+// WarehouseStockSummary totals the stock held in a warehouse. Variants and
+// Products count the stock rows with units on hand.
+type WarehouseStockSummary struct {
+	WarehouseID        uuid.UUID `json:"warehouse_id" db:"warehouse_id"`
+	Products           int       `json:"products" db:"products"`
+	Variants           int       `json:"variants" db:"variants"`
+	Quantity           int       `json:"quantity" db:"quantity"`
+	Reserved           int       `json:"reserved" db:"reserved"`
+	Available          int       `json:"available" db:"available"`
+	ActiveReservations int       `json:"active_reservations" db:"active_reservations"`
+	Capacity           *int      `json:"capacity,omitempty" db:"-"`
+	FreeCapacity       *int      `json:"free_capacity,omitempty" db:"-"`
+}
+
+// Stock represents inventory stock for a product variant in a warehouse
 type Stock struct {
 	ID          uuid.UUID `json:"id" db:"id"`
 	ProductID   uuid.UUID `json:"product_id" db:"product_id" validate:"required"`
+	VariantID   uuid.UUID `json:"variant_id" db:"variant_id" validate:"required"`
 	WarehouseID uuid.UUID `json:"warehouse_id" db:"warehouse_id" validate:"required"`
 	Quantity    int       `json:"quantity" db:"quantity" validate:"min=0"`
 	Reserved    int       `json:"reserved" db:"reserved" validate:"min=0"`
@@ -73,3 +103,81 @@ type StockReservation struct {
 	CreatedAt  time.Time              `json:"created_at" db:"created_at"`
 	UpdatedAt  time.Time              `json:"updated_at" db:"updated_at"`
 }
+
+// StockTransferStatus represents the status of a stock transfer
+type StockTransferStatus string
+
+const (
+	TransferStatusDraft             StockTransferStatus = "draft"
+	TransferStatusInTransit         StockTransferStatus = "in_transit"
+	TransferStatusPartiallyReceived StockTransferStatus = "partially_received"
+	TransferStatusReceived          StockTransferStatus = "received"
+	TransferStatusCancelled         StockTransferStatus = "cancelled"
+)
+
+// StockTransfer moves stock of one or more variants from one warehouse to
+// another
+type StockTransfer struct {
+	ID                     uuid.UUID           `json:"id" db:"id"`
+	SourceWarehouseID      uuid.UUID           `json:"source_warehouse_id" db:"source_warehouse_id" validate:"required"`
+	DestinationWarehouseID uuid.UUID           `json:"destination_warehouse_id" db:"destination_warehouse_id" validate:"required"`
+	Status                 StockTransferStatus `json:"status" db:"status" validate:"required,oneof=draft in_transit partially_received received cancelled"`
+	Notes                  *string             `json:"notes,omitempty" db:"notes"`
+	ShippedAt              *time.Time          `json:"shipped_at,omitempty" db:"shipped_at"`
+	ReceivedAt             *time.Time          `json:"received_at,omitempty" db:"received_at"`
+	CancelledAt            *time.Time          `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	CreatedAt              time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt              time.Time           `json:"updated_at" db:"updated_at"`
+	Items                  []StockTransferItem `json:"items" db:"-"`
+}
+
+// StockTransferItem is a variant and quantity on a transfer. Discrepancy is
+// the shipped quantity written off when the transfer was closed short, and
+// InTransit what was shipped but neither received nor written off.
+type StockTransferItem struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	TransferID  uuid.UUID `json:"transfer_id" db:"transfer_id" validate:"required"`
+	ProductID   uuid.UUID `json:"product_id" db:"product_id" validate:"required"`
+	VariantID   uuid.UUID `json:"variant_id" db:"variant_id" validate:"required"`
+	Quantity    int       `json:"quantity" db:"quantity" validate:"required,min=1"`
+	Shipped     int       `json:"shipped" db:"shipped" validate:"min=0"`
+	Received    int       `json:"received" db:"received" validate:"min=0"`
+	Discrepancy int       `json:"discrepancy" db:"discrepancy" validate:"min=0"`
+	InTransit   int       `json:"in_transit" db:"in_transit"`
+}
+
+// InTransitStock is the quantity of a variant shipped on a transfer that has
+// not arrived at its destination yet
+type InTransitStock struct {
+	TransferID             uuid.UUID  `json:"transfer_id" db:"transfer_id"`
+	SourceWarehouseID      uuid.UUID  `json:"source_warehouse_id" db:"source_warehouse_id"`
+	DestinationWarehouseID uuid.UUID  `json:"destination_warehouse_id" db:"destination_warehouse_id"`
+	ProductID              uuid.UUID  `json:"product_id" db:"product_id"`
+	VariantID              uuid.UUID  `json:"variant_id" db:"variant_id"`
+	Quantity               int        `json:"quantity" db:"quantity"`
+	ShippedAt              *time.Time `json:"shipped_at,omitempty" db:"shipped_at"`
+}
+
+// StockDrift compares the counters of a stock row with the quantity
+// recomputed from its movements and the reserved quantity of its active
+// reservations
+type StockDrift struct {
+	StockID          uuid.UUID `json:"stock_id" db:"stock_id"`
+	ProductID        uuid.UUID `json:"product_id" db:"product_id"`
+	VariantID        uuid.UUID `json:"variant_id" db:"variant_id"`
+	WarehouseID      uuid.UUID `json:"warehouse_id" db:"warehouse_id"`
+	Quantity         int       `json:"quantity" db:"quantity"`
+	ExpectedQuantity int       `json:"expected_quantity" db:"expected_quantity"`
+	Reserved         int       `json:"reserved" db:"reserved"`
+	ExpectedReserved int       `json:"expected_reserved" db:"expected_reserved"`
+}
+
+// ProductAvailability is the unreserved stock of a product variant summed
+// over active warehouses. UpdatedAt is the latest change to any of its stock
+// rows.
+type ProductAvailability struct {
+	ProductID uuid.UUID `json:"product_id" db:"product_id"`
+	VariantID uuid.UUID `json:"variant_id" db:"variant_id"`
+	Available int       `json:"available" db:"available"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/inventory/models"
+)
+
+// ReconciliationReferenceType tags the "adjust" movements that record ledger
+// repairs. They document a correction of the counters rather than a change
+// in stock, so they are left out when the quantity is recomputed.
+const ReconciliationReferenceType = "reconciliation"
+
+// stockLedgerQuery recomputes the quantity of every stock row from its
+// movements and its reserved quantity from its active reservations
+const stockLedgerQuery = `
+	SELECT s.id AS stock_id, s.product_id, s.variant_id, s.warehouse_id,
+		s.quantity, COALESCE(m.quantity, 0)::int AS expected_quantity,
+		s.reserved, COALESCE(r.reserved, 0)::int AS expected_reserved
+	FROM stock s
+	LEFT JOIN LATERAL (
+		SELECT SUM(CASE type
+			WHEN 'in' THEN quantity
+			WHEN 'out' THEN -quantity
+			WHEN 'adjust' THEN quantity
+			WHEN 'transfer' THEN quantity
+			ELSE 0 END) AS quantity
+		FROM stock_movements
+		WHERE stock_id = s.id AND reference_type IS DISTINCT FROM '` + ReconciliationReferenceType + `'
+	) m ON true
+	LEFT JOIN LATERAL (
+		SELECT SUM(quantity) AS reserved FROM stock_reservations
+		WHERE stock_id = s.id AND status = 'active'
+	) r ON true`
+
+// LedgerRepository recomputes stock counters from the movement ledger
+type LedgerRepository struct {
+	db DBTX
+}
+
+// NewLedgerRepository creates a new LedgerRepository
+func NewLedgerRepository(db DBTX) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// ListDrift returns up to limit stock rows after afterID whose counters
+// disagree with the ledger, optionally only in one warehouse, ordered by ID
+// so callers can page through them
+func (r *LedgerRepository) ListDrift(ctx context.Context, warehouseID *uuid.UUID, afterID uuid.UUID, limit int) ([]models.StockDrift, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT * FROM (`+stockLedgerQuery+`
+			WHERE s.id > $2 AND ($1::uuid IS NULL OR s.warehouse_id = $1)
+		) l
+		WHERE quantity <> expected_quantity OR reserved <> expected_reserved
+		ORDER BY stock_id
+		LIMIT $3`, warehouseID, afterID, limit)
+	drift, err := collectAll[models.StockDrift](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock drift: %w", err)
+	}
+	return drift, nil
+}
+
+// GetDrift recomputes the counters of one stock row
+func (r *LedgerRepository) GetDrift(ctx context.Context, stockID uuid.UUID) (*models.StockDrift, error) {
+	rows, err := r.db.Query(ctx, stockLedgerQuery+` WHERE s.id = $1`, stockID)
+	drift, err := collectOne[models.StockDrift](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock drift: %w", err)
+	}
+	return drift, nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/inventory/models"
+)
+
+const reservationColumns = `id, stock_id, order_id, quantity, expires_at, status, created_at, updated_at`
+
+// ReservationRepository provides access to stock reservations
+type ReservationRepository struct {
+	db DBTX
+}
+
+// NewReservationRepository creates a new ReservationRepository
+func NewReservationRepository(db DBTX) *ReservationRepository {
+	return &ReservationRepository{db: db}
+}
+
+// ListByOrder returns the reservations held for an order
+func (r *ReservationRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.StockReservation, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+reservationColumns+` FROM stock_reservations WHERE order_id = $1 ORDER BY created_at`, orderID)
+	reservations, err := collectAll[models.StockReservation](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reservations: %w", err)
+	}
+	return reservations, nil
+}
+
+// ListActiveByOrderForUpdate returns the active reservations of an order and
+// locks them until the surrounding transaction ends
+func (r *ReservationRepository) ListActiveByOrderForUpdate(ctx context.Context, orderID uuid.UUID) ([]models.StockReservation, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+reservationColumns+` FROM stock_reservations
+		WHERE order_id = $1 AND status = $2 ORDER BY stock_id FOR UPDATE`, orderID, models.ReservationStatusActive)
+	reservations, err := collectAll[models.StockReservation](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reservations: %w", err)
+	}
+	return reservations, nil
+}
+
+// ListActiveByOrderVariantForUpdate returns the active reservations of an
+// order for one variant, newest first, and locks them until the surrounding
+// transaction ends
+func (r *ReservationRepository) ListActiveByOrderVariantForUpdate(ctx context.Context, orderID, variantID uuid.UUID) ([]models.StockReservation, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT r.id, r.stock_id, r.order_id, r.quantity, r.expires_at, r.status, r.created_at, r.updated_at
+		FROM stock_reservations r JOIN stock s ON s.id = r.stock_id
+		WHERE r.order_id = $1 AND s.variant_id = $2 AND r.status = $3
+		ORDER BY r.created_at DESC
+		FOR UPDATE OF r`, orderID, variantID, models.ReservationStatusActive)
+	reservations, err := collectAll[models.StockReservation](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reservations: %w", err)
+	}
+	return reservations, nil
+}
+
+// Create inserts a new reservation
+func (r *ReservationRepository) Create(ctx context.Context, res *models.StockReservation) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO stock_reservations (stock_id, order_id, quantity, expires_at, status)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`,
+		res.StockID, res.OrderID, res.Quantity, res.ExpiresAt, res.Status,
+	).Scan(&res.ID, &res.CreatedAt, &res.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create reservation: %w", err)
+	}
+	return nil
+}
+
+// UpdateQuantity sets the quantity and expiry of a reservation
+func (r *ReservationRepository) UpdateQuantity(ctx context.Context, res *models.StockReservation) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE stock_reservations SET quantity = $2, expires_at = $3, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`, res.ID, res.Quantity, res.ExpiresAt,
+	).Scan(&res.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update reservation: %w", err)
+	}
+	return nil
+}
+
+// UpdateStatus sets the status of a reservation
+func (r *ReservationRepository) UpdateStatus(ctx context.Context, res *models.StockReservation) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE stock_reservations SET status = $2, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`, res.ID, res.Status,
+	).Scan(&res.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update reservation: %w", err)
+	}
+	return nil
+}
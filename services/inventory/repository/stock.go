@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"main.go/services/inventory/models"
+)
+
+const stockColumns = `id, product_id, variant_id, warehouse_id, quantity, reserved, created_at, updated_at`
+
+// StockRepository provides access to stock levels and stock movements
+type StockRepository struct {
+	db DBTX
+}
+
+// NewStockRepository creates a new StockRepository
+func NewStockRepository(db DBTX) *StockRepository {
+	return &StockRepository{db: db}
+}
+
+// GetForUpdate returns the stock row of a variant in a warehouse and locks it
+// until the surrounding transaction ends
+func (r *StockRepository) GetForUpdate(ctx context.Context, variantID, warehouseID uuid.UUID) (*models.Stock, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+stockColumns+` FROM stock
+		WHERE variant_id = $1 AND warehouse_id = $2 FOR UPDATE`, variantID, warehouseID)
+	stock, err := collectOne[models.Stock](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock: %w", err)
+	}
+	return stock, nil
+}
+
+// GetByIDForUpdate returns a stock row by its ID and locks it until the
+// surrounding transaction ends
+func (r *StockRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*models.Stock, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+stockColumns+` FROM stock WHERE id = $1 FOR UPDATE`, id)
+	stock, err := collectOne[models.Stock](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock: %w", err)
+	}
+	return stock, nil
+}
+
+// GetOrCreateForUpdate returns the locked stock row of a product variant in a
+// warehouse, creating an empty one first if none exists
+func (r *StockRepository) GetOrCreateForUpdate(ctx context.Context, productID, variantID, warehouseID uuid.UUID) (*models.Stock, error) {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO stock (product_id, variant_id, warehouse_id) VALUES ($1, $2, $3)
+		ON CONFLICT (variant_id, warehouse_id) DO NOTHING`, productID, variantID, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stock: %w", err)
+	}
+	return r.GetForUpdate(ctx, variantID, warehouseID)
+}
+
+// ListByProduct returns the stock of every variant of a product across
+// warehouses
+func (r *StockRepository) ListByProduct(ctx context.Context, productID uuid.UUID) ([]models.Stock, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+stockColumns+` FROM stock WHERE product_id = $1 ORDER BY variant_id, warehouse_id`, productID)
+	stock, err := collectAll[models.Stock](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock: %w", err)
+	}
+	return stock, nil
+}
+
+// ListByVariant returns the stock of a variant across warehouses
+func (r *StockRepository) ListByVariant(ctx context.Context, variantID uuid.UUID) ([]models.Stock, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+stockColumns+` FROM stock WHERE variant_id = $1 ORDER BY warehouse_id`, variantID)
+	stock, err := collectAll[models.Stock](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock: %w", err)
+	}
+	return stock, nil
+}
+
+// ListByWarehouse returns the stock rows of a warehouse that hold or reserve
+// units
+func (r *StockRepository) ListByWarehouse(ctx context.Context, warehouseID uuid.UUID) ([]models.Stock, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+stockColumns+` FROM stock
+		WHERE warehouse_id = $1 AND (quantity > 0 OR reserved > 0)
+		ORDER BY product_id, variant_id`, warehouseID)
+	stock, err := collectAll[models.Stock](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock: %w", err)
+	}
+	return stock, nil
+}
+
+// ListAvailableForUpdate returns the stock rows of a variant in active
+// warehouses in fulfillment order and locks them until the surrounding
+// transaction ends. Warehouses in region come first, then the lowest
+// priority, then the most unreserved stock.
+func (r *StockRepository) ListAvailableForUpdate(ctx context.Context, variantID uuid.UUID, region string) ([]models.Stock, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT s.id, s.product_id, s.variant_id, s.warehouse_id, s.quantity, s.reserved, s.created_at, s.updated_at
+		FROM stock s JOIN warehouses w ON w.id = s.warehouse_id
+		WHERE s.variant_id = $1 AND w.is_active
+		ORDER BY (w.region = NULLIF($2::text, '')) IS TRUE DESC, w.priority, s.quantity - s.reserved DESC, s.id
+		FOR UPDATE OF s`, variantID, region)
+	stock, err := collectAll[models.Stock](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock: %w", err)
+	}
+	return stock, nil
+}
+
+// UpdateCounters persists the quantity and reserved counters of a stock row
+func (r *StockRepository) UpdateCounters(ctx context.Context, s *models.Stock) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE stock SET quantity = $2, reserved = $3, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`,
+		s.ID, s.Quantity, s.Reserved,
+	).Scan(&s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update stock: %w", err)
+	}
+	return nil
+}
+
+// CreateMovement records a stock movement
+func (r *StockRepository) CreateMovement(ctx context.Context, m *models.StockMovement) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO stock_movements (stock_id, type, quantity, reference_id, reference_type, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		m.StockID, m.Type, m.Quantity, m.ReferenceID, m.ReferenceType, m.Reason,
+	).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create stock movement: %w", err)
+	}
+	return nil
+}
+
+// ListMovementsByReference returns the movements recorded for a reference,
+// such as an order or a transfer
+func (r *StockRepository) ListMovementsByReference(ctx context.Context, referenceID uuid.UUID) ([]models.StockMovement, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, stock_id, type, quantity, reference_id, reference_type, reason, created_at
+		FROM stock_movements WHERE reference_id = $1 ORDER BY created_at`, referenceID)
+	movements, err := collectAll[models.StockMovement](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock movements: %w", err)
+	}
+	return movements, nil
+}
+
+// FindMovement returns the movement of the given type already recorded on a
+// stock row for a reference, used to make movement requests idempotent
+func (r *StockRepository) FindMovement(ctx context.Context, stockID uuid.UUID, movementType models.StockMovementType, referenceID uuid.UUID, referenceType *string) (*models.StockMovement, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, stock_id, type, quantity, reference_id, reference_type, reason, created_at
+		FROM stock_movements
+		WHERE stock_id = $1 AND type = $2 AND reference_id = $3 AND reference_type IS NOT DISTINCT FROM $4
+		LIMIT 1`, stockID, movementType, referenceID, referenceType)
+	movement, err := collectOne[models.StockMovement](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stock movement: %w", err)
+	}
+	return movement, nil
+}
+
+// ListAvailabilityChanges returns up to limit variants whose stock changed
+// after the (updated_at, variant_id) position given by since and afterID,
+// ordered by that position so callers can page through the feed
+func (r *StockRepository) ListAvailabilityChanges(ctx context.Context, since time.Time, afterID uuid.UUID, limit int) ([]models.ProductAvailability, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT product_id, variant_id, available, updated_at FROM (
+			SELECT s.product_id, s.variant_id,
+				COALESCE(SUM(s.quantity - s.reserved) FILTER (WHERE w.is_active), 0)::int AS available,
+				MAX(GREATEST(s.updated_at, w.updated_at)) AS updated_at
+			FROM stock s JOIN warehouses w ON w.id = s.warehouse_id
+			GROUP BY s.product_id, s.variant_id
+		) a
+		WHERE (updated_at, variant_id) > ($1, $2)
+		ORDER BY updated_at, variant_id
+		LIMIT $3`, since, afterID, limit)
+	changes, err := collectAll[models.ProductAvailability](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list availability changes: %w", err)
+	}
+	return changes, nil
+}
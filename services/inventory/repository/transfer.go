@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/inventory/models"
+)
+
+const transferColumns = `id, source_warehouse_id, destination_warehouse_id, status, notes,
+	shipped_at, received_at, cancelled_at, created_at, updated_at`
+
+const transferItemColumns = `id, transfer_id, product_id, variant_id, quantity, shipped, received, discrepancy,
+	shipped - received - discrepancy AS in_transit`
+
+// TransferFilter narrows a transfer listing. WarehouseID matches either end
+// of a transfer.
+type TransferFilter struct {
+	Status        *models.StockTransferStatus
+	WarehouseID   *uuid.UUID
+	Discrepancies bool
+	Limit         int
+}
+
+// TransferRepository provides access to stock transfers and their items
+type TransferRepository struct {
+	db DBTX
+}
+
+// NewTransferRepository creates a new TransferRepository
+func NewTransferRepository(db DBTX) *TransferRepository {
+	return &TransferRepository{db: db}
+}
+
+// GetByID returns a transfer by its ID, without items
+func (r *TransferRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.StockTransfer, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+transferColumns+` FROM stock_transfers WHERE id = $1`, id)
+	transfer, err := collectOne[models.StockTransfer](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// GetByIDForUpdate returns a transfer by its ID, without items, and locks it
+// until the surrounding transaction ends
+func (r *TransferRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*models.StockTransfer, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+transferColumns+` FROM stock_transfers WHERE id = $1 FOR UPDATE`, id)
+	transfer, err := collectOne[models.StockTransfer](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// List returns the newest transfers matching f, without items
+func (r *TransferRepository) List(ctx context.Context, f TransferFilter) ([]models.StockTransfer, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+transferColumns+` FROM stock_transfers t
+		WHERE ($1::text IS NULL OR t.status = $1)
+			AND ($2::uuid IS NULL OR $2 IN (t.source_warehouse_id, t.destination_warehouse_id))
+			AND (NOT $3 OR EXISTS (
+				SELECT 1 FROM stock_transfer_items i WHERE i.transfer_id = t.id AND i.discrepancy > 0))
+		ORDER BY t.created_at DESC, t.id
+		LIMIT $4`, f.Status, f.WarehouseID, f.Discrepancies, f.Limit)
+	transfers, err := collectAll[models.StockTransfer](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transfers: %w", err)
+	}
+	return transfers, nil
+}
+
+// Create inserts a new transfer
+func (r *TransferRepository) Create(ctx context.Context, t *models.StockTransfer) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO stock_transfers (source_warehouse_id, destination_warehouse_id, status, notes)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`,
+		t.SourceWarehouseID, t.DestinationWarehouseID, t.Status, t.Notes,
+	).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create transfer: %w", err)
+	}
+	return nil
+}
+
+// UpdateStatus persists the status and status timestamps of a transfer
+func (r *TransferRepository) UpdateStatus(ctx context.Context, t *models.StockTransfer) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE stock_transfers
+		SET status = $2, shipped_at = $3, received_at = $4, cancelled_at = $5, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`,
+		t.ID, t.Status, t.ShippedAt, t.ReceivedAt, t.CancelledAt,
+	).Scan(&t.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update transfer: %w", err)
+	}
+	return nil
+}
+
+// ListItems returns the items of the given transfers ordered by transfer and
+// variant, the order their stock rows are locked in
+func (r *TransferRepository) ListItems(ctx context.Context, transferIDs []uuid.UUID) ([]models.StockTransferItem, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+transferItemColumns+` FROM stock_transfer_items
+		WHERE transfer_id = ANY($1) ORDER BY transfer_id, variant_id`, transferIDs)
+	items, err := collectAll[models.StockTransferItem](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transfer items: %w", err)
+	}
+	return items, nil
+}
+
+// CreateItem inserts a transfer item
+func (r *TransferRepository) CreateItem(ctx context.Context, item *models.StockTransferItem) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO stock_transfer_items (transfer_id, product_id, variant_id, quantity)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`,
+		item.TransferID, item.ProductID, item.VariantID, item.Quantity,
+	).Scan(&item.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create transfer item: %w", err)
+	}
+	return nil
+}
+
+// UpdateItemCounts persists the shipped, received and discrepancy counts of
+// a transfer item
+func (r *TransferRepository) UpdateItemCounts(ctx context.Context, item *models.StockTransferItem) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE stock_transfer_items SET shipped = $2, received = $3, discrepancy = $4
+		WHERE id = $1 RETURNING shipped - received - discrepancy`,
+		item.ID, item.Shipped, item.Received, item.Discrepancy,
+	).Scan(&item.InTransit)
+	if err != nil {
+		return fmt.Errorf("failed to update transfer item: %w", err)
+	}
+	return nil
+}
+
+// ListInTransit returns the quantities shipped on open transfers that have
+// not arrived yet, optionally only for one variant or one warehouse at
+// either end, oldest shipment first
+func (r *TransferRepository) ListInTransit(ctx context.Context, variantID, warehouseID *uuid.UUID) ([]models.InTransitStock, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT t.id AS transfer_id, t.source_warehouse_id, t.destination_warehouse_id,
+			i.product_id, i.variant_id, i.shipped - i.received - i.discrepancy AS quantity, t.shipped_at
+		FROM stock_transfers t JOIN stock_transfer_items i ON i.transfer_id = t.id
+		WHERE t.status IN ('in_transit', 'partially_received')
+			AND i.shipped - i.received - i.discrepancy > 0
+			AND ($1::uuid IS NULL OR i.variant_id = $1)
+			AND ($2::uuid IS NULL OR $2 IN (t.source_warehouse_id, t.destination_warehouse_id))
+		ORDER BY t.shipped_at, t.id, i.variant_id`, variantID, warehouseID)
+	stock, err := collectAll[models.InTransitStock](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list in-transit stock: %w", err)
+	}
+	return stock, nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/inventory/models"
+)
+
+const warehouseColumns = `id, name, code, address, region, priority, capacity, is_active, created_at, updated_at`
+
+// WarehouseRepository provides access to warehouses
+type WarehouseRepository struct {
+	db DBTX
+}
+
+// NewWarehouseRepository creates a new WarehouseRepository
+func NewWarehouseRepository(db DBTX) *WarehouseRepository {
+	return &WarehouseRepository{db: db}
+}
+
+// GetByID returns a warehouse by its ID
+func (r *WarehouseRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Warehouse, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+warehouseColumns+` FROM warehouses WHERE id = $1`, id)
+	warehouse, err := collectOne[models.Warehouse](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get warehouse: %w", err)
+	}
+	return warehouse, nil
+}
+
+// GetByIDForUpdate returns a warehouse by its ID and locks it until the
+// surrounding transaction ends
+func (r *WarehouseRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*models.Warehouse, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+warehouseColumns+` FROM warehouses WHERE id = $1 FOR UPDATE`, id)
+	warehouse, err := collectOne[models.Warehouse](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get warehouse: %w", err)
+	}
+	return warehouse, nil
+}
+
+// List returns warehouses by priority and code, optionally only those with
+// the given active flag
+func (r *WarehouseRepository) List(ctx context.Context, active *bool) ([]models.Warehouse, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+warehouseColumns+` FROM warehouses
+		WHERE $1::boolean IS NULL OR is_active = $1
+		ORDER BY priority, code`, active)
+	warehouses, err := collectAll[models.Warehouse](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list warehouses: %w", err)
+	}
+	return warehouses, nil
+}
+
+// Create inserts a new warehouse
+func (r *WarehouseRepository) Create(ctx context.Context, w *models.Warehouse) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO warehouses (name, code, address, region, priority, capacity, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`,
+		w.Name, w.Code, w.Address, w.Region, w.Priority, w.Capacity, w.IsActive,
+	).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create warehouse: %w", err)
+	}
+	return nil
+}
+
+// Update persists the editable fields of a warehouse, including its active
+// flag
+func (r *WarehouseRepository) Update(ctx context.Context, w *models.Warehouse) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE warehouses
+		SET name = $2, code = $3, address = $4, region = $5, priority = $6, capacity = $7,
+			is_active = $8, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`,
+		w.ID, w.Name, w.Code, w.Address, w.Region, w.Priority, w.Capacity, w.IsActive,
+	).Scan(&w.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update warehouse: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a warehouse
+func (r *WarehouseRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM warehouses WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete warehouse: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// HasHistory reports whether a warehouse ever held stock or was part of a
+// transfer
+func (r *WarehouseRepository) HasHistory(ctx context.Context, id uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM stock WHERE warehouse_id = $1)
+			OR EXISTS (SELECT 1 FROM stock_transfers WHERE $1 IN (source_warehouse_id, destination_warehouse_id))`,
+		id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check warehouse history: %w", err)
+	}
+	return exists, nil
+}
+
+// StockSummary totals the stock and active reservations of a warehouse
+func (r *WarehouseRepository) StockSummary(ctx context.Context, id uuid.UUID) (*models.WarehouseStockSummary, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT $1::uuid AS warehouse_id,
+			COUNT(DISTINCT s.product_id) FILTER (WHERE s.quantity > 0)::int AS products,
+			COUNT(*) FILTER (WHERE s.quantity > 0)::int AS variants,
+			COALESCE(SUM(s.quantity), 0)::int AS quantity,
+			COALESCE(SUM(s.reserved), 0)::int AS reserved,
+			COALESCE(SUM(s.quantity - s.reserved), 0)::int AS available,
+			(SELECT COUNT(*) FROM stock_reservations sr JOIN stock rs ON rs.id = sr.stock_id
+				WHERE rs.warehouse_id = $1 AND sr.status = 'active')::int AS active_reservations
+		FROM stock s WHERE s.warehouse_id = $1`, id)
+	summary, err := collectOne[models.WarehouseStockSummary](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize warehouse stock: %w", err)
+	}
+	return summary, nil
+}
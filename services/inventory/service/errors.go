@@ -0,0 +1,11 @@
+package service
+
+import "errors"
+
+var (
+	// ErrValidation is returned when input fails business validation
+	ErrValidation = errors.New("validation failed")
+	// ErrInvalidState is returned when an operation is not allowed in the
+	// current state of a record
+	ErrInvalidState = errors.New("invalid state")
+)
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/inventory/models"
+	"main.go/services/inventory/repository"
+)
+
+// Page sizes of the drift report
+const (
+	defaultDriftLimit = 500
+	maxDriftLimit     = 5000
+	maxRepairStock    = 1000
+)
+
+// LedgerRepairInput selects the stock rows to repair. Without StockIDs
+// every drifted row is repaired, optionally only in one warehouse.
+type LedgerRepairInput struct {
+	StockIDs    []uuid.UUID `json:"stock_ids,omitempty"`
+	WarehouseID *uuid.UUID  `json:"warehouse_id,omitempty"`
+}
+
+// LedgerRepair is the outcome of repairing one stock row. Movement is the
+// "adjust" movement recording the correction; Skipped explains why a row
+// was left alone.
+type LedgerRepair struct {
+	Drift    models.StockDrift     `json:"drift"`
+	Movement *models.StockMovement `json:"movement,omitempty"`
+	Skipped  *string               `json:"skipped,omitempty"`
+}
+
+// LedgerReport is the result of a scheduled ledger check
+type LedgerReport struct {
+	RunID     uuid.UUID           `json:"run_id"`
+	CheckedAt time.Time           `json:"checked_at"`
+	Drift     []models.StockDrift `json:"drift"`
+	Repairs   []LedgerRepair      `json:"repairs,omitempty"`
+}
+
+// LedgerService checks the quantity and reserved counters of stock rows
+// against the movement ledger and active reservations and repairs them
+type LedgerService struct {
+	pool       *pgxpool.Pool
+	autoRepair bool
+	now        func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewLedgerService creates a new LedgerService. With autoRepair the
+// scheduled check repairs the drift it finds instead of only reporting it.
+func NewLedgerService(pool *pgxpool.Pool, autoRepair bool) *LedgerService {
+	return &LedgerService{pool: pool, autoRepair: autoRepair, now: time.Now}
+}
+
+// ListDrift returns the stock rows after afterID whose counters disagree with
+// the ledger, optionally only in one warehouse
+func (s *LedgerService) ListDrift(ctx context.Context, warehouseID *uuid.UUID, afterID uuid.UUID, limit int) ([]models.StockDrift, error) {
+	if limit <= 0 {
+		limit = defaultDriftLimit
+	}
+	if limit > maxDriftLimit {
+		return nil, fmt.Errorf("%w: limit must be at most %d", ErrValidation, maxDriftLimit)
+	}
+	drift, err := repository.NewLedgerRepository(s.pool).ListDrift(ctx, warehouseID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if drift == nil {
+		drift = []models.StockDrift{}
+	}
+	return drift, nil
+}
+
+// Repair sets the counters of drifted stock rows to the values recomputed
+// from the ledger. Each row is repaired in its own transaction with an
+// "adjust" movement recording the correction; all movements of a run share
+// its reference ID. Rows whose movements add up to a negative quantity, or
+// to less than is reserved, need a person to look at them and are skipped.
+func (s *LedgerService) Repair(ctx context.Context, in LedgerRepairInput) ([]LedgerRepair, error) {
+	if len(in.StockIDs) > maxRepairStock {
+		return nil, fmt.Errorf("%w: at most %d stock rows can be repaired at once", ErrValidation, maxRepairStock)
+	}
+	stockIDs := in.StockIDs
+	if len(stockIDs) == 0 {
+		drift, err := s.allDrift(ctx, in.WarehouseID)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range drift {
+			stockIDs = append(stockIDs, d.StockID)
+		}
+	}
+	return s.repair(ctx, uuid.New(), stockIDs)
+}
+
+func (s *LedgerService) repair(ctx context.Context, runID uuid.UUID, stockIDs []uuid.UUID) ([]LedgerRepair, error) {
+	repairs := make([]LedgerRepair, 0, len(stockIDs))
+	for _, id := range stockIDs {
+		repair, err := s.repairStock(ctx, runID, id)
+		if err != nil {
+			return repairs, fmt.Errorf("failed to repair stock %s: %w", id, err)
+		}
+		repairs = append(repairs, *repair)
+	}
+	return repairs, nil
+}
+
+// repairStock recomputes and repairs one stock row while it is locked, so
+// no movement or reservation can change it in between
+func (s *LedgerService) repairStock(ctx context.Context, runID, stockID uuid.UUID) (*LedgerRepair, error) {
+	repair := &LedgerRepair{}
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		stocks := repository.NewStockRepository(tx)
+		stock, err := stocks.GetByIDForUpdate(ctx, stockID)
+		if err != nil {
+			return err
+		}
+		drift, err := repository.NewLedgerRepository(tx).GetDrift(ctx, stockID)
+		if err != nil {
+			return err
+		}
+		repair.Drift = *drift
+
+		skip := func(reason string) error {
+			repair.Skipped = &reason
+			return nil
+		}
+		switch {
+		case drift.Quantity == drift.ExpectedQuantity && drift.Reserved == drift.ExpectedReserved:
+			return skip("no drift")
+		case drift.ExpectedQuantity < 0:
+			return skip(fmt.Sprintf("movements add up to %d units", drift.ExpectedQuantity))
+		case drift.ExpectedReserved > drift.ExpectedQuantity:
+			return skip(fmt.Sprintf("%d units are reserved but movements add up to %d", drift.ExpectedReserved, drift.ExpectedQuantity))
+		}
+
+		stock.Quantity, stock.Reserved = drift.ExpectedQuantity, drift.ExpectedReserved
+		if err := stocks.UpdateCounters(ctx, stock); err != nil {
+			return err
+		}
+		refType := repository.ReconciliationReferenceType
+		reason := fmt.Sprintf("ledger repair: quantity %d -> %d, reserved %d -> %d",
+			drift.Quantity, drift.ExpectedQuantity, drift.Reserved, drift.ExpectedReserved)
+		repair.Movement = &models.StockMovement{
+			StockID:       stock.ID,
+			Type:          models.StockMovementAdjust,
+			Quantity:      drift.ExpectedQuantity - drift.Quantity,
+			ReferenceID:   &runID,
+			ReferenceType: &refType,
+			Reason:        &reason,
+		}
+		return stocks.CreateMovement(ctx, repair.Movement)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: stock %s does not exist", ErrValidation, stockID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repair, nil
+}
+
+// allDrift pages through every drifted stock row
+func (s *LedgerService) allDrift(ctx context.Context, warehouseID *uuid.UUID) ([]models.StockDrift, error) {
+	ledger := repository.NewLedgerRepository(s.pool)
+	var all []models.StockDrift
+	var afterID uuid.UUID
+	for {
+		page, err := ledger.ListDrift(ctx, warehouseID, afterID, maxDriftLimit)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < maxDriftLimit {
+			return all, nil
+		}
+		afterID = page[len(page)-1].StockID
+	}
+}
+
+// Check looks for drift in every stock row and, with auto repair, repairs
+// what it finds
+func (s *LedgerService) Check(ctx context.Context) (*LedgerReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &LedgerReport{RunID: uuid.New(), CheckedAt: s.now()}
+	drift, err := s.allDrift(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	report.Drift = drift
+	if drift == nil {
+		report.Drift = []models.StockDrift{}
+	}
+	if !s.autoRepair || len(drift) == 0 {
+		return report, nil
+	}
+	stockIDs := make([]uuid.UUID, len(drift))
+	for i, d := range drift {
+		stockIDs[i] = d.StockID
+	}
+	report.Repairs, err = s.repair(ctx, report.RunID, stockIDs)
+	return report, err
+}
+
+// RunLedgerCheck calls Check every interval until ctx is done, logging an
+// alert for every drifted stock row
+func (s *LedgerService) RunLedgerCheck(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.Check(ctx)
+			if err != nil {
+				log.Printf("stock ledger check failed: %v", err)
+			}
+			if report == nil {
+				continue
+			}
+			for _, d := range report.Drift {
+				log.Printf("ALERT stock ledger drift: stock %s (variant %s, warehouse %s) has quantity %d, expected %d, reserved %d, expected %d",
+					d.StockID, d.VariantID, d.WarehouseID, d.Quantity, d.ExpectedQuantity, d.Reserved, d.ExpectedReserved)
+			}
+			for _, r := range report.Repairs {
+				if r.Skipped != nil {
+					log.Printf("ALERT stock ledger repair skipped: stock %s: %s", r.Drift.StockID, *r.Skipped)
+				}
+			}
+			if len(report.Drift) > 0 {
+				log.Printf("stock ledger check %s found %d drifted stock rows, repaired %d",
+					report.RunID, len(report.Drift), repairedCount(report.Repairs))
+			}
+		}
+	}
+}
+
+func repairedCount(repairs []LedgerRepair) int {
+	n := 0
+	for _, r := range repairs {
+		if r.Movement != nil {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/inventory/models"
+	"main.go/services/inventory/repository"
+)
+
+// reservationReferenceType tags movements that reserve or release stock
+const reservationReferenceType = "reservation"
+
+// DefaultReservationTTL is how long a reservation holds stock
+const DefaultReservationTTL = 7 * 24 * time.Hour
+
+// ReleaseReservationsInput identifies the order whose reservations are
+// released
+type ReleaseReservationsInput struct {
+	OrderID uuid.UUID `json:"order_id"`
+	Reason  *string   `json:"reason,omitempty"`
+}
+
+// SetReservationInput holds the quantity of a product variant to keep
+// reserved for an order. Without a VariantID the product's default variant
+// is reserved. Region prefers warehouses in that region.
+type SetReservationInput struct {
+	OrderID   uuid.UUID  `json:"order_id"`
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Quantity  int        `json:"quantity"`
+	Region    *string    `json:"region,omitempty"`
+}
+
+// ListReservations returns the reservations held for an order
+func (s *StockService) ListReservations(ctx context.Context, orderID uuid.UUID) ([]models.StockReservation, error) {
+	return repository.NewReservationRepository(s.pool).ListByOrder(ctx, orderID)
+}
+
+// ReleaseReservations cancels the active reservations of an order and
+// returns the reserved quantity to available stock with a "release"
+// movement. Releasing an order without active reservations is a no-op, so
+// callers can safely retry.
+func (s *StockService) ReleaseReservations(ctx context.Context, in ReleaseReservationsInput) ([]models.StockReservation, error) {
+	if in.OrderID == uuid.Nil {
+		return nil, fmt.Errorf("%w: order_id is required", ErrValidation)
+	}
+
+	var released []models.StockReservation
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		reservations := repository.NewReservationRepository(tx)
+		stocks := repository.NewStockRepository(tx)
+
+		active, err := reservations.ListActiveByOrderForUpdate(ctx, in.OrderID)
+		if err != nil {
+			return err
+		}
+		refType := reservationReferenceType
+		for i := range active {
+			res := &active[i]
+			stock, err := stocks.GetByIDForUpdate(ctx, res.StockID)
+			if err != nil {
+				return err
+			}
+			stock.Reserved = max(stock.Reserved-res.Quantity, 0)
+			if err := stocks.UpdateCounters(ctx, stock); err != nil {
+				return err
+			}
+			refID := res.ID
+			err = stocks.CreateMovement(ctx, &models.StockMovement{
+				StockID:       stock.ID,
+				Type:          models.StockMovementRelease,
+				Quantity:      res.Quantity,
+				ReferenceID:   &refID,
+				ReferenceType: &refType,
+				Reason:        in.Reason,
+			})
+			if err != nil {
+				return err
+			}
+			res.Status = models.ReservationStatusCancelled
+			if err := reservations.UpdateStatus(ctx, res); err != nil {
+				return err
+			}
+		}
+		released = active
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return released, nil
+}
+
+// SetReservation makes the active reservations of an order for a variant add
+// up to the requested quantity. Extra stock is reserved in active
+// warehouses, preferring the requested region, then the lowest warehouse
+// priority, then the most unreserved stock; surplus is released from the
+// newest reservations first. Setting the same quantity again is a no-op, so callers
+// can safely retry.
+func (s *StockService) SetReservation(ctx context.Context, in SetReservationInput) ([]models.StockReservation, error) {
+	if in.OrderID == uuid.Nil || in.ProductID == uuid.Nil {
+		return nil, fmt.Errorf("%w: order_id and product_id are required", ErrValidation)
+	}
+	if in.Quantity < 0 {
+		return nil, fmt.Errorf("%w: quantity must not be negative", ErrValidation)
+	}
+
+	var result []models.StockReservation
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		reservations := repository.NewReservationRepository(tx)
+		stocks := repository.NewStockRepository(tx)
+
+		variantID := variantOrDefault(in.ProductID, in.VariantID)
+		region := ""
+		if in.Region != nil {
+			region = strings.TrimSpace(*in.Region)
+		}
+		available, err := stocks.ListAvailableForUpdate(ctx, variantID, region)
+		if err != nil {
+			return err
+		}
+		for _, stock := range available {
+			if stock.ProductID != in.ProductID {
+				return fmt.Errorf("%w: variant %s belongs to product %s", ErrValidation, variantID, stock.ProductID)
+			}
+		}
+		byStock := make(map[uuid.UUID]*models.Stock, len(available))
+		for i := range available {
+			byStock[available[i].ID] = &available[i]
+		}
+
+		active, err := reservations.ListActiveByOrderVariantForUpdate(ctx, in.OrderID, variantID)
+		if err != nil {
+			return err
+		}
+		current := 0
+		for _, res := range active {
+			current += res.Quantity
+		}
+
+		refType := reservationReferenceType
+		expiresAt := s.now().Add(DefaultReservationTTL)
+		move := func(stock *models.Stock, t models.StockMovementType, qty int, res *models.StockReservation) error {
+			if err := stocks.UpdateCounters(ctx, stock); err != nil {
+				return err
+			}
+			refID := res.ID
+			return stocks.CreateMovement(ctx, &models.StockMovement{
+				StockID:       stock.ID,
+				Type:          t,
+				Quantity:      qty,
+				ReferenceID:   &refID,
+				ReferenceType: &refType,
+			})
+		}
+
+		// Release surplus from the newest reservations
+		surplus := current - in.Quantity
+		for i := 0; i < len(active) && surplus > 0; i++ {
+			res := &active[i]
+			stock, ok := byStock[res.StockID]
+			if !ok {
+				if stock, err = stocks.GetByIDForUpdate(ctx, res.StockID); err != nil {
+					return err
+				}
+			}
+			n := min(res.Quantity, surplus)
+			stock.Reserved = max(stock.Reserved-n, 0)
+			res.Quantity -= n
+			surplus -= n
+			if res.Quantity == 0 {
+				res.Quantity = n
+				res.Status = models.ReservationStatusCancelled
+				if err := reservations.UpdateStatus(ctx, res); err != nil {
+					return err
+				}
+			} else if err := reservations.UpdateQuantity(ctx, res); err != nil {
+				return err
+			}
+			if err := move(stock, models.StockMovementRelease, n, res); err != nil {
+				return err
+			}
+		}
+
+		// Reserve the shortfall, topping up existing reservations first
+		shortfall := in.Quantity - current
+		for i := 0; i < len(available) && shortfall > 0; i++ {
+			stock := &available[i]
+			n := min(stock.Quantity-stock.Reserved, shortfall)
+			if n <= 0 {
+				continue
+			}
+			var res *models.StockReservation
+			for j := range active {
+				if active[j].StockID == stock.ID {
+					res = &active[j]
+					break
+				}
+			}
+			if res != nil {
+				res.Quantity += n
+				res.ExpiresAt = expiresAt
+				if err := reservations.UpdateQuantity(ctx, res); err != nil {
+					return err
+				}
+			} else {
+				res = &models.StockReservation{
+					StockID:   stock.ID,
+					OrderID:   in.OrderID,
+					Quantity:  n,
+					ExpiresAt: expiresAt,
+					Status:    models.ReservationStatusActive,
+				}
+				if err := reservations.Create(ctx, res); err != nil {
+					return err
+				}
+				active = append(active, *res)
+			}
+			stock.Reserved += n
+			shortfall -= n
+			if err := move(stock, models.StockMovementReserve, n, res); err != nil {
+				return err
+			}
+		}
+		if shortfall > 0 {
+			return fmt.Errorf("%w: %d units of variant %s are not available", ErrInvalidState, shortfall, variantID)
+		}
+
+		for _, res := range active {
+			if res.Status == models.ReservationStatusActive {
+				result = append(result, res)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
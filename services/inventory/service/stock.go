@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/inventory/models"
+	"main.go/services/inventory/repository"
+)
+
+// MovementInput describes a stock movement requested by another service or
+// by warehouse staff. Without a VariantID it applies to the product's
+// default variant, whose ID equals the product ID.
+type MovementInput struct {
+	ProductID     uuid.UUID                `json:"product_id"`
+	VariantID     *uuid.UUID               `json:"variant_id,omitempty"`
+	WarehouseID   uuid.UUID                `json:"warehouse_id"`
+	Type          models.StockMovementType `json:"type"`
+	Quantity      int                      `json:"quantity"`
+	ReferenceID   *uuid.UUID               `json:"reference_id,omitempty"`
+	ReferenceType *string                  `json:"reference_type,omitempty"`
+	Reason        *string                  `json:"reason,omitempty"`
+}
+
+// MovementResult is the stock level after a movement was applied
+type MovementResult struct {
+	Stock    *models.Stock         `json:"stock"`
+	Movement *models.StockMovement `json:"movement"`
+}
+
+// StockService manages stock levels through recorded movements
+type StockService struct {
+	pool *pgxpool.Pool
+	now  func() time.Time
+}
+
+// NewStockService creates a new StockService
+func NewStockService(pool *pgxpool.Pool) *StockService {
+	return &StockService{pool: pool, now: time.Now}
+}
+
+// ListByProduct returns the stock of every variant of a product across
+// warehouses
+func (s *StockService) ListByProduct(ctx context.Context, productID uuid.UUID) ([]models.Stock, error) {
+	return repository.NewStockRepository(s.pool).ListByProduct(ctx, productID)
+}
+
+// ListByVariant returns the stock of a variant across warehouses
+func (s *StockService) ListByVariant(ctx context.Context, variantID uuid.UUID) ([]models.Stock, error) {
+	return repository.NewStockRepository(s.pool).ListByVariant(ctx, variantID)
+}
+
+// variantOrDefault returns variantID, or the default variant of a product
+// that predates variants, which shares the product's ID
+func variantOrDefault(productID uuid.UUID, variantID *uuid.UUID) uuid.UUID {
+	if variantID != nil {
+		return *variantID
+	}
+	return productID
+}
+
+// Page sizes of the availability feed
+const (
+	defaultAvailabilityLimit = 500
+	maxAvailabilityLimit     = 5000
+)
+
+// AvailabilityChanges returns the availability of variants whose stock
+// changed after the (since, afterID) position, oldest change first. Other
+// services page through it to mirror stock levels.
+func (s *StockService) AvailabilityChanges(ctx context.Context, since time.Time, afterID uuid.UUID, limit int) ([]models.ProductAvailability, error) {
+	if limit <= 0 {
+		limit = defaultAvailabilityLimit
+	}
+	if limit > maxAvailabilityLimit {
+		return nil, fmt.Errorf("%w: limit must be at most %d", ErrValidation, maxAvailabilityLimit)
+	}
+	return repository.NewStockRepository(s.pool).ListAvailabilityChanges(ctx, since, afterID, limit)
+}
+
+// RecordMovement applies a movement to the stock of a product in a warehouse.
+// A movement with a reference that was already applied to the same stock row
+// is returned as is, so callers can safely retry. Inactive warehouses and
+// warehouses at capacity do not accept "in" movements.
+func (s *StockService) RecordMovement(ctx context.Context, in MovementInput) (*MovementResult, error) {
+	if in.ProductID == uuid.Nil || in.WarehouseID == uuid.Nil {
+		return nil, fmt.Errorf("%w: product_id and warehouse_id are required", ErrValidation)
+	}
+	if in.Quantity < 1 {
+		return nil, fmt.Errorf("%w: quantity must be at least 1", ErrValidation)
+	}
+
+	result := &MovementResult{}
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		// Inbound movements lock the warehouse so concurrent deliveries
+		// cannot exceed its capacity together
+		warehouses := repository.NewWarehouseRepository(tx)
+		getWarehouse := warehouses.GetByID
+		if in.Type == models.StockMovementIn {
+			getWarehouse = warehouses.GetByIDForUpdate
+		}
+		warehouse, err := getWarehouse(ctx, in.WarehouseID)
+		if err != nil {
+			return err
+		}
+
+		stocks := repository.NewStockRepository(tx)
+		stock, err := stocks.GetOrCreateForUpdate(ctx, in.ProductID, variantOrDefault(in.ProductID, in.VariantID), in.WarehouseID)
+		if err != nil {
+			return err
+		}
+		if stock.ProductID != in.ProductID {
+			return fmt.Errorf("%w: variant %s belongs to product %s", ErrValidation, stock.VariantID, stock.ProductID)
+		}
+		result.Stock = stock
+
+		if in.ReferenceID != nil {
+			existing, err := stocks.FindMovement(ctx, stock.ID, in.Type, *in.ReferenceID, in.ReferenceType)
+			if err == nil {
+				result.Movement = existing
+				return nil
+			}
+			if !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+		}
+
+		switch in.Type {
+		case models.StockMovementIn:
+			if err := checkInbound(ctx, warehouses, warehouse, in.Quantity); err != nil {
+				return err
+			}
+			stock.Quantity += in.Quantity
+		case models.StockMovementOut:
+			if available := stock.Quantity - stock.Reserved; in.Quantity > available {
+				return fmt.Errorf("%w: only %d unreserved units in stock", ErrInvalidState, available)
+			}
+			stock.Quantity -= in.Quantity
+		default:
+			return fmt.Errorf("%w: unsupported movement type %q", ErrValidation, in.Type)
+		}
+		if err := stocks.UpdateCounters(ctx, stock); err != nil {
+			return err
+		}
+
+		result.Movement = &models.StockMovement{
+			StockID:       stock.ID,
+			Type:          in.Type,
+			Quantity:      in.Quantity,
+			ReferenceID:   in.ReferenceID,
+			ReferenceType: in.ReferenceType,
+			Reason:        in.Reason,
+		}
+		return stocks.CreateMovement(ctx, result.Movement)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// checkInbound rejects receiving qty units into a warehouse that is inactive
+// or has no room left for them
+func checkInbound(ctx context.Context, warehouses *repository.WarehouseRepository, w *models.Warehouse, qty int) error {
+	if !w.IsActive {
+		return fmt.Errorf("%w: warehouse %s is inactive", ErrInvalidState, w.Code)
+	}
+	if w.Capacity == nil {
+		return nil
+	}
+	summary, err := warehouses.StockSummary(ctx, w.ID)
+	if err != nil {
+		return err
+	}
+	if free := *w.Capacity - summary.Quantity; qty > free {
+		return fmt.Errorf("%w: warehouse %s has room for %d more units", ErrInvalidState, w.Code, max(free, 0))
+	}
+	return nil
+}
@@ -0,0 +1,463 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/inventory/models"
+	"main.go/services/inventory/repository"
+)
+
+// transferReferenceType tags movements that ship or receive a transfer
+const transferReferenceType = "transfer"
+
+// Transfer list limits
+const (
+	defaultTransferLimit = 50
+	maxTransferLimit     = 500
+	maxTransferItems     = 200
+)
+
+// TransferItemInput is a quantity of a product variant on a transfer.
+// Without a VariantID it refers to the product's default variant.
+type TransferItemInput struct {
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Quantity  int        `json:"quantity"`
+}
+
+// CreateTransferInput describes stock to move from one warehouse to another
+type CreateTransferInput struct {
+	SourceWarehouseID      uuid.UUID           `json:"source_warehouse_id"`
+	DestinationWarehouseID uuid.UUID           `json:"destination_warehouse_id"`
+	Items                  []TransferItemInput `json:"items"`
+	Notes                  *string             `json:"notes,omitempty"`
+}
+
+// ReceiveTransferInput records stock arriving at the destination of a
+// transfer. Without Items everything still in transit is received, unless
+// the receipt closes the transfer. Close ends the transfer, writing off
+// whatever has not arrived as a discrepancy.
+type ReceiveTransferInput struct {
+	Items []TransferItemInput `json:"items,omitempty"`
+	Close bool                `json:"close"`
+}
+
+// ListTransfersInput narrows a transfer listing
+type ListTransfersInput struct {
+	Status        string
+	WarehouseID   *uuid.UUID
+	Discrepancies bool
+	Limit         int
+}
+
+// TransferService moves stock between warehouses through transfers that are
+// shipped from the source and received at the destination
+type TransferService struct {
+	pool *pgxpool.Pool
+	now  func() time.Time
+}
+
+// NewTransferService creates a new TransferService
+func NewTransferService(pool *pgxpool.Pool) *TransferService {
+	return &TransferService{pool: pool, now: time.Now}
+}
+
+// List returns the newest transfers matching in, with their items
+func (s *TransferService) List(ctx context.Context, in ListTransfersInput) ([]models.StockTransfer, error) {
+	f := repository.TransferFilter{WarehouseID: in.WarehouseID, Discrepancies: in.Discrepancies, Limit: in.Limit}
+	if in.Status != "" {
+		status := models.StockTransferStatus(in.Status)
+		if !validTransferStatus(status) {
+			return nil, fmt.Errorf("%w: unknown transfer status %q", ErrValidation, in.Status)
+		}
+		f.Status = &status
+	}
+	if f.Limit <= 0 {
+		f.Limit = defaultTransferLimit
+	}
+	if f.Limit > maxTransferLimit {
+		return nil, fmt.Errorf("%w: limit must be at most %d", ErrValidation, maxTransferLimit)
+	}
+
+	transfers := repository.NewTransferRepository(s.pool)
+	list, err := transfers.List(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return []models.StockTransfer{}, nil
+	}
+	ids := make([]uuid.UUID, len(list))
+	byID := make(map[uuid.UUID]*models.StockTransfer, len(list))
+	for i := range list {
+		ids[i] = list[i].ID
+		byID[list[i].ID] = &list[i]
+	}
+	items, err := transfers.ListItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		t := byID[item.TransferID]
+		t.Items = append(t.Items, item)
+	}
+	return list, nil
+}
+
+// Get returns a transfer with its items
+func (s *TransferService) Get(ctx context.Context, id uuid.UUID) (*models.StockTransfer, error) {
+	transfers := repository.NewTransferRepository(s.pool)
+	t, err := transfers.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Items, err = transfers.ListItems(ctx, []uuid.UUID{id}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ListInTransit returns the stock shipped on open transfers that has not
+// arrived yet, optionally only for one variant or one warehouse
+func (s *TransferService) ListInTransit(ctx context.Context, variantID, warehouseID *uuid.UUID) ([]models.InTransitStock, error) {
+	stock, err := repository.NewTransferRepository(s.pool).ListInTransit(ctx, variantID, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	if stock == nil {
+		stock = []models.InTransitStock{}
+	}
+	return stock, nil
+}
+
+// Create stores a draft transfer. Stock does not move until it is shipped.
+// The source may be inactive, so an inactive warehouse can be emptied, but
+// the destination must accept stock.
+func (s *TransferService) Create(ctx context.Context, in CreateTransferInput) (*models.StockTransfer, error) {
+	if in.SourceWarehouseID == uuid.Nil || in.DestinationWarehouseID == uuid.Nil {
+		return nil, fmt.Errorf("%w: source_warehouse_id and destination_warehouse_id are required", ErrValidation)
+	}
+	if in.SourceWarehouseID == in.DestinationWarehouseID {
+		return nil, fmt.Errorf("%w: source and destination must be different warehouses", ErrValidation)
+	}
+	if len(in.Items) == 0 {
+		return nil, fmt.Errorf("%w: at least one item is required", ErrValidation)
+	}
+	if len(in.Items) > maxTransferItems {
+		return nil, fmt.Errorf("%w: a transfer can have at most %d items", ErrValidation, maxTransferItems)
+	}
+	items := make([]models.StockTransferItem, 0, len(in.Items))
+	seen := make(map[uuid.UUID]bool, len(in.Items))
+	for _, req := range in.Items {
+		if req.ProductID == uuid.Nil {
+			return nil, fmt.Errorf("%w: product_id is required", ErrValidation)
+		}
+		if req.Quantity < 1 {
+			return nil, fmt.Errorf("%w: quantity for product %s must be at least 1", ErrValidation, req.ProductID)
+		}
+		variantID := variantOrDefault(req.ProductID, req.VariantID)
+		if seen[variantID] {
+			return nil, fmt.Errorf("%w: variant %s is listed more than once", ErrValidation, variantID)
+		}
+		seen[variantID] = true
+		items = append(items, models.StockTransferItem{ProductID: req.ProductID, VariantID: variantID, Quantity: req.Quantity})
+	}
+
+	t := &models.StockTransfer{
+		SourceWarehouseID:      in.SourceWarehouseID,
+		DestinationWarehouseID: in.DestinationWarehouseID,
+		Status:                 models.TransferStatusDraft,
+		Notes:                  trimmedOrNil(in.Notes),
+	}
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		warehouses := repository.NewWarehouseRepository(tx)
+		if _, err := warehouses.GetByID(ctx, in.SourceWarehouseID); err != nil {
+			return warehouseNotFound(err, "source", in.SourceWarehouseID)
+		}
+		destination, err := warehouses.GetByID(ctx, in.DestinationWarehouseID)
+		if err != nil {
+			return warehouseNotFound(err, "destination", in.DestinationWarehouseID)
+		}
+		if !destination.IsActive {
+			return fmt.Errorf("%w: destination warehouse %s is inactive", ErrInvalidState, destination.Code)
+		}
+
+		transfers := repository.NewTransferRepository(tx)
+		if err := transfers.Create(ctx, t); err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].TransferID = t.ID
+			if err := transfers.CreateItem(ctx, &items[i]); err != nil {
+				return err
+			}
+		}
+		t.Items = items
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Ship takes the stock of a draft transfer out of the source warehouse with
+// a negative "transfer" movement per item referencing the transfer. Only
+// unreserved stock can be shipped, and only to a destination that is still
+// active; if any item is short nothing is shipped.
+func (s *TransferService) Ship(ctx context.Context, id uuid.UUID) (*models.StockTransfer, error) {
+	var t *models.StockTransfer
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		transfers := repository.NewTransferRepository(tx)
+		stocks := repository.NewStockRepository(tx)
+
+		var err error
+		if t, err = transfers.GetByIDForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if t.Status != models.TransferStatusDraft {
+			return fmt.Errorf("%w: a %s transfer cannot be shipped", ErrInvalidState, t.Status)
+		}
+		destination, err := repository.NewWarehouseRepository(tx).GetByID(ctx, t.DestinationWarehouseID)
+		if err != nil {
+			return err
+		}
+		if !destination.IsActive {
+			return fmt.Errorf("%w: destination warehouse %s is inactive", ErrInvalidState, destination.Code)
+		}
+		if t.Items, err = transfers.ListItems(ctx, []uuid.UUID{id}); err != nil {
+			return err
+		}
+
+		refType := transferReferenceType
+		reason := "shipped to " + destination.Code
+		for i := range t.Items {
+			item := &t.Items[i]
+			stock, err := stocks.GetForUpdate(ctx, item.VariantID, t.SourceWarehouseID)
+			if errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("%w: variant %s is not stocked in the source warehouse", ErrInvalidState, item.VariantID)
+			}
+			if err != nil {
+				return err
+			}
+			if stock.ProductID != item.ProductID {
+				return fmt.Errorf("%w: variant %s belongs to product %s", ErrValidation, item.VariantID, stock.ProductID)
+			}
+			if available := stock.Quantity - stock.Reserved; item.Quantity > available {
+				return fmt.Errorf("%w: only %d unreserved units of variant %s in the source warehouse", ErrInvalidState, available, item.VariantID)
+			}
+			stock.Quantity -= item.Quantity
+			if err := stocks.UpdateCounters(ctx, stock); err != nil {
+				return err
+			}
+			err = stocks.CreateMovement(ctx, &models.StockMovement{
+				StockID:       stock.ID,
+				Type:          models.StockMovementTransfer,
+				Quantity:      -item.Quantity,
+				ReferenceID:   &t.ID,
+				ReferenceType: &refType,
+				Reason:        &reason,
+			})
+			if err != nil {
+				return err
+			}
+			item.Shipped = item.Quantity
+			if err := transfers.UpdateItemCounts(ctx, item); err != nil {
+				return err
+			}
+		}
+
+		now := s.now()
+		t.Status = models.TransferStatusInTransit
+		t.ShippedAt = &now
+		return transfers.UpdateStatus(ctx, t)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Receive books stock of a shipped transfer into the destination warehouse
+// with a positive "transfer" movement per item referencing the transfer.
+// Items can arrive over several receipts but never more than was shipped.
+// The transfer is received once nothing is in transit, either because
+// everything arrived or because it was closed with the rest written off.
+// Closing without items writes off everything still in transit.
+func (s *TransferService) Receive(ctx context.Context, id uuid.UUID, in ReceiveTransferInput) (*models.StockTransfer, error) {
+	var t *models.StockTransfer
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		transfers := repository.NewTransferRepository(tx)
+		warehouses := repository.NewWarehouseRepository(tx)
+		stocks := repository.NewStockRepository(tx)
+
+		var err error
+		if t, err = transfers.GetByIDForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if t.Status != models.TransferStatusInTransit && t.Status != models.TransferStatusPartiallyReceived {
+			return fmt.Errorf("%w: a %s transfer cannot be received", ErrInvalidState, t.Status)
+		}
+		if t.Items, err = transfers.ListItems(ctx, []uuid.UUID{id}); err != nil {
+			return err
+		}
+		receipt, err := receiptQuantities(t.Items, in.Items, in.Close)
+		if err != nil {
+			return err
+		}
+
+		total := 0
+		for _, qty := range receipt {
+			total += qty
+		}
+		if total > 0 {
+			destination, err := warehouses.GetByIDForUpdate(ctx, t.DestinationWarehouseID)
+			if err != nil {
+				return err
+			}
+			if err := checkInbound(ctx, warehouses, destination, total); err != nil {
+				return err
+			}
+		}
+		source, err := warehouses.GetByID(ctx, t.SourceWarehouseID)
+		if err != nil {
+			return err
+		}
+
+		refType := transferReferenceType
+		reason := "received from " + source.Code
+		done := true
+		for i := range t.Items {
+			item := &t.Items[i]
+			if qty := receipt[item.VariantID]; qty > 0 {
+				stock, err := stocks.GetOrCreateForUpdate(ctx, item.ProductID, item.VariantID, t.DestinationWarehouseID)
+				if err != nil {
+					return err
+				}
+				stock.Quantity += qty
+				if err := stocks.UpdateCounters(ctx, stock); err != nil {
+					return err
+				}
+				err = stocks.CreateMovement(ctx, &models.StockMovement{
+					StockID:       stock.ID,
+					Type:          models.StockMovementTransfer,
+					Quantity:      qty,
+					ReferenceID:   &t.ID,
+					ReferenceType: &refType,
+					Reason:        &reason,
+				})
+				if err != nil {
+					return err
+				}
+				item.Received += qty
+			}
+			if in.Close {
+				item.Discrepancy = item.Shipped - item.Received
+			}
+			if err := transfers.UpdateItemCounts(ctx, item); err != nil {
+				return err
+			}
+			if item.InTransit > 0 {
+				done = false
+			}
+		}
+
+		t.Status = models.TransferStatusPartiallyReceived
+		if done {
+			now := s.now()
+			t.Status = models.TransferStatusReceived
+			t.ReceivedAt = &now
+		}
+		return transfers.UpdateStatus(ctx, t)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Cancel cancels a transfer that has not been shipped
+func (s *TransferService) Cancel(ctx context.Context, id uuid.UUID) (*models.StockTransfer, error) {
+	var t *models.StockTransfer
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		transfers := repository.NewTransferRepository(tx)
+		var err error
+		if t, err = transfers.GetByIDForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if t.Status != models.TransferStatusDraft {
+			return fmt.Errorf("%w: a %s transfer cannot be cancelled", ErrInvalidState, t.Status)
+		}
+		if t.Items, err = transfers.ListItems(ctx, []uuid.UUID{id}); err != nil {
+			return err
+		}
+		now := s.now()
+		t.Status = models.TransferStatusCancelled
+		t.CancelledAt = &now
+		return transfers.UpdateStatus(ctx, t)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// receiptQuantities returns the quantity received per variant. Without lines
+// that is everything in transit, or nothing when the receipt closes the
+// transfer.
+func receiptQuantities(items []models.StockTransferItem, lines []TransferItemInput, closing bool) (map[uuid.UUID]int, error) {
+	receipt := make(map[uuid.UUID]int, len(items))
+	if len(lines) == 0 && closing {
+		return receipt, nil
+	}
+	if len(lines) == 0 {
+		for _, item := range items {
+			receipt[item.VariantID] = item.InTransit
+		}
+		return receipt, nil
+	}
+
+	byVariant := make(map[uuid.UUID]models.StockTransferItem, len(items))
+	for _, item := range items {
+		byVariant[item.VariantID] = item
+	}
+	for _, line := range lines {
+		variantID := variantOrDefault(line.ProductID, line.VariantID)
+		item, ok := byVariant[variantID]
+		if !ok || item.ProductID != line.ProductID {
+			return nil, fmt.Errorf("%w: variant %s is not on this transfer", ErrValidation, variantID)
+		}
+		if line.Quantity < 1 {
+			return nil, fmt.Errorf("%w: quantity for variant %s must be at least 1", ErrValidation, variantID)
+		}
+		receipt[variantID] += line.Quantity
+		if receipt[variantID] > item.InTransit {
+			return nil, fmt.Errorf("%w: only %d units of variant %s are in transit", ErrValidation, item.InTransit, variantID)
+		}
+	}
+	return receipt, nil
+}
+
+// warehouseNotFound reports a missing transfer warehouse as a validation
+// error
+func warehouseNotFound(err error, end string, id uuid.UUID) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: %s warehouse %s does not exist", ErrValidation, end, id)
+	}
+	return err
+}
+
+func validTransferStatus(s models.StockTransferStatus) bool {
+	switch s {
+	case models.TransferStatusDraft, models.TransferStatusInTransit, models.TransferStatusPartiallyReceived,
+		models.TransferStatusReceived, models.TransferStatusCancelled:
+		return true
+	}
+	return false
+}
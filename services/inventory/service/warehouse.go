@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/text/language"
+	"main.go/services/inventory/models"
+	"main.go/services/inventory/repository"
+)
+
+// defaultWarehousePriority is the priority of warehouses created without one
+const defaultWarehousePriority = 100
+
+// WarehouseInput holds the editable fields of a warehouse. A nil Priority
+// keeps the default priority on create.
+type WarehouseInput struct {
+	Name     string                   `json:"name"`
+	Code     string                   `json:"code"`
+	Address  *models.WarehouseAddress `json:"address,omitempty"`
+	Region   *string                  `json:"region,omitempty"`
+	Priority *int                     `json:"priority,omitempty"`
+	Capacity *int                     `json:"capacity,omitempty"`
+}
+
+// WarehouseStatus is a warehouse together with the stock it still holds,
+// returned when it is activated or deactivated
+type WarehouseStatus struct {
+	Warehouse *models.Warehouse             `json:"warehouse"`
+	Stock     *models.WarehouseStockSummary `json:"stock"`
+}
+
+// WarehouseService manages warehouses and reports the stock they hold
+type WarehouseService struct {
+	pool *pgxpool.Pool
+}
+
+// NewWarehouseService creates a new WarehouseService
+func NewWarehouseService(pool *pgxpool.Pool) *WarehouseService {
+	return &WarehouseService{pool: pool}
+}
+
+// List returns warehouses in priority order, optionally only active or only
+// inactive ones
+func (s *WarehouseService) List(ctx context.Context, active *bool) ([]models.Warehouse, error) {
+	warehouses, err := repository.NewWarehouseRepository(s.pool).List(ctx, active)
+	if err != nil {
+		return nil, err
+	}
+	if warehouses == nil {
+		warehouses = []models.Warehouse{}
+	}
+	return warehouses, nil
+}
+
+// Get returns a warehouse by its ID
+func (s *WarehouseService) Get(ctx context.Context, id uuid.UUID) (*models.Warehouse, error) {
+	return repository.NewWarehouseRepository(s.pool).GetByID(ctx, id)
+}
+
+// Create validates and stores a new, active warehouse. Codes are stored in
+// upper case and must be unique.
+func (s *WarehouseService) Create(ctx context.Context, in WarehouseInput) (*models.Warehouse, error) {
+	w := &models.Warehouse{Priority: defaultWarehousePriority, IsActive: true}
+	if err := applyWarehouseInput(w, in); err != nil {
+		return nil, err
+	}
+	if err := repository.NewWarehouseRepository(s.pool).Create(ctx, w); err != nil {
+		return nil, codeConflict(err, w.Code)
+	}
+	return w, nil
+}
+
+// Update replaces the editable fields of a warehouse. A nil Priority keeps
+// the current priority.
+func (s *WarehouseService) Update(ctx context.Context, id uuid.UUID, in WarehouseInput) (*models.Warehouse, error) {
+	var w *models.Warehouse
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		warehouses := repository.NewWarehouseRepository(tx)
+		var err error
+		if w, err = warehouses.GetByIDForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if err := applyWarehouseInput(w, in); err != nil {
+			return err
+		}
+		return codeConflict(warehouses.Update(ctx, w), w.Code)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Delete removes a warehouse that never held stock or took part in a
+// transfer. Other warehouses are deactivated instead so their history is
+// kept.
+func (s *WarehouseService) Delete(ctx context.Context, id uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		warehouses := repository.NewWarehouseRepository(tx)
+		w, err := warehouses.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		hasHistory, err := warehouses.HasHistory(ctx, id)
+		if err != nil {
+			return err
+		}
+		if hasHistory {
+			return fmt.Errorf("%w: warehouse %s has stock history, deactivate it instead", ErrInvalidState, w.Code)
+		}
+		return warehouses.Delete(ctx, id)
+	})
+}
+
+// Activate lets a warehouse receive stock and take reservations again
+func (s *WarehouseService) Activate(ctx context.Context, id uuid.UUID) (*WarehouseStatus, error) {
+	return s.setActive(ctx, id, true)
+}
+
+// Deactivate stops a warehouse from receiving stock and taking new
+// reservations. Stock already held can still be shipped or moved out, and
+// existing reservations are kept; the returned summary shows what remains.
+func (s *WarehouseService) Deactivate(ctx context.Context, id uuid.UUID) (*WarehouseStatus, error) {
+	return s.setActive(ctx, id, false)
+}
+
+func (s *WarehouseService) setActive(ctx context.Context, id uuid.UUID, active bool) (*WarehouseStatus, error) {
+	status := &WarehouseStatus{}
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		warehouses := repository.NewWarehouseRepository(tx)
+		w, err := warehouses.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if w.IsActive != active {
+			w.IsActive = active
+			if err := warehouses.Update(ctx, w); err != nil {
+				return err
+			}
+		}
+		status.Warehouse = w
+		status.Stock, err = stockSummary(ctx, warehouses, w)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// StockSummary totals the stock held in a warehouse and the room left in it
+func (s *WarehouseService) StockSummary(ctx context.Context, id uuid.UUID) (*models.WarehouseStockSummary, error) {
+	warehouses := repository.NewWarehouseRepository(s.pool)
+	w, err := warehouses.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return stockSummary(ctx, warehouses, w)
+}
+
+// ListStock returns the stock rows of a warehouse that hold or reserve units
+func (s *WarehouseService) ListStock(ctx context.Context, id uuid.UUID) ([]models.Stock, error) {
+	if _, err := repository.NewWarehouseRepository(s.pool).GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	stock, err := repository.NewStockRepository(s.pool).ListByWarehouse(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if stock == nil {
+		stock = []models.Stock{}
+	}
+	return stock, nil
+}
+
+func stockSummary(ctx context.Context, warehouses *repository.WarehouseRepository, w *models.Warehouse) (*models.WarehouseStockSummary, error) {
+	summary, err := warehouses.StockSummary(ctx, w.ID)
+	if err != nil {
+		return nil, err
+	}
+	if w.Capacity != nil {
+		free := max(*w.Capacity-summary.Quantity, 0)
+		summary.Capacity, summary.FreeCapacity = w.Capacity, &free
+	}
+	return summary, nil
+}
+
+// applyWarehouseInput normalizes and validates in and copies it onto w
+func applyWarehouseInput(w *models.Warehouse, in WarehouseInput) error {
+	name := strings.TrimSpace(in.Name)
+	code := strings.ToUpper(strings.TrimSpace(in.Code))
+	if name == "" || len(name) > 255 {
+		return fmt.Errorf("%w: name must be between 1 and 255 characters", ErrValidation)
+	}
+	if code == "" || len(code) > 50 {
+		return fmt.Errorf("%w: code must be between 1 and 50 characters", ErrValidation)
+	}
+	if strings.ContainsFunc(code, func(r rune) bool {
+		return !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+	}) {
+		return fmt.Errorf("%w: code may only contain letters, digits, '-' and '_'", ErrValidation)
+	}
+	if in.Priority != nil && *in.Priority < 0 {
+		return fmt.Errorf("%w: priority must not be negative", ErrValidation)
+	}
+	if in.Capacity != nil && *in.Capacity < 0 {
+		return fmt.Errorf("%w: capacity must not be negative", ErrValidation)
+	}
+	region := trimmedOrNil(in.Region)
+	if region != nil && len(*region) > 50 {
+		return fmt.Errorf("%w: region must be at most 50 characters", ErrValidation)
+	}
+	if in.Address != nil {
+		if err := normalizeWarehouseAddress(in.Address); err != nil {
+			return err
+		}
+	}
+
+	w.Name, w.Code, w.Address, w.Region, w.Capacity = name, code, in.Address, region, in.Capacity
+	if in.Priority != nil {
+		w.Priority = *in.Priority
+	}
+	return nil
+}
+
+// normalizeWarehouseAddress trims every field, upper-cases the country and
+// postal code and checks that the address is complete
+func normalizeWarehouseAddress(a *models.WarehouseAddress) error {
+	a.Line1 = strings.TrimSpace(a.Line1)
+	a.Line2 = trimmedOrNil(a.Line2)
+	a.City = strings.TrimSpace(a.City)
+	a.State = strings.TrimSpace(a.State)
+	a.PostalCode = strings.Join(strings.Fields(strings.ToUpper(a.PostalCode)), " ")
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+
+	if a.Line1 == "" || a.City == "" {
+		return fmt.Errorf("%w: address line1 and city are required", ErrValidation)
+	}
+	if len(a.Country) != 2 {
+		return fmt.Errorf("%w: address country must be an ISO 3166-1 alpha-2 code", ErrValidation)
+	}
+	region, err := language.ParseRegion(a.Country)
+	if err != nil || !region.IsCountry() || region.String() != a.Country {
+		return fmt.Errorf("%w: address country %q is not a known country code", ErrValidation, a.Country)
+	}
+	if len(a.PostalCode) > 20 {
+		return fmt.Errorf("%w: address postal_code is too long", ErrValidation)
+	}
+	return nil
+}
+
+// codeConflict turns a violation of the unique warehouse code into
+// ErrInvalidState
+func codeConflict(err error, code string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "warehouses_code_key" {
+		return fmt.Errorf("%w: warehouse code %s is already in use", ErrInvalidState, code)
+	}
+	return err
+}
+
+// trimmedOrNil trims s and returns nil if nothing is left
+func trimmedOrNil(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := strings.TrimSpace(*s)
+	if v == "" {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,85 @@
+package carrier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrUnknownShipment is returned when a carrier does not know a tracking
+// number
+var ErrUnknownShipment = errors.New("unknown tracking number")
+
+// EventCode is a normalized tracking milestone reported by a carrier
+type EventCode string
+
+const (
+	EventLabelCreated   EventCode = "label_created"
+	EventInTransit      EventCode = "in_transit"
+	EventOutForDelivery EventCode = "out_for_delivery"
+	EventDelivered      EventCode = "delivered"
+	EventException      EventCode = "exception"
+)
+
+// Departed reports whether the event means the parcel has left the warehouse
+func (c EventCode) Departed() bool {
+	return c == EventInTransit || c == EventOutForDelivery || c == EventDelivered
+}
+
+// LabelRequest describes the parcel a label is created for
+type LabelRequest struct {
+	Reference string
+	Address   json.RawMessage
+}
+
+// Label is a shipping label issued by a carrier
+type Label struct {
+	TrackingNo string
+	LabelURL   string
+}
+
+// Event is a tracking event reported by a carrier. ExternalID identifies the
+// event at the carrier so repeated polls and webhooks can be de-duplicated.
+type Event struct {
+	ExternalID  string    `json:"external_id"`
+	Code        EventCode `json:"code"`
+	Description string    `json:"description,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+// WebhookPayload is the body carriers push tracking updates with
+type WebhookPayload struct {
+	TrackingNo string  `json:"tracking_no"`
+	Events     []Event `json:"events"`
+}
+
+// Carrier is implemented by shipping carriers
+type Carrier interface {
+	Name() string
+	CreateLabel(ctx context.Context, req LabelRequest) (*Label, error)
+	Track(ctx context.Context, trackingNo string) ([]Event, error)
+	CancelLabel(ctx context.Context, trackingNo string) error
+}
+
+// Registry looks carriers up by name
+type Registry map[string]Carrier
+
+// NewRegistry creates a Registry of the given carriers
+func NewRegistry(carriers ...Carrier) Registry {
+	r := make(Registry, len(carriers))
+	for _, c := range carriers {
+		r[c.Name()] = c
+	}
+	return r
+}
+
+// Names returns the names of the registered carriers
+func (r Registry) Names() []string {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	return names
+}
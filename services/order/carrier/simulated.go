@@ -0,0 +1,101 @@
+package carrier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// simulatedSteps are the milestones a simulated parcel goes through, one per
+// Step after the label is created
+var simulatedSteps = []struct {
+	code        EventCode
+	description string
+	location    string
+}{
+	{EventLabelCreated, "Shipping label created", "Origin facility"},
+	{EventInTransit, "Picked up by carrier", "Origin facility"},
+	{EventInTransit, "Arrived at sorting hub", "Regional hub"},
+	{EventOutForDelivery, "Out for delivery", "Destination depot"},
+	{EventDelivered, "Delivered", "Destination"},
+}
+
+// Simulated is an in-process carrier for local development. Parcels advance
+// one milestone every Step until they are delivered. Labels are kept in
+// memory and are lost on restart.
+type Simulated struct {
+	Step time.Duration
+
+	mu     sync.Mutex
+	labels map[string]time.Time
+	now    func() time.Time
+}
+
+// NewSimulated creates a Simulated carrier advancing parcels every step
+func NewSimulated(step time.Duration) *Simulated {
+	return &Simulated{Step: step, labels: map[string]time.Time{}, now: time.Now}
+}
+
+// Name implements Carrier
+func (c *Simulated) Name() string { return "simulated" }
+
+// CreateLabel implements Carrier
+func (c *Simulated) CreateLabel(_ context.Context, req LabelRequest) (*Label, error) {
+	trackingNo := "SIM" + strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", "")[:12])
+
+	c.mu.Lock()
+	c.labels[trackingNo] = c.now()
+	c.mu.Unlock()
+
+	return &Label{
+		TrackingNo: trackingNo,
+		LabelURL:   fmt.Sprintf("sim://labels/%s.pdf?ref=%s", trackingNo, req.Reference),
+	}, nil
+}
+
+// Track implements Carrier
+func (c *Simulated) Track(_ context.Context, trackingNo string) ([]Event, error) {
+	c.mu.Lock()
+	createdAt, ok := c.labels[trackingNo]
+	c.mu.Unlock()
+	if !ok {
+		return nil, ErrUnknownShipment
+	}
+
+	elapsed := c.now().Sub(createdAt)
+	events := make([]Event, 0, len(simulatedSteps))
+	for i, step := range simulatedSteps {
+		at := createdAt.Add(time.Duration(i) * c.Step)
+		if i > 0 && time.Duration(i)*c.Step > elapsed {
+			break
+		}
+		events = append(events, Event{
+			ExternalID:  fmt.Sprintf("%s-%d", trackingNo, i),
+			Code:        step.code,
+			Description: step.description,
+			Location:    step.location,
+			OccurredAt:  at,
+		})
+	}
+	return events, nil
+}
+
+// CancelLabel implements Carrier. Labels can only be cancelled before pickup.
+func (c *Simulated) CancelLabel(_ context.Context, trackingNo string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	createdAt, ok := c.labels[trackingNo]
+	if !ok {
+		return ErrUnknownShipment
+	}
+	if c.now().Sub(createdAt) >= c.Step {
+		return fmt.Errorf("label %s was already picked up", trackingNo)
+	}
+	delete(c.labels, trackingNo)
+	return nil
+}
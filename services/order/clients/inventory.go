@@ -0,0 +1,50 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// InventoryClient talks to the inventory service
+type InventoryClient struct {
+	http httpClient
+}
+
+// NewInventoryClient creates a client for the inventory service at baseURL
+func NewInventoryClient(baseURL string) *InventoryClient {
+	return &InventoryClient{http: newHTTPClient(baseURL)}
+}
+
+// StockMovement is a stock movement request sent to the inventory service
+type StockMovement struct {
+	ProductID     uuid.UUID  `json:"product_id"`
+	VariantID     *uuid.UUID `json:"variant_id,omitempty"`
+	WarehouseID   uuid.UUID  `json:"warehouse_id"`
+	Type          string     `json:"type"`
+	Quantity      int        `json:"quantity"`
+	ReferenceID   *uuid.UUID `json:"reference_id,omitempty"`
+	ReferenceType *string    `json:"reference_type,omitempty"`
+	Reason        *string    `json:"reason,omitempty"`
+}
+
+// RecordMovement applies a stock movement. Movements carrying a reference are
+// idempotent on the inventory side.
+func (c *InventoryClient) RecordMovement(ctx context.Context, m StockMovement) error {
+	return c.http.do(ctx, http.MethodPost, "/stock/movements", m, nil)
+}
+
+// ReleaseReservations releases the active stock reservations of an order.
+// Releasing an order without active reservations succeeds.
+func (c *InventoryClient) ReleaseReservations(ctx context.Context, orderID uuid.UUID, reason string) error {
+	body := map[string]any{"order_id": orderID, "reason": reason}
+	return c.http.do(ctx, http.MethodPost, "/reservations/release", body, nil)
+}
+
+// SetReservation makes the stock reserved for a product variant of an order
+// add up to quantity
+func (c *InventoryClient) SetReservation(ctx context.Context, orderID, productID, variantID uuid.UUID, quantity int) error {
+	body := map[string]any{"order_id": orderID, "product_id": productID, "variant_id": variantID, "quantity": quantity}
+	return c.http.do(ctx, http.MethodPut, "/reservations", body, nil)
+}
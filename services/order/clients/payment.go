@@ -0,0 +1,139 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentClient talks to the payment service
+type PaymentClient struct {
+	http httpClient
+}
+
+// NewPaymentClient creates a client for the payment service at baseURL
+func NewPaymentClient(baseURL string) *PaymentClient {
+	return &PaymentClient{http: newHTTPClient(baseURL)}
+}
+
+// Payment is the payment service's view of a payment
+type Payment struct {
+	ID              uuid.UUID  `json:"id"`
+	OrderID         uuid.UUID  `json:"order_id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	Amount          float64    `json:"amount"`
+	Currency        string     `json:"currency"`
+	Status          string     `json:"status"`
+	PaymentMethodID *uuid.UUID `json:"payment_method_id,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// Refund is the payment service's view of a refund
+type Refund struct {
+	ID        uuid.UUID `json:"id"`
+	PaymentID uuid.UUID `json:"payment_id"`
+	Amount    float64   `json:"amount"`
+	Currency  string    `json:"currency"`
+	Status    string    `json:"status"`
+	// IdempotencyKey is the key the refund was requested with, if any
+	IdempotencyKey *string `json:"idempotency_key,omitempty"`
+}
+
+// Dispute is the payment service's view of a dispute
+type Dispute struct {
+	ID        uuid.UUID `json:"id"`
+	PaymentID uuid.UUID `json:"payment_id"`
+	Amount    float64   `json:"amount"`
+	Currency  string    `json:"currency"`
+	Status    string    `json:"status"`
+}
+
+// ListPayments returns the payments of an order
+func (c *PaymentClient) ListPayments(ctx context.Context, orderID uuid.UUID) ([]Payment, error) {
+	var payments []Payment
+	if err := c.http.do(ctx, http.MethodGet, "/payments?order_id="+orderID.String(), nil, &payments); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// ListRefunds returns the refunds of a payment
+func (c *PaymentClient) ListRefunds(ctx context.Context, paymentID uuid.UUID) ([]Refund, error) {
+	var refunds []Refund
+	if err := c.http.do(ctx, http.MethodGet, "/payments/"+paymentID.String()+"/refunds", nil, &refunds); err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// ListDisputes returns the disputes raised against a payment
+func (c *PaymentClient) ListDisputes(ctx context.Context, paymentID uuid.UUID) ([]Dispute, error) {
+	var disputes []Dispute
+	if err := c.http.do(ctx, http.MethodGet, "/payments/"+paymentID.String()+"/disputes", nil, &disputes); err != nil {
+		return nil, err
+	}
+	return disputes, nil
+}
+
+// Refund refunds amount of a captured payment. A non-empty idempotencyKey
+// makes the payment service return the earlier refund made with that key
+// instead of refunding twice.
+func (c *PaymentClient) Refund(ctx context.Context, paymentID uuid.UUID, amount float64, currency, reason, idempotencyKey string) (*Refund, error) {
+	body := map[string]any{"amount": amount, "currency": currency, "reason": reason}
+	if idempotencyKey != "" {
+		body["idempotency_key"] = idempotencyKey
+	}
+	var refund Refund
+	if err := c.http.do(ctx, http.MethodPost, "/payments/"+paymentID.String()+"/refunds", body, &refund); err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// Void cancels a payment that has not been captured
+func (c *PaymentClient) Void(ctx context.Context, paymentID uuid.UUID) (*Payment, error) {
+	var payment Payment
+	if err := c.http.do(ctx, http.MethodPost, "/payments/"+paymentID.String()+"/void", nil, &payment); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// CreatePayment records a new pending payment for an order
+func (c *PaymentClient) CreatePayment(ctx context.Context, orderID, userID uuid.UUID, amount float64, currency string, methodID *uuid.UUID) (*Payment, error) {
+	body := map[string]any{
+		"order_id":          orderID,
+		"user_id":           userID,
+		"amount":            amount,
+		"currency":          currency,
+		"payment_method_id": methodID,
+	}
+	var payment Payment
+	if err := c.http.do(ctx, http.MethodPost, "/payments", body, &payment); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// Authorize runs risk scoring and gateway authorization for a pending
+// payment. The returned payment stays pending when held for review.
+func (c *PaymentClient) Authorize(ctx context.Context, paymentID uuid.UUID) (*Payment, error) {
+	var result struct {
+		Payment Payment `json:"payment"`
+	}
+	if err := c.http.do(ctx, http.MethodPost, "/payments/"+paymentID.String()+"/authorize", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result.Payment, nil
+}
+
+// Capture captures an authorized payment
+func (c *PaymentClient) Capture(ctx context.Context, paymentID uuid.UUID) (*Payment, error) {
+	var payment Payment
+	if err := c.http.do(ctx, http.MethodPost, "/payments/"+paymentID.String()+"/capture", nil, &payment); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
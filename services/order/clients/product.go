@@ -0,0 +1,74 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProductClient talks to the product service
+type ProductClient struct {
+	http httpClient
+}
+
+// NewProductClient creates a client for the product service at baseURL
+func NewProductClient(baseURL string) *ProductClient {
+	return &ProductClient{http: newHTTPClient(baseURL)}
+}
+
+// FXRate is the rate the product service applied to a converted price
+type FXRate struct {
+	RateID        uuid.UUID `json:"rate_id"`
+	BaseCurrency  string    `json:"base_currency"`
+	QuoteCurrency string    `json:"quote_currency"`
+	Rate          float64   `json:"rate"`
+	EffectiveAt   time.Time `json:"effective_at"`
+}
+
+// Price is the price of a product variant resolved in a currency
+type Price struct {
+	ProductID      uuid.UUID `json:"product_id"`
+	VariantID      uuid.UUID `json:"variant_id"`
+	Name           string    `json:"name"`
+	SKU            *string   `json:"sku,omitempty"`
+	Status         string    `json:"status"`
+	Currency       string    `json:"currency"`
+	Price          float64   `json:"price"`
+	CompareAtPrice *float64  `json:"compare_at_price,omitempty"`
+	Source         string    `json:"source"`
+	BaseCurrency   string    `json:"base_currency"`
+	BasePrice      float64   `json:"base_price"`
+	FXRate         *FXRate   `json:"fx_rate,omitempty"`
+	// Components lists what each unit of a bundle contains
+	Components []PriceComponent `json:"components,omitempty"`
+}
+
+// PriceComponent is a product variant contained in each unit of a bundle
+type PriceComponent struct {
+	ProductID uuid.UUID `json:"product_id"`
+	VariantID uuid.UUID `json:"variant_id"`
+	Name      string    `json:"name"`
+	SKU       *string   `json:"sku,omitempty"`
+	Status    string    `json:"status"`
+	Quantity  int       `json:"quantity"`
+}
+
+// ResolvePrice returns the price of a product variant in currency as of at.
+// A nil variantID prices the product's default variant.
+func (c *ProductClient) ResolvePrice(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, currency string, at time.Time) (*Price, error) {
+	q := url.Values{}
+	if variantID != nil {
+		q.Set("variant_id", variantID.String())
+	}
+	q.Set("currency", currency)
+	q.Set("at", at.UTC().Format(time.RFC3339))
+
+	var price Price
+	if err := c.http.do(ctx, http.MethodGet, "/products/"+productID.String()+"/price?"+q.Encode(), nil, &price); err != nil {
+		return nil, err
+	}
+	return &price, nil
+}
@@ -59,3 +59,238 @@ CREATE INDEX idx_orders_created ON orders(created_at);
 CREATE INDEX idx_orders_order_number ON orders(order_number);
 CREATE INDEX idx_order_items_order ON order_items(order_id);
 CREATE INDEX idx_order_status_history_order ON order_status_history(order_id);
+
+CREATE TABLE IF NOT EXISTS order_flags (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id   UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    flag       VARCHAR(50) NOT NULL,
+    note       TEXT,
+    source     VARCHAR(50),  -- service or user that raised the flag
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    cleared_at TIMESTAMPTZ
+);
+
+-- Only one active flag of each kind per order
+CREATE UNIQUE INDEX idx_order_flags_active ON order_flags(order_id, flag) WHERE cleared_at IS NULL;
+CREATE INDEX idx_order_flags_flag ON order_flags(flag) WHERE cleared_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS order_fx_rates (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id       UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    base_currency  VARCHAR(3) NOT NULL,
+    quote_currency VARCHAR(3) NOT NULL,
+    rate           DECIMAL(18, 8) NOT NULL CHECK (rate > 0),
+    rate_id        UUID NOT NULL,  -- references product service fx_rates
+    effective_at   TIMESTAMPTZ NOT NULL,
+    locked_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(order_id, base_currency)
+);
+
+CREATE TABLE IF NOT EXISTS order_returns (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id        UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id         UUID NOT NULL,
+    rma_number      VARCHAR(50) NOT NULL UNIQUE,
+    status          VARCHAR(30) NOT NULL DEFAULT 'requested' CHECK (status IN (
+        'requested', 'approved', 'rejected', 'received', 'refunded'
+    )),
+    reason          TEXT,
+    currency        VARCHAR(3) NOT NULL,
+    refund_amount   DECIMAL(12, 2) CHECK (refund_amount >= 0),
+    refunded_amount DECIMAL(12, 2) NOT NULL DEFAULT 0 CHECK (refunded_amount >= 0),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS return_items (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    return_id         UUID NOT NULL REFERENCES order_returns(id) ON DELETE CASCADE,
+    order_item_id     UUID NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    product_id        UUID NOT NULL,
+    quantity          INT NOT NULL CHECK (quantity > 0),
+    unit_price        DECIMAL(12, 2) NOT NULL CHECK (unit_price >= 0),
+    reason            TEXT,
+    received_quantity INT NOT NULL DEFAULT 0 CHECK (received_quantity >= 0 AND received_quantity <= quantity),
+    disposition       VARCHAR(20) CHECK (disposition IN ('restock', 'write_off')),
+    warehouse_id      UUID,  -- references inventory service warehouse for restocked goods
+    received_at       TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS return_status_history (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    return_id  UUID NOT NULL REFERENCES order_returns(id) ON DELETE CASCADE,
+    status     VARCHAR(30) NOT NULL,
+    actor      VARCHAR(255),
+    note       TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_returns_order ON order_returns(order_id);
+CREATE INDEX idx_order_returns_status ON order_returns(status);
+CREATE INDEX idx_return_items_return ON return_items(return_id);
+CREATE INDEX idx_return_items_order_item ON return_items(order_item_id);
+CREATE INDEX idx_return_status_history_return ON return_status_history(return_id);
+CREATE INDEX idx_order_shipments_order ON order_shipments(order_id);
+
+-- Orders ship in parts, each shipment from one warehouse
+ALTER TABLE order_shipments ADD COLUMN IF NOT EXISTS warehouse_id UUID;  -- references inventory service warehouse the goods left from
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check CHECK (status IN (
+    'pending', 'confirmed', 'processing', 'partially_shipped', 'shipped', 'delivered', 'cancelled', 'refunded'
+));
+
+CREATE TABLE IF NOT EXISTS order_shipment_items (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    shipment_id   UUID NOT NULL REFERENCES order_shipments(id) ON DELETE CASCADE,
+    order_item_id UUID NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    product_id    UUID NOT NULL,
+    quantity      INT NOT NULL CHECK (quantity > 0),
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_shipment_items_shipment ON order_shipment_items(shipment_id);
+CREATE INDEX idx_order_shipment_items_order_item ON order_shipment_items(order_item_id);
+
+CREATE TABLE IF NOT EXISTS shipment_tracking_events (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    shipment_id UUID NOT NULL REFERENCES order_shipments(id) ON DELETE CASCADE,
+    carrier     VARCHAR(100) NOT NULL,
+    external_id VARCHAR(255) NOT NULL,  -- carrier event ID, de-duplicates polls and webhooks
+    code        VARCHAR(50) NOT NULL,
+    description TEXT,
+    location    VARCHAR(255),
+    occurred_at TIMESTAMPTZ NOT NULL,
+    source      VARCHAR(20) NOT NULL CHECK (source IN ('poll', 'webhook')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(shipment_id, external_id)
+);
+
+CREATE INDEX idx_shipment_tracking_events_shipment ON shipment_tracking_events(shipment_id, occurred_at);
+CREATE INDEX idx_order_shipments_tracking ON order_shipments(carrier, tracking_no);
+
+-- Carriers return a printable label when a shipment is booked
+ALTER TABLE order_shipments ADD COLUMN IF NOT EXISTS label_url TEXT;
+
+CREATE TABLE IF NOT EXISTS order_cancellation_tasks (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id   UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    task       VARCHAR(30) NOT NULL CHECK (task IN ('release_stock', 'restock_shipments', 'settle_payments')),
+    status     VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'failed')),
+    attempts   INT NOT NULL DEFAULT 0 CHECK (attempts >= 0),
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(order_id, task)
+);
+
+CREATE INDEX idx_order_cancellation_tasks_status ON order_cancellation_tasks(status, updated_at);
+
+-- Shipments of a cancelled order that never left are cancelled with it
+ALTER TABLE order_shipments ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS order_revisions (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id         UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    revision         INT NOT NULL CHECK (revision > 0),
+    actor            VARCHAR(100),
+    reason           TEXT,
+    items            JSONB NOT NULL,
+    subtotal         DECIMAL(12, 2) NOT NULL CHECK (subtotal >= 0),
+    tax_amount       DECIMAL(12, 2) NOT NULL DEFAULT 0 CHECK (tax_amount >= 0),
+    shipping_amount  DECIMAL(12, 2) NOT NULL DEFAULT 0 CHECK (shipping_amount >= 0),
+    discount_amount  DECIMAL(12, 2) NOT NULL DEFAULT 0 CHECK (discount_amount >= 0),
+    total            DECIMAL(12, 2) NOT NULL CHECK (total >= 0),
+    shipping_address JSONB,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(order_id, revision)
+);
+
+-- Set while an edit moves reservations and payments, so the order is not
+-- edited, shipped or cancelled in between
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS editing_since TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS user_addresses (
+    id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id             UUID NOT NULL,  -- references user/auth service (external ID)
+    label               VARCHAR(100),
+    address             JSONB NOT NULL,
+    is_default_shipping BOOLEAN NOT NULL DEFAULT FALSE,
+    is_default_billing  BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_addresses_user ON user_addresses(user_id);
+CREATE UNIQUE INDEX idx_user_addresses_default_shipping ON user_addresses(user_id) WHERE is_default_shipping;
+CREATE UNIQUE INDEX idx_user_addresses_default_billing ON user_addresses(user_id) WHERE is_default_billing;
+
+CREATE INDEX idx_orders_created_id ON orders(created_at, id);
+CREATE INDEX idx_orders_user_created ON orders(user_id, created_at, id);
+CREATE INDEX idx_orders_total ON orders(total, created_at, id);
+CREATE INDEX idx_orders_shipping_country ON orders((shipping_address->>'country'));
+CREATE INDEX idx_order_items_sku ON order_items(sku);
+CREATE INDEX idx_order_items_product ON order_items(product_id);
+
+CREATE TABLE IF NOT EXISTS invoice_series (
+    series      VARCHAR(20) PRIMARY KEY,
+    last_number INT NOT NULL CHECK (last_number > 0)
+);
+
+CREATE TABLE IF NOT EXISTS invoices (
+    id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id            UUID NOT NULL REFERENCES orders(id),
+    order_number        VARCHAR(50) NOT NULL,
+    kind                VARCHAR(20) NOT NULL CHECK (kind IN ('invoice', 'credit_note')),
+    series              VARCHAR(20) NOT NULL REFERENCES invoice_series(series),
+    number              INT NOT NULL CHECK (number > 0),
+    document_number     VARCHAR(40) NOT NULL UNIQUE,
+    credited_invoice_id UUID REFERENCES invoices(id),
+    refund_id           UUID UNIQUE,  -- references payment service refund
+    currency            VARCHAR(3) NOT NULL,
+    seller              JSONB NOT NULL,
+    buyer               JSONB NOT NULL,
+    lines               JSONB NOT NULL,
+    tax_breakdown       JSONB NOT NULL,
+    net_amount          DECIMAL(12, 2) NOT NULL,
+    tax_amount          DECIMAL(12, 2) NOT NULL CHECK (tax_amount >= 0),
+    total               DECIMAL(12, 2) NOT NULL,
+    issued_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(series, number),
+    CHECK ((kind = 'credit_note') = (credited_invoice_id IS NOT NULL))
+);
+
+CREATE INDEX idx_invoices_order ON invoices(order_id);
+CREATE UNIQUE INDEX idx_invoices_one_per_order ON invoices(order_id) WHERE kind = 'invoice';
+
+CREATE TABLE IF NOT EXISTS invoice_documents (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    invoice_id   UUID NOT NULL REFERENCES invoices(id),
+    format       VARCHAR(10) NOT NULL CHECK (format IN ('pdf', 'html')),
+    content_type VARCHAR(100) NOT NULL,
+    content      BYTEA NOT NULL,
+    sha256       VARCHAR(64) NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(invoice_id, format)
+);
+
+-- Order, shipment and return lines refer to the product variant they were
+-- sold as. Lines that predate variants belong to the product's default
+-- variant, whose ID equals the product ID.
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_id UUID;  -- references product service (external ID)
+UPDATE order_items SET variant_id = product_id WHERE variant_id IS NULL;
+ALTER TABLE order_items ALTER COLUMN variant_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_order_items_variant ON order_items(variant_id);
+
+ALTER TABLE order_shipment_items ADD COLUMN IF NOT EXISTS variant_id UUID;
+UPDATE order_shipment_items SET variant_id = product_id WHERE variant_id IS NULL;
+ALTER TABLE order_shipment_items ALTER COLUMN variant_id SET NOT NULL;
+
+ALTER TABLE return_items ADD COLUMN IF NOT EXISTS variant_id UUID;
+UPDATE return_items SET variant_id = product_id WHERE variant_id IS NULL;
+ALTER TABLE return_items ALTER COLUMN variant_id SET NOT NULL;
+
+-- Bundle lines list the component variants and quantities each unit
+-- contains; stock is reserved and moved for those instead of the bundle
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS components JSONB;
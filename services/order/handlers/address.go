@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+
+	"main.go/services/order/service"
+)
+
+// AddressHandler exposes users' address books over HTTP
+type AddressHandler struct {
+	addresses *service.AddressService
+}
+
+// NewAddressHandler creates a new AddressHandler
+func NewAddressHandler(addresses *service.AddressService) *AddressHandler {
+	return &AddressHandler{addresses: addresses}
+}
+
+// RegisterRoutes registers the address book routes on mux
+func (h *AddressHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /users/{id}/addresses", h.create)
+	mux.HandleFunc("GET /users/{id}/addresses", h.listByUser)
+	mux.HandleFunc("GET /addresses/{id}", h.get)
+	mux.HandleFunc("PUT /addresses/{id}", h.update)
+	mux.HandleFunc("DELETE /addresses/{id}", h.delete)
+}
+
+func (h *AddressHandler) create(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.SaveAddressInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	address, err := h.addresses.Create(r.Context(), userID, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, address)
+}
+
+func (h *AddressHandler) listByUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	addresses, err := h.addresses.ListByUser(r.Context(), userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, addresses)
+}
+
+func (h *AddressHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	address, err := h.addresses.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, address)
+}
+
+func (h *AddressHandler) update(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.SaveAddressInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	address, err := h.addresses.Update(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, address)
+}
+
+func (h *AddressHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.addresses.Delete(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
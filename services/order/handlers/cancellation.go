@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+
+	"main.go/services/order/service"
+)
+
+// CancellationHandler exposes order cancellation over HTTP
+type CancellationHandler struct {
+	cancellations *service.CancellationService
+}
+
+// NewCancellationHandler creates a new CancellationHandler
+func NewCancellationHandler(cancellations *service.CancellationService) *CancellationHandler {
+	return &CancellationHandler{cancellations: cancellations}
+}
+
+// RegisterRoutes registers the cancellation routes on mux
+func (h *CancellationHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /orders/{id}/cancel", h.cancel)
+	mux.HandleFunc("GET /orders/{id}/cancellation", h.get)
+	mux.HandleFunc("POST /orders/{id}/cancellation/retry", h.retry)
+}
+
+func (h *CancellationHandler) cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.CancelOrderInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := h.cancellations.Cancel(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *CancellationHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := h.cancellations.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *CancellationHandler) retry(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := h.cancellations.Retry(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"main.go/services/order/service"
+)
+
+// EditHandler exposes order edits and revisions over HTTP
+type EditHandler struct {
+	edits *service.EditService
+}
+
+// NewEditHandler creates a new EditHandler
+func NewEditHandler(edits *service.EditService) *EditHandler {
+	return &EditHandler{edits: edits}
+}
+
+// RegisterRoutes registers the edit routes on mux
+func (h *EditHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /orders/{id}/edits", h.edit)
+	mux.HandleFunc("GET /orders/{id}/revisions", h.listRevisions)
+}
+
+func (h *EditHandler) edit(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.EditOrderInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := h.edits.Edit(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *EditHandler) listRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	revisions, err := h.edits.ListRevisions(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, revisions)
+}
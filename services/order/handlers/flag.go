@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+
+	"main.go/services/order/service"
+)
+
+// FlagHandler exposes order flags over HTTP
+type FlagHandler struct {
+	flags *service.FlagService
+}
+
+// NewFlagHandler creates a new FlagHandler
+func NewFlagHandler(flags *service.FlagService) *FlagHandler {
+	return &FlagHandler{flags: flags}
+}
+
+// RegisterRoutes registers the flag routes on mux
+func (h *FlagHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /orders/flagged", h.listFlagged)
+	mux.HandleFunc("GET /orders/{id}/flags", h.list)
+	mux.HandleFunc("POST /orders/{id}/flags", h.set)
+	mux.HandleFunc("DELETE /orders/{id}/flags/{flag}", h.clear)
+}
+
+func (h *FlagHandler) listFlagged(w http.ResponseWriter, r *http.Request) {
+	orders, err := h.flags.ListOrdersWithFlag(r.Context(), r.URL.Query().Get("flag"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, orders)
+}
+
+func (h *FlagHandler) list(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	flags, err := h.flags.ListByOrder(r.Context(), orderID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, flags)
+}
+
+func (h *FlagHandler) set(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.SetFlagInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	flag, err := h.flags.Set(r.Context(), orderID, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, flag)
+}
+
+func (h *FlagHandler) clear(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.flags.Clear(r.Context(), orderID, r.PathValue("flag")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
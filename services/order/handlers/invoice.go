@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"main.go/services/order/service"
+)
+
+// InvoiceHandler exposes invoices, credit notes and their documents over HTTP
+type InvoiceHandler struct {
+	invoices *service.InvoiceService
+}
+
+// NewInvoiceHandler creates a new InvoiceHandler
+func NewInvoiceHandler(invoices *service.InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{invoices: invoices}
+}
+
+// RegisterRoutes registers the invoice routes on mux
+func (h *InvoiceHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /orders/{id}/invoice", h.issue)
+	mux.HandleFunc("POST /orders/{id}/credit-notes", h.issueCreditNotes)
+	mux.HandleFunc("GET /orders/{id}/invoices", h.listByOrder)
+	mux.HandleFunc("GET /invoices/{id}", h.get)
+	mux.HandleFunc("GET /invoices/{id}/pdf", h.download("pdf"))
+	mux.HandleFunc("GET /invoices/{id}/html", h.download("html"))
+}
+
+func (h *InvoiceHandler) issue(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	inv, err := h.invoices.Issue(r.Context(), orderID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, inv)
+}
+
+func (h *InvoiceHandler) issueCreditNotes(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	notes, err := h.invoices.IssueCreditNotes(r.Context(), orderID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, notes)
+}
+
+func (h *InvoiceHandler) listByOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	invoices, err := h.invoices.ListByOrder(r.Context(), orderID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, invoices)
+}
+
+func (h *InvoiceHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	inv, err := h.invoices.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, inv)
+}
+
+// download serves the stored document of an invoice in format
+func (h *InvoiceHandler) download(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathUUID(r, "id")
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		inv, doc, err := h.invoices.Document(r.Context(), id, format)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", doc.ContentType)
+		w.Header().Set("Content-Disposition", `inline; filename="`+inv.DocumentNumber+"."+format+`"`)
+		w.Header().Set("ETag", `"`+doc.SHA256+`"`)
+		if _, err := w.Write(doc.Content); err != nil {
+			log.Printf("failed to write invoice document: %v", err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"main.go/services/order/service"
+)
+
+// OrderHandler exposes orders over HTTP
+type OrderHandler struct {
+	orders *service.OrderService
+}
+
+// NewOrderHandler creates a new OrderHandler
+func NewOrderHandler(orders *service.OrderService) *OrderHandler {
+	return &OrderHandler{orders: orders}
+}
+
+// RegisterRoutes registers the order routes on mux
+func (h *OrderHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /orders", h.place)
+	mux.HandleFunc("GET /orders/{id}", h.get)
+	mux.HandleFunc("GET /orders/{id}/items", h.listItems)
+	mux.HandleFunc("GET /orders/{id}/history", h.listHistory)
+	mux.HandleFunc("GET /users/{id}/purchases/{product_id}", h.getPurchase)
+	mux.HandleFunc("GET /co-purchases", h.coPurchases)
+}
+
+func (h *OrderHandler) place(w http.ResponseWriter, r *http.Request) {
+	var in service.PlaceOrderInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	order, err := h.orders.Place(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, order)
+}
+
+func (h *OrderHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	order, err := h.orders.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, order)
+}
+
+func (h *OrderHandler) listItems(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	items, err := h.orders.ListItems(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h *OrderHandler) listHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	history, err := h.orders.ListStatusHistory(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+func (h *OrderHandler) getPurchase(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	productID, err := pathUUID(r, "product_id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	purchase, err := h.orders.ProductPurchase(r.Context(), userID, productID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, purchase)
+}
+
+// coPurchases pages through products bought together in at least
+// ?min_orders= orders placed since the RFC 3339 ?since=, after the
+// ?after_product= and ?after_related= position
+func (h *OrderHandler) coPurchases(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var (
+		since                      time.Time
+		afterProduct, afterRelated uuid.UUID
+		minOrders, limit           int
+		err                        error
+	)
+	if v := q.Get("since"); v != "" {
+		if since, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			writeError(w, fmt.Errorf("%w: since must be an RFC 3339 timestamp", service.ErrValidation))
+			return
+		}
+	}
+	if v := q.Get("after_product"); v != "" {
+		if afterProduct, err = uuid.Parse(v); err != nil {
+			writeError(w, fmt.Errorf("%w: after_product must be a UUID", service.ErrValidation))
+			return
+		}
+	}
+	if v := q.Get("after_related"); v != "" {
+		if afterRelated, err = uuid.Parse(v); err != nil {
+			writeError(w, fmt.Errorf("%w: after_related must be a UUID", service.ErrValidation))
+			return
+		}
+	}
+	if v := q.Get("min_orders"); v != "" {
+		if minOrders, err = strconv.Atoi(v); err != nil {
+			writeError(w, fmt.Errorf("%w: min_orders must be an integer", service.ErrValidation))
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, fmt.Errorf("%w: limit must be an integer", service.ErrValidation))
+			return
+		}
+	}
+	pairs, err := h.orders.CoPurchases(r.Context(), since, minOrders, afterProduct, afterRelated, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pairs)
+}
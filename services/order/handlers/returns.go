@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"main.go/services/order/models"
+	"main.go/services/order/service"
+)
+
+// ReturnHandler exposes returns (RMAs) over HTTP
+type ReturnHandler struct {
+	returns *service.ReturnService
+}
+
+// NewReturnHandler creates a new ReturnHandler
+func NewReturnHandler(returns *service.ReturnService) *ReturnHandler {
+	return &ReturnHandler{returns: returns}
+}
+
+// RegisterRoutes registers the return routes on mux
+func (h *ReturnHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /orders/{id}/returns", h.request)
+	mux.HandleFunc("GET /orders/{id}/returns", h.listByOrder)
+	mux.HandleFunc("GET /returns/{id}", h.get)
+	mux.HandleFunc("GET /returns/{id}/history", h.listHistory)
+	mux.HandleFunc("POST /returns/{id}/approve", h.decide(h.returns.Approve))
+	mux.HandleFunc("POST /returns/{id}/reject", h.decide(h.returns.Reject))
+	mux.HandleFunc("POST /returns/{id}/receive", h.receive)
+	mux.HandleFunc("POST /returns/{id}/refund", h.decide(h.returns.Refund))
+}
+
+func (h *ReturnHandler) request(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.RequestReturnInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	ret, err := h.returns.Request(r.Context(), orderID, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, ret)
+}
+
+func (h *ReturnHandler) listByOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	returns, err := h.returns.ListByOrder(r.Context(), orderID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, returns)
+}
+
+func (h *ReturnHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ret, err := h.returns.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ret)
+}
+
+func (h *ReturnHandler) listHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	history, err := h.returns.ListHistory(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+func (h *ReturnHandler) receive(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.ReceiveReturnInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	ret, err := h.returns.Receive(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ret)
+}
+
+// decide adapts a staff decision on a return to an HTTP handler
+func (h *ReturnHandler) decide(fn func(context.Context, uuid.UUID, service.ReturnDecisionInput) (*models.OrderReturn, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathUUID(r, "id")
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		var in service.ReturnDecisionInput
+		if err := decodeJSON(w, r, &in); err != nil {
+			writeError(w, err)
+			return
+		}
+		ret, err := fn(r.Context(), id, in)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, ret)
+	}
+}
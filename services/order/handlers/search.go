@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"main.go/services/order/models"
+	"main.go/services/order/service"
+)
+
+// exportColumns are the CSV columns of an order export
+var exportColumns = []string{
+	"id", "order_number", "user_id", "status", "currency", "subtotal", "tax_amount",
+	"shipping_amount", "discount_amount", "total", "shipping_country", "created_at", "updated_at",
+}
+
+// SearchHandler exposes order search and export over HTTP
+type SearchHandler struct {
+	search *service.SearchService
+}
+
+// NewSearchHandler creates a new SearchHandler
+func NewSearchHandler(search *service.SearchService) *SearchHandler {
+	return &SearchHandler{search: search}
+}
+
+// RegisterRoutes registers the search routes on mux
+func (h *SearchHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /orders", h.list)
+	mux.HandleFunc("GET /orders/export", h.export)
+}
+
+func (h *SearchHandler) list(w http.ResponseWriter, r *http.Request) {
+	in, err := searchInput(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	page, err := h.search.Search(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *SearchHandler) export(w http.ResponseWriter, r *http.Request) {
+	in, err := searchInput(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var write func(*models.Order) error
+	finish := func() error { return nil }
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "csv":
+		cw := csv.NewWriter(w)
+		started := false
+		header := func() error {
+			started = true
+			startExport(w, "text/csv", "orders.csv")
+			return cw.Write(exportColumns)
+		}
+		write = func(o *models.Order) error {
+			if !started {
+				if err := header(); err != nil {
+					return err
+				}
+			}
+			return cw.Write(exportRow(o))
+		}
+		finish = func() error {
+			if !started {
+				if err := header(); err != nil {
+					return err
+				}
+			}
+			cw.Flush()
+			return cw.Error()
+		}
+	case "json":
+		enc := json.NewEncoder(w)
+		n := 0
+		write = func(o *models.Order) error {
+			sep := ","
+			if n == 0 {
+				startExport(w, "application/json", "orders.json")
+				sep = "["
+			}
+			n++
+			if _, err := w.Write([]byte(sep)); err != nil {
+				return err
+			}
+			return enc.Encode(o)
+		}
+		finish = func() error {
+			if n == 0 {
+				startExport(w, "application/json", "orders.json")
+				_, err := w.Write([]byte("[]\n"))
+				return err
+			}
+			_, err := w.Write([]byte("]\n"))
+			return err
+		}
+	default:
+		writeError(w, fmt.Errorf("%w: format must be csv or json", service.ErrValidation))
+		return
+	}
+
+	// Errors before the first row still get a proper error response; once
+	// rows are streamed the response can only be cut short
+	rows := 0
+	err = h.search.Export(r.Context(), in, func(o *models.Order) error {
+		rows++
+		return write(o)
+	})
+	if err != nil && rows == 0 {
+		writeError(w, err)
+		return
+	}
+	if err == nil {
+		err = finish()
+	}
+	if err != nil {
+		log.Printf("order export aborted after %d rows: %v", rows, err)
+	}
+}
+
+// startExport writes the headers of a file download
+func startExport(w http.ResponseWriter, contentType, filename string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+// exportRow renders an order as a CSV row matching exportColumns
+func exportRow(o *models.Order) []string {
+	country := ""
+	if o.ShippingAddress != nil {
+		country = o.ShippingAddress.Country
+	}
+	money := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	return []string{
+		o.ID.String(), o.OrderNumber, o.UserID.String(), string(o.Status), o.Currency,
+		money(o.Subtotal), money(o.TaxAmount), money(o.ShippingAmount), money(o.DiscountAmount), money(o.Total),
+		country, o.CreatedAt.UTC().Format(time.RFC3339), o.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// searchInput parses the query parameters of an order search
+func searchInput(r *http.Request) (service.SearchOrdersInput, error) {
+	q := r.URL.Query()
+	in := service.SearchOrdersInput{
+		OrderNumber:     q.Get("order_number"),
+		Currency:        q.Get("currency"),
+		SKU:             q.Get("sku"),
+		ShippingCountry: q.Get("shipping_country"),
+		Sort:            q.Get("sort"),
+		Cursor:          q.Get("cursor"),
+	}
+	for _, v := range q["status"] {
+		for _, status := range strings.Split(v, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				in.Statuses = append(in.Statuses, models.OrderStatus(status))
+			}
+		}
+	}
+
+	var err error
+	if in.UserID, err = queryUUID(r, "user_id"); err != nil {
+		return in, err
+	}
+	if in.ProductID, err = queryUUID(r, "product_id"); err != nil {
+		return in, err
+	}
+	if in.CreatedFrom, err = queryTime(r, "created_from"); err != nil {
+		return in, err
+	}
+	if in.CreatedTo, err = queryTime(r, "created_to"); err != nil {
+		return in, err
+	}
+	if in.MinTotal, err = queryFloat(r, "min_total"); err != nil {
+		return in, err
+	}
+	if in.MaxTotal, err = queryFloat(r, "max_total"); err != nil {
+		return in, err
+	}
+	if v := q.Get("limit"); v != "" {
+		if in.Limit, err = strconv.Atoi(v); err != nil {
+			return in, fmt.Errorf("%w: limit must be an integer", service.ErrValidation)
+		}
+	}
+	return in, nil
+}
+
+// queryUUID parses an optional UUID query parameter
+func queryUUID(r *http.Request, name string) (*uuid.UUID, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be a UUID", service.ErrValidation, name)
+	}
+	return &id, nil
+}
+
+// queryTime parses an optional RFC 3339 query parameter
+func queryTime(r *http.Request, name string) (*time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be an RFC 3339 timestamp", service.ErrValidation, name)
+	}
+	return &t, nil
+}
+
+// queryFloat parses an optional decimal query parameter
+func queryFloat(r *http.Request, name string) (*float64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be a number", service.ErrValidation, name)
+	}
+	return &f, nil
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+
+	"main.go/services/order/service"
+)
+
+// ShipmentHandler exposes order shipments over HTTP
+type ShipmentHandler struct {
+	shipments *service.ShipmentService
+}
+
+// NewShipmentHandler creates a new ShipmentHandler
+func NewShipmentHandler(shipments *service.ShipmentService) *ShipmentHandler {
+	return &ShipmentHandler{shipments: shipments}
+}
+
+// RegisterRoutes registers the shipment routes on mux
+func (h *ShipmentHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /orders/{id}/shipments", h.create)
+	mux.HandleFunc("GET /orders/{id}/shipments", h.listByOrder)
+	mux.HandleFunc("GET /shipments/{id}", h.get)
+	mux.HandleFunc("PUT /shipments/{id}/tracking", h.setTracking)
+	mux.HandleFunc("POST /shipments/{id}/deliver", h.deliver)
+}
+
+func (h *ShipmentHandler) create(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.CreateShipmentInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	shipment, err := h.shipments.Create(r.Context(), orderID, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, shipment)
+}
+
+func (h *ShipmentHandler) listByOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	shipments, err := h.shipments.ListByOrder(r.Context(), orderID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, shipments)
+}
+
+func (h *ShipmentHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	shipment, err := h.shipments.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, shipment)
+}
+
+func (h *ShipmentHandler) setTracking(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.TrackingInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	shipment, err := h.shipments.SetTracking(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, shipment)
+}
+
+func (h *ShipmentHandler) deliver(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.DeliveryInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	shipment, err := h.shipments.MarkDelivered(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, shipment)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"main.go/services/order/carrier"
+	"main.go/services/order/service"
+)
+
+// signatureHeader carries the hex HMAC-SHA256 of a webhook body
+const signatureHeader = "X-Carrier-Signature"
+
+// TrackingHandler exposes carrier labels, tracking events and carrier
+// webhooks over HTTP
+type TrackingHandler struct {
+	shipments     *service.ShipmentService
+	webhookSecret []byte
+}
+
+// NewTrackingHandler creates a new TrackingHandler. Webhooks are rejected
+// unless webhookSecret is set.
+func NewTrackingHandler(shipments *service.ShipmentService, webhookSecret string) *TrackingHandler {
+	return &TrackingHandler{shipments: shipments, webhookSecret: []byte(webhookSecret)}
+}
+
+// RegisterRoutes registers the tracking routes on mux
+func (h *TrackingHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /shipments/{id}/label", h.createLabel)
+	mux.HandleFunc("DELETE /shipments/{id}/label", h.cancelLabel)
+	mux.HandleFunc("GET /shipments/{id}/events", h.listEvents)
+	mux.HandleFunc("POST /carriers/{carrier}/webhook", h.webhook)
+}
+
+func (h *TrackingHandler) createLabel(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.LabelInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	shipment, err := h.shipments.CreateLabel(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, shipment)
+}
+
+func (h *TrackingHandler) cancelLabel(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	shipment, err := h.shipments.CancelLabel(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, shipment)
+}
+
+func (h *TrackingHandler) listEvents(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	events, err := h.shipments.ListTrackingEvents(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+func (h *TrackingHandler) webhook(w http.ResponseWriter, r *http.Request) {
+	if len(h.webhookSecret) == 0 {
+		writeJSON(w, http.StatusForbidden, errorResponse{Error: "carrier webhooks are not configured"})
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: invalid request body: %v", service.ErrValidation, err))
+		return
+	}
+	if !h.validSignature(body, r.Header.Get(signatureHeader)) {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid signature"})
+		return
+	}
+
+	var payload carrier.WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		writeError(w, fmt.Errorf("%w: invalid request body: %v", service.ErrValidation, err))
+		return
+	}
+	shipment, err := h.shipments.HandleWebhook(r.Context(), r.PathValue("carrier"), payload)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, shipment)
+}
+
+// validSignature checks the hex HMAC-SHA256 of body against signature
+func (h *TrackingHandler) validSignature(body []byte, signature string) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, h.webhookSecret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
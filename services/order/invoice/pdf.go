@@ -0,0 +1,248 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"main.go/services/order/models"
+)
+
+// A4 page geometry in PDF points
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+	margin     = 50.0
+)
+
+// Table column right edges; the description column starts at the margin
+const (
+	colDescriptionEnd = 250.0
+	colQuantity       = 290.0
+	colUnitPrice      = 350.0
+	colNet            = 410.0
+	colTaxRate        = 455.0
+	colTax            = 500.0
+	colGross          = pageWidth - margin
+)
+
+// helveticaWidths holds the advance widths of printable ASCII in Helvetica,
+// in thousandths of the font size. Bold text is measured with the same
+// table, which is close enough for the digits used in aligned columns.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// winAnsi encodes text for the standard PDF fonts, replacing characters
+// they cannot show
+var winAnsi = encoding.ReplaceUnsupported(charmap.Windows1252.NewEncoder())
+
+// pdfDoc lays out text on A4 pages and serializes them as a PDF using the
+// built-in Helvetica fonts, so no font files need to be embedded
+type pdfDoc struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func newPDFDoc() *pdfDoc {
+	d := &pdfDoc{}
+	d.newPage()
+	return d
+}
+
+func (d *pdfDoc) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - margin
+}
+
+// room starts a new page unless h points fit above the bottom margin. It
+// reports whether a page was started.
+func (d *pdfDoc) room(h float64) bool {
+	if d.y-h >= margin {
+		return false
+	}
+	d.newPage()
+	return true
+}
+
+// text draws s with its left edge at x on the current line
+func (d *pdfDoc) text(x, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.pages[len(d.pages)-1], "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, d.y, escapePDF(s))
+}
+
+// textRight draws s with its right edge at right on the current line
+func (d *pdfDoc) textRight(right, size float64, bold bool, s string) {
+	d.text(right-textWidth(s, size), size, bold, s)
+}
+
+// rule draws a horizontal line across the page just below the current line
+func (d *pdfDoc) rule() {
+	fmt.Fprintf(d.pages[len(d.pages)-1], "0.5 w %.2f %.2f m %.2f %.2f l S\n", margin, d.y-4, pageWidth-margin, d.y-4)
+}
+
+// bytes serializes the document
+func (d *pdfDoc) bytes(title string) []byte {
+	var out bytes.Buffer
+	offsets := []int{0}
+	obj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets)-1, body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	obj(fmt.Sprintf("<< /Title (%s) /Producer (order-service) >>", escapePDF(title)))
+	for i, content := range d.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 7+2*i))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets))
+	for _, off := range offsets[1:] {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets), xref)
+	return out.Bytes()
+}
+
+// escapePDF encodes s as the body of a PDF literal string
+func escapePDF(s string) string {
+	encoded, err := winAnsi.String(s)
+	if err != nil {
+		encoded = s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`, "\r", " ", "\n", " ")
+	return r.Replace(encoded)
+}
+
+// textWidth measures s in points at the given font size
+func textWidth(s string, size float64) float64 {
+	w := 0
+	for _, c := range s {
+		if c >= 32 && c < 127 {
+			w += helveticaWidths[c-32]
+		} else {
+			w += 556
+		}
+	}
+	return float64(w) * size / 1000
+}
+
+// truncate shortens s with an ellipsis so it fits in width points
+func truncate(s string, size, width float64) string {
+	if textWidth(s, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// RenderPDF renders inv as a single or multi-page A4 PDF. credited is the
+// document number of the invoice a credit note refers to.
+func RenderPDF(inv *models.Invoice, credited string) ([]byte, error) {
+	v := newView(inv, credited)
+	d := newPDFDoc()
+
+	d.text(margin, 18, true, v.Title+" "+v.DocumentNumber)
+	d.y -= 18
+	meta := "Issued " + v.IssuedAt + "  ·  Order " + v.OrderNumber
+	if v.Credits != "" {
+		meta += "  ·  Credits invoice " + v.Credits
+	}
+	d.text(margin, 9, false, meta)
+	d.y -= 30
+
+	top := d.y
+	lowest := d.y
+	for i, p := range []party{v.Seller, v.Buyer} {
+		x := margin + float64(i)*270
+		d.y = top
+		d.text(x, 10, true, p.Name)
+		d.y -= 13
+		lines := append([]string(nil), p.AddressLines...)
+		if p.TaxID != "" {
+			lines = append(lines, "Tax ID: "+p.TaxID)
+		}
+		if p.Email != "" {
+			lines = append(lines, p.Email)
+		}
+		for _, l := range lines {
+			d.text(x, 9, false, truncate(l, 9, 250))
+			d.y -= 12
+		}
+		lowest = min(lowest, d.y)
+	}
+	d.y = lowest - 20
+
+	header := func() {
+		d.text(margin, 9, true, "Description")
+		d.textRight(colQuantity, 9, true, "Qty")
+		d.textRight(colUnitPrice, 9, true, "Unit price")
+		d.textRight(colNet, 9, true, "Net")
+		d.textRight(colTaxRate, 9, true, "Tax rate")
+		d.textRight(colTax, 9, true, "Tax")
+		d.textRight(colGross, 9, true, "Gross")
+		d.rule()
+		d.y -= 16
+	}
+	header()
+	for _, l := range v.Lines {
+		if d.room(14) {
+			header()
+		}
+		d.text(margin, 9, false, truncate(l.Description, 9, colDescriptionEnd-margin))
+		d.textRight(colQuantity, 9, false, l.Quantity)
+		d.textRight(colUnitPrice, 9, false, l.UnitPrice)
+		d.textRight(colNet, 9, false, l.NetAmount)
+		d.textRight(colTaxRate, 9, false, l.TaxRate)
+		d.textRight(colTax, 9, false, l.TaxAmount)
+		d.textRight(colGross, 9, false, l.GrossAmount)
+		d.y -= 14
+	}
+	d.y -= 10
+
+	d.room(float64(len(v.Taxes)+5) * 14)
+	d.text(colTaxRate-90, 9, true, "Tax rate")
+	d.textRight(colTax, 9, true, "Taxable")
+	d.textRight(colGross, 9, true, "Tax")
+	d.y -= 14
+	for _, t := range v.Taxes {
+		d.text(colTaxRate-90, 9, false, t.Rate)
+		d.textRight(colTax, 9, false, t.TaxableAmount)
+		d.textRight(colGross, 9, false, t.TaxAmount)
+		d.y -= 14
+	}
+	d.y -= 10
+	for _, row := range [][2]string{{"Net", v.NetAmount}, {"Tax", v.TaxAmount}} {
+		d.text(colTaxRate-90, 9, false, row[0])
+		d.textRight(colGross, 9, false, row[1]+" "+v.Currency)
+		d.y -= 14
+	}
+	d.text(colTaxRate-90, 11, true, "Total")
+	d.textRight(colGross, 11, true, v.Total+" "+v.Currency)
+
+	return d.bytes(v.Title + " " + v.DocumentNumber), nil
+}
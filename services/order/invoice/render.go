@@ -0,0 +1,171 @@
+// Package invoice renders invoices and credit notes to HTML and PDF without
+// external tools
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strconv"
+
+	"main.go/services/order/models"
+)
+
+// view is an invoice with every value formatted for printing
+type view struct {
+	Title          string
+	DocumentNumber string
+	IssuedAt       string
+	OrderNumber    string
+	Credits        string
+	Currency       string
+	Seller         party
+	Buyer          party
+	Lines          []line
+	Taxes          []taxLine
+	NetAmount      string
+	TaxAmount      string
+	Total          string
+}
+
+type party struct {
+	Name         string
+	TaxID        string
+	AddressLines []string
+	Email        string
+}
+
+type line struct {
+	Description string
+	Quantity    string
+	UnitPrice   string
+	NetAmount   string
+	TaxRate     string
+	TaxAmount   string
+	GrossAmount string
+}
+
+type taxLine struct {
+	Rate          string
+	TaxableAmount string
+	TaxAmount     string
+}
+
+// newView formats inv for printing. credited is the document number of the
+// invoice a credit note refers to.
+func newView(inv *models.Invoice, credited string) view {
+	v := view{
+		Title:          "Invoice",
+		DocumentNumber: inv.DocumentNumber,
+		IssuedAt:       inv.IssuedAt.UTC().Format("2006-01-02"),
+		OrderNumber:    inv.OrderNumber,
+		Currency:       inv.Currency,
+		Seller:         newParty(inv.Seller),
+		Buyer:          newParty(inv.Buyer),
+		NetAmount:      money(inv.NetAmount),
+		TaxAmount:      money(inv.TaxAmount),
+		Total:          money(inv.Total),
+	}
+	if inv.Kind == models.InvoiceKindCreditNote {
+		v.Title = "Credit Note"
+		v.Credits = credited
+	}
+	for _, l := range inv.Lines {
+		desc := l.Description
+		if l.SKU != nil {
+			desc += " (" + *l.SKU + ")"
+		}
+		v.Lines = append(v.Lines, line{
+			Description: desc,
+			Quantity:    strconv.Itoa(l.Quantity),
+			UnitPrice:   money(l.UnitPrice),
+			NetAmount:   money(l.NetAmount),
+			TaxRate:     percent(l.TaxRate),
+			TaxAmount:   money(l.TaxAmount),
+			GrossAmount: money(l.GrossAmount),
+		})
+	}
+	for _, t := range inv.TaxBreakdown {
+		v.Taxes = append(v.Taxes, taxLine{
+			Rate:          percent(t.Rate),
+			TaxableAmount: money(t.TaxableAmount),
+			TaxAmount:     money(t.TaxAmount),
+		})
+	}
+	return v
+}
+
+func newParty(p models.InvoiceParty) party {
+	out := party{Name: p.Name, AddressLines: p.AddressLines}
+	if p.TaxID != nil {
+		out.TaxID = *p.TaxID
+	}
+	if p.Email != nil {
+		out.Email = *p.Email
+	}
+	return out
+}
+
+func money(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// percent formats a rate such as 0.19 as "19.00%"
+func percent(rate float64) string {
+	return strconv.FormatFloat(rate*100, 'f', 2, 64) + "%"
+}
+
+var htmlTemplate = template.Must(template.New("invoice").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} {{.DocumentNumber}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 12px; margin: 40px; color: #222; }
+h1 { font-size: 22px; margin: 0 0 4px; }
+table { border-collapse: collapse; width: 100%; margin-top: 16px; }
+th, td { padding: 4px 6px; border-bottom: 1px solid #ddd; }
+th { text-align: left; background: #f4f4f4; }
+td.num, th.num { text-align: right; }
+.parties { display: flex; justify-content: space-between; margin-top: 24px; }
+.totals { width: 40%; margin-left: auto; }
+</style>
+</head>
+<body>
+<h1>{{.Title}} {{.DocumentNumber}}</h1>
+<div>Issued {{.IssuedAt}} &middot; Order {{.OrderNumber}}{{if .Credits}} &middot; Credits invoice {{.Credits}}{{end}}</div>
+<div class="parties">
+{{template "party" .Seller}}
+{{template "party" .Buyer}}
+</div>
+<table>
+<tr><th>Description</th><th class="num">Qty</th><th class="num">Unit price</th><th class="num">Net</th><th class="num">Tax rate</th><th class="num">Tax</th><th class="num">Gross</th></tr>
+{{range .Lines}}<tr><td>{{.Description}}</td><td class="num">{{.Quantity}}</td><td class="num">{{.UnitPrice}}</td><td class="num">{{.NetAmount}}</td><td class="num">{{.TaxRate}}</td><td class="num">{{.TaxAmount}}</td><td class="num">{{.GrossAmount}}</td></tr>
+{{end}}</table>
+<table class="totals">
+<tr><th>Tax rate</th><th class="num">Taxable</th><th class="num">Tax</th></tr>
+{{range .Taxes}}<tr><td>{{.Rate}}</td><td class="num">{{.TaxableAmount}}</td><td class="num">{{.TaxAmount}}</td></tr>
+{{end}}</table>
+<table class="totals">
+<tr><td>Net</td><td class="num">{{.NetAmount}} {{.Currency}}</td></tr>
+<tr><td>Tax</td><td class="num">{{.TaxAmount}} {{.Currency}}</td></tr>
+<tr><th>Total</th><th class="num">{{.Total}} {{.Currency}}</th></tr>
+</table>
+</body>
+</html>
+{{define "party"}}<div>
+<strong>{{.Name}}</strong><br>
+{{range .AddressLines}}{{.}}<br>
+{{end}}{{if .TaxID}}Tax ID: {{.TaxID}}<br>
+{{end}}{{if .Email}}{{.Email}}<br>
+{{end}}</div>{{end}}`))
+
+// RenderHTML renders inv as a standalone HTML page. credited is the document
+// number of the invoice a credit note refers to.
+func RenderHTML(inv *models.Invoice, credited string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, newView(inv, credited)); err != nil {
+		return nil, fmt.Errorf("failed to render invoice html: %w", err)
+	}
+	return buf.Bytes(), nil
+}
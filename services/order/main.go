@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"main.go/services/order/carrier"
+	"main.go/services/order/clients"
+	"main.go/services/order/db"
+	"main.go/services/order/handlers"
+	"main.go/services/order/models"
+	"main.go/services/order/service"
+)
+
+func main() {
+	// Load database configuration from environment variables
+	config := db.LoadConfig()
+
+	// Connect to the database
+	if err := db.Connect(config); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := db.GetDB()
+	products := clients.NewProductClient(getEnv("PRODUCT_SERVICE_URL", "http://localhost:8081"))
+	inventory := clients.NewInventoryClient(getEnv("INVENTORY_SERVICE_URL", "http://localhost:8082"))
+	payments := clients.NewPaymentClient(getEnv("PAYMENT_SERVICE_URL", "http://localhost:8084"))
+
+	returnWindow := service.DefaultReturnWindow
+	if days, err := strconv.Atoi(getEnv("ORDER_RETURN_WINDOW_DAYS", "")); err == nil && days > 0 {
+		returnWindow = time.Duration(days) * 24 * time.Hour
+	}
+
+	seller := models.InvoiceParty{Name: getEnv("INVOICE_SELLER_NAME", "Example Store")}
+	if taxID := os.Getenv("INVOICE_SELLER_TAX_ID"); taxID != "" {
+		seller.TaxID = &taxID
+	}
+	if email := os.Getenv("INVOICE_SELLER_EMAIL"); email != "" {
+		seller.Email = &email
+	}
+	for _, line := range strings.Split(os.Getenv("INVOICE_SELLER_ADDRESS"), ";") {
+		if line = strings.TrimSpace(line); line != "" {
+			seller.AddressLines = append(seller.AddressLines, line)
+		}
+	}
+
+	carriers := carrier.NewRegistry(carrier.NewSimulated(time.Minute))
+	shipments := service.NewShipmentService(pool, inventory, carriers)
+	go shipments.RunTrackingPoller(ctx, time.Minute)
+
+	cancellations := service.NewCancellationService(pool, inventory, payments, carriers)
+	go cancellations.RunCancellationRetrier(ctx, time.Minute)
+
+	mux := http.NewServeMux()
+	handlers.NewOrderHandler(service.NewOrderService(pool, products, inventory)).RegisterRoutes(mux)
+	handlers.NewSearchHandler(service.NewSearchService(pool)).RegisterRoutes(mux)
+	handlers.NewCancellationHandler(cancellations).RegisterRoutes(mux)
+	handlers.NewEditHandler(service.NewEditService(pool, products, inventory, payments)).RegisterRoutes(mux)
+	handlers.NewInvoiceHandler(service.NewInvoiceService(pool, payments, seller)).RegisterRoutes(mux)
+	handlers.NewFlagHandler(service.NewFlagService(pool)).RegisterRoutes(mux)
+	handlers.NewAddressHandler(service.NewAddressService(pool)).RegisterRoutes(mux)
+	handlers.NewShipmentHandler(shipments).RegisterRoutes(mux)
+	handlers.NewTrackingHandler(shipments, os.Getenv("CARRIER_WEBHOOK_SECRET")).RegisterRoutes(mux)
+	handlers.NewReturnHandler(service.NewReturnService(pool, inventory, payments, returnWindow)).RegisterRoutes(mux)
+
+	addr := getEnv("ORDER_HTTP_ADDR", ":8083")
+	log.Printf("Order service listening on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Fatalf("Order service stopped: %v", err)
+	}
+}
+
+// getEnv gets an environment variable or returns a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -11,16 +11,19 @@ import (
 type OrderStatus string
 
 const (
-	OrderStatusPending    OrderStatus = "pending"
-	OrderStatusConfirmed  OrderStatus = "confirmed"
-	OrderStatusProcessing OrderStatus = "processing"
-	OrderStatusShipped    OrderStatus = "shipped"
-	OrderStatusDelivered  OrderStatus = "delivered"
-	OrderStatusCancelled  OrderStatus = "cancelled"
-	OrderStatusRefunded   OrderStatus = "refunded"
+	OrderStatusPending          OrderStatus = "pending"
+	OrderStatusConfirmed        OrderStatus = "confirmed"
+	OrderStatusProcessing       OrderStatus = "processing"
+	OrderStatusPartiallyShipped OrderStatus = "partially_shipped"
+	OrderStatusShipped          OrderStatus = "shipped"
+	OrderStatusDelivered        OrderStatus = "delivered"
+	OrderStatusCancelled        OrderStatus = "cancelled"
+	OrderStatusRefunded         OrderStatus = "refunded"
 )
 
-// Address represents a shipping or billing address
+// Address represents a shipping or billing address. Country is an ISO
+// 3166-1 alpha-2 code; whether State and PostalCode are required depends on
+// the country.
 type Address struct {
 	FirstName  string  `json:"first_name" validate:"required"`
 	LastName   string  `json:"last_name" validate:"required"`
@@ -28,30 +31,31 @@ type Address struct {
 	Address1   string  `json:"address1" validate:"required"`
 	Address2   *string `json:"address2,omitempty"`
 	City       string  `json:"city" validate:"required"`
-	State      string  `json:"state" validate:"required"`
-	PostalCode string  `json:"postal_code" validate:"required"`
-	Country    string  `json:"country" validate:"required"`
+	State      string  `json:"state,omitempty"`
+	PostalCode string  `json:"postal_code,omitempty"`
+	Country    string  `json:"country" validate:"required,len=2"`
 	Phone      *string `json:"phone,omitempty"`
 	Email      *string `json:"email,omitempty" validate:"omitempty,email"`
 }
 
 // Order represents an order
 type Order struct {
-	ID              uuid.UUID       `json:"id" db:"id"`
-	UserID          uuid.UUID       `json:"user_id" db:"user_id" validate:"required"`
-	OrderNumber     string          `json:"order_number" db:"order_number" validate:"required,min=1,max=50"`
-	Status          OrderStatus     `json:"status" db:"status" validate:"required,oneof=pending confirmed processing shipped delivered cancelled refunded"`
-	Subtotal        float64         `json:"subtotal" db:"subtotal" validate:"required,min=0"`
-	TaxAmount       float64         `json:"tax_amount" db:"tax_amount" validate:"min=0"`
-	ShippingAmount  float64         `json:"shipping_amount" db:"shipping_amount" validate:"min=0"`
-	DiscountAmount  float64         `json:"discount_amount" db:"discount_amount" validate:"min=0"`
-	Total           float64         `json:"total" db:"total" validate:"required,min=0"`
-	Currency        string          `json:"currency" db:"currency" validate:"required,len=3"`
-	ShippingAddress json.RawMessage `json:"shipping_address" db:"shipping_address"`
-	BillingAddress  json.RawMessage `json:"billing_address" db:"billing_address"`
-	Notes           *string         `json:"notes,omitempty" db:"notes"`
-	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
+	ID              uuid.UUID     `json:"id" db:"id"`
+	UserID          uuid.UUID     `json:"user_id" db:"user_id" validate:"required"`
+	OrderNumber     string        `json:"order_number" db:"order_number" validate:"required,min=1,max=50"`
+	Status          OrderStatus   `json:"status" db:"status" validate:"required,oneof=pending confirmed processing partially_shipped shipped delivered cancelled refunded"`
+	Subtotal        float64       `json:"subtotal" db:"subtotal" validate:"required,min=0"`
+	TaxAmount       float64       `json:"tax_amount" db:"tax_amount" validate:"min=0"`
+	ShippingAmount  float64       `json:"shipping_amount" db:"shipping_amount" validate:"min=0"`
+	DiscountAmount  float64       `json:"discount_amount" db:"discount_amount" validate:"min=0"`
+	Total           float64       `json:"total" db:"total" validate:"required,min=0"`
+	Currency        string        `json:"currency" db:"currency" validate:"required,len=3"`
+	ShippingAddress *Address      `json:"shipping_address" db:"shipping_address"`
+	BillingAddress  *Address      `json:"billing_address" db:"billing_address"`
+	Notes           *string       `json:"notes,omitempty" db:"notes"`
+	CreatedAt       time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at" db:"updated_at"`
+	FXRates         []OrderFXRate `json:"fx_rates,omitempty" db:"-"`
 }
 
 // OrderItem represents an item in an order
@@ -59,13 +63,34 @@ type OrderItem struct {
 	ID         uuid.UUID       `json:"id" db:"id"`
 	OrderID    uuid.UUID       `json:"order_id" db:"order_id" validate:"required"`
 	ProductID  uuid.UUID       `json:"product_id" db:"product_id" validate:"required"`
+	VariantID  uuid.UUID       `json:"variant_id" db:"variant_id"`
 	SKU        *string         `json:"sku,omitempty" db:"sku" validate:"omitempty,max=100"`
 	Name       string          `json:"name" db:"name" validate:"required,min=1,max=500"`
 	Quantity   int             `json:"quantity" db:"quantity" validate:"required,min=1"`
 	UnitPrice  float64         `json:"unit_price" db:"unit_price" validate:"required,min=0"`
 	TotalPrice float64         `json:"total_price" db:"total_price" validate:"required,min=0"`
 	Metadata   json.RawMessage `json:"metadata,omitempty" db:"metadata"`
-	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+	// Components lists what each unit of a bundle contains
+	Components []OrderItemComponent `json:"components,omitempty" db:"components"`
+	CreatedAt  time.Time            `json:"created_at" db:"created_at"`
+}
+
+// OrderItemComponent is a product variant contained in each unit of a
+// bundle order item
+type OrderItemComponent struct {
+	ProductID uuid.UUID `json:"product_id"`
+	VariantID uuid.UUID `json:"variant_id"`
+	SKU       *string   `json:"sku,omitempty"`
+	Name      string    `json:"name"`
+	Quantity  int       `json:"quantity"`
+}
+
+// ProductCoPurchase counts the orders that contained both a product and a
+// related product
+type ProductCoPurchase struct {
+	ProductID uuid.UUID `json:"product_id" db:"product_id"`
+	RelatedID uuid.UUID `json:"related_id" db:"related_id"`
+	Orders    int       `json:"orders" db:"orders"`
 }
 
 // OrderStatusHistory represents a status change in an order
@@ -79,12 +104,269 @@ type OrderStatusHistory struct {
 
 // OrderShipment represents shipment information for an order
 type OrderShipment struct {
-	ID          uuid.UUID  `json:"id" db:"id"`
-	OrderID     uuid.UUID  `json:"order_id" db:"order_id" validate:"required"`
-	Carrier     *string    `json:"carrier,omitempty" db:"carrier" validate:"omitempty,max=100"`
-	TrackingNo  *string    `json:"tracking_no,omitempty" db:"tracking_no" validate:"omitempty,max=255"`
-	ShippedAt   *time.Time `json:"shipped_at,omitempty" db:"shipped_at"`
-	DeliveredAt *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	ID          uuid.UUID      `json:"id" db:"id"`
+	OrderID     uuid.UUID      `json:"order_id" db:"order_id" validate:"required"`
+	WarehouseID *uuid.UUID     `json:"warehouse_id,omitempty" db:"warehouse_id"`
+	Carrier     *string        `json:"carrier,omitempty" db:"carrier" validate:"omitempty,max=100"`
+	TrackingNo  *string        `json:"tracking_no,omitempty" db:"tracking_no" validate:"omitempty,max=255"`
+	LabelURL    *string        `json:"label_url,omitempty" db:"label_url"`
+	ShippedAt   *time.Time     `json:"shipped_at,omitempty" db:"shipped_at"`
+	DeliveredAt *time.Time     `json:"delivered_at,omitempty" db:"delivered_at"`
+	CancelledAt *time.Time     `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at" db:"updated_at"`
+	Items       []ShipmentItem `json:"items,omitempty" db:"-"`
+}
+
+// TrackingEventSource represents how a tracking event was received
+type TrackingEventSource string
+
+const (
+	TrackingSourcePoll    TrackingEventSource = "poll"
+	TrackingSourceWebhook TrackingEventSource = "webhook"
+)
+
+// ShipmentTrackingEvent represents a tracking event reported by a carrier
+type ShipmentTrackingEvent struct {
+	ID          uuid.UUID           `json:"id" db:"id"`
+	ShipmentID  uuid.UUID           `json:"shipment_id" db:"shipment_id" validate:"required"`
+	Carrier     string              `json:"carrier" db:"carrier" validate:"required,max=100"`
+	ExternalID  string              `json:"external_id" db:"external_id" validate:"required,max=255"`
+	Code        string              `json:"code" db:"code" validate:"required,max=50"`
+	Description *string             `json:"description,omitempty" db:"description"`
+	Location    *string             `json:"location,omitempty" db:"location" validate:"omitempty,max=255"`
+	OccurredAt  time.Time           `json:"occurred_at" db:"occurred_at"`
+	Source      TrackingEventSource `json:"source" db:"source" validate:"required,oneof=poll webhook"`
+	CreatedAt   time.Time           `json:"created_at" db:"created_at"`
+}
+
+// ShipmentItem represents a quantity of an order item contained in a shipment
+type ShipmentItem struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	ShipmentID  uuid.UUID `json:"shipment_id" db:"shipment_id" validate:"required"`
+	OrderItemID uuid.UUID `json:"order_item_id" db:"order_item_id" validate:"required"`
+	ProductID   uuid.UUID `json:"product_id" db:"product_id" validate:"required"`
+	VariantID   uuid.UUID `json:"variant_id" db:"variant_id"`
+	Quantity    int       `json:"quantity" db:"quantity" validate:"required,min=1"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// OrderFlag represents a support-visible flag raised on an order, such as a
+// payment dispute reported by the payment service
+type OrderFlag struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	OrderID   uuid.UUID  `json:"order_id" db:"order_id" validate:"required"`
+	Flag      string     `json:"flag" db:"flag" validate:"required,min=1,max=50"`
+	Note      *string    `json:"note,omitempty" db:"note"`
+	Source    *string    `json:"source,omitempty" db:"source" validate:"omitempty,max=50"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	ClearedAt *time.Time `json:"cleared_at,omitempty" db:"cleared_at"`
+}
+
+// OrderFXRate represents an FX rate locked at checkout for converting prices
+// from a product base currency into the order currency
+type OrderFXRate struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	OrderID       uuid.UUID `json:"order_id" db:"order_id" validate:"required"`
+	BaseCurrency  string    `json:"base_currency" db:"base_currency" validate:"required,len=3"`
+	QuoteCurrency string    `json:"quote_currency" db:"quote_currency" validate:"required,len=3"`
+	Rate          float64   `json:"rate" db:"rate" validate:"required,gt=0"`
+	RateID        uuid.UUID `json:"rate_id" db:"rate_id" validate:"required"`
+	EffectiveAt   time.Time `json:"effective_at" db:"effective_at"`
+	LockedAt      time.Time `json:"locked_at" db:"locked_at"`
+}
+
+// ReturnStatus represents the status of a return (RMA)
+type ReturnStatus string
+
+const (
+	ReturnStatusRequested ReturnStatus = "requested"
+	ReturnStatusApproved  ReturnStatus = "approved"
+	ReturnStatusRejected  ReturnStatus = "rejected"
+	ReturnStatusReceived  ReturnStatus = "received"
+	ReturnStatusRefunded  ReturnStatus = "refunded"
+)
+
+// ReturnDisposition represents what happens to returned goods on receipt
+type ReturnDisposition string
+
+const (
+	ReturnDispositionRestock  ReturnDisposition = "restock"
+	ReturnDispositionWriteOff ReturnDisposition = "write_off"
+)
+
+// OrderReturn represents a customer return (RMA) for items of an order
+type OrderReturn struct {
+	ID             uuid.UUID    `json:"id" db:"id"`
+	OrderID        uuid.UUID    `json:"order_id" db:"order_id" validate:"required"`
+	UserID         uuid.UUID    `json:"user_id" db:"user_id" validate:"required"`
+	RMANumber      string       `json:"rma_number" db:"rma_number" validate:"required,max=50"`
+	Status         ReturnStatus `json:"status" db:"status" validate:"required,oneof=requested approved rejected received refunded"`
+	Reason         *string      `json:"reason,omitempty" db:"reason"`
+	Currency       string       `json:"currency" db:"currency" validate:"required,len=3"`
+	RefundAmount   *float64     `json:"refund_amount,omitempty" db:"refund_amount" validate:"omitempty,min=0"`
+	RefundedAmount float64      `json:"refunded_amount" db:"refunded_amount" validate:"min=0"`
+	CreatedAt      time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at" db:"updated_at"`
+	Items          []ReturnItem `json:"items,omitempty" db:"-"`
+}
+
+// ReturnItem represents a quantity of an order item included in a return
+type ReturnItem struct {
+	ID               uuid.UUID          `json:"id" db:"id"`
+	ReturnID         uuid.UUID          `json:"return_id" db:"return_id" validate:"required"`
+	OrderItemID      uuid.UUID          `json:"order_item_id" db:"order_item_id" validate:"required"`
+	ProductID        uuid.UUID          `json:"product_id" db:"product_id" validate:"required"`
+	VariantID        uuid.UUID          `json:"variant_id" db:"variant_id"`
+	Quantity         int                `json:"quantity" db:"quantity" validate:"required,min=1"`
+	UnitPrice        float64            `json:"unit_price" db:"unit_price" validate:"min=0"`
+	Reason           *string            `json:"reason,omitempty" db:"reason"`
+	ReceivedQuantity int                `json:"received_quantity" db:"received_quantity" validate:"min=0"`
+	Disposition      *ReturnDisposition `json:"disposition,omitempty" db:"disposition" validate:"omitempty,oneof=restock write_off"`
+	WarehouseID      *uuid.UUID         `json:"warehouse_id,omitempty" db:"warehouse_id"`
+	ReceivedAt       *time.Time         `json:"received_at,omitempty" db:"received_at"`
+	CreatedAt        time.Time          `json:"created_at" db:"created_at"`
+}
+
+// ReturnStatusHistory represents a step in the lifecycle of a return
+type ReturnStatusHistory struct {
+	ID        uuid.UUID    `json:"id" db:"id"`
+	ReturnID  uuid.UUID    `json:"return_id" db:"return_id" validate:"required"`
+	Status    ReturnStatus `json:"status" db:"status" validate:"required"`
+	Actor     *string      `json:"actor,omitempty" db:"actor" validate:"omitempty,max=255"`
+	Note      *string      `json:"note,omitempty" db:"note"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+}
+
+// CancellationTaskType represents a side effect of cancelling an order
+type CancellationTaskType string
+
+const (
+	CancellationTaskReleaseStock     CancellationTaskType = "release_stock"
+	CancellationTaskRestockShipments CancellationTaskType = "restock_shipments"
+	CancellationTaskSettlePayments   CancellationTaskType = "settle_payments"
+)
+
+// CancellationTaskStatus represents the progress of a cancellation task
+type CancellationTaskStatus string
+
+const (
+	CancellationTaskPending   CancellationTaskStatus = "pending"
+	CancellationTaskCompleted CancellationTaskStatus = "completed"
+	CancellationTaskFailed    CancellationTaskStatus = "failed"
+)
+
+// CancellationTask represents a side effect in another service that must
+// be carried out for a cancelled order, retried until it completes
+type CancellationTask struct {
+	ID        uuid.UUID              `json:"id" db:"id"`
+	OrderID   uuid.UUID              `json:"order_id" db:"order_id" validate:"required"`
+	Task      CancellationTaskType   `json:"task" db:"task" validate:"required,oneof=release_stock restock_shipments settle_payments"`
+	Status    CancellationTaskStatus `json:"status" db:"status" validate:"required,oneof=pending completed failed"`
+	Attempts  int                    `json:"attempts" db:"attempts" validate:"min=0"`
+	LastError *string                `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at" db:"updated_at"`
+}
+
+// OrderRevision is an immutable snapshot of an order's items and amounts,
+// recorded at checkout and after every edit
+type OrderRevision struct {
+	ID              uuid.UUID   `json:"id" db:"id"`
+	OrderID         uuid.UUID   `json:"order_id" db:"order_id" validate:"required"`
+	Revision        int         `json:"revision" db:"revision" validate:"required,min=1"`
+	Actor           *string     `json:"actor,omitempty" db:"actor" validate:"omitempty,max=100"`
+	Reason          *string     `json:"reason,omitempty" db:"reason"`
+	Items           []OrderItem `json:"items" db:"items"`
+	Subtotal        float64     `json:"subtotal" db:"subtotal" validate:"min=0"`
+	TaxAmount       float64     `json:"tax_amount" db:"tax_amount" validate:"min=0"`
+	ShippingAmount  float64     `json:"shipping_amount" db:"shipping_amount" validate:"min=0"`
+	DiscountAmount  float64     `json:"discount_amount" db:"discount_amount" validate:"min=0"`
+	Total           float64     `json:"total" db:"total" validate:"min=0"`
+	ShippingAddress *Address    `json:"shipping_address" db:"shipping_address"`
+	CreatedAt       time.Time   `json:"created_at" db:"created_at"`
+}
+
+// SavedAddress is an address in a user's address book that checkout can
+// reference instead of repeating it
+type SavedAddress struct {
+	ID                uuid.UUID `json:"id" db:"id"`
+	UserID            uuid.UUID `json:"user_id" db:"user_id" validate:"required"`
+	Label             *string   `json:"label,omitempty" db:"label" validate:"omitempty,max=100"`
+	Address           Address   `json:"address" db:"address" validate:"required"`
+	IsDefaultShipping bool      `json:"is_default_shipping" db:"is_default_shipping"`
+	IsDefaultBilling  bool      `json:"is_default_billing" db:"is_default_billing"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// InvoiceKind distinguishes invoices from credit notes
+type InvoiceKind string
+
+const (
+	InvoiceKindInvoice    InvoiceKind = "invoice"
+	InvoiceKindCreditNote InvoiceKind = "credit_note"
+)
+
+// InvoiceParty is the seller or buyer as printed on an invoice
+type InvoiceParty struct {
+	Name         string     `json:"name"`
+	TaxID        *string    `json:"tax_id,omitempty"`
+	AddressLines []string   `json:"address_lines,omitempty"`
+	Email        *string    `json:"email,omitempty"`
+	UserID       *uuid.UUID `json:"user_id,omitempty"`
+}
+
+// InvoiceLine is a line of an invoice. Amounts are in the invoice currency;
+// net amounts exclude tax.
+type InvoiceLine struct {
+	Description string  `json:"description"`
+	SKU         *string `json:"sku,omitempty"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	NetAmount   float64 `json:"net_amount"`
+	TaxRate     float64 `json:"tax_rate"`
+	TaxAmount   float64 `json:"tax_amount"`
+	GrossAmount float64 `json:"gross_amount"`
+}
+
+// InvoiceTaxLine sums the lines of an invoice taxed at the same rate
+type InvoiceTaxLine struct {
+	Rate          float64 `json:"rate"`
+	TaxableAmount float64 `json:"taxable_amount"`
+	TaxAmount     float64 `json:"tax_amount"`
+}
+
+// Invoice is an issued invoice or credit note. Seller, buyer and lines are
+// snapshots taken at issue time and never change afterwards.
+type Invoice struct {
+	ID             uuid.UUID        `json:"id" db:"id"`
+	OrderID        uuid.UUID        `json:"order_id" db:"order_id" validate:"required"`
+	OrderNumber    string           `json:"order_number" db:"order_number" validate:"required"`
+	Kind           InvoiceKind      `json:"kind" db:"kind" validate:"required,oneof=invoice credit_note"`
+	Series         string           `json:"series" db:"series" validate:"required,max=20"`
+	Number         int              `json:"number" db:"number" validate:"required,min=1"`
+	DocumentNumber string           `json:"document_number" db:"document_number" validate:"required,max=40"`
+	CreditedID     *uuid.UUID       `json:"credited_invoice_id,omitempty" db:"credited_invoice_id"`
+	RefundID       *uuid.UUID       `json:"refund_id,omitempty" db:"refund_id"`
+	Currency       string           `json:"currency" db:"currency" validate:"required,len=3"`
+	Seller         InvoiceParty     `json:"seller" db:"seller"`
+	Buyer          InvoiceParty     `json:"buyer" db:"buyer"`
+	Lines          []InvoiceLine    `json:"lines" db:"lines"`
+	TaxBreakdown   []InvoiceTaxLine `json:"tax_breakdown" db:"tax_breakdown"`
+	NetAmount      float64          `json:"net_amount" db:"net_amount"`
+	TaxAmount      float64          `json:"tax_amount" db:"tax_amount" validate:"min=0"`
+	Total          float64          `json:"total" db:"total"`
+	IssuedAt       time.Time        `json:"issued_at" db:"issued_at"`
+}
+
+// InvoiceDocument is an invoice rendered to a downloadable format
+type InvoiceDocument struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	InvoiceID   uuid.UUID `json:"invoice_id" db:"invoice_id" validate:"required"`
+	Format      string    `json:"format" db:"format" validate:"required,oneof=pdf html"`
+	ContentType string    `json:"content_type" db:"content_type" validate:"required"`
+	Content     []byte    `json:"-" db:"content"`
+	SHA256      string    `json:"sha256" db:"sha256" validate:"required,len=64"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/order/models"
+)
+
+const savedAddressColumns = `id, user_id, label, address, is_default_shipping, is_default_billing, created_at, updated_at`
+
+// AddressRepository provides access to users' saved addresses
+type AddressRepository struct {
+	db DBTX
+}
+
+// NewAddressRepository creates a new AddressRepository
+func NewAddressRepository(db DBTX) *AddressRepository {
+	return &AddressRepository{db: db}
+}
+
+// Create inserts a saved address
+func (r *AddressRepository) Create(ctx context.Context, a *models.SavedAddress) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO user_addresses (user_id, label, address, is_default_shipping, is_default_billing)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`,
+		a.UserID, a.Label, a.Address, a.IsDefaultShipping, a.IsDefaultBilling,
+	).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create saved address: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns a saved address by its ID
+func (r *AddressRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.SavedAddress, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+savedAddressColumns+` FROM user_addresses WHERE id = $1`, id)
+	a, err := collectOne[models.SavedAddress](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved address: %w", err)
+	}
+	return a, nil
+}
+
+// GetDefault returns the default shipping or billing address of a user
+func (r *AddressRepository) GetDefault(ctx context.Context, userID uuid.UUID, billing bool) (*models.SavedAddress, error) {
+	column := "is_default_shipping"
+	if billing {
+		column = "is_default_billing"
+	}
+	rows, err := r.db.Query(ctx, `SELECT `+savedAddressColumns+` FROM user_addresses WHERE user_id = $1 AND `+column, userID)
+	a, err := collectOne[models.SavedAddress](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default address: %w", err)
+	}
+	return a, nil
+}
+
+// ListByUser returns the saved addresses of a user, defaults first
+func (r *AddressRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.SavedAddress, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+savedAddressColumns+` FROM user_addresses WHERE user_id = $1
+		ORDER BY is_default_shipping DESC, is_default_billing DESC, created_at`, userID)
+	addresses, err := collectAll[models.SavedAddress](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved addresses: %w", err)
+	}
+	return addresses, nil
+}
+
+// Update stores the label, address and default flags of a saved address
+func (r *AddressRepository) Update(ctx context.Context, a *models.SavedAddress) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE user_addresses SET label = $2, address = $3, is_default_shipping = $4,
+			is_default_billing = $5, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at`,
+		a.ID, a.Label, a.Address, a.IsDefaultShipping, a.IsDefaultBilling,
+	).Scan(&a.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update saved address: %w", err)
+	}
+	return nil
+}
+
+// ClearDefaults unsets the default shipping and/or billing flag on every
+// address of a user
+func (r *AddressRepository) ClearDefaults(ctx context.Context, userID uuid.UUID, shipping, billing bool) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE user_addresses
+		SET is_default_shipping = is_default_shipping AND NOT $2,
+			is_default_billing = is_default_billing AND NOT $3,
+			updated_at = NOW()
+		WHERE user_id = $1 AND ((is_default_shipping AND $2) OR (is_default_billing AND $3))`,
+		userID, shipping, billing)
+	if err != nil {
+		return fmt.Errorf("failed to clear default addresses: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a saved address
+func (r *AddressRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM user_addresses WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved address: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/order/models"
+)
+
+const cancellationTaskColumns = `id, order_id, task, status, attempts, last_error, created_at, updated_at`
+
+// CancellationRepository provides access to order cancellation tasks
+type CancellationRepository struct {
+	db DBTX
+}
+
+// NewCancellationRepository creates a new CancellationRepository
+func NewCancellationRepository(db DBTX) *CancellationRepository {
+	return &CancellationRepository{db: db}
+}
+
+// CreateTask inserts a pending cancellation task
+func (r *CancellationRepository) CreateTask(ctx context.Context, t *models.CancellationTask) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO order_cancellation_tasks (order_id, task, status)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at`,
+		t.OrderID, t.Task, t.Status,
+	).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create cancellation task: %w", err)
+	}
+	return nil
+}
+
+// GetForUpdate returns a cancellation task by its ID and locks it until the
+// surrounding transaction ends
+func (r *CancellationRepository) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.CancellationTask, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+cancellationTaskColumns+` FROM order_cancellation_tasks WHERE id = $1 FOR UPDATE`, id)
+	t, err := collectOne[models.CancellationTask](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cancellation task: %w", err)
+	}
+	return t, nil
+}
+
+// ListByOrder returns the cancellation tasks of an order
+func (r *CancellationRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.CancellationTask, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+cancellationTaskColumns+` FROM order_cancellation_tasks WHERE order_id = $1 ORDER BY created_at, task`, orderID)
+	tasks, err := collectAll[models.CancellationTask](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cancellation tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// ListPending returns pending cancellation tasks, least recently attempted
+// first
+func (r *CancellationRepository) ListPending(ctx context.Context, limit int) ([]models.CancellationTask, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+cancellationTaskColumns+` FROM order_cancellation_tasks
+		WHERE status = $1 ORDER BY updated_at LIMIT $2`, models.CancellationTaskPending, limit)
+	tasks, err := collectAll[models.CancellationTask](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending cancellation tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// Update persists the status, attempts and last error of a task
+func (r *CancellationRepository) Update(ctx context.Context, t *models.CancellationTask) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE order_cancellation_tasks SET status = $2, attempts = $3, last_error = $4, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`,
+		t.ID, t.Status, t.Attempts, t.LastError,
+	).Scan(&t.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update cancellation task: %w", err)
+	}
+	return nil
+}
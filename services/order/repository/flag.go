@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/order/models"
+)
+
+// FlagRepository provides access to order flags
+type FlagRepository struct {
+	db DBTX
+}
+
+// NewFlagRepository creates a new FlagRepository
+func NewFlagRepository(db DBTX) *FlagRepository {
+	return &FlagRepository{db: db}
+}
+
+// Set raises a flag on an order. If the flag is already active its note and
+// source are refreshed instead of creating a duplicate.
+func (r *FlagRepository) Set(ctx context.Context, f *models.OrderFlag) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO order_flags (order_id, flag, note, source)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (order_id, flag) WHERE cleared_at IS NULL
+		DO UPDATE SET note = EXCLUDED.note, source = EXCLUDED.source
+		RETURNING id, created_at`,
+		f.OrderID, f.Flag, f.Note, f.Source,
+	).Scan(&f.ID, &f.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set order flag: %w", err)
+	}
+	return nil
+}
+
+// Clear clears the active flag of the given kind on an order
+func (r *FlagRepository) Clear(ctx context.Context, orderID uuid.UUID, flag string) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE order_flags SET cleared_at = NOW()
+		WHERE order_id = $1 AND flag = $2 AND cleared_at IS NULL`, orderID, flag)
+	if err != nil {
+		return fmt.Errorf("failed to clear order flag: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListByOrder returns all flags ever raised on an order, newest first
+func (r *FlagRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.OrderFlag, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, order_id, flag, note, source, created_at, cleared_at
+		FROM order_flags WHERE order_id = $1 ORDER BY created_at DESC`, orderID)
+	flags, err := collectAll[models.OrderFlag](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order flags: %w", err)
+	}
+	return flags, nil
+}
+
+// ListOrdersWithFlag returns orders that currently carry the given flag,
+// most recently flagged first
+func (r *FlagRepository) ListOrdersWithFlag(ctx context.Context, flag string, limit int) ([]models.Order, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT o.id, o.user_id, o.order_number, o.status, o.subtotal, o.tax_amount, o.shipping_amount,
+			o.discount_amount, o.total, o.currency, o.shipping_address, o.billing_address, o.notes,
+			o.created_at, o.updated_at
+		FROM orders o
+		JOIN order_flags f ON f.order_id = o.id AND f.cleared_at IS NULL
+		WHERE f.flag = $1
+		ORDER BY f.created_at DESC
+		LIMIT $2`, flag, limit)
+	orders, err := collectAll[models.Order](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list flagged orders: %w", err)
+	}
+	return orders, nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/order/models"
+)
+
+const invoiceColumns = `id, order_id, order_number, kind, series, number, document_number, credited_invoice_id,
+	refund_id, currency, seller, buyer, lines, tax_breakdown, net_amount, tax_amount, total, issued_at`
+
+// InvoiceRepository provides access to invoices, credit notes and their
+// rendered documents
+type InvoiceRepository struct {
+	db DBTX
+}
+
+// NewInvoiceRepository creates a new InvoiceRepository
+func NewInvoiceRepository(db DBTX) *InvoiceRepository {
+	return &InvoiceRepository{db: db}
+}
+
+// NextNumber reserves the next number of a series. The series row stays
+// locked until the surrounding transaction ends, so numbers are gapless as
+// long as the invoice is inserted in the same transaction.
+func (r *InvoiceRepository) NextNumber(ctx context.Context, series string) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO invoice_series (series, last_number) VALUES ($1, 1)
+		ON CONFLICT (series) DO UPDATE SET last_number = invoice_series.last_number + 1
+		RETURNING last_number`, series).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve invoice number: %w", err)
+	}
+	return n, nil
+}
+
+// Create inserts an issued invoice or credit note
+func (r *InvoiceRepository) Create(ctx context.Context, inv *models.Invoice) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO invoices (order_id, order_number, kind, series, number, document_number, credited_invoice_id,
+			refund_id, currency, seller, buyer, lines, tax_breakdown, net_amount, tax_amount, total, issued_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		RETURNING id`,
+		inv.OrderID, inv.OrderNumber, inv.Kind, inv.Series, inv.Number, inv.DocumentNumber, inv.CreditedID,
+		inv.RefundID, inv.Currency, inv.Seller, inv.Buyer, inv.Lines, inv.TaxBreakdown, inv.NetAmount,
+		inv.TaxAmount, inv.Total, inv.IssuedAt,
+	).Scan(&inv.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create invoice: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns an invoice or credit note by its ID
+func (r *InvoiceRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Invoice, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+invoiceColumns+` FROM invoices WHERE id = $1`, id)
+	inv, err := collectOne[models.Invoice](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+	return inv, nil
+}
+
+// GetInvoiceByOrder returns the invoice of an order
+func (r *InvoiceRepository) GetInvoiceByOrder(ctx context.Context, orderID uuid.UUID) (*models.Invoice, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+invoiceColumns+` FROM invoices WHERE order_id = $1 AND kind = 'invoice'`, orderID)
+	inv, err := collectOne[models.Invoice](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order invoice: %w", err)
+	}
+	return inv, nil
+}
+
+// ListByOrder returns the invoice and credit notes of an order in issue order
+func (r *InvoiceRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.Invoice, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+invoiceColumns+` FROM invoices WHERE order_id = $1 ORDER BY issued_at, number`, orderID)
+	invoices, err := collectAll[models.Invoice](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invoices: %w", err)
+	}
+	return invoices, nil
+}
+
+// CreditedRefunds returns the IDs of refunds of an order that already have a
+// credit note
+func (r *InvoiceRepository) CreditedRefunds(ctx context.Context, orderID uuid.UUID) (map[uuid.UUID]bool, error) {
+	rows, err := r.db.Query(ctx, `SELECT refund_id FROM invoices WHERE order_id = $1 AND refund_id IS NOT NULL`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credited refunds: %w", err)
+	}
+	defer rows.Close()
+
+	credited := map[uuid.UUID]bool{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to list credited refunds: %w", err)
+		}
+		credited[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list credited refunds: %w", err)
+	}
+	return credited, nil
+}
+
+// SaveDocument stores a rendered document of an invoice
+func (r *InvoiceRepository) SaveDocument(ctx context.Context, doc *models.InvoiceDocument) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO invoice_documents (invoice_id, format, content_type, content, sha256)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		doc.InvoiceID, doc.Format, doc.ContentType, doc.Content, doc.SHA256,
+	).Scan(&doc.ID, &doc.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save invoice document: %w", err)
+	}
+	return nil
+}
+
+// GetDocument returns the rendered document of an invoice in a format
+func (r *InvoiceRepository) GetDocument(ctx context.Context, invoiceID uuid.UUID, format string) (*models.InvoiceDocument, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, invoice_id, format, content_type, content, sha256, created_at
+		FROM invoice_documents WHERE invoice_id = $1 AND format = $2`, invoiceID, format)
+	doc, err := collectOne[models.InvoiceDocument](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice document: %w", err)
+	}
+	return doc, nil
+}
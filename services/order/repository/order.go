@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/order/models"
+)
+
+const orderColumns = `id, user_id, order_number, status, subtotal, tax_amount, shipping_amount,
+	discount_amount, total, currency, shipping_address, billing_address, notes, created_at, updated_at`
+
+// OrderRepository provides access to orders
+type OrderRepository struct {
+	db DBTX
+}
+
+// NewOrderRepository creates a new OrderRepository
+func NewOrderRepository(db DBTX) *OrderRepository {
+	return &OrderRepository{db: db}
+}
+
+// GetByID returns an order by its ID
+func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Order, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = $1`, id)
+	order, err := collectOne[models.Order](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	return order, nil
+}
+
+// GetByIDForUpdate returns an order by its ID and locks the row until the
+// surrounding transaction ends
+func (r *OrderRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*models.Order, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = $1 FOR UPDATE`, id)
+	order, err := collectOne[models.Order](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	return order, nil
+}
+
+// ListItems returns the items of an order
+func (r *OrderRepository) ListItems(ctx context.Context, orderID uuid.UUID) ([]models.OrderItem, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, order_id, product_id, variant_id, sku, name, quantity, unit_price, total_price, metadata, components, created_at
+		FROM order_items WHERE order_id = $1 ORDER BY created_at, id`, orderID)
+	items, err := collectAll[models.OrderItem](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order items: %w", err)
+	}
+	return items, nil
+}
+
+// UpdateStatus sets the status of an order and records it in the history
+func (r *OrderRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status models.OrderStatus, note *string) error {
+	tag, err := r.db.Exec(ctx, `UPDATE orders SET status = $2, updated_at = NOW() WHERE id = $1`, id, status)
+	if err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return r.AddStatusHistory(ctx, id, status, note)
+}
+
+// AddStatusHistory records a status entry in an order's history
+func (r *OrderRepository) AddStatusHistory(ctx context.Context, id uuid.UUID, status models.OrderStatus, note *string) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO order_status_history (order_id, status, note) VALUES ($1, $2, $3)`, id, status, note)
+	if err != nil {
+		return fmt.Errorf("failed to record order status history: %w", err)
+	}
+	return nil
+}
+
+// ListStatusHistory returns the status changes of an order, oldest first
+func (r *OrderRepository) ListStatusHistory(ctx context.Context, orderID uuid.UUID) ([]models.OrderStatusHistory, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, order_id, status, note, created_at
+		FROM order_status_history WHERE order_id = $1 ORDER BY created_at`, orderID)
+	history, err := collectAll[models.OrderStatusHistory](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order status history: %w", err)
+	}
+	return history, nil
+}
+
+// Create inserts a new order
+func (r *OrderRepository) Create(ctx context.Context, o *models.Order) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO orders (user_id, order_number, status, subtotal, tax_amount, shipping_amount,
+			discount_amount, total, currency, shipping_address, billing_address, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at`,
+		o.UserID, o.OrderNumber, o.Status, o.Subtotal, o.TaxAmount, o.ShippingAmount,
+		o.DiscountAmount, o.Total, o.Currency, o.ShippingAddress, o.BillingAddress, o.Notes,
+	).Scan(&o.ID, &o.CreatedAt, &o.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+	return nil
+}
+
+// CreateItem inserts an order item
+func (r *OrderRepository) CreateItem(ctx context.Context, item *models.OrderItem) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO order_items (order_id, product_id, variant_id, sku, name, quantity, unit_price, total_price, metadata, components)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at`,
+		item.OrderID, item.ProductID, item.VariantID, item.SKU, item.Name, item.Quantity, item.UnitPrice, item.TotalPrice, item.Metadata, item.Components,
+	).Scan(&item.ID, &item.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create order item: %w", err)
+	}
+	return nil
+}
+
+// RestoreItem inserts an order item keeping its original ID and creation time
+func (r *OrderRepository) RestoreItem(ctx context.Context, item *models.OrderItem) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO order_items (id, order_id, product_id, variant_id, sku, name, quantity, unit_price, total_price, metadata, components, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		item.ID, item.OrderID, item.ProductID, item.VariantID, item.SKU, item.Name, item.Quantity, item.UnitPrice, item.TotalPrice, item.Metadata, item.Components, item.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to restore order item: %w", err)
+	}
+	return nil
+}
+
+// UpdateItem stores the quantity and prices of an order item
+func (r *OrderRepository) UpdateItem(ctx context.Context, item *models.OrderItem) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE order_items SET quantity = $2, unit_price = $3, total_price = $4, metadata = $5
+		WHERE id = $1`,
+		item.ID, item.Quantity, item.UnitPrice, item.TotalPrice, item.Metadata,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update order item: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteItem removes an item from an order
+func (r *OrderRepository) DeleteItem(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM order_items WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete order item: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateAmounts stores the amounts and shipping address of an order
+func (r *OrderRepository) UpdateAmounts(ctx context.Context, o *models.Order) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE orders SET subtotal = $2, tax_amount = $3, shipping_amount = $4, discount_amount = $5,
+			total = $6, shipping_address = $7, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at`,
+		o.ID, o.Subtotal, o.TaxAmount, o.ShippingAmount, o.DiscountAmount, o.Total, o.ShippingAddress,
+	).Scan(&o.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update order amounts: %w", err)
+	}
+	return nil
+}
+
+// EditingSince returns when the edit in progress on an order started, or nil
+func (r *OrderRepository) EditingSince(ctx context.Context, id uuid.UUID) (*time.Time, error) {
+	var since *time.Time
+	err := r.db.QueryRow(ctx, `SELECT editing_since FROM orders WHERE id = $1`, id).Scan(&since)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get order edit state: %w", err)
+	}
+	return since, nil
+}
+
+// SetEditing marks an order as being edited since the given time, or clears
+// the mark when since is nil
+func (r *OrderRepository) SetEditing(ctx context.Context, id uuid.UUID, since *time.Time) error {
+	tag, err := r.db.Exec(ctx, `UPDATE orders SET editing_since = $2 WHERE id = $1`, id, since)
+	if err != nil {
+		return fmt.Errorf("failed to update order edit state: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateFXRate records an FX rate locked for an order
+func (r *OrderRepository) CreateFXRate(ctx context.Context, rate *models.OrderFXRate) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO order_fx_rates (order_id, base_currency, quote_currency, rate, rate_id, effective_at, locked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		rate.OrderID, rate.BaseCurrency, rate.QuoteCurrency, rate.Rate, rate.RateID, rate.EffectiveAt, rate.LockedAt,
+	).Scan(&rate.ID)
+	if err != nil {
+		return fmt.Errorf("failed to lock order fx rate: %w", err)
+	}
+	return nil
+}
+
+// ListFXRates returns the FX rates locked for an order
+func (r *OrderRepository) ListFXRates(ctx context.Context, orderID uuid.UUID) ([]models.OrderFXRate, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, order_id, base_currency, quote_currency, rate, rate_id, effective_at, locked_at
+		FROM order_fx_rates WHERE order_id = $1 ORDER BY base_currency`, orderID)
+	rates, err := collectAll[models.OrderFXRate](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order fx rates: %w", err)
+	}
+	return rates, nil
+}
+
+// LatestDeliveredAt returns when the last delivered shipment of an order
+// arrived, or ErrNotFound if nothing was delivered
+func (r *OrderRepository) LatestDeliveredAt(ctx context.Context, orderID uuid.UUID) (time.Time, error) {
+	var deliveredAt *time.Time
+	err := r.db.QueryRow(ctx, `SELECT MAX(delivered_at) FROM order_shipments WHERE order_id = $1`, orderID).Scan(&deliveredAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get delivery time: %w", err)
+	}
+	if deliveredAt == nil {
+		return time.Time{}, ErrNotFound
+	}
+	return *deliveredAt, nil
+}
+
+// LatestProductDelivery returns when a shipment last delivered an order item
+// of productID ordered by userID, or ErrNotFound if none was delivered.
+// Cancelled and refunded orders do not count.
+func (r *OrderRepository) LatestProductDelivery(ctx context.Context, userID, productID uuid.UUID) (time.Time, error) {
+	var deliveredAt *time.Time
+	err := r.db.QueryRow(ctx, `
+		SELECT MAX(s.delivered_at)
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.id
+		JOIN order_shipment_items si ON si.order_item_id = oi.id
+		JOIN order_shipments s ON s.id = si.shipment_id
+		WHERE o.user_id = $1 AND oi.product_id = $2
+			AND o.status NOT IN ('cancelled', 'refunded')`, userID, productID).Scan(&deliveredAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get product delivery time: %w", err)
+	}
+	if deliveredAt == nil {
+		return time.Time{}, ErrNotFound
+	}
+	return *deliveredAt, nil
+}
+
+// CoPurchases returns up to limit pairs of products bought together in at
+// least minOrders orders placed since since, ordered by product and related
+// product after the (afterProduct, afterRelated) position. Cancelled and
+// refunded orders do not count.
+func (r *OrderRepository) CoPurchases(ctx context.Context, since time.Time, minOrders int, afterProduct, afterRelated uuid.UUID, limit int) ([]models.ProductCoPurchase, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT a.product_id, b.product_id AS related_id, COUNT(DISTINCT o.id)::int AS orders
+		FROM orders o
+		JOIN order_items a ON a.order_id = o.id
+		JOIN order_items b ON b.order_id = o.id AND b.product_id <> a.product_id
+		WHERE o.created_at >= $1 AND o.status NOT IN ('cancelled', 'refunded')
+			AND (a.product_id, b.product_id) > ($3, $4)
+		GROUP BY a.product_id, b.product_id
+		HAVING COUNT(DISTINCT o.id) >= $2
+		ORDER BY a.product_id, b.product_id
+		LIMIT $5`, since, minOrders, afterProduct, afterRelated, limit)
+	pairs, err := collectAll[models.ProductCoPurchase](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list co-purchases: %w", err)
+	}
+	return pairs, nil
+}
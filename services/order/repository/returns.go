@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/order/models"
+)
+
+const returnColumns = `id, order_id, user_id, rma_number, status, reason, currency, refund_amount,
+	refunded_amount, created_at, updated_at`
+
+const returnItemColumns = `id, return_id, order_item_id, product_id, variant_id, quantity, unit_price, reason,
+	received_quantity, disposition, warehouse_id, received_at, created_at`
+
+// ReturnRepository provides access to returns, their items and history
+type ReturnRepository struct {
+	db DBTX
+}
+
+// NewReturnRepository creates a new ReturnRepository
+func NewReturnRepository(db DBTX) *ReturnRepository {
+	return &ReturnRepository{db: db}
+}
+
+// Create inserts a new return
+func (r *ReturnRepository) Create(ctx context.Context, ret *models.OrderReturn) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO order_returns (order_id, user_id, rma_number, status, reason, currency)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`,
+		ret.OrderID, ret.UserID, ret.RMANumber, ret.Status, ret.Reason, ret.Currency,
+	).Scan(&ret.ID, &ret.CreatedAt, &ret.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create return: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns a return by its ID
+func (r *ReturnRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.OrderReturn, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+returnColumns+` FROM order_returns WHERE id = $1`, id)
+	ret, err := collectOne[models.OrderReturn](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get return: %w", err)
+	}
+	return ret, nil
+}
+
+// GetByIDForUpdate returns a return by its ID and locks it until the
+// surrounding transaction ends
+func (r *ReturnRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*models.OrderReturn, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+returnColumns+` FROM order_returns WHERE id = $1 FOR UPDATE`, id)
+	ret, err := collectOne[models.OrderReturn](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get return: %w", err)
+	}
+	return ret, nil
+}
+
+// ListByOrder returns the returns of an order, newest first
+func (r *ReturnRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.OrderReturn, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+returnColumns+` FROM order_returns WHERE order_id = $1 ORDER BY created_at DESC`, orderID)
+	returns, err := collectAll[models.OrderReturn](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list returns: %w", err)
+	}
+	return returns, nil
+}
+
+// Update persists the status and refund amounts of a return
+func (r *ReturnRepository) Update(ctx context.Context, ret *models.OrderReturn) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE order_returns SET status = $2, refund_amount = $3, refunded_amount = $4, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`,
+		ret.ID, ret.Status, ret.RefundAmount, ret.RefundedAmount,
+	).Scan(&ret.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update return: %w", err)
+	}
+	return nil
+}
+
+// CreateItem inserts a return item
+func (r *ReturnRepository) CreateItem(ctx context.Context, item *models.ReturnItem) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO return_items (return_id, order_item_id, product_id, variant_id, quantity, unit_price, reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`,
+		item.ReturnID, item.OrderItemID, item.ProductID, item.VariantID, item.Quantity, item.UnitPrice, item.Reason,
+	).Scan(&item.ID, &item.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create return item: %w", err)
+	}
+	return nil
+}
+
+// ListItems returns the items of a return
+func (r *ReturnRepository) ListItems(ctx context.Context, returnID uuid.UUID) ([]models.ReturnItem, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+returnItemColumns+` FROM return_items WHERE return_id = $1 ORDER BY created_at, id`, returnID)
+	items, err := collectAll[models.ReturnItem](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list return items: %w", err)
+	}
+	return items, nil
+}
+
+// UpdateItemReceipt records how much of a return item was received and what
+// was done with it
+func (r *ReturnRepository) UpdateItemReceipt(ctx context.Context, item *models.ReturnItem) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE return_items SET received_quantity = $2, disposition = $3, warehouse_id = $4, received_at = $5
+		WHERE id = $1`,
+		item.ID, item.ReceivedQuantity, item.Disposition, item.WarehouseID, item.ReceivedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update return item: %w", err)
+	}
+	return nil
+}
+
+// ReturnedQuantities returns, per order item, the quantity already included
+// in returns that were not rejected
+func (r *ReturnRepository) ReturnedQuantities(ctx context.Context, orderID uuid.UUID) (map[uuid.UUID]int, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT ri.order_item_id,
+			SUM(CASE WHEN rt.status IN ('received', 'refunded') THEN ri.received_quantity ELSE ri.quantity END)
+		FROM return_items ri
+		JOIN order_returns rt ON rt.id = ri.return_id
+		WHERE rt.order_id = $1 AND rt.status <> $2
+		GROUP BY ri.order_item_id`, orderID, models.ReturnStatusRejected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum returned quantities: %w", err)
+	}
+	defer rows.Close()
+
+	quantities := map[uuid.UUID]int{}
+	for rows.Next() {
+		var id uuid.UUID
+		var qty int
+		if err := rows.Scan(&id, &qty); err != nil {
+			return nil, fmt.Errorf("failed to scan returned quantity: %w", err)
+		}
+		quantities[id] = qty
+	}
+	return quantities, rows.Err()
+}
+
+// AddHistory records a step in the lifecycle of a return
+func (r *ReturnRepository) AddHistory(ctx context.Context, h *models.ReturnStatusHistory) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO return_status_history (return_id, status, actor, note)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		h.ReturnID, h.Status, h.Actor, h.Note,
+	).Scan(&h.ID, &h.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record return history: %w", err)
+	}
+	return nil
+}
+
+// ListHistory returns the lifecycle of a return, oldest first
+func (r *ReturnRepository) ListHistory(ctx context.Context, returnID uuid.UUID) ([]models.ReturnStatusHistory, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, return_id, status, actor, note, created_at
+		FROM return_status_history WHERE return_id = $1 ORDER BY created_at`, returnID)
+	history, err := collectAll[models.ReturnStatusHistory](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list return history: %w", err)
+	}
+	return history, nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/order/models"
+)
+
+// RevisionRepository provides access to order revisions
+type RevisionRepository struct {
+	db DBTX
+}
+
+// NewRevisionRepository creates a new RevisionRepository
+func NewRevisionRepository(db DBTX) *RevisionRepository {
+	return &RevisionRepository{db: db}
+}
+
+// Create records the next revision of an order. Callers must hold the order
+// row lock so revision numbers are assigned in order.
+func (r *RevisionRepository) Create(ctx context.Context, rev *models.OrderRevision) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO order_revisions (order_id, revision, actor, reason, items, subtotal, tax_amount,
+			shipping_amount, discount_amount, total, shipping_address)
+		VALUES ($1, (SELECT COALESCE(MAX(revision), 0) + 1 FROM order_revisions WHERE order_id = $1),
+			$2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, revision, created_at`,
+		rev.OrderID, rev.Actor, rev.Reason, rev.Items, rev.Subtotal, rev.TaxAmount,
+		rev.ShippingAmount, rev.DiscountAmount, rev.Total, rev.ShippingAddress,
+	).Scan(&rev.ID, &rev.Revision, &rev.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create order revision: %w", err)
+	}
+	return nil
+}
+
+// ListByOrder returns the revisions of an order, oldest first
+func (r *RevisionRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.OrderRevision, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, order_id, revision, actor, reason, items, subtotal, tax_amount, shipping_amount,
+			discount_amount, total, shipping_address, created_at
+		FROM order_revisions WHERE order_id = $1 ORDER BY revision`, orderID)
+	revisions, err := collectAll[models.OrderRevision](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// Count returns the number of revisions recorded for an order
+func (r *RevisionRepository) Count(ctx context.Context, orderID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM order_revisions WHERE order_id = $1`, orderID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count order revisions: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/order/models"
+)
+
+// OrderSort names a column orders can be sorted by
+type OrderSort string
+
+const (
+	OrderSortCreatedAt   OrderSort = "created_at"
+	OrderSortUpdatedAt   OrderSort = "updated_at"
+	OrderSortTotal       OrderSort = "total"
+	OrderSortOrderNumber OrderSort = "order_number"
+)
+
+// orderSortColumns maps sort names to the column and the SQL type cursor
+// values are cast to
+var orderSortColumns = map[OrderSort]struct{ column, cast string }{
+	OrderSortCreatedAt:   {"o.created_at", "timestamptz"},
+	OrderSortUpdatedAt:   {"o.updated_at", "timestamptz"},
+	OrderSortTotal:       {"o.total", "numeric"},
+	OrderSortOrderNumber: {"o.order_number", "text"},
+}
+
+// ValidOrderSort reports whether orders can be sorted by s
+func ValidOrderSort(s OrderSort) bool {
+	_, ok := orderSortColumns[s]
+	return ok
+}
+
+// OrderFilter narrows an order search. Empty fields do not filter.
+type OrderFilter struct {
+	OrderNumber     string
+	UserID          *uuid.UUID
+	Statuses        []models.OrderStatus
+	Currency        string
+	CreatedFrom     *time.Time
+	CreatedTo       *time.Time
+	MinTotal        *float64
+	MaxTotal        *float64
+	SKU             string
+	ProductID       *uuid.UUID
+	ShippingCountry string
+}
+
+// OrderKey is the position of an order in a sorted search: the sort column
+// value followed by the (created_at, id) tie-breaker
+type OrderKey struct {
+	Value     string    `json:"v"`
+	CreatedAt time.Time `json:"c"`
+	ID        uuid.UUID `json:"i"`
+}
+
+// OrderQuery is a filtered, sorted search that continues after After when
+// it is set
+type OrderQuery struct {
+	Filter     OrderFilter
+	Sort       OrderSort
+	Descending bool
+	After      *OrderKey
+}
+
+// SortKey returns the position of o in a search sorted by s
+func SortKey(o *models.Order, s OrderSort) OrderKey {
+	key := OrderKey{CreatedAt: o.CreatedAt, ID: o.ID}
+	switch s {
+	case OrderSortUpdatedAt:
+		key.Value = o.UpdatedAt.Format(time.RFC3339Nano)
+	case OrderSortTotal:
+		key.Value = strconv.FormatFloat(o.Total, 'f', 2, 64)
+	case OrderSortOrderNumber:
+		key.Value = o.OrderNumber
+	default:
+		key.Value = o.CreatedAt.Format(time.RFC3339Nano)
+	}
+	return key
+}
+
+// Search returns up to limit orders matching q
+func (r *OrderRepository) Search(ctx context.Context, q OrderQuery, limit int) ([]models.Order, error) {
+	sql, args := buildOrderSearch(q)
+	args = append(args, limit)
+	rows, err := r.db.Query(ctx, sql+fmt.Sprintf(" LIMIT $%d", len(args)), args...)
+	orders, err := collectAll[models.Order](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search orders: %w", err)
+	}
+	return orders, nil
+}
+
+// Stream calls fn for every order matching q, reading rows from the
+// database as fn consumes them
+func (r *OrderRepository) Stream(ctx context.Context, q OrderQuery, fn func(*models.Order) error) error {
+	sql, args := buildOrderSearch(q)
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to search orders: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		o, err := pgx.RowToStructByName[models.Order](rows)
+		if err != nil {
+			return fmt.Errorf("failed to read order: %w", err)
+		}
+		if err := fn(&o); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to search orders: %w", err)
+	}
+	return nil
+}
+
+// buildOrderSearch renders q as a SELECT with positional arguments
+func buildOrderSearch(q OrderQuery) (string, []any) {
+	var (
+		where []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	f := q.Filter
+	if f.OrderNumber != "" {
+		where = append(where, "o.order_number = "+arg(f.OrderNumber))
+	}
+	if f.UserID != nil {
+		where = append(where, "o.user_id = "+arg(*f.UserID))
+	}
+	if len(f.Statuses) > 0 {
+		statuses := make([]string, len(f.Statuses))
+		for i, s := range f.Statuses {
+			statuses[i] = string(s)
+		}
+		where = append(where, "o.status = ANY("+arg(statuses)+")")
+	}
+	if f.Currency != "" {
+		where = append(where, "o.currency = "+arg(f.Currency))
+	}
+	if f.CreatedFrom != nil {
+		where = append(where, "o.created_at >= "+arg(*f.CreatedFrom))
+	}
+	if f.CreatedTo != nil {
+		where = append(where, "o.created_at < "+arg(*f.CreatedTo))
+	}
+	if f.MinTotal != nil {
+		where = append(where, "o.total >= "+arg(*f.MinTotal))
+	}
+	if f.MaxTotal != nil {
+		where = append(where, "o.total <= "+arg(*f.MaxTotal))
+	}
+	if f.SKU != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = o.id AND oi.sku = "+arg(f.SKU)+")")
+	}
+	if f.ProductID != nil {
+		where = append(where, "EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = o.id AND oi.product_id = "+arg(*f.ProductID)+")")
+	}
+	if f.ShippingCountry != "" {
+		where = append(where, "o.shipping_address->>'country' = "+arg(f.ShippingCountry))
+	}
+
+	sortBy := q.Sort
+	if !ValidOrderSort(sortBy) {
+		sortBy = OrderSortCreatedAt
+	}
+	sort := orderSortColumns[sortBy]
+	dir, cmp := "ASC", ">"
+	if q.Descending {
+		dir, cmp = "DESC", "<"
+	}
+	keyColumns := []string{"o.created_at", "o.id"}
+	if sortBy != OrderSortCreatedAt {
+		keyColumns = append([]string{sort.column}, keyColumns...)
+	}
+	if q.After != nil {
+		values := []string{arg(q.After.CreatedAt), arg(q.After.ID)}
+		if sortBy != OrderSortCreatedAt {
+			values = append([]string{arg(q.After.Value) + "::" + sort.cast}, values...)
+		}
+		where = append(where, "("+strings.Join(keyColumns, ", ")+") "+cmp+" ("+strings.Join(values, ", ")+")")
+	}
+
+	sql := `SELECT o.id, o.user_id, o.order_number, o.status, o.subtotal, o.tax_amount, o.shipping_amount,
+			o.discount_amount, o.total, o.currency, o.shipping_address, o.billing_address, o.notes,
+			o.created_at, o.updated_at
+		FROM orders o`
+	if len(where) > 0 {
+		sql += "\n\t\tWHERE " + strings.Join(where, "\n\t\t\tAND ")
+	}
+	order := make([]string, len(keyColumns))
+	for i, col := range keyColumns {
+		order[i] = col + " " + dir
+	}
+	sql += "\n\t\tORDER BY " + strings.Join(order, ", ")
+	return sql, args
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/order/models"
+)
+
+const shipmentColumns = `id, order_id, warehouse_id, carrier, tracking_no, label_url, shipped_at, delivered_at,
+	cancelled_at, created_at, updated_at`
+
+const shipmentItemColumns = `id, shipment_id, order_item_id, product_id, variant_id, quantity, created_at`
+
+// ShipmentRepository provides access to order shipments and their items
+type ShipmentRepository struct {
+	db DBTX
+}
+
+// NewShipmentRepository creates a new ShipmentRepository
+func NewShipmentRepository(db DBTX) *ShipmentRepository {
+	return &ShipmentRepository{db: db}
+}
+
+// Create inserts a new shipment
+func (r *ShipmentRepository) Create(ctx context.Context, s *models.OrderShipment) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO order_shipments (order_id, warehouse_id, carrier, tracking_no, label_url, shipped_at, delivered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`,
+		s.OrderID, s.WarehouseID, s.Carrier, s.TrackingNo, s.LabelURL, s.ShippedAt, s.DeliveredAt,
+	).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create shipment: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns a shipment by its ID
+func (r *ShipmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.OrderShipment, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+shipmentColumns+` FROM order_shipments WHERE id = $1`, id)
+	s, err := collectOne[models.OrderShipment](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipment: %w", err)
+	}
+	return s, nil
+}
+
+// GetByIDForUpdate returns a shipment by its ID and locks it until the
+// surrounding transaction ends
+func (r *ShipmentRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*models.OrderShipment, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+shipmentColumns+` FROM order_shipments WHERE id = $1 FOR UPDATE`, id)
+	s, err := collectOne[models.OrderShipment](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipment: %w", err)
+	}
+	return s, nil
+}
+
+// ListByOrder returns the shipments of an order, oldest first
+func (r *ShipmentRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.OrderShipment, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+shipmentColumns+` FROM order_shipments WHERE order_id = $1 ORDER BY created_at, id`, orderID)
+	shipments, err := collectAll[models.OrderShipment](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shipments: %w", err)
+	}
+	return shipments, nil
+}
+
+// Update persists the tracking and delivery details of a shipment
+func (r *ShipmentRepository) Update(ctx context.Context, s *models.OrderShipment) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE order_shipments SET carrier = $2, tracking_no = $3, label_url = $4, shipped_at = $5, delivered_at = $6,
+			cancelled_at = $7, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`,
+		s.ID, s.Carrier, s.TrackingNo, s.LabelURL, s.ShippedAt, s.DeliveredAt, s.CancelledAt,
+	).Scan(&s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update shipment: %w", err)
+	}
+	return nil
+}
+
+// CreateItem inserts a shipment line item
+func (r *ShipmentRepository) CreateItem(ctx context.Context, item *models.ShipmentItem) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO order_shipment_items (shipment_id, order_item_id, product_id, variant_id, quantity)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		item.ShipmentID, item.OrderItemID, item.ProductID, item.VariantID, item.Quantity,
+	).Scan(&item.ID, &item.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create shipment item: %w", err)
+	}
+	return nil
+}
+
+// ListItems returns the line items of a shipment
+func (r *ShipmentRepository) ListItems(ctx context.Context, shipmentID uuid.UUID) ([]models.ShipmentItem, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+shipmentItemColumns+` FROM order_shipment_items WHERE shipment_id = $1 ORDER BY created_at, id`, shipmentID)
+	items, err := collectAll[models.ShipmentItem](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shipment items: %w", err)
+	}
+	return items, nil
+}
+
+// GetByTrackingForUpdate returns the shipment with a carrier's tracking
+// number and locks it until the surrounding transaction ends
+func (r *ShipmentRepository) GetByTrackingForUpdate(ctx context.Context, carrier, trackingNo string) (*models.OrderShipment, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+shipmentColumns+` FROM order_shipments
+		WHERE carrier = $1 AND tracking_no = $2 ORDER BY created_at DESC LIMIT 1 FOR UPDATE`, carrier, trackingNo)
+	s, err := collectOne[models.OrderShipment](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipment: %w", err)
+	}
+	return s, nil
+}
+
+// ListAwaitingDelivery returns tracked shipments of the given carriers that
+// have not been delivered yet, least recently updated first
+func (r *ShipmentRepository) ListAwaitingDelivery(ctx context.Context, carriers []string, limit int) ([]models.OrderShipment, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+shipmentColumns+` FROM order_shipments
+		WHERE carrier = ANY($1) AND tracking_no IS NOT NULL AND delivered_at IS NULL AND cancelled_at IS NULL
+		ORDER BY updated_at LIMIT $2`, carriers, limit)
+	shipments, err := collectAll[models.OrderShipment](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shipments awaiting delivery: %w", err)
+	}
+	return shipments, nil
+}
+
+// AllocatedQuantities returns, per order item, the quantity included in the
+// order's shipments that were not cancelled
+func (r *ShipmentRepository) AllocatedQuantities(ctx context.Context, orderID uuid.UUID) (map[uuid.UUID]int, error) {
+	return r.sumQuantities(ctx, `
+		SELECT si.order_item_id, SUM(si.quantity)
+		FROM order_shipment_items si
+		JOIN order_shipments s ON s.id = si.shipment_id
+		WHERE s.order_id = $1 AND s.cancelled_at IS NULL
+		GROUP BY si.order_item_id`, orderID)
+}
+
+// DepartedQuantities returns, per order item, the quantity included in the
+// order's shipments that have left the warehouse
+func (r *ShipmentRepository) DepartedQuantities(ctx context.Context, orderID uuid.UUID) (map[uuid.UUID]int, error) {
+	return r.sumQuantities(ctx, `
+		SELECT si.order_item_id, SUM(si.quantity)
+		FROM order_shipment_items si
+		JOIN order_shipments s ON s.id = si.shipment_id
+		WHERE s.order_id = $1 AND s.shipped_at IS NOT NULL
+		GROUP BY si.order_item_id`, orderID)
+}
+
+func (r *ShipmentRepository) sumQuantities(ctx context.Context, query string, orderID uuid.UUID) (map[uuid.UUID]int, error) {
+	rows, err := r.db.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum shipped quantities: %w", err)
+	}
+	defer rows.Close()
+
+	quantities := map[uuid.UUID]int{}
+	for rows.Next() {
+		var id uuid.UUID
+		var qty int
+		if err := rows.Scan(&id, &qty); err != nil {
+			return nil, fmt.Errorf("failed to scan shipped quantity: %w", err)
+		}
+		quantities[id] = qty
+	}
+	return quantities, rows.Err()
+}
+
+// AddTrackingEvent stores a tracking event unless the shipment already has
+// an event with the same external ID. It reports whether the event was new.
+func (r *ShipmentRepository) AddTrackingEvent(ctx context.Context, e *models.ShipmentTrackingEvent) (bool, error) {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO shipment_tracking_events (shipment_id, carrier, external_id, code, description, location, occurred_at, source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (shipment_id, external_id) DO NOTHING
+		RETURNING id, created_at`,
+		e.ShipmentID, e.Carrier, e.ExternalID, e.Code, e.Description, e.Location, e.OccurredAt, e.Source,
+	).Scan(&e.ID, &e.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to add tracking event: %w", err)
+	}
+	return true, nil
+}
+
+// ListTrackingEvents returns the tracking events of a shipment in the order
+// they occurred
+func (r *ShipmentRepository) ListTrackingEvents(ctx context.Context, shipmentID uuid.UUID) ([]models.ShipmentTrackingEvent, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, shipment_id, carrier, external_id, code, description, location, occurred_at, source, created_at
+		FROM shipment_tracking_events WHERE shipment_id = $1 ORDER BY occurred_at, created_at`, shipmentID)
+	events, err := collectAll[models.ShipmentTrackingEvent](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tracking events: %w", err)
+	}
+	return events, nil
+}
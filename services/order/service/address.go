@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/text/language"
+	"main.go/services/order/models"
+	"main.go/services/order/repository"
+)
+
+// maxSavedAddresses caps the size of a user's address book
+const maxSavedAddresses = 50
+
+// addressRule describes the postal conventions of a country
+type addressRule struct {
+	postalCode    *regexp.Regexp
+	stateRequired bool
+}
+
+// addressRules holds the countries with known postal code formats. Postal
+// codes are matched after normalization. Countries not listed accept any
+// postal code and do not require a state.
+var addressRules = map[string]addressRule{
+	"US": {regexp.MustCompile(`^\d{5}(-\d{4})?$`), true},
+	"CA": {regexp.MustCompile(`^[A-Z]\d[A-Z] \d[A-Z]\d$`), true},
+	"MX": {regexp.MustCompile(`^\d{5}$`), true},
+	"BR": {regexp.MustCompile(`^\d{5}-\d{3}$`), true},
+	"AU": {regexp.MustCompile(`^\d{4}$`), true},
+	"IN": {regexp.MustCompile(`^\d{6}$`), true},
+	"JP": {regexp.MustCompile(`^\d{3}-\d{4}$`), true},
+	"GB": {regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? \d[A-Z]{2}$`), false},
+	"NL": {regexp.MustCompile(`^\d{4} [A-Z]{2}$`), false},
+	"DE": {regexp.MustCompile(`^\d{5}$`), false},
+	"FR": {regexp.MustCompile(`^\d{5}$`), false},
+	"IT": {regexp.MustCompile(`^\d{5}$`), false},
+	"ES": {regexp.MustCompile(`^\d{5}$`), false},
+	"SE": {regexp.MustCompile(`^\d{3} \d{2}$`), false},
+	"PL": {regexp.MustCompile(`^\d{2}-\d{3}$`), false},
+	"AT": {regexp.MustCompile(`^\d{4}$`), false},
+	"BE": {regexp.MustCompile(`^\d{4}$`), false},
+	"CH": {regexp.MustCompile(`^\d{4}$`), false},
+	"DK": {regexp.MustCompile(`^\d{4}$`), false},
+	"NO": {regexp.MustCompile(`^\d{4}$`), false},
+	"NZ": {regexp.MustCompile(`^\d{4}$`), false},
+}
+
+// postalCodeFormats rewrites postal codes typed without their separator
+var postalCodeFormats = map[string]struct {
+	compact *regexp.Regexp
+	format  string
+}{
+	"CA": {regexp.MustCompile(`^([A-Z]\d[A-Z])(\d[A-Z]\d)$`), "$1 $2"},
+	"BR": {regexp.MustCompile(`^(\d{5})(\d{3})$`), "$1-$2"},
+	"JP": {regexp.MustCompile(`^(\d{3})(\d{4})$`), "$1-$2"},
+	"GB": {regexp.MustCompile(`^([A-Z]{1,2}\d[A-Z\d]?)(\d[A-Z]{2})$`), "$1 $2"},
+	"NL": {regexp.MustCompile(`^(\d{4})([A-Z]{2})$`), "$1 $2"},
+	"SE": {regexp.MustCompile(`^(\d{3})(\d{2})$`), "$1 $2"},
+	"PL": {regexp.MustCompile(`^(\d{2})(\d{3})$`), "$1-$2"},
+}
+
+// normalizeAddress trims every field, upper-cases the country and postal
+// code and validates the address against the rules of its country. field
+// names the address in error messages.
+func normalizeAddress(a *models.Address, field string) error {
+	a.FirstName = strings.TrimSpace(a.FirstName)
+	a.LastName = strings.TrimSpace(a.LastName)
+	a.Address1 = strings.TrimSpace(a.Address1)
+	a.City = strings.TrimSpace(a.City)
+	a.State = strings.TrimSpace(a.State)
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	a.PostalCode = strings.Join(strings.Fields(strings.ToUpper(a.PostalCode)), " ")
+	a.Company = trimmedOrNil(a.Company)
+	a.Address2 = trimmedOrNil(a.Address2)
+	a.Phone = trimmedOrNil(a.Phone)
+	a.Email = trimmedOrNil(a.Email)
+
+	if a.FirstName == "" || a.LastName == "" {
+		return fmt.Errorf("%w: %s first_name and last_name are required", ErrValidation, field)
+	}
+	if a.Address1 == "" || a.City == "" {
+		return fmt.Errorf("%w: %s address1 and city are required", ErrValidation, field)
+	}
+	if len(a.Country) != 2 {
+		return fmt.Errorf("%w: %s country must be an ISO 3166-1 alpha-2 code", ErrValidation, field)
+	}
+	region, err := language.ParseRegion(a.Country)
+	if err != nil || !region.IsCountry() || region.String() != a.Country {
+		return fmt.Errorf("%w: %s country %q is not a known country code", ErrValidation, field, a.Country)
+	}
+	if a.Email != nil {
+		if _, err := mail.ParseAddress(*a.Email); err != nil {
+			return fmt.Errorf("%w: %s email is invalid", ErrValidation, field)
+		}
+	}
+
+	rule, ok := addressRules[a.Country]
+	if !ok {
+		if len(a.PostalCode) > 20 {
+			return fmt.Errorf("%w: %s postal_code is too long", ErrValidation, field)
+		}
+		return nil
+	}
+	if f, ok := postalCodeFormats[a.Country]; ok {
+		a.PostalCode = f.compact.ReplaceAllString(a.PostalCode, f.format)
+	}
+	if !rule.postalCode.MatchString(a.PostalCode) {
+		return fmt.Errorf("%w: %s postal_code %q is not valid for %s", ErrValidation, field, a.PostalCode, a.Country)
+	}
+	if rule.stateRequired && a.State == "" {
+		return fmt.Errorf("%w: %s state is required for %s", ErrValidation, field, a.Country)
+	}
+	return nil
+}
+
+// SaveAddressInput holds an address to save in a user's address book
+type SaveAddressInput struct {
+	Label             *string        `json:"label,omitempty"`
+	Address           models.Address `json:"address"`
+	IsDefaultShipping bool           `json:"is_default_shipping"`
+	IsDefaultBilling  bool           `json:"is_default_billing"`
+}
+
+// AddressService manages users' saved addresses
+type AddressService struct {
+	pool *pgxpool.Pool
+}
+
+// NewAddressService creates a new AddressService
+func NewAddressService(pool *pgxpool.Pool) *AddressService {
+	return &AddressService{pool: pool}
+}
+
+// Create adds an address to a user's address book. Marking it as a default
+// takes the default over from the user's other addresses.
+func (s *AddressService) Create(ctx context.Context, userID uuid.UUID, in SaveAddressInput) (*models.SavedAddress, error) {
+	if userID == uuid.Nil {
+		return nil, fmt.Errorf("%w: user_id is required", ErrValidation)
+	}
+	if err := normalizeAddress(&in.Address, "address"); err != nil {
+		return nil, err
+	}
+
+	a := &models.SavedAddress{
+		UserID:            userID,
+		Label:             trimmedOrNil(in.Label),
+		Address:           in.Address,
+		IsDefaultShipping: in.IsDefaultShipping,
+		IsDefaultBilling:  in.IsDefaultBilling,
+	}
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		addresses := repository.NewAddressRepository(tx)
+		existing, err := addresses.ListByUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if len(existing) >= maxSavedAddresses {
+			return fmt.Errorf("%w: address book is full (%d addresses)", ErrInvalidState, maxSavedAddresses)
+		}
+		if err := addresses.ClearDefaults(ctx, userID, a.IsDefaultShipping, a.IsDefaultBilling); err != nil {
+			return err
+		}
+		return addresses.Create(ctx, a)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Get returns a saved address
+func (s *AddressService) Get(ctx context.Context, id uuid.UUID) (*models.SavedAddress, error) {
+	return repository.NewAddressRepository(s.pool).GetByID(ctx, id)
+}
+
+// ListByUser returns a user's address book, defaults first
+func (s *AddressService) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.SavedAddress, error) {
+	return repository.NewAddressRepository(s.pool).ListByUser(ctx, userID)
+}
+
+// Update replaces a saved address. Orders placed with it keep their copy.
+func (s *AddressService) Update(ctx context.Context, id uuid.UUID, in SaveAddressInput) (*models.SavedAddress, error) {
+	if err := normalizeAddress(&in.Address, "address"); err != nil {
+		return nil, err
+	}
+
+	var saved *models.SavedAddress
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		addresses := repository.NewAddressRepository(tx)
+		a, err := addresses.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		a.Label = trimmedOrNil(in.Label)
+		a.Address = in.Address
+		if err := addresses.ClearDefaults(ctx, a.UserID, in.IsDefaultShipping && !a.IsDefaultShipping, in.IsDefaultBilling && !a.IsDefaultBilling); err != nil {
+			return err
+		}
+		a.IsDefaultShipping = in.IsDefaultShipping
+		a.IsDefaultBilling = in.IsDefaultBilling
+		saved = a
+		return addresses.Update(ctx, a)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// Delete removes a saved address
+func (s *AddressService) Delete(ctx context.Context, id uuid.UUID) error {
+	return repository.NewAddressRepository(s.pool).Delete(ctx, id)
+}
+
+// resolveAddress returns the address to use for an order: an inline
+// address, a saved one of the user, or the user's default when neither is
+// given. It returns nil when the user has no default either.
+func resolveAddress(ctx context.Context, db repository.DBTX, userID uuid.UUID, inline *models.Address, savedID *uuid.UUID, billing bool, field string) (*models.Address, error) {
+	if inline != nil && savedID != nil {
+		return nil, fmt.Errorf("%w: give either %s or %s_id", ErrValidation, field, field)
+	}
+	if inline != nil {
+		a := *inline
+		if err := normalizeAddress(&a, field); err != nil {
+			return nil, err
+		}
+		return &a, nil
+	}
+
+	addresses := repository.NewAddressRepository(db)
+	var (
+		saved *models.SavedAddress
+		err   error
+	)
+	if savedID != nil {
+		saved, err = addresses.GetByID(ctx, *savedID)
+		if err == nil && saved.UserID != userID {
+			err = repository.ErrNotFound
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %s_id %s is not in the user's address book", ErrValidation, field, *savedID)
+		}
+	} else {
+		saved, err = addresses.GetDefault(ctx, userID, billing)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &saved.Address, nil
+}
@@ -0,0 +1,425 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/order/carrier"
+	"main.go/services/order/clients"
+	"main.go/services/order/models"
+	"main.go/services/order/repository"
+)
+
+// maxCancellationAttempts is how often a cancellation task is tried before
+// it is marked failed and the order is flagged for support
+const maxCancellationAttempts = 10
+
+// cancellationRetryBatch caps the number of tasks retried per run
+const cancellationRetryBatch = 100
+
+// OrderFlagCancellationFailed marks orders whose cancellation side effects
+// could not be completed
+const OrderFlagCancellationFailed = "cancellation_failed"
+
+// cancelledShipmentReferenceType tags movements restocking cancelled shipments
+const cancelledShipmentReferenceType = "shipment_cancellation"
+
+// cancellableStatuses lists the statuses an order can be cancelled from
+var cancellableStatuses = map[models.OrderStatus]bool{
+	models.OrderStatusPending:    true,
+	models.OrderStatusConfirmed:  true,
+	models.OrderStatusProcessing: true,
+}
+
+// cancellationTasks are the side effects carried out for every cancellation,
+// in order
+var cancellationTasks = []models.CancellationTaskType{
+	models.CancellationTaskReleaseStock,
+	models.CancellationTaskRestockShipments,
+	models.CancellationTaskSettlePayments,
+}
+
+// cancellationNotes describe completed tasks in the order status history
+var cancellationNotes = map[models.CancellationTaskType]string{
+	models.CancellationTaskReleaseStock:     "stock reservations released",
+	models.CancellationTaskRestockShipments: "unshipped items restocked",
+	models.CancellationTaskSettlePayments:   "payments voided or refunded",
+}
+
+// StockReleaser returns stock held for an order to the inventory service
+type StockReleaser interface {
+	StockRecorder
+	ReleaseReservations(ctx context.Context, orderID uuid.UUID, reason string) error
+}
+
+// PaymentSettler voids and refunds payments through the payment service
+type PaymentSettler interface {
+	PaymentRefunder
+	Void(ctx context.Context, paymentID uuid.UUID) (*clients.Payment, error)
+}
+
+// CancelOrderInput holds the reason for cancelling an order
+type CancelOrderInput struct {
+	Reason string `json:"reason"`
+}
+
+// CancellationResult is a cancelled order and the progress of its side
+// effects
+type CancellationResult struct {
+	Order *models.Order             `json:"order"`
+	Tasks []models.CancellationTask `json:"tasks"`
+}
+
+// CancellationService cancels orders and carries the cancellation over to
+// inventory, carriers and payments. Side effects are recorded as tasks and
+// retried until they complete, so a cancellation is eventually consistent
+// even when another service is unavailable.
+type CancellationService struct {
+	pool      *pgxpool.Pool
+	inventory StockReleaser
+	payments  PaymentSettler
+	carriers  carrier.Registry
+	now       func() time.Time
+}
+
+// NewCancellationService creates a new CancellationService
+func NewCancellationService(pool *pgxpool.Pool, inventory StockReleaser, payments PaymentSettler, carriers carrier.Registry) *CancellationService {
+	return &CancellationService{pool: pool, inventory: inventory, payments: payments, carriers: carriers, now: time.Now}
+}
+
+// Cancel moves an order to cancelled with the reason in its history, then
+// releases reserved stock, restocks shipments that have not left, voids
+// uncaptured payments and refunds captured ones. Side effects that fail stay
+// pending and are retried in the background.
+func (s *CancellationService) Cancel(ctx context.Context, id uuid.UUID, in CancelOrderInput) (*CancellationResult, error) {
+	reason := strings.TrimSpace(in.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrValidation)
+	}
+
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		orders := repository.NewOrderRepository(tx)
+		o, err := orders.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !cancellableStatuses[o.Status] {
+			return fmt.Errorf("%w: order in status %s cannot be cancelled", ErrInvalidState, o.Status)
+		}
+		if err := checkNotEditing(ctx, orders, o.ID, s.now()); err != nil {
+			return err
+		}
+		if err := orders.UpdateStatus(ctx, o.ID, models.OrderStatusCancelled, &reason); err != nil {
+			return err
+		}
+
+		tasks := repository.NewCancellationRepository(tx)
+		for _, task := range cancellationTasks {
+			t := &models.CancellationTask{OrderID: o.ID, Task: task, Status: models.CancellationTaskPending}
+			if err := tasks.CreateTask(ctx, t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.runPending(ctx, id)
+	return s.Get(ctx, id)
+}
+
+// Get returns a cancelled order and the progress of its side effects
+func (s *CancellationService) Get(ctx context.Context, id uuid.UUID) (*CancellationResult, error) {
+	order, err := repository.NewOrderRepository(s.pool).GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := repository.NewCancellationRepository(s.pool).ListByOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &CancellationResult{Order: order, Tasks: tasks}, nil
+}
+
+// Retry puts the failed side effects of a cancelled order back to pending
+// and runs them again
+func (s *CancellationService) Retry(ctx context.Context, id uuid.UUID) (*CancellationResult, error) {
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		repo := repository.NewCancellationRepository(tx)
+		tasks, err := repo.ListByOrder(ctx, id)
+		if err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			return fmt.Errorf("%w: order has no cancellation in progress", ErrInvalidState)
+		}
+		for i := range tasks {
+			if tasks[i].Status != models.CancellationTaskFailed {
+				continue
+			}
+			tasks[i].Status = models.CancellationTaskPending
+			tasks[i].Attempts = 0
+			if err := repo.Update(ctx, &tasks[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.runPending(ctx, id)
+	return s.Get(ctx, id)
+}
+
+// RetryPending runs pending cancellation tasks of every order. It returns
+// the number of tasks that completed.
+func (s *CancellationService) RetryPending(ctx context.Context) (int, error) {
+	tasks, err := repository.NewCancellationRepository(s.pool).ListPending(ctx, cancellationRetryBatch)
+	if err != nil {
+		return 0, err
+	}
+	completed := 0
+	for _, t := range tasks {
+		ok, err := s.runTask(ctx, t.ID)
+		if err != nil {
+			log.Printf("cancellation task %s of order %s failed: %v", t.Task, t.OrderID, err)
+			continue
+		}
+		if ok {
+			completed++
+		}
+	}
+	return completed, nil
+}
+
+// RunCancellationRetrier calls RetryPending every interval until ctx is done
+func (s *CancellationService) RunCancellationRetrier(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.RetryPending(ctx)
+			if err != nil {
+				log.Printf("cancellation retry failed: %v", err)
+			} else if n > 0 {
+				log.Printf("completed %d cancellation tasks", n)
+			}
+		}
+	}
+}
+
+// runPending runs the pending tasks of an order in order, logging failures
+// so they are left for the retrier
+func (s *CancellationService) runPending(ctx context.Context, orderID uuid.UUID) {
+	tasks, err := repository.NewCancellationRepository(s.pool).ListByOrder(ctx, orderID)
+	if err != nil {
+		log.Printf("failed to load cancellation tasks of order %s: %v", orderID, err)
+		return
+	}
+	for _, t := range tasks {
+		if t.Status != models.CancellationTaskPending {
+			continue
+		}
+		if _, err := s.runTask(ctx, t.ID); err != nil {
+			log.Printf("cancellation task %s of order %s failed: %v", t.Task, orderID, err)
+		}
+	}
+}
+
+// runTask carries out a pending task while holding its lock and records the
+// outcome. A task failing for the last time is marked failed and the order is
+// flagged for support. It reports whether the task completed.
+func (s *CancellationService) runTask(ctx context.Context, taskID uuid.UUID) (bool, error) {
+	var taskErr error
+	completed := false
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		tasks := repository.NewCancellationRepository(tx)
+		t, err := tasks.GetForUpdate(ctx, taskID)
+		if err != nil {
+			return err
+		}
+		if t.Status != models.CancellationTaskPending {
+			return nil
+		}
+		order, err := repository.NewOrderRepository(tx).GetByID(ctx, t.OrderID)
+		if err != nil {
+			return err
+		}
+
+		t.Attempts++
+		taskErr = s.execute(ctx, tx, t.Task, order)
+		if taskErr == nil {
+			completed = true
+			t.Status = models.CancellationTaskCompleted
+			t.LastError = nil
+			if err := tasks.Update(ctx, t); err != nil {
+				return err
+			}
+			note := cancellationNotes[t.Task]
+			return repository.NewOrderRepository(tx).AddStatusHistory(ctx, order.ID, models.OrderStatusCancelled, &note)
+		}
+
+		msg := taskErr.Error()
+		t.LastError = &msg
+		if t.Attempts >= maxCancellationAttempts {
+			t.Status = models.CancellationTaskFailed
+			note := fmt.Sprintf("%s failed after %d attempts: %s", t.Task, t.Attempts, msg)
+			source := "order-service"
+			flag := &models.OrderFlag{OrderID: order.ID, Flag: OrderFlagCancellationFailed, Note: &note, Source: &source}
+			if err := repository.NewFlagRepository(tx).Set(ctx, flag); err != nil {
+				return err
+			}
+		}
+		return tasks.Update(ctx, t)
+	})
+	if err != nil {
+		return false, err
+	}
+	return completed, taskErr
+}
+
+// execute carries out a single cancellation side effect. Every side effect is
+// idempotent so it can be retried after a partial failure.
+func (s *CancellationService) execute(ctx context.Context, tx pgx.Tx, task models.CancellationTaskType, order *models.Order) error {
+	reason := "order " + order.OrderNumber + " cancelled"
+	switch task {
+	case models.CancellationTaskReleaseStock:
+		return s.inventory.ReleaseReservations(ctx, order.ID, reason)
+	case models.CancellationTaskRestockShipments:
+		return s.restockShipments(ctx, tx, order.ID, reason)
+	case models.CancellationTaskSettlePayments:
+		return s.settlePayments(ctx, order.ID, reason)
+	default:
+		return fmt.Errorf("unknown cancellation task %q", task)
+	}
+}
+
+// restockShipments cancels the labels of shipments that have not left the
+// warehouse, books their items, or the components of bundles, back in and
+// marks them cancelled
+func (s *CancellationService) restockShipments(ctx context.Context, tx pgx.Tx, orderID uuid.UUID, reason string) error {
+	shipments := repository.NewShipmentRepository(tx)
+	list, err := shipments.ListByOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	orderItems, err := repository.NewOrderRepository(tx).ListItems(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]models.OrderItem, len(orderItems))
+	for _, item := range orderItems {
+		byID[item.ID] = item
+	}
+
+	refType := cancelledShipmentReferenceType
+	for _, listed := range list {
+		if listed.ShippedAt != nil || listed.CancelledAt != nil {
+			continue
+		}
+		sh, err := shipments.GetByIDForUpdate(ctx, listed.ID)
+		if err != nil {
+			return err
+		}
+
+		if sh.Carrier != nil && sh.TrackingNo != nil {
+			if c, ok := s.carriers[*sh.Carrier]; ok {
+				err := c.CancelLabel(ctx, *sh.TrackingNo)
+				if err != nil && !errors.Is(err, carrier.ErrUnknownShipment) {
+					return fmt.Errorf("failed to cancel %s label %s: %w", *sh.Carrier, *sh.TrackingNo, err)
+				}
+			}
+		}
+
+		items, err := shipments.ListItems(ctx, sh.ID)
+		if err != nil {
+			return err
+		}
+		if sh.WarehouseID == nil {
+			items = nil
+		}
+		for _, item := range items {
+			for _, st := range itemStock(byID[item.OrderItemID], item.Quantity) {
+				refID := item.ID
+				err := s.inventory.RecordMovement(ctx, clients.StockMovement{
+					ProductID:     st.ProductID,
+					VariantID:     &st.VariantID,
+					WarehouseID:   *sh.WarehouseID,
+					Type:          "in",
+					Quantity:      st.Quantity,
+					ReferenceID:   &refID,
+					ReferenceType: &refType,
+					Reason:        &reason,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to restock shipment item %s: %w", item.ID, err)
+				}
+			}
+		}
+
+		now := s.now()
+		sh.CancelledAt = &now
+		if err := shipments.Update(ctx, sh); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settlePayments voids uncaptured payments of an order and refunds what
+// remains of captured ones. It fails, to be retried, while a payment is
+// disputed.
+func (s *CancellationService) settlePayments(ctx context.Context, orderID uuid.UUID, reason string) error {
+	payments, err := s.payments.ListPayments(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to list payments: %w", err)
+	}
+
+	for _, p := range payments {
+		switch p.Status {
+		case "pending", "authorized":
+			voided, err := s.payments.Void(ctx, p.ID)
+			if err != nil {
+				return fmt.Errorf("failed to void payment %s: %w", p.ID, err)
+			}
+			if voided.Status != "cancelled" {
+				return fmt.Errorf("void of payment %s left it %s", p.ID, voided.Status)
+			}
+		case "captured", "partially_refunded":
+			refunds, err := s.payments.ListRefunds(ctx, p.ID)
+			if err != nil {
+				return fmt.Errorf("failed to list refunds of payment %s: %w", p.ID, err)
+			}
+			remaining, err := refundableAmount(ctx, s.payments, p, refunds)
+			if err != nil {
+				return err
+			}
+			if remaining < 0.01 {
+				continue
+			}
+			refund, err := s.payments.Refund(ctx, p.ID, remaining, p.Currency, reason, "")
+			if err != nil {
+				return fmt.Errorf("failed to refund payment %s: %w", p.ID, err)
+			}
+			if refund.Status != "completed" {
+				return fmt.Errorf("refund %s of payment %s is %s", refund.ID, p.ID, refund.Status)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/order/clients"
+	"main.go/services/order/models"
+	"main.go/services/order/repository"
+)
+
+// ProductPricer resolves product variant prices in the order currency
+type ProductPricer interface {
+	ResolvePrice(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, currency string, at time.Time) (*clients.Price, error)
+}
+
+// StockAllocator reserves the stock of placed orders and releases it when
+// placement fails
+type StockAllocator interface {
+	StockReserver
+	ReleaseReservations(ctx context.Context, orderID uuid.UUID, reason string) error
+}
+
+// PlaceOrderItem is a product and quantity requested at checkout. Without a
+// VariantID the product's default variant is ordered.
+type PlaceOrderItem struct {
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Quantity  int        `json:"quantity"`
+}
+
+// PlaceOrderInput holds the contents of a checkout
+type PlaceOrderInput struct {
+	UserID            uuid.UUID        `json:"user_id"`
+	Currency          string           `json:"currency"`
+	Items             []PlaceOrderItem `json:"items"`
+	ShippingAmount    float64          `json:"shipping_amount"`
+	TaxAmount         float64          `json:"tax_amount"`
+	DiscountAmount    float64          `json:"discount_amount"`
+	ShippingAddress   *models.Address  `json:"shipping_address,omitempty"`
+	ShippingAddressID *uuid.UUID       `json:"shipping_address_id,omitempty"`
+	BillingAddress    *models.Address  `json:"billing_address,omitempty"`
+	BillingAddressID  *uuid.UUID       `json:"billing_address_id,omitempty"`
+	Notes             *string          `json:"notes,omitempty"`
+}
+
+// itemPricing is stored in OrderItem.Metadata to explain how a unit price
+// was derived
+type itemPricing struct {
+	PriceSource   string   `json:"price_source"`
+	BaseCurrency  string   `json:"base_currency"`
+	BaseUnitPrice float64  `json:"base_unit_price"`
+	FXRate        *float64 `json:"fx_rate,omitempty"`
+}
+
+// Place prices every item in the order currency and creates a pending order.
+// Prices converted from another currency lock the FX rate in effect at
+// checkout so later rate changes do not affect the order. Addresses are given
+// inline or by saved address ID, falling back to the user's defaults. Stock
+// is reserved for every item, and for each component of a bundle; the order
+// is not placed if any of it is unavailable.
+func (s *OrderService) Place(ctx context.Context, in PlaceOrderInput) (*models.Order, error) {
+	currency := strings.ToUpper(strings.TrimSpace(in.Currency))
+	if in.UserID == uuid.Nil {
+		return nil, fmt.Errorf("%w: user_id is required", ErrValidation)
+	}
+	if len(currency) != 3 {
+		return nil, fmt.Errorf("%w: currency must be a 3 letter ISO code", ErrValidation)
+	}
+	if len(in.Items) == 0 {
+		return nil, fmt.Errorf("%w: at least one item is required", ErrValidation)
+	}
+	if in.ShippingAmount < 0 || in.TaxAmount < 0 || in.DiscountAmount < 0 {
+		return nil, fmt.Errorf("%w: amounts must not be negative", ErrValidation)
+	}
+
+	shipping, err := resolveAddress(ctx, s.pool, in.UserID, in.ShippingAddress, in.ShippingAddressID, false, "shipping_address")
+	if err != nil {
+		return nil, err
+	}
+	billing, err := resolveAddress(ctx, s.pool, in.UserID, in.BillingAddress, in.BillingAddressID, true, "billing_address")
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	items := make([]models.OrderItem, 0, len(in.Items))
+	locks := map[string]*models.OrderFXRate{}
+	subtotal := 0.0
+	for _, req := range in.Items {
+		if req.Quantity < 1 {
+			return nil, fmt.Errorf("%w: quantity for product %s must be at least 1", ErrValidation, req.ProductID)
+		}
+		price, err := s.products.ResolvePrice(ctx, req.ProductID, req.VariantID, currency, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to price product %s: %w", req.ProductID, err)
+		}
+		if err := checkAvailable(price); err != nil {
+			return nil, err
+		}
+		if err := lockRate(locks, price, now); err != nil {
+			return nil, err
+		}
+
+		meta := itemPricing{PriceSource: price.Source, BaseCurrency: price.BaseCurrency, BaseUnitPrice: price.BasePrice}
+		if price.FXRate != nil {
+			meta.FXRate = &price.FXRate.Rate
+		}
+		metadata, err := json.Marshal(meta)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode item metadata: %w", err)
+		}
+
+		total := roundCents(price.Price * float64(req.Quantity))
+		subtotal += total
+		items = append(items, models.OrderItem{
+			ProductID:  req.ProductID,
+			VariantID:  price.VariantID,
+			SKU:        price.SKU,
+			Name:       price.Name,
+			Quantity:   req.Quantity,
+			UnitPrice:  price.Price,
+			TotalPrice: total,
+			Metadata:   metadata,
+			Components: itemComponents(price),
+		})
+	}
+
+	subtotal = roundCents(subtotal)
+	total := roundCents(subtotal + in.ShippingAmount + in.TaxAmount - in.DiscountAmount)
+	if total < 0 {
+		return nil, fmt.Errorf("%w: discount exceeds order value", ErrValidation)
+	}
+
+	order := &models.Order{
+		UserID:          in.UserID,
+		OrderNumber:     newOrderNumber(now),
+		Status:          models.OrderStatusPending,
+		Subtotal:        subtotal,
+		TaxAmount:       in.TaxAmount,
+		ShippingAmount:  in.ShippingAmount,
+		DiscountAmount:  in.DiscountAmount,
+		Total:           total,
+		Currency:        currency,
+		ShippingAddress: shipping,
+		BillingAddress:  billing,
+		Notes:           in.Notes,
+	}
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		orders := repository.NewOrderRepository(tx)
+		if err := orders.Create(ctx, order); err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].OrderID = order.ID
+			if err := orders.CreateItem(ctx, &items[i]); err != nil {
+				return err
+			}
+		}
+		for _, lock := range locks {
+			lock.OrderID = order.ID
+			if err := orders.CreateFXRate(ctx, lock); err != nil {
+				return err
+			}
+			order.FXRates = append(order.FXRates, *lock)
+		}
+		note := "order placed"
+		if err := repository.NewRevisionRepository(tx).Create(ctx, newRevision(order, items, nil, &note)); err != nil {
+			return err
+		}
+		if err := orders.AddStatusHistory(ctx, order.ID, order.Status, &note); err != nil {
+			return err
+		}
+
+		// Reserving last keeps the order uncommitted until its stock is held
+		for line, qty := range variantQuantities(items) {
+			if err := s.inventory.SetReservation(ctx, order.ID, line.ProductID, line.VariantID, qty); err != nil {
+				return fmt.Errorf("failed to reserve %d of variant %s: %w", qty, line.VariantID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if order.ID != uuid.Nil {
+			if rerr := s.inventory.ReleaseReservations(ctx, order.ID, "order was not placed"); rerr != nil {
+				log.Printf("failed to release reservations of unplaced order %s: %v", order.ID, rerr)
+			}
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+// checkAvailable rejects a product that is not for sale, or a bundle with a
+// component that is not
+func checkAvailable(price *clients.Price) error {
+	if price.Status != "active" {
+		return fmt.Errorf("%w: product %s is not available", ErrValidation, price.ProductID)
+	}
+	for _, c := range price.Components {
+		if c.Status != "active" {
+			return fmt.Errorf("%w: product %s contains %s, which is not available", ErrValidation, price.ProductID, c.ProductID)
+		}
+	}
+	return nil
+}
+
+// itemComponents returns the components of a bundle as they are stored on
+// its order item, or nil for other products
+func itemComponents(price *clients.Price) []models.OrderItemComponent {
+	if len(price.Components) == 0 {
+		return nil
+	}
+	components := make([]models.OrderItemComponent, 0, len(price.Components))
+	for _, c := range price.Components {
+		components = append(components, models.OrderItemComponent{
+			ProductID: c.ProductID,
+			VariantID: c.VariantID,
+			SKU:       c.SKU,
+			Name:      c.Name,
+			Quantity:  c.Quantity,
+		})
+	}
+	return components
+}
+
+// lockRate records the FX rate used for price, rejecting a checkout where
+// two items were converted from the same currency at different rates
+func lockRate(locks map[string]*models.OrderFXRate, price *clients.Price, now time.Time) error {
+	if price.FXRate == nil {
+		return nil
+	}
+	if existing, ok := locks[price.BaseCurrency]; ok {
+		if existing.RateID != price.FXRate.RateID {
+			return fmt.Errorf("%w: fx rate for %s changed during checkout, please retry", ErrInvalidState, price.BaseCurrency)
+		}
+		return nil
+	}
+	locks[price.BaseCurrency] = &models.OrderFXRate{
+		BaseCurrency:  price.FXRate.BaseCurrency,
+		QuoteCurrency: price.FXRate.QuoteCurrency,
+		Rate:          price.FXRate.Rate,
+		RateID:        price.FXRate.RateID,
+		EffectiveAt:   price.FXRate.EffectiveAt,
+		LockedAt:      now,
+	}
+	return nil
+}
+
+// newOrderNumber returns a human readable, practically unique order number
+func newOrderNumber(now time.Time) string {
+	return fmt.Sprintf("ORD-%s-%s", now.UTC().Format("20060102"), strings.ToUpper(uuid.NewString()[:8]))
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,749 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/order/clients"
+	"main.go/services/order/models"
+	"main.go/services/order/repository"
+)
+
+// OrderFlagPaymentAdjustmentFailed marks edited orders whose payments could
+// not be brought in line with the new total
+const OrderFlagPaymentAdjustmentFailed = "payment_adjustment_failed"
+
+// editLease is how long an edit holds its order before it is considered
+// abandoned, e.g. after a crash between the edit and its payment adjustment
+const editLease = 15 * time.Minute
+
+// editableStatuses lists the statuses an order can be edited in
+var editableStatuses = map[models.OrderStatus]bool{
+	models.OrderStatusPending:    true,
+	models.OrderStatusConfirmed:  true,
+	models.OrderStatusProcessing: true,
+}
+
+// StockReserver adjusts the stock reserved for an order
+type StockReserver interface {
+	SetReservation(ctx context.Context, orderID, productID, variantID uuid.UUID, quantity int) error
+}
+
+// PaymentAdjuster collects and returns the difference of an edited order
+// through the payment service
+type PaymentAdjuster interface {
+	PaymentSettler
+	CreatePayment(ctx context.Context, orderID, userID uuid.UUID, amount float64, currency string, methodID *uuid.UUID) (*clients.Payment, error)
+	Authorize(ctx context.Context, paymentID uuid.UUID) (*clients.Payment, error)
+	Capture(ctx context.Context, paymentID uuid.UUID) (*clients.Payment, error)
+}
+
+// EditOrderItem changes the quantity of an order item, or adds a product
+// when OrderItemID is empty. A quantity of 0 removes the item. Added products
+// without a VariantID are added as their default variant.
+type EditOrderItem struct {
+	OrderItemID *uuid.UUID `json:"order_item_id,omitempty"`
+	ProductID   *uuid.UUID `json:"product_id,omitempty"`
+	VariantID   *uuid.UUID `json:"variant_id,omitempty"`
+	Quantity    int        `json:"quantity"`
+}
+
+// EditOrderInput holds the changes made to an order. Amounts left empty keep
+// their current value.
+type EditOrderInput struct {
+	Actor             string          `json:"actor"`
+	Reason            string          `json:"reason"`
+	Items             []EditOrderItem `json:"items,omitempty"`
+	ShippingAddress   *models.Address `json:"shipping_address,omitempty"`
+	ShippingAddressID *uuid.UUID      `json:"shipping_address_id,omitempty"`
+	ShippingAmount    *float64        `json:"shipping_amount,omitempty"`
+	TaxAmount         *float64        `json:"tax_amount,omitempty"`
+	DiscountAmount    *float64        `json:"discount_amount,omitempty"`
+}
+
+// EditResult is an edited order, the revision recording the edit and the
+// amount collected (positive) or returned (negative) to the customer
+type EditResult struct {
+	Order             *models.Order         `json:"order"`
+	Items             []models.OrderItem    `json:"items"`
+	Revision          *models.OrderRevision `json:"revision"`
+	PaymentAdjustment float64               `json:"payment_adjustment"`
+}
+
+// orderSnapshot is the state of an order before an edit, kept to revert it
+type orderSnapshot struct {
+	order *models.Order
+	items []models.OrderItem
+}
+
+// EditService edits orders before they ship. Every edit is recorded as an
+// immutable revision, and reservations and payments follow the new contents.
+type EditService struct {
+	pool      *pgxpool.Pool
+	products  ProductPricer
+	inventory StockReserver
+	payments  PaymentAdjuster
+	now       func() time.Time
+}
+
+// NewEditService creates a new EditService
+func NewEditService(pool *pgxpool.Pool, products ProductPricer, inventory StockReserver, payments PaymentAdjuster) *EditService {
+	return &EditService{pool: pool, products: products, inventory: inventory, payments: payments, now: time.Now}
+}
+
+// Edit changes the items, amounts or shipping address of an order that has
+// not started shipping. Existing items keep their unit price; added products
+// are priced now, converted at the FX rate locked for the order where there
+// is one. Reservations are moved to the new quantities and the payment
+// difference is authorized (and captured if the order was captured) or
+// voided and refunded. When reservations or payments cannot be adjusted
+// before any money moved, the edit is reverted and the error returned.
+func (s *EditService) Edit(ctx context.Context, id uuid.UUID, in EditOrderInput) (*EditResult, error) {
+	actor := strings.TrimSpace(in.Actor)
+	if actor == "" {
+		return nil, fmt.Errorf("%w: actor is required", ErrValidation)
+	}
+	if len(in.Items) == 0 && in.ShippingAddress == nil && in.ShippingAddressID == nil && in.ShippingAmount == nil && in.TaxAmount == nil && in.DiscountAmount == nil {
+		return nil, fmt.Errorf("%w: nothing to change", ErrValidation)
+	}
+	for _, amount := range []*float64{in.ShippingAmount, in.TaxAmount, in.DiscountAmount} {
+		if amount != nil && *amount < 0 {
+			return nil, fmt.Errorf("%w: amounts must not be negative", ErrValidation)
+		}
+	}
+	for _, req := range in.Items {
+		if (req.OrderItemID == nil) == (req.ProductID == nil) {
+			return nil, fmt.Errorf("%w: each item needs either order_item_id or product_id", ErrValidation)
+		}
+		if req.VariantID != nil && req.ProductID == nil {
+			return nil, fmt.Errorf("%w: variant_id is only valid with product_id", ErrValidation)
+		}
+		if req.Quantity < 0 || (req.ProductID != nil && req.Quantity < 1) {
+			return nil, fmt.Errorf("%w: invalid quantity %d", ErrValidation, req.Quantity)
+		}
+	}
+
+	order, err := repository.NewOrderRepository(s.pool).GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !editableStatuses[order.Status] {
+		return nil, fmt.Errorf("%w: order in status %s cannot be edited", ErrInvalidState, order.Status)
+	}
+	if err := s.checkNoPaymentInProgress(ctx, id); err != nil {
+		return nil, err
+	}
+	var shipping *models.Address
+	if in.ShippingAddress != nil || in.ShippingAddressID != nil {
+		shipping, err = resolveAddress(ctx, s.pool, order.UserID, in.ShippingAddress, in.ShippingAddressID, false, "shipping_address")
+		if err != nil {
+			return nil, err
+		}
+	}
+	prices, err := s.priceAddedProducts(ctx, order.Currency, in.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	var before orderSnapshot
+	result := &EditResult{}
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		orders := repository.NewOrderRepository(tx)
+		o, err := orders.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !editableStatuses[o.Status] {
+			return fmt.Errorf("%w: order in status %s cannot be edited", ErrInvalidState, o.Status)
+		}
+		if err := checkNotEditing(ctx, orders, o.ID, s.now()); err != nil {
+			return err
+		}
+		allocated, err := repository.NewShipmentRepository(tx).AllocatedQuantities(ctx, o.ID)
+		if err != nil {
+			return err
+		}
+		if len(allocated) > 0 {
+			return fmt.Errorf("%w: order has shipments, cancel them before editing", ErrInvalidState)
+		}
+		inv, err := repository.NewInvoiceRepository(tx).GetInvoiceByOrder(ctx, o.ID)
+		if err == nil {
+			return fmt.Errorf("%w: order was invoiced as %s and can no longer be edited", ErrInvalidState, inv.DocumentNumber)
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		items, err := orders.ListItems(ctx, o.ID)
+		if err != nil {
+			return err
+		}
+
+		revisions := repository.NewRevisionRepository(tx)
+		n, err := revisions.Count(ctx, o.ID)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			note := "order as placed"
+			if err := revisions.Create(ctx, newRevision(o, items, nil, &note)); err != nil {
+				return err
+			}
+		}
+
+		prevOrder := *o
+		before = orderSnapshot{order: &prevOrder, items: append([]models.OrderItem(nil), items...)}
+		items, err = s.applyItemChanges(ctx, orders, o, items, in.Items, prices)
+		if err != nil {
+			return err
+		}
+		if shipping != nil {
+			o.ShippingAddress = shipping
+		}
+		if in.ShippingAmount != nil {
+			o.ShippingAmount = *in.ShippingAmount
+		}
+		if in.TaxAmount != nil {
+			o.TaxAmount = *in.TaxAmount
+		}
+		if in.DiscountAmount != nil {
+			o.DiscountAmount = *in.DiscountAmount
+		}
+		o.Subtotal = 0
+		for _, item := range items {
+			o.Subtotal += item.TotalPrice
+		}
+		o.Subtotal = roundCents(o.Subtotal)
+		o.Total = roundCents(o.Subtotal + o.ShippingAmount + o.TaxAmount - o.DiscountAmount)
+		if o.Total < 0 {
+			return fmt.Errorf("%w: discount exceeds order value", ErrValidation)
+		}
+		if err := orders.UpdateAmounts(ctx, o); err != nil {
+			return err
+		}
+
+		reason := optionalString(strings.TrimSpace(in.Reason))
+		rev := newRevision(o, items, &actor, reason)
+		if err := revisions.Create(ctx, rev); err != nil {
+			return err
+		}
+		note := fmt.Sprintf("order edited (revision %d), total %.2f -> %.2f %s", rev.Revision, before.order.Total, o.Total, o.Currency)
+		if err := orders.AddStatusHistory(ctx, o.ID, o.Status, &note); err != nil {
+			return err
+		}
+		result.Order = o
+		result.Items = items
+		result.Revision = rev
+		now := s.now()
+		return orders.SetEditing(ctx, o.ID, &now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer s.finishEdit(ctx, id)
+
+	changed, err := s.moveReservations(ctx, id, variantQuantities(before.items), variantQuantities(result.Items))
+	if err != nil {
+		s.restoreReservations(ctx, id, changed, variantQuantities(before.items))
+		return nil, s.revert(ctx, before, actor, err)
+	}
+
+	delta, moved, err := s.adjustPayments(ctx, result.Order, result.Revision)
+	result.PaymentAdjustment = delta
+	if err != nil && !moved {
+		s.restoreReservations(ctx, id, changed, variantQuantities(before.items))
+		return nil, s.revert(ctx, before, actor, err)
+	}
+	if err != nil {
+		log.Printf("payment adjustment for edited order %s failed: %v", id, err)
+		note := fmt.Sprintf("revision %d: %v", result.Revision.Revision, err)
+		source := "order-service"
+		flag := &models.OrderFlag{OrderID: id, Flag: OrderFlagPaymentAdjustmentFailed, Note: &note, Source: &source}
+		if ferr := repository.NewFlagRepository(s.pool).Set(ctx, flag); ferr != nil {
+			log.Printf("failed to flag order %s: %v", id, ferr)
+		}
+	}
+	return result, nil
+}
+
+// ListRevisions returns the revisions of an order, oldest first
+func (s *EditService) ListRevisions(ctx context.Context, id uuid.UUID) ([]models.OrderRevision, error) {
+	if _, err := repository.NewOrderRepository(s.pool).GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return repository.NewRevisionRepository(s.pool).ListByOrder(ctx, id)
+}
+
+// finishEdit releases an order held by an edit once its reservations and
+// payments have moved
+func (s *EditService) finishEdit(ctx context.Context, id uuid.UUID) {
+	if err := repository.NewOrderRepository(s.pool).SetEditing(ctx, id, nil); err != nil {
+		log.Printf("failed to finish edit of order %s: %v", id, err)
+	}
+}
+
+// checkNotEditing rejects changes to an order locked by orders while an edit
+// is still moving its reservations and payments
+func checkNotEditing(ctx context.Context, orders *repository.OrderRepository, id uuid.UUID, now time.Time) error {
+	since, err := orders.EditingSince(ctx, id)
+	if err != nil {
+		return err
+	}
+	if since != nil && now.Sub(*since) < editLease {
+		return fmt.Errorf("%w: order is being edited", ErrInvalidState)
+	}
+	return nil
+}
+
+// checkNoPaymentInProgress rejects edits while a payment of the order is
+// still pending, since its amount was chosen for the old total
+func (s *EditService) checkNoPaymentInProgress(ctx context.Context, orderID uuid.UUID) error {
+	payments, err := s.payments.ListPayments(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to list payments: %w", err)
+	}
+	for _, p := range payments {
+		if p.Status == "pending" {
+			return fmt.Errorf("%w: payment %s is still in progress", ErrInvalidState, p.ID)
+		}
+	}
+	return nil
+}
+
+// priceAddedProducts resolves the current price of every product variant
+// added by an edit, keyed by the index of its request
+func (s *EditService) priceAddedProducts(ctx context.Context, currency string, reqs []EditOrderItem) (map[int]*clients.Price, error) {
+	prices := map[int]*clients.Price{}
+	seen := map[uuid.UUID]bool{}
+	for i, req := range reqs {
+		if req.ProductID == nil {
+			continue
+		}
+		price, err := s.products.ResolvePrice(ctx, *req.ProductID, req.VariantID, currency, s.now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to price product %s: %w", *req.ProductID, err)
+		}
+		if err := checkAvailable(price); err != nil {
+			return nil, err
+		}
+		if seen[price.VariantID] {
+			return nil, fmt.Errorf("%w: variant %s is listed twice", ErrValidation, price.VariantID)
+		}
+		seen[price.VariantID] = true
+		prices[i] = price
+	}
+	return prices, nil
+}
+
+// applyItemChanges updates, removes and adds items of a locked order and
+// returns its new items. Added products converted from a currency the order
+// already has a locked rate for use that rate.
+func (s *EditService) applyItemChanges(ctx context.Context, orders *repository.OrderRepository, o *models.Order, items []models.OrderItem, reqs []EditOrderItem, prices map[int]*clients.Price) ([]models.OrderItem, error) {
+	rates, err := orders.ListFXRates(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+	locked := map[string]models.OrderFXRate{}
+	for _, rate := range rates {
+		locked[rate.BaseCurrency] = rate
+	}
+
+	byID := map[uuid.UUID]int{}
+	byVariant := map[uuid.UUID]uuid.UUID{}
+	for i, item := range items {
+		byID[item.ID] = i
+		byVariant[item.VariantID] = item.ID
+	}
+	removed := map[uuid.UUID]bool{}
+	seen := map[uuid.UUID]bool{}
+	var added []models.OrderItem
+	locks := map[string]*models.OrderFXRate{}
+	now := s.now()
+
+	for n, req := range reqs {
+		if req.OrderItemID != nil {
+			i, ok := byID[*req.OrderItemID]
+			if !ok {
+				return nil, fmt.Errorf("%w: item %s does not belong to the order", ErrValidation, *req.OrderItemID)
+			}
+			if seen[*req.OrderItemID] {
+				return nil, fmt.Errorf("%w: item %s is listed twice", ErrValidation, *req.OrderItemID)
+			}
+			seen[*req.OrderItemID] = true
+			if req.Quantity == 0 {
+				removed[*req.OrderItemID] = true
+				if err := orders.DeleteItem(ctx, *req.OrderItemID); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			items[i].Quantity = req.Quantity
+			items[i].TotalPrice = roundCents(items[i].UnitPrice * float64(req.Quantity))
+			if err := orders.UpdateItem(ctx, &items[i]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		price := prices[n]
+		if itemID, ok := byVariant[price.VariantID]; ok {
+			return nil, fmt.Errorf("%w: variant %s is already on the order as item %s", ErrValidation, price.VariantID, itemID)
+		}
+		unitPrice := price.Price
+		meta := itemPricing{PriceSource: price.Source, BaseCurrency: price.BaseCurrency, BaseUnitPrice: price.BasePrice}
+		if price.FXRate != nil {
+			if rate, ok := locked[price.BaseCurrency]; ok {
+				unitPrice = roundCents(price.BasePrice * rate.Rate)
+				meta.FXRate = &rate.Rate
+			} else {
+				if err := lockRate(locks, price, now); err != nil {
+					return nil, err
+				}
+				meta.FXRate = &price.FXRate.Rate
+			}
+		}
+		metadata, err := json.Marshal(meta)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode item metadata: %w", err)
+		}
+		item := models.OrderItem{
+			OrderID:    o.ID,
+			ProductID:  *req.ProductID,
+			VariantID:  price.VariantID,
+			SKU:        price.SKU,
+			Name:       price.Name,
+			Quantity:   req.Quantity,
+			UnitPrice:  unitPrice,
+			TotalPrice: roundCents(unitPrice * float64(req.Quantity)),
+			Metadata:   metadata,
+			Components: itemComponents(price),
+		}
+		if err := orders.CreateItem(ctx, &item); err != nil {
+			return nil, err
+		}
+		added = append(added, item)
+	}
+	for _, lock := range locks {
+		lock.OrderID = o.ID
+		if err := orders.CreateFXRate(ctx, lock); err != nil {
+			return nil, err
+		}
+	}
+
+	kept := make([]models.OrderItem, 0, len(items)+len(added))
+	for _, item := range items {
+		if !removed[item.ID] {
+			kept = append(kept, item)
+		}
+	}
+	kept = append(kept, added...)
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("%w: an order must keep at least one item, cancel it instead", ErrValidation)
+	}
+	return kept, nil
+}
+
+// moveReservations sets the reservation of every variant whose quantity
+// changed. It returns the variants already moved, also on error.
+func (s *EditService) moveReservations(ctx context.Context, orderID uuid.UUID, before, after map[stockLine]int) ([]stockLine, error) {
+	var changed []stockLine
+	for line, qty := range mergeKeys(before, after) {
+		if before[line] == qty {
+			continue
+		}
+		if err := s.inventory.SetReservation(ctx, orderID, line.ProductID, line.VariantID, qty); err != nil {
+			return changed, fmt.Errorf("failed to reserve %d of variant %s: %w", qty, line.VariantID, err)
+		}
+		changed = append(changed, line)
+	}
+	return changed, nil
+}
+
+// restoreReservations puts the reservations of variants back to their
+// quantities before an edit, logging what cannot be restored
+func (s *EditService) restoreReservations(ctx context.Context, orderID uuid.UUID, lines []stockLine, before map[stockLine]int) {
+	for _, line := range lines {
+		if err := s.inventory.SetReservation(ctx, orderID, line.ProductID, line.VariantID, before[line]); err != nil {
+			log.Printf("failed to restore reservation of variant %s for order %s: %v", line.VariantID, orderID, err)
+		}
+	}
+}
+
+// revert puts an order back to its snapshot and records that as a new
+// revision. It returns cause, or the revert error if that fails as well.
+func (s *EditService) revert(ctx context.Context, snap orderSnapshot, actor string, cause error) error {
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		orders := repository.NewOrderRepository(tx)
+		o, err := orders.GetByIDForUpdate(ctx, snap.order.ID)
+		if err != nil {
+			return err
+		}
+		current, err := orders.ListItems(ctx, o.ID)
+		if err != nil {
+			return err
+		}
+		keep := map[uuid.UUID]bool{}
+		for _, item := range snap.items {
+			keep[item.ID] = true
+		}
+		existing := map[uuid.UUID]bool{}
+		for _, item := range current {
+			existing[item.ID] = true
+			if !keep[item.ID] {
+				if err := orders.DeleteItem(ctx, item.ID); err != nil {
+					return err
+				}
+			}
+		}
+		for i := range snap.items {
+			item := &snap.items[i]
+			if existing[item.ID] {
+				err = orders.UpdateItem(ctx, item)
+			} else {
+				err = orders.RestoreItem(ctx, item)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err := orders.UpdateAmounts(ctx, snap.order); err != nil {
+			return err
+		}
+
+		reason := "edit reverted: " + cause.Error()
+		rev := newRevision(snap.order, snap.items, &actor, &reason)
+		if err := repository.NewRevisionRepository(tx).Create(ctx, rev); err != nil {
+			return err
+		}
+		return orders.AddStatusHistory(ctx, o.ID, o.Status, &reason)
+	})
+	if err != nil {
+		log.Printf("failed to revert edit of order %s after %v: %v", snap.order.ID, cause, err)
+		return fmt.Errorf("%w (revert failed: %v)", cause, err)
+	}
+	return cause
+}
+
+// adjustPayments brings what the customer has paid or authorized in line
+// with the order total. Orders without live payments are left alone since
+// the customer pays the new total at checkout. It returns the difference and
+// whether any money moved before an error.
+func (s *EditService) adjustPayments(ctx context.Context, order *models.Order, rev *models.OrderRevision) (float64, bool, error) {
+	payments, err := s.payments.ListPayments(ctx, order.ID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to list payments: %w", err)
+	}
+
+	var authorized, captured []clients.Payment
+	refundable := map[uuid.UUID]float64{}
+	paid := 0.0
+	for _, p := range payments {
+		switch p.Status {
+		case "pending":
+			return 0, false, fmt.Errorf("%w: payment %s is still in progress", ErrInvalidState, p.ID)
+		case "authorized":
+			authorized = append(authorized, p)
+			paid += p.Amount
+		case "captured", "partially_refunded":
+			refunds, err := s.payments.ListRefunds(ctx, p.ID)
+			if err != nil {
+				return 0, false, fmt.Errorf("failed to list refunds of payment %s: %w", p.ID, err)
+			}
+			remaining, err := refundableAmount(ctx, s.payments, p, refunds)
+			if err != nil {
+				return 0, false, err
+			}
+			captured = append(captured, p)
+			refundable[p.ID] = remaining
+			paid += remaining
+		}
+	}
+	if len(authorized) == 0 && len(captured) == 0 {
+		return 0, false, nil
+	}
+
+	delta := roundCents(order.Total - paid)
+	live := append(append([]clients.Payment(nil), authorized...), captured...)
+	sort.SliceStable(live, func(i, j int) bool { return live[i].CreatedAt.After(live[j].CreatedAt) })
+	switch {
+	case delta >= 0.01:
+		moved, err := s.collect(ctx, order, live[0], delta, len(captured) > 0)
+		return delta, moved, err
+	case delta <= -0.01:
+		moved, err := s.release(ctx, order, rev, authorized, captured, refundable, -delta)
+		return delta, moved, err
+	}
+	return 0, false, nil
+}
+
+// collect authorizes amount with the payment method of latest, capturing it
+// when the order was already captured
+func (s *EditService) collect(ctx context.Context, order *models.Order, latest clients.Payment, amount float64, capture bool) (bool, error) {
+	p, err := s.payments.CreatePayment(ctx, order.ID, latest.UserID, amount, latest.Currency, latest.PaymentMethodID)
+	if err != nil {
+		return false, fmt.Errorf("failed to create incremental payment: %w", err)
+	}
+	if err := s.authorize(ctx, p); err != nil {
+		return false, err
+	}
+	if !capture {
+		return true, nil
+	}
+	if _, err := s.payments.Capture(ctx, p.ID); err != nil {
+		return true, fmt.Errorf("failed to capture incremental payment %s: %w", p.ID, err)
+	}
+	return true, nil
+}
+
+// release returns amount to the customer, voiding or shrinking
+// authorizations before refunding captured payments. Refunds are keyed by
+// the revision so a retried edit does not refund twice.
+func (s *EditService) release(ctx context.Context, order *models.Order, rev *models.OrderRevision, authorized, captured []clients.Payment, refundable map[uuid.UUID]float64, amount float64) (bool, error) {
+	moved := false
+	remaining := amount
+	reason := "order " + order.OrderNumber + " edited"
+
+	sort.SliceStable(authorized, func(i, j int) bool { return authorized[i].CreatedAt.After(authorized[j].CreatedAt) })
+	for _, p := range authorized {
+		if remaining < 0.01 {
+			break
+		}
+		if p.Amount > remaining {
+			// Replace the authorization with a smaller one before letting it go
+			replacement, err := s.payments.CreatePayment(ctx, order.ID, p.UserID, roundCents(p.Amount-remaining), p.Currency, p.PaymentMethodID)
+			if err != nil {
+				return moved, fmt.Errorf("failed to create replacement payment for %s: %w", p.ID, err)
+			}
+			if err := s.authorize(ctx, replacement); err != nil {
+				return moved, err
+			}
+			moved = true
+		}
+		voided, err := s.payments.Void(ctx, p.ID)
+		if err != nil {
+			return moved, fmt.Errorf("failed to void payment %s: %w", p.ID, err)
+		}
+		if voided.Status != "cancelled" {
+			return moved, fmt.Errorf("void of payment %s left it %s", p.ID, voided.Status)
+		}
+		moved = true
+		remaining = roundCents(remaining - min(p.Amount, remaining))
+	}
+
+	for _, p := range captured {
+		if remaining < 0.01 {
+			break
+		}
+		refundAmount := roundCents(min(remaining, refundable[p.ID]))
+		if refundAmount < 0.01 {
+			continue
+		}
+		refund, err := s.payments.Refund(ctx, p.ID, refundAmount, p.Currency, reason, editRefundKey(rev.ID, p.ID))
+		if err != nil {
+			return moved, fmt.Errorf("failed to refund payment %s: %w", p.ID, err)
+		}
+		if refund.Status != "completed" {
+			return true, fmt.Errorf("refund %s of payment %s is %s", refund.ID, p.ID, refund.Status)
+		}
+		moved = true
+		remaining = roundCents(remaining - refundAmount)
+	}
+	if remaining >= 0.01 {
+		return moved, fmt.Errorf("%w: %.2f %s could not be returned from the order's payments", ErrInvalidState, remaining, order.Currency)
+	}
+	return moved, nil
+}
+
+// editRefundKey is the idempotency key of the refund of a payment for an
+// order revision
+func editRefundKey(revisionID, paymentID uuid.UUID) string {
+	return "edit:" + revisionID.String() + ":" + paymentID.String()
+}
+
+// authorize authorizes a new payment, voiding it again when it is declined
+// or held for review
+func (s *EditService) authorize(ctx context.Context, p *clients.Payment) error {
+	authorized, err := s.payments.Authorize(ctx, p.ID)
+	if err == nil && authorized.Status == "authorized" {
+		return nil
+	}
+	if _, verr := s.payments.Void(ctx, p.ID); verr != nil {
+		log.Printf("failed to void unauthorized payment %s: %v", p.ID, verr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to authorize payment %s: %w", p.ID, err)
+	}
+	return fmt.Errorf("%w: payment %s was not authorized (%s)", ErrInvalidState, p.ID, authorized.Status)
+}
+
+// newRevision snapshots the items and amounts of an order
+func newRevision(o *models.Order, items []models.OrderItem, actor, reason *string) *models.OrderRevision {
+	return &models.OrderRevision{
+		OrderID:         o.ID,
+		Actor:           actor,
+		Reason:          reason,
+		Items:           items,
+		Subtotal:        o.Subtotal,
+		TaxAmount:       o.TaxAmount,
+		ShippingAmount:  o.ShippingAmount,
+		DiscountAmount:  o.DiscountAmount,
+		Total:           o.Total,
+		ShippingAddress: o.ShippingAddress,
+	}
+}
+
+// stockLine identifies the stock an order item reserves
+type stockLine struct {
+	ProductID uuid.UUID
+	VariantID uuid.UUID
+}
+
+// stockQuantity is a quantity of the stock of a line
+type stockQuantity struct {
+	stockLine
+	Quantity int
+}
+
+// itemStock returns the stock qty units of an order item take: the item's
+// own variant, or the components of a bundle
+func itemStock(item models.OrderItem, qty int) []stockQuantity {
+	if len(item.Components) == 0 {
+		return []stockQuantity{{stockLine{ProductID: item.ProductID, VariantID: item.VariantID}, qty}}
+	}
+	stock := make([]stockQuantity, 0, len(item.Components))
+	for _, c := range item.Components {
+		stock = append(stock, stockQuantity{stockLine{ProductID: c.ProductID, VariantID: c.VariantID}, c.Quantity * qty})
+	}
+	return stock
+}
+
+// variantQuantities sums the stock order items take per product variant
+func variantQuantities(items []models.OrderItem) map[stockLine]int {
+	totals := map[stockLine]int{}
+	for _, item := range items {
+		for _, s := range itemStock(item, item.Quantity) {
+			totals[s.stockLine] += s.Quantity
+		}
+	}
+	return totals
+}
+
+// mergeKeys returns after with a zero entry for every variant only in before
+func mergeKeys(before, after map[stockLine]int) map[stockLine]int {
+	merged := make(map[stockLine]int, len(after))
+	for line := range before {
+		merged[line] = 0
+	}
+	for line, qty := range after {
+		merged[line] = qty
+	}
+	return merged
+}
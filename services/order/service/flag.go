@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/order/models"
+	"main.go/services/order/repository"
+)
+
+// maxFlaggedOrders caps the number of orders returned by ListOrdersWithFlag
+const maxFlaggedOrders = 500
+
+// SetFlagInput holds a flag to raise on an order
+type SetFlagInput struct {
+	Flag   string  `json:"flag"`
+	Note   *string `json:"note,omitempty"`
+	Source *string `json:"source,omitempty"`
+}
+
+// FlagService manages support-visible flags on orders
+type FlagService struct {
+	pool *pgxpool.Pool
+}
+
+// NewFlagService creates a new FlagService
+func NewFlagService(pool *pgxpool.Pool) *FlagService {
+	return &FlagService{pool: pool}
+}
+
+// Set raises a flag on an existing order
+func (s *FlagService) Set(ctx context.Context, orderID uuid.UUID, in SetFlagInput) (*models.OrderFlag, error) {
+	if in.Flag == "" || len(in.Flag) > 50 {
+		return nil, fmt.Errorf("%w: flag must be between 1 and 50 characters", ErrValidation)
+	}
+	if _, err := repository.NewOrderRepository(s.pool).GetByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+
+	flag := &models.OrderFlag{OrderID: orderID, Flag: in.Flag, Note: in.Note, Source: in.Source}
+	if err := repository.NewFlagRepository(s.pool).Set(ctx, flag); err != nil {
+		return nil, err
+	}
+	return flag, nil
+}
+
+// Clear clears an active flag on an order
+func (s *FlagService) Clear(ctx context.Context, orderID uuid.UUID, flag string) error {
+	return repository.NewFlagRepository(s.pool).Clear(ctx, orderID, flag)
+}
+
+// ListByOrder returns the flag history of an order
+func (s *FlagService) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.OrderFlag, error) {
+	return repository.NewFlagRepository(s.pool).ListByOrder(ctx, orderID)
+}
+
+// ListOrdersWithFlag returns orders currently carrying a flag
+func (s *FlagService) ListOrdersWithFlag(ctx context.Context, flag string) ([]models.Order, error) {
+	if flag == "" {
+		return nil, fmt.Errorf("%w: flag is required", ErrValidation)
+	}
+	return repository.NewFlagRepository(s.pool).ListOrdersWithFlag(ctx, flag, maxFlaggedOrders)
+}
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/order/clients"
+	"main.go/services/order/invoice"
+	"main.go/services/order/models"
+	"main.go/services/order/repository"
+)
+
+// Number series prefixes; the issue year is appended so numbering restarts
+// every year
+const (
+	invoiceSeriesPrefix    = "INV"
+	creditNoteSeriesPrefix = "CN"
+)
+
+// uninvoiceableStatuses lists the statuses an order cannot be invoiced in
+var uninvoiceableStatuses = map[models.OrderStatus]bool{
+	models.OrderStatusPending:   true,
+	models.OrderStatusCancelled: true,
+}
+
+// documentFormats are the formats every invoice is rendered to at issue time
+var documentFormats = []struct {
+	format      string
+	contentType string
+	render      func(*models.Invoice, string) ([]byte, error)
+}{
+	{"pdf", "application/pdf", invoice.RenderPDF},
+	{"html", "text/html; charset=utf-8", invoice.RenderHTML},
+}
+
+// InvoiceService issues invoices for orders and credit notes for their
+// refunds. Documents are numbered without gaps per series, snapshot the
+// seller, buyer and lines at issue time and are rendered once to PDF and
+// HTML.
+type InvoiceService struct {
+	pool     *pgxpool.Pool
+	payments PaymentRefunder
+	seller   models.InvoiceParty
+	now      func() time.Time
+}
+
+// NewInvoiceService creates a new InvoiceService issuing documents in the
+// name of seller
+func NewInvoiceService(pool *pgxpool.Pool, payments PaymentRefunder, seller models.InvoiceParty) *InvoiceService {
+	return &InvoiceService{pool: pool, payments: payments, seller: seller, now: time.Now}
+}
+
+// Issue issues the invoice of an order. Each order is invoiced once.
+func (s *InvoiceService) Issue(ctx context.Context, orderID uuid.UUID) (*models.Invoice, error) {
+	var inv *models.Invoice
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		orders := repository.NewOrderRepository(tx)
+		invoices := repository.NewInvoiceRepository(tx)
+		order, err := orders.GetByIDForUpdate(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if uninvoiceableStatuses[order.Status] {
+			return fmt.Errorf("%w: order in status %s cannot be invoiced", ErrInvalidState, order.Status)
+		}
+		existing, err := invoices.GetInvoiceByOrder(ctx, orderID)
+		if err == nil {
+			return fmt.Errorf("%w: order was already invoiced as %s", ErrInvalidState, existing.DocumentNumber)
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		items, err := orders.ListItems(ctx, orderID)
+		if err != nil {
+			return err
+		}
+
+		inv = &models.Invoice{
+			OrderID:     order.ID,
+			OrderNumber: order.OrderNumber,
+			Kind:        models.InvoiceKindInvoice,
+			Currency:    order.Currency,
+			Seller:      s.seller,
+			Buyer:       buyerParty(order),
+			NetAmount:   roundCents(order.Subtotal + order.ShippingAmount - order.DiscountAmount),
+			TaxAmount:   order.TaxAmount,
+			Total:       order.Total,
+			IssuedAt:    s.now(),
+		}
+		inv.Lines, inv.TaxBreakdown = invoiceLines(order, items)
+		return s.issue(ctx, invoices, inv, invoiceSeriesPrefix, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// IssueCreditNotes issues a credit note against the order's invoice for
+// every completed refund that does not have one yet, and returns the new
+// credit notes. Tax is credited in proportion to the invoice.
+func (s *InvoiceService) IssueCreditNotes(ctx context.Context, orderID uuid.UUID) ([]models.Invoice, error) {
+	var refunds []clients.Refund
+	payments, err := s.payments.ListPayments(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payments: %w", err)
+	}
+	for _, p := range payments {
+		list, err := s.payments.ListRefunds(ctx, p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list refunds of payment %s: %w", p.ID, err)
+		}
+		for _, rf := range list {
+			if rf.Status == "completed" {
+				refunds = append(refunds, rf)
+			}
+		}
+	}
+
+	issued := []models.Invoice{}
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := repository.NewOrderRepository(tx).GetByIDForUpdate(ctx, orderID); err != nil {
+			return err
+		}
+		invoices := repository.NewInvoiceRepository(tx)
+		original, err := invoices.GetInvoiceByOrder(ctx, orderID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: order has not been invoiced", ErrInvalidState)
+		}
+		if err != nil {
+			return err
+		}
+		credited, err := invoices.CreditedRefunds(ctx, orderID)
+		if err != nil {
+			return err
+		}
+
+		for _, rf := range refunds {
+			if credited[rf.ID] {
+				continue
+			}
+			if !strings.EqualFold(rf.Currency, original.Currency) {
+				return fmt.Errorf("%w: refund %s is in %s but the invoice is in %s", ErrInvalidState, rf.ID, rf.Currency, original.Currency)
+			}
+			cn := creditNote(original, rf, s.now())
+			if err := s.issue(ctx, invoices, cn, creditNoteSeriesPrefix, original.DocumentNumber); err != nil {
+				return err
+			}
+			issued = append(issued, *cn)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// Get returns an invoice or credit note
+func (s *InvoiceService) Get(ctx context.Context, id uuid.UUID) (*models.Invoice, error) {
+	return repository.NewInvoiceRepository(s.pool).GetByID(ctx, id)
+}
+
+// ListByOrder returns the invoice and credit notes of an order
+func (s *InvoiceService) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.Invoice, error) {
+	if _, err := repository.NewOrderRepository(s.pool).GetByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return repository.NewInvoiceRepository(s.pool).ListByOrder(ctx, orderID)
+}
+
+// Document returns an invoice and its rendered document in format
+func (s *InvoiceService) Document(ctx context.Context, id uuid.UUID, format string) (*models.Invoice, *models.InvoiceDocument, error) {
+	invoices := repository.NewInvoiceRepository(s.pool)
+	inv, err := invoices.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, err := invoices.GetDocument(ctx, id, format)
+	if err != nil {
+		return nil, nil, err
+	}
+	return inv, doc, nil
+}
+
+// issue numbers inv in the series of its issue year, stores it and renders
+// its documents, all in the caller's transaction so a failure leaves no gap
+func (s *InvoiceService) issue(ctx context.Context, invoices *repository.InvoiceRepository, inv *models.Invoice, prefix, credited string) error {
+	inv.Series = fmt.Sprintf("%s-%d", prefix, inv.IssuedAt.UTC().Year())
+	n, err := invoices.NextNumber(ctx, inv.Series)
+	if err != nil {
+		return err
+	}
+	inv.Number = n
+	inv.DocumentNumber = fmt.Sprintf("%s-%06d", inv.Series, n)
+	if err := invoices.Create(ctx, inv); err != nil {
+		return err
+	}
+
+	for _, f := range documentFormats {
+		content, err := f.render(inv, credited)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		doc := &models.InvoiceDocument{
+			InvoiceID:   inv.ID,
+			Format:      f.format,
+			ContentType: f.contentType,
+			Content:     content,
+			SHA256:      hex.EncodeToString(sum[:]),
+		}
+		if err := invoices.SaveDocument(ctx, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invoiceLines turns the items, shipping and discount of an order into
+// invoice lines. The order's tax is spread over the lines at its effective
+// rate, with rounding differences put on the largest line.
+func invoiceLines(order *models.Order, items []models.OrderItem) ([]models.InvoiceLine, []models.InvoiceTaxLine) {
+	lines := make([]models.InvoiceLine, 0, len(items)+2)
+	for _, item := range items {
+		lines = append(lines, models.InvoiceLine{
+			Description: item.Name,
+			SKU:         item.SKU,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			NetAmount:   item.TotalPrice,
+		})
+	}
+	if order.ShippingAmount > 0 {
+		lines = append(lines, models.InvoiceLine{Description: "Shipping", Quantity: 1, UnitPrice: order.ShippingAmount, NetAmount: order.ShippingAmount})
+	}
+	if order.DiscountAmount > 0 {
+		lines = append(lines, models.InvoiceLine{Description: "Discount", Quantity: 1, UnitPrice: -order.DiscountAmount, NetAmount: -order.DiscountAmount})
+	}
+
+	net := roundCents(order.Subtotal + order.ShippingAmount - order.DiscountAmount)
+	rate := 0.0
+	if net > 0 {
+		rate = math.Round(order.TaxAmount/net*10000) / 10000
+	}
+	allocated := 0.0
+	largest := 0
+	for i := range lines {
+		lines[i].TaxRate = rate
+		if net > 0 {
+			lines[i].TaxAmount = roundCents(lines[i].NetAmount * order.TaxAmount / net)
+		}
+		allocated += lines[i].TaxAmount
+		if lines[i].NetAmount > lines[largest].NetAmount {
+			largest = i
+		}
+	}
+	if len(lines) > 0 {
+		lines[largest].TaxAmount = roundCents(lines[largest].TaxAmount + order.TaxAmount - allocated)
+	}
+	for i := range lines {
+		lines[i].GrossAmount = roundCents(lines[i].NetAmount + lines[i].TaxAmount)
+	}
+
+	breakdown := []models.InvoiceTaxLine{{Rate: rate, TaxableAmount: net, TaxAmount: order.TaxAmount}}
+	return lines, breakdown
+}
+
+// creditNote builds an unnumbered credit note for a refund against the
+// original invoice
+func creditNote(original *models.Invoice, rf clients.Refund, now time.Time) *models.Invoice {
+	tax := 0.0
+	if original.Total > 0 {
+		tax = roundCents(rf.Amount * original.TaxAmount / original.Total)
+	}
+	net := roundCents(rf.Amount - tax)
+	rate := 0.0
+	if len(original.TaxBreakdown) > 0 {
+		rate = original.TaxBreakdown[0].Rate
+	}
+	refundID := rf.ID
+	originalID := original.ID
+	return &models.Invoice{
+		OrderID:     original.OrderID,
+		OrderNumber: original.OrderNumber,
+		Kind:        models.InvoiceKindCreditNote,
+		CreditedID:  &originalID,
+		RefundID:    &refundID,
+		Currency:    original.Currency,
+		Seller:      original.Seller,
+		Buyer:       original.Buyer,
+		Lines: []models.InvoiceLine{{
+			Description: "Refund against invoice " + original.DocumentNumber,
+			Quantity:    1,
+			UnitPrice:   net,
+			NetAmount:   net,
+			TaxRate:     rate,
+			TaxAmount:   tax,
+			GrossAmount: rf.Amount,
+		}},
+		TaxBreakdown: []models.InvoiceTaxLine{{Rate: rate, TaxableAmount: net, TaxAmount: tax}},
+		NetAmount:    net,
+		TaxAmount:    tax,
+		Total:        rf.Amount,
+		IssuedAt:     now,
+	}
+}
+
+// buyerParty snapshots the buyer of an order from its billing address,
+// falling back to the shipping address
+func buyerParty(o *models.Order) models.InvoiceParty {
+	userID := o.UserID
+	p := models.InvoiceParty{UserID: &userID}
+	a := o.BillingAddress
+	if a == nil {
+		a = o.ShippingAddress
+	}
+	if a == nil {
+		p.Name = "Customer " + o.UserID.String()
+		return p
+	}
+
+	p.Name = strings.TrimSpace(a.FirstName + " " + a.LastName)
+	if a.Company != nil {
+		p.AddressLines = append(p.AddressLines, p.Name)
+		p.Name = *a.Company
+	}
+	p.AddressLines = append(p.AddressLines, a.Address1)
+	if a.Address2 != nil {
+		p.AddressLines = append(p.AddressLines, *a.Address2)
+	}
+	p.AddressLines = append(p.AddressLines, strings.TrimSpace(a.PostalCode+" "+a.City))
+	if a.State != "" {
+		p.AddressLines = append(p.AddressLines, a.State)
+	}
+	p.AddressLines = append(p.AddressLines, a.Country)
+	p.Email = a.Email
+	return p
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/order/models"
+	"main.go/services/order/repository"
+)
+
+// ProductPurchase tells whether a user received a product they ordered.
+// DeliveredAt is the latest delivery of the product to the user.
+type ProductPurchase struct {
+	UserID      uuid.UUID  `json:"user_id"`
+	ProductID   uuid.UUID  `json:"product_id"`
+	Delivered   bool       `json:"delivered"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+// Co-purchase paging limits
+const (
+	defaultCoPurchaseLimit = 1000
+	maxCoPurchaseLimit     = 5000
+)
+
+// OrderService manages orders and their lifecycle
+type OrderService struct {
+	pool      *pgxpool.Pool
+	products  ProductPricer
+	inventory StockAllocator
+	now       func() time.Time
+}
+
+// NewOrderService creates a new OrderService
+func NewOrderService(pool *pgxpool.Pool, products ProductPricer, inventory StockAllocator) *OrderService {
+	return &OrderService{pool: pool, products: products, inventory: inventory, now: time.Now}
+}
+
+// Get returns an order by its ID together with its locked FX rates
+func (s *OrderService) Get(ctx context.Context, id uuid.UUID) (*models.Order, error) {
+	orders := repository.NewOrderRepository(s.pool)
+	order, err := orders.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	order.FXRates, err = orders.ListFXRates(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// ListItems returns the items of an order
+func (s *OrderService) ListItems(ctx context.Context, id uuid.UUID) ([]models.OrderItem, error) {
+	return repository.NewOrderRepository(s.pool).ListItems(ctx, id)
+}
+
+// ListStatusHistory returns the status history of an order
+func (s *OrderService) ListStatusHistory(ctx context.Context, id uuid.UUID) ([]models.OrderStatusHistory, error) {
+	return repository.NewOrderRepository(s.pool).ListStatusHistory(ctx, id)
+}
+
+// ProductPurchase reports whether a delivered shipment of one of the user's
+// orders that was not cancelled or refunded contained the product
+func (s *OrderService) ProductPurchase(ctx context.Context, userID, productID uuid.UUID) (*ProductPurchase, error) {
+	purchase := &ProductPurchase{UserID: userID, ProductID: productID}
+	deliveredAt, err := repository.NewOrderRepository(s.pool).LatestProductDelivery(ctx, userID, productID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return purchase, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	purchase.Delivered, purchase.DeliveredAt = true, &deliveredAt
+	return purchase, nil
+}
+
+// CoPurchases pages through pairs of products bought together in at least
+// minOrders orders placed since since. Other services page through it to
+// recommend products.
+func (s *OrderService) CoPurchases(ctx context.Context, since time.Time, minOrders int, afterProduct, afterRelated uuid.UUID, limit int) ([]models.ProductCoPurchase, error) {
+	if limit <= 0 {
+		limit = defaultCoPurchaseLimit
+	}
+	if limit > maxCoPurchaseLimit {
+		return nil, fmt.Errorf("%w: limit must be at most %d", ErrValidation, maxCoPurchaseLimit)
+	}
+	if minOrders < 1 {
+		minOrders = 1
+	}
+	pairs, err := repository.NewOrderRepository(s.pool).CoPurchases(ctx, since, minOrders, afterProduct, afterRelated, limit)
+	if err != nil {
+		return nil, err
+	}
+	if pairs == nil {
+		pairs = []models.ProductCoPurchase{}
+	}
+	return pairs, nil
+}
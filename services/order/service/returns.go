@@ -0,0 +1,634 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/order/clients"
+	"main.go/services/order/models"
+	"main.go/services/order/repository"
+)
+
+// DefaultReturnWindow is how long after delivery a return may be requested
+const DefaultReturnWindow = 30 * 24 * time.Hour
+
+// returnReferenceType tags inventory movements created by returns
+const returnReferenceType = "return"
+
+// StockRecorder records stock movements in the inventory service
+type StockRecorder interface {
+	RecordMovement(ctx context.Context, m clients.StockMovement) error
+}
+
+// PaymentRefunder issues refunds through the payment service
+type PaymentRefunder interface {
+	ListPayments(ctx context.Context, orderID uuid.UUID) ([]clients.Payment, error)
+	ListRefunds(ctx context.Context, paymentID uuid.UUID) ([]clients.Refund, error)
+	ListDisputes(ctx context.Context, paymentID uuid.UUID) ([]clients.Dispute, error)
+	Refund(ctx context.Context, paymentID uuid.UUID, amount float64, currency, reason, idempotencyKey string) (*clients.Refund, error)
+}
+
+// ReturnItemInput is an order item and quantity requested for return
+type ReturnItemInput struct {
+	OrderItemID uuid.UUID `json:"order_item_id"`
+	Quantity    int       `json:"quantity"`
+	Reason      *string   `json:"reason,omitempty"`
+}
+
+// RequestReturnInput holds a customer's return request
+type RequestReturnInput struct {
+	UserID uuid.UUID         `json:"user_id"`
+	Reason *string           `json:"reason,omitempty"`
+	Items  []ReturnItemInput `json:"items"`
+}
+
+// ReturnDecisionInput holds a staff decision on a return
+type ReturnDecisionInput struct {
+	Actor string  `json:"actor"`
+	Note  *string `json:"note,omitempty"`
+}
+
+// ReceiveItemInput records what was received for a return item
+type ReceiveItemInput struct {
+	ReturnItemID     uuid.UUID                `json:"return_item_id"`
+	ReceivedQuantity int                      `json:"received_quantity"`
+	Disposition      models.ReturnDisposition `json:"disposition"`
+	WarehouseID      *uuid.UUID               `json:"warehouse_id,omitempty"`
+}
+
+// ReceiveReturnInput holds the receipt of returned goods. Return items not
+// listed are treated as not received.
+type ReceiveReturnInput struct {
+	Actor string             `json:"actor"`
+	Note  *string            `json:"note,omitempty"`
+	Items []ReceiveItemInput `json:"items"`
+}
+
+// ReturnService manages returns (RMAs) of delivered order items
+type ReturnService struct {
+	pool      *pgxpool.Pool
+	inventory StockRecorder
+	payments  PaymentRefunder
+	window    time.Duration
+	now       func() time.Time
+}
+
+// NewReturnService creates a new ReturnService. A non-positive window falls
+// back to DefaultReturnWindow.
+func NewReturnService(pool *pgxpool.Pool, inventory StockRecorder, payments PaymentRefunder, window time.Duration) *ReturnService {
+	if window <= 0 {
+		window = DefaultReturnWindow
+	}
+	return &ReturnService{pool: pool, inventory: inventory, payments: payments, window: window, now: time.Now}
+}
+
+// Request opens a return for items of a delivered order. The request must be
+// made within the return window after the last delivery and may not exceed
+// the quantity not already being returned.
+func (s *ReturnService) Request(ctx context.Context, orderID uuid.UUID, in RequestReturnInput) (*models.OrderReturn, error) {
+	if in.UserID == uuid.Nil {
+		return nil, fmt.Errorf("%w: user_id is required", ErrValidation)
+	}
+	if len(in.Items) == 0 {
+		return nil, fmt.Errorf("%w: at least one item is required", ErrValidation)
+	}
+
+	var ret *models.OrderReturn
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		orders := repository.NewOrderRepository(tx)
+		returns := repository.NewReturnRepository(tx)
+
+		order, err := orders.GetByIDForUpdate(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if order.UserID != in.UserID {
+			return fmt.Errorf("%w: order does not belong to user", ErrValidation)
+		}
+		if order.Status != models.OrderStatusDelivered {
+			return fmt.Errorf("%w: only delivered orders can be returned, order is %s", ErrInvalidState, order.Status)
+		}
+		deliveredAt, err := orders.LatestDeliveredAt(ctx, orderID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: order has no delivered shipment", ErrInvalidState)
+		}
+		if err != nil {
+			return err
+		}
+		if s.now().After(deliveredAt.Add(s.window)) {
+			return fmt.Errorf("%w: return window closed on %s", ErrInvalidState, deliveredAt.Add(s.window).Format(time.RFC3339))
+		}
+
+		orderItems, err := orders.ListItems(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		byID := make(map[uuid.UUID]models.OrderItem, len(orderItems))
+		for _, item := range orderItems {
+			byID[item.ID] = item
+		}
+		returned, err := returns.ReturnedQuantities(ctx, orderID)
+		if err != nil {
+			return err
+		}
+
+		items := make([]models.ReturnItem, 0, len(in.Items))
+		for _, req := range in.Items {
+			orderItem, ok := byID[req.OrderItemID]
+			if !ok {
+				return fmt.Errorf("%w: item %s is not part of the order", ErrValidation, req.OrderItemID)
+			}
+			if req.Quantity < 1 {
+				return fmt.Errorf("%w: quantity for item %s must be at least 1", ErrValidation, req.OrderItemID)
+			}
+			if available := orderItem.Quantity - returned[orderItem.ID]; req.Quantity > available {
+				return fmt.Errorf("%w: only %d of item %s can still be returned", ErrValidation, available, req.OrderItemID)
+			}
+			returned[orderItem.ID] += req.Quantity
+			items = append(items, models.ReturnItem{
+				OrderItemID: orderItem.ID,
+				ProductID:   orderItem.ProductID,
+				VariantID:   orderItem.VariantID,
+				Quantity:    req.Quantity,
+				UnitPrice:   orderItem.UnitPrice,
+				Reason:      req.Reason,
+			})
+		}
+
+		r := &models.OrderReturn{
+			OrderID:   orderID,
+			UserID:    in.UserID,
+			RMANumber: newRMANumber(s.now()),
+			Status:    models.ReturnStatusRequested,
+			Reason:    in.Reason,
+			Currency:  order.Currency,
+		}
+		if err := returns.Create(ctx, r); err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].ReturnID = r.ID
+			if err := returns.CreateItem(ctx, &items[i]); err != nil {
+				return err
+			}
+		}
+		r.Items = items
+		actor := "user:" + in.UserID.String()
+		ret = r
+		return addReturnHistory(ctx, returns, r, &actor, in.Reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Get returns a return with its items
+func (s *ReturnService) Get(ctx context.Context, id uuid.UUID) (*models.OrderReturn, error) {
+	returns := repository.NewReturnRepository(s.pool)
+	ret, err := returns.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ret.Items, err = returns.ListItems(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ListByOrder returns the returns of an order
+func (s *ReturnService) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.OrderReturn, error) {
+	return repository.NewReturnRepository(s.pool).ListByOrder(ctx, orderID)
+}
+
+// ListHistory returns the lifecycle of a return
+func (s *ReturnService) ListHistory(ctx context.Context, id uuid.UUID) ([]models.ReturnStatusHistory, error) {
+	return repository.NewReturnRepository(s.pool).ListHistory(ctx, id)
+}
+
+// Approve accepts a requested return so the goods can be sent back
+func (s *ReturnService) Approve(ctx context.Context, id uuid.UUID, in ReturnDecisionInput) (*models.OrderReturn, error) {
+	return s.decide(ctx, id, in, models.ReturnStatusApproved)
+}
+
+// Reject declines a requested return, releasing its quantities for future
+// requests
+func (s *ReturnService) Reject(ctx context.Context, id uuid.UUID, in ReturnDecisionInput) (*models.OrderReturn, error) {
+	return s.decide(ctx, id, in, models.ReturnStatusRejected)
+}
+
+func (s *ReturnService) decide(ctx context.Context, id uuid.UUID, in ReturnDecisionInput, status models.ReturnStatus) (*models.OrderReturn, error) {
+	if strings.TrimSpace(in.Actor) == "" {
+		return nil, fmt.Errorf("%w: actor is required", ErrValidation)
+	}
+
+	var ret *models.OrderReturn
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		returns := repository.NewReturnRepository(tx)
+		r, err := returns.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if r.Status != models.ReturnStatusRequested {
+			return fmt.Errorf("%w: return is %s", ErrInvalidState, r.Status)
+		}
+		r.Status = status
+		if err := returns.Update(ctx, r); err != nil {
+			return err
+		}
+		ret = r
+		return addReturnHistory(ctx, returns, r, &in.Actor, in.Note)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Receive records the goods that came back for an approved return. Restocked
+// items, or the components of returned bundles, are booked into the chosen
+// warehouse with an inventory "in" movement; written off items are not. A refund for the received quantity is attempted
+// immediately and can be retried with Refund if it does not complete.
+func (s *ReturnService) Receive(ctx context.Context, id uuid.UUID, in ReceiveReturnInput) (*models.OrderReturn, error) {
+	if strings.TrimSpace(in.Actor) == "" {
+		return nil, fmt.Errorf("%w: actor is required", ErrValidation)
+	}
+
+	ret, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ret.Status != models.ReturnStatusApproved {
+		return nil, fmt.Errorf("%w: return is %s", ErrInvalidState, ret.Status)
+	}
+
+	receipts := make(map[uuid.UUID]ReceiveItemInput, len(in.Items))
+	for _, item := range in.Items {
+		if _, dup := receipts[item.ReturnItemID]; dup {
+			return nil, fmt.Errorf("%w: item %s received twice", ErrValidation, item.ReturnItemID)
+		}
+		receipts[item.ReturnItemID] = item
+	}
+
+	now := s.now()
+	received := 0
+	for i := range ret.Items {
+		item := &ret.Items[i]
+		receipt, ok := receipts[item.ID]
+		if !ok {
+			continue
+		}
+		delete(receipts, item.ID)
+		if receipt.ReceivedQuantity < 0 || receipt.ReceivedQuantity > item.Quantity {
+			return nil, fmt.Errorf("%w: received quantity for item %s must be between 0 and %d", ErrValidation, item.ID, item.Quantity)
+		}
+		switch receipt.Disposition {
+		case models.ReturnDispositionRestock:
+			if receipt.WarehouseID == nil {
+				return nil, fmt.Errorf("%w: warehouse_id is required to restock item %s", ErrValidation, item.ID)
+			}
+		case models.ReturnDispositionWriteOff:
+			receipt.WarehouseID = nil
+		default:
+			return nil, fmt.Errorf("%w: disposition must be restock or write_off", ErrValidation)
+		}
+		disposition := receipt.Disposition
+		item.ReceivedQuantity = receipt.ReceivedQuantity
+		item.Disposition = &disposition
+		item.WarehouseID = receipt.WarehouseID
+		item.ReceivedAt = &now
+		received += receipt.ReceivedQuantity
+	}
+	for unknown := range receipts {
+		return nil, fmt.Errorf("%w: item %s is not part of the return", ErrValidation, unknown)
+	}
+	if received == 0 {
+		return nil, fmt.Errorf("%w: nothing was received; reject the return instead", ErrValidation)
+	}
+
+	// Restock before committing the receipt. Movements reference the return
+	// item, so a retry after a failure does not book the stock twice.
+	orderItems, err := repository.NewOrderRepository(s.pool).ListItems(ctx, ret.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.OrderItem, len(orderItems))
+	for _, item := range orderItems {
+		byID[item.ID] = item
+	}
+	for _, item := range ret.Items {
+		if item.Disposition == nil || *item.Disposition != models.ReturnDispositionRestock || item.ReceivedQuantity == 0 {
+			continue
+		}
+		orderItem, ok := byID[item.OrderItemID]
+		if !ok {
+			orderItem = models.OrderItem{ProductID: item.ProductID, VariantID: item.VariantID}
+		}
+		for _, st := range itemStock(orderItem, item.ReceivedQuantity) {
+			refID, refType := item.ID, returnReferenceType
+			reason := "returned under " + ret.RMANumber
+			err := s.inventory.RecordMovement(ctx, clients.StockMovement{
+				ProductID:     st.ProductID,
+				VariantID:     &st.VariantID,
+				WarehouseID:   *item.WarehouseID,
+				Type:          "in",
+				Quantity:      st.Quantity,
+				ReferenceID:   &refID,
+				ReferenceType: &refType,
+				Reason:        &reason,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to restock item %s: %w", item.ID, err)
+			}
+		}
+	}
+
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		returns := repository.NewReturnRepository(tx)
+		r, err := returns.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if r.Status != models.ReturnStatusApproved {
+			return fmt.Errorf("%w: return is %s", ErrInvalidState, r.Status)
+		}
+		amount := 0.0
+		for i := range ret.Items {
+			if err := returns.UpdateItemReceipt(ctx, &ret.Items[i]); err != nil {
+				return err
+			}
+			amount += float64(ret.Items[i].ReceivedQuantity) * ret.Items[i].UnitPrice
+		}
+		amount = roundCents(amount)
+		r.Status = models.ReturnStatusReceived
+		r.RefundAmount = &amount
+		if err := returns.Update(ctx, r); err != nil {
+			return err
+		}
+		r.Items = ret.Items
+		ret = r
+		return addReturnHistory(ctx, returns, r, &in.Actor, in.Note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refunded, err := s.Refund(ctx, id, ReturnDecisionInput{Actor: in.Actor})
+	if err != nil {
+		log.Printf("refund for return %s did not complete: %v", ret.RMANumber, err)
+		return ret, nil
+	}
+	return refunded, nil
+}
+
+// Refund issues the outstanding refund of a received return across the
+// order's captured payments, in the order currency. The payment service is
+// called without holding the return lock: every refund is keyed by the return
+// and payment IDs, and the refunded total is saved after each one, so a failed
+// or concurrent attempt can be retried without refunding twice. Tax and
+// shipping are not refunded.
+func (s *ReturnService) Refund(ctx context.Context, id uuid.UUID, in ReturnDecisionInput) (*models.OrderReturn, error) {
+	if strings.TrimSpace(in.Actor) == "" {
+		return nil, fmt.Errorf("%w: actor is required", ErrValidation)
+	}
+
+	ret, err := repository.NewReturnRepository(s.pool).GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ret.Status != models.ReturnStatusReceived || ret.RefundAmount == nil {
+		return nil, fmt.Errorf("%w: return is %s", ErrInvalidState, ret.Status)
+	}
+	refundErr := s.issueRefunds(ctx, ret)
+
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		returns := repository.NewReturnRepository(tx)
+		r, err := returns.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if r.Status == models.ReturnStatusRefunded && refundErr == nil {
+			// a concurrent attempt finished the refund first
+			ret = r
+			return nil
+		}
+		if r.Status != models.ReturnStatusReceived {
+			return fmt.Errorf("%w: return is %s", ErrInvalidState, r.Status)
+		}
+		r.RefundedAmount = max(r.RefundedAmount, ret.RefundedAmount)
+		ret = r
+
+		if refundErr != nil {
+			note := "refund incomplete: " + refundErr.Error()
+			if err := returns.Update(ctx, r); err != nil {
+				return err
+			}
+			return addReturnHistory(ctx, returns, r, &in.Actor, &note)
+		}
+
+		r.Status = models.ReturnStatusRefunded
+		if err := returns.Update(ctx, r); err != nil {
+			return err
+		}
+		note := fmt.Sprintf("refunded %.2f %s", r.RefundedAmount, r.Currency)
+		if err := addReturnHistory(ctx, returns, r, &in.Actor, &note); err != nil {
+			return err
+		}
+		return s.markOrderRefunded(ctx, tx, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if refundErr != nil {
+		return nil, refundErr
+	}
+	return ret, nil
+}
+
+// issueRefunds refunds what remains of r.RefundAmount, spreading it over the
+// order's refundable payments. Refunds already made for r are found by their
+// key and counted instead of repeated; r.RefundedAmount is saved after every
+// new refund.
+func (s *ReturnService) issueRefunds(ctx context.Context, r *models.OrderReturn) error {
+	if roundCents(*r.RefundAmount-r.RefundedAmount) <= 0 {
+		return nil
+	}
+
+	payments, err := s.payments.ListPayments(ctx, r.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to list payments: %w", err)
+	}
+	issued := 0.0
+	refundable := map[uuid.UUID]float64{}
+	for _, p := range payments {
+		if p.Status != "captured" && p.Status != "partially_refunded" && p.Status != "refunded" {
+			continue
+		}
+		refunds, err := s.payments.ListRefunds(ctx, p.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list refunds of payment %s: %w", p.ID, err)
+		}
+		key := returnRefundKey(r.ID, p.ID)
+		refundedForReturn := false
+		for _, rf := range refunds {
+			if rf.Status != "completed" {
+				continue
+			}
+			if rf.IdempotencyKey != nil && *rf.IdempotencyKey == key {
+				issued += rf.Amount
+				refundedForReturn = true
+			}
+		}
+		if refundedForReturn {
+			continue
+		}
+		if refundable[p.ID], err = refundableAmount(ctx, s.payments, p, refunds); err != nil {
+			return err
+		}
+	}
+	r.RefundedAmount = roundCents(max(r.RefundedAmount, issued))
+
+	remaining := roundCents(*r.RefundAmount - r.RefundedAmount)
+	for _, p := range payments {
+		if remaining <= 0 {
+			break
+		}
+		amount := roundCents(min(remaining, refundable[p.ID]))
+		if amount < 0.01 {
+			continue
+		}
+
+		refund, err := s.payments.Refund(ctx, p.ID, amount, r.Currency, "return "+r.RMANumber, returnRefundKey(r.ID, p.ID))
+		if err != nil {
+			return fmt.Errorf("failed to refund payment %s: %w", p.ID, err)
+		}
+		if refund.Status != "completed" {
+			return fmt.Errorf("refund %s of payment %s is %s", refund.ID, p.ID, refund.Status)
+		}
+		r.RefundedAmount = roundCents(r.RefundedAmount + refund.Amount)
+		remaining = roundCents(remaining - refund.Amount)
+		if err := s.saveRefundProgress(ctx, r); err != nil {
+			return err
+		}
+	}
+	if remaining > 0 {
+		return fmt.Errorf("%w: %.2f %s could not be refunded from the order's payments", ErrInvalidState, remaining, r.Currency)
+	}
+	return nil
+}
+
+// saveRefundProgress stores r.RefundedAmount in a short transaction of its
+// own, never lowering a total saved by a concurrent attempt
+func (s *ReturnService) saveRefundProgress(ctx context.Context, r *models.OrderReturn) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		returns := repository.NewReturnRepository(tx)
+		current, err := returns.GetByIDForUpdate(ctx, r.ID)
+		if err != nil {
+			return err
+		}
+		if current.RefundedAmount >= r.RefundedAmount {
+			return nil
+		}
+		current.RefundedAmount = r.RefundedAmount
+		return returns.Update(ctx, current)
+	})
+}
+
+// returnRefundKey is the idempotency key of the refund of a return against
+// one payment
+func returnRefundKey(returnID, paymentID uuid.UUID) string {
+	return "return:" + returnID.String() + ":" + paymentID.String()
+}
+
+// refundableAmount returns what can still be refunded of a captured payment
+// with the given refunds: its amount less completed refunds and lost
+// disputes. It refuses while a dispute on the payment is unresolved, since
+// the disputed money may still go back to the cardholder.
+func refundableAmount(ctx context.Context, payments PaymentRefunder, p clients.Payment, refunds []clients.Refund) (float64, error) {
+	disputes, err := payments.ListDisputes(ctx, p.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list disputes of payment %s: %w", p.ID, err)
+	}
+	left := p.Amount
+	for _, rf := range refunds {
+		if rf.Status == "completed" {
+			left -= rf.Amount
+		}
+	}
+	for _, d := range disputes {
+		switch d.Status {
+		case "lost":
+			left -= d.Amount
+		case "opened", "evidence_required":
+			return 0, fmt.Errorf("%w: payment %s has an unresolved dispute", ErrInvalidState, p.ID)
+		}
+	}
+	return roundCents(left), nil
+}
+
+// markOrderRefunded moves the order to refunded once every item has been
+// returned and refunded in full
+func (s *ReturnService) markOrderRefunded(ctx context.Context, tx pgx.Tx, r *models.OrderReturn) error {
+	orders := repository.NewOrderRepository(tx)
+	order, err := orders.GetByIDForUpdate(ctx, r.OrderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != models.OrderStatusDelivered {
+		return nil
+	}
+
+	items, err := orders.ListItems(ctx, r.OrderID)
+	if err != nil {
+		return err
+	}
+	returns := repository.NewReturnRepository(tx)
+	list, err := returns.ListByOrder(ctx, r.OrderID)
+	if err != nil {
+		return err
+	}
+	refunded := map[uuid.UUID]int{}
+	for _, ret := range list {
+		if ret.Status != models.ReturnStatusRefunded {
+			continue
+		}
+		retItems, err := returns.ListItems(ctx, ret.ID)
+		if err != nil {
+			return err
+		}
+		for _, item := range retItems {
+			refunded[item.OrderItemID] += item.ReceivedQuantity
+		}
+	}
+	for _, item := range items {
+		if refunded[item.ID] < item.Quantity {
+			return nil
+		}
+	}
+
+	note := "all items returned under " + r.RMANumber
+	return orders.UpdateStatus(ctx, r.OrderID, models.OrderStatusRefunded, &note)
+}
+
+// addReturnHistory records the current status of r in its history
+func addReturnHistory(ctx context.Context, returns *repository.ReturnRepository, r *models.OrderReturn, actor, note *string) error {
+	return returns.AddHistory(ctx, &models.ReturnStatusHistory{
+		ReturnID: r.ID,
+		Status:   r.Status,
+		Actor:    actor,
+		Note:     note,
+	})
+}
+
+// newRMANumber returns a human readable, practically unique RMA number
+func newRMANumber(now time.Time) string {
+	return fmt.Sprintf("RMA-%s-%s", now.UTC().Format("20060102"), strings.ToUpper(uuid.NewString()[:8]))
+}
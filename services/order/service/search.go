@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/order/models"
+	"main.go/services/order/repository"
+)
+
+const (
+	// defaultSearchLimit is the page size when none is requested
+	defaultSearchLimit = 50
+	// maxSearchLimit caps the page size of order searches
+	maxSearchLimit = 500
+)
+
+// validOrderStatuses lists the statuses orders can be filtered by
+var validOrderStatuses = map[models.OrderStatus]bool{
+	models.OrderStatusPending:          true,
+	models.OrderStatusConfirmed:        true,
+	models.OrderStatusProcessing:       true,
+	models.OrderStatusPartiallyShipped: true,
+	models.OrderStatusShipped:          true,
+	models.OrderStatusDelivered:        true,
+	models.OrderStatusCancelled:        true,
+	models.OrderStatusRefunded:         true,
+}
+
+// SearchOrdersInput holds the filters, sort order and page of an order
+// search. Sort is a column name, prefixed with "-" for descending order.
+type SearchOrdersInput struct {
+	OrderNumber     string
+	UserID          *uuid.UUID
+	Statuses        []models.OrderStatus
+	Currency        string
+	CreatedFrom     *time.Time
+	CreatedTo       *time.Time
+	MinTotal        *float64
+	MaxTotal        *float64
+	SKU             string
+	ProductID       *uuid.UUID
+	ShippingCountry string
+	Sort            string
+	Limit           int
+	Cursor          string
+}
+
+// OrderPage is a page of search results. NextCursor continues the search
+// and is empty on the last page.
+type OrderPage struct {
+	Orders     []models.Order `json:"orders"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// searchCursor is the opaque position handed out as NextCursor. It carries
+// the sort order so a cursor cannot be replayed against a different one.
+type searchCursor struct {
+	Sort string              `json:"s"`
+	Key  repository.OrderKey `json:"k"`
+}
+
+// SearchService finds and exports orders for support and finance
+type SearchService struct {
+	pool *pgxpool.Pool
+}
+
+// NewSearchService creates a new SearchService
+func NewSearchService(pool *pgxpool.Pool) *SearchService {
+	return &SearchService{pool: pool}
+}
+
+// Search returns a page of orders matching in, using keyset pagination on
+// the sort column followed by (created_at, id) so pages stay stable while
+// orders are added
+func (s *SearchService) Search(ctx context.Context, in SearchOrdersInput) (*OrderPage, error) {
+	q, err := buildQuery(in)
+	if err != nil {
+		return nil, err
+	}
+	limit := in.Limit
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	if limit < 1 || limit > maxSearchLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrValidation, maxSearchLimit)
+	}
+
+	orders, err := repository.NewOrderRepository(s.pool).Search(ctx, q, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &OrderPage{Orders: orders}
+	if len(orders) > limit {
+		page.Orders = orders[:limit]
+		last := &page.Orders[limit-1]
+		page.NextCursor = encodeCursor(searchCursor{Sort: in.Sort, Key: repository.SortKey(last, q.Sort)})
+	}
+	if page.Orders == nil {
+		page.Orders = []models.Order{}
+	}
+	return page, nil
+}
+
+// Export calls fn for every order matching in, in sort order, without
+// loading the result set into memory. Limit is ignored.
+func (s *SearchService) Export(ctx context.Context, in SearchOrdersInput, fn func(*models.Order) error) error {
+	q, err := buildQuery(in)
+	if err != nil {
+		return err
+	}
+	return repository.NewOrderRepository(s.pool).Stream(ctx, q, fn)
+}
+
+// buildQuery validates and normalizes a search into a repository query
+func buildQuery(in SearchOrdersInput) (repository.OrderQuery, error) {
+	for _, status := range in.Statuses {
+		if !validOrderStatuses[status] {
+			return repository.OrderQuery{}, fmt.Errorf("%w: unknown status %q", ErrValidation, status)
+		}
+	}
+	if in.CreatedFrom != nil && in.CreatedTo != nil && !in.CreatedFrom.Before(*in.CreatedTo) {
+		return repository.OrderQuery{}, fmt.Errorf("%w: created_from must be before created_to", ErrValidation)
+	}
+	if in.MinTotal != nil && in.MaxTotal != nil && *in.MinTotal > *in.MaxTotal {
+		return repository.OrderQuery{}, fmt.Errorf("%w: min_total must not exceed max_total", ErrValidation)
+	}
+
+	q := repository.OrderQuery{
+		Filter: repository.OrderFilter{
+			OrderNumber:     strings.TrimSpace(in.OrderNumber),
+			UserID:          in.UserID,
+			Statuses:        in.Statuses,
+			Currency:        strings.ToUpper(strings.TrimSpace(in.Currency)),
+			CreatedFrom:     in.CreatedFrom,
+			CreatedTo:       in.CreatedTo,
+			MinTotal:        in.MinTotal,
+			MaxTotal:        in.MaxTotal,
+			SKU:             strings.TrimSpace(in.SKU),
+			ProductID:       in.ProductID,
+			ShippingCountry: strings.ToUpper(strings.TrimSpace(in.ShippingCountry)),
+		},
+		Sort: repository.OrderSortCreatedAt,
+	}
+	if in.Sort != "" {
+		q.Descending = strings.HasPrefix(in.Sort, "-")
+		q.Sort = repository.OrderSort(strings.TrimPrefix(in.Sort, "-"))
+		if !repository.ValidOrderSort(q.Sort) {
+			return repository.OrderQuery{}, fmt.Errorf("%w: cannot sort by %q", ErrValidation, q.Sort)
+		}
+	}
+
+	if in.Cursor != "" {
+		c, err := decodeCursor(in.Cursor)
+		if err != nil {
+			return repository.OrderQuery{}, err
+		}
+		if c.Sort != in.Sort {
+			return repository.OrderQuery{}, fmt.Errorf("%w: cursor belongs to a search sorted by %q", ErrValidation, c.Sort)
+		}
+		q.After = &c.Key
+	}
+	return q, nil
+}
+
+// encodeCursor renders c as an opaque URL-safe string
+func encodeCursor(c searchCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor parses a cursor returned by encodeCursor
+func decodeCursor(s string) (*searchCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrValidation)
+	}
+	var c searchCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Key.ID == uuid.Nil {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrValidation)
+	}
+	return &c, nil
+}
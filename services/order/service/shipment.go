@@ -0,0 +1,444 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/order/carrier"
+	"main.go/services/order/clients"
+	"main.go/services/order/models"
+	"main.go/services/order/repository"
+)
+
+// shipmentReferenceType tags inventory movements created by shipments
+const shipmentReferenceType = "shipment"
+
+// shippableStatuses lists the order statuses shipments can be created in
+var shippableStatuses = map[models.OrderStatus]bool{
+	models.OrderStatusConfirmed:        true,
+	models.OrderStatusProcessing:       true,
+	models.OrderStatusPartiallyShipped: true,
+}
+
+// ShipmentItemInput is an order item and quantity packed into a shipment
+type ShipmentItemInput struct {
+	OrderItemID uuid.UUID `json:"order_item_id"`
+	Quantity    int       `json:"quantity"`
+}
+
+// CreateShipmentInput holds a shipment leaving a warehouse
+type CreateShipmentInput struct {
+	WarehouseID uuid.UUID           `json:"warehouse_id"`
+	Carrier     *string             `json:"carrier,omitempty"`
+	TrackingNo  *string             `json:"tracking_no,omitempty"`
+	ShippedAt   *time.Time          `json:"shipped_at,omitempty"`
+	Items       []ShipmentItemInput `json:"items"`
+}
+
+// TrackingInput holds the carrier and tracking number of a shipment
+type TrackingInput struct {
+	Carrier    string `json:"carrier"`
+	TrackingNo string `json:"tracking_no"`
+}
+
+// DeliveryInput holds when a shipment was delivered, defaulting to now
+type DeliveryInput struct {
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+// StockShipper books shipped stock out of the inventory service, releasing
+// what the order reserved for it
+type StockShipper interface {
+	StockRecorder
+	StockReserver
+}
+
+// ShipmentService manages the shipments an order is fulfilled with
+type ShipmentService struct {
+	pool      *pgxpool.Pool
+	inventory StockShipper
+	carriers  carrier.Registry
+	now       func() time.Time
+}
+
+// NewShipmentService creates a new ShipmentService
+func NewShipmentService(pool *pgxpool.Pool, inventory StockShipper, carriers carrier.Registry) *ShipmentService {
+	return &ShipmentService{pool: pool, inventory: inventory, carriers: carriers, now: time.Now}
+}
+
+// Create ships quantities of order items from a warehouse. Stock is taken out
+// of the warehouse with an inventory "out" movement per line item, or per
+// component of a bundle, and the order status is derived again from all of
+// its shipments.
+//
+// When Carrier names a registered carrier and no tracking number is given, a
+// label is created with that carrier and the shipment only counts as shipped
+// once the carrier reports it picked up. Otherwise it ships immediately.
+func (s *ShipmentService) Create(ctx context.Context, orderID uuid.UUID, in CreateShipmentInput) (*models.OrderShipment, error) {
+	if in.WarehouseID == uuid.Nil {
+		return nil, fmt.Errorf("%w: warehouse_id is required", ErrValidation)
+	}
+	if len(in.Items) == 0 {
+		return nil, fmt.Errorf("%w: at least one item is required", ErrValidation)
+	}
+
+	carrierName := trimmedOrNil(in.Carrier)
+	var labelCarrier carrier.Carrier
+	if carrierName != nil && trimmedOrNil(in.TrackingNo) == nil {
+		labelCarrier = s.carriers[*carrierName]
+	}
+	var shippedAt *time.Time
+	if labelCarrier == nil {
+		at := s.now()
+		if in.ShippedAt != nil {
+			at = *in.ShippedAt
+		}
+		shippedAt = &at
+	}
+
+	var shipment *models.OrderShipment
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		orders := repository.NewOrderRepository(tx)
+		shipments := repository.NewShipmentRepository(tx)
+
+		order, err := orders.GetByIDForUpdate(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if !shippableStatuses[order.Status] {
+			return fmt.Errorf("%w: order in status %s cannot be shipped", ErrInvalidState, order.Status)
+		}
+		if err := checkNotEditing(ctx, orders, orderID, s.now()); err != nil {
+			return err
+		}
+
+		orderItems, err := orders.ListItems(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		byID := make(map[uuid.UUID]models.OrderItem, len(orderItems))
+		for _, item := range orderItems {
+			byID[item.ID] = item
+		}
+		shipped, err := shipments.AllocatedQuantities(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		unshipped := remainingStock(orderItems, shipped)
+
+		items := make([]models.ShipmentItem, 0, len(in.Items))
+		for _, req := range in.Items {
+			orderItem, ok := byID[req.OrderItemID]
+			if !ok {
+				return fmt.Errorf("%w: item %s is not part of the order", ErrValidation, req.OrderItemID)
+			}
+			if req.Quantity < 1 {
+				return fmt.Errorf("%w: quantity for item %s must be at least 1", ErrValidation, req.OrderItemID)
+			}
+			if remaining := orderItem.Quantity - shipped[orderItem.ID]; req.Quantity > remaining {
+				return fmt.Errorf("%w: only %d of item %s remain to be shipped", ErrValidation, remaining, req.OrderItemID)
+			}
+			shipped[orderItem.ID] += req.Quantity
+			items = append(items, models.ShipmentItem{
+				OrderItemID: orderItem.ID,
+				ProductID:   orderItem.ProductID,
+				VariantID:   orderItem.VariantID,
+				Quantity:    req.Quantity,
+			})
+		}
+
+		sh := &models.OrderShipment{
+			OrderID:     orderID,
+			WarehouseID: &in.WarehouseID,
+			Carrier:     carrierName,
+			TrackingNo:  trimmedOrNil(in.TrackingNo),
+			ShippedAt:   shippedAt,
+		}
+		if err := shipments.Create(ctx, sh); err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].ShipmentID = sh.ID
+			if err := shipments.CreateItem(ctx, &items[i]); err != nil {
+				return err
+			}
+		}
+		sh.Items = items
+
+		if err := s.takeStock(ctx, sh, byID, unshipped, remainingStock(orderItems, shipped)); err != nil {
+			return err
+		}
+		if labelCarrier != nil {
+			if err := s.attachLabel(ctx, shipments, sh, order, labelCarrier); err != nil {
+				return err
+			}
+		}
+		shipment = sh
+		return deriveFulfillmentStatus(ctx, tx, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// Get returns a shipment with its line items
+func (s *ShipmentService) Get(ctx context.Context, id uuid.UUID) (*models.OrderShipment, error) {
+	shipments := repository.NewShipmentRepository(s.pool)
+	sh, err := shipments.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sh.Items, err = shipments.ListItems(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return sh, nil
+}
+
+// ListByOrder returns the shipments of an order with their line items
+func (s *ShipmentService) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.OrderShipment, error) {
+	shipments := repository.NewShipmentRepository(s.pool)
+	list, err := shipments.ListByOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Items, err = shipments.ListItems(ctx, list[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// SetTracking sets the carrier and tracking number of a shipment
+func (s *ShipmentService) SetTracking(ctx context.Context, id uuid.UUID, in TrackingInput) (*models.OrderShipment, error) {
+	carrier := strings.TrimSpace(in.Carrier)
+	trackingNo := strings.TrimSpace(in.TrackingNo)
+	if carrier == "" || trackingNo == "" {
+		return nil, fmt.Errorf("%w: carrier and tracking_no are required", ErrValidation)
+	}
+
+	var shipment *models.OrderShipment
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		shipments := repository.NewShipmentRepository(tx)
+		sh, err := shipments.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if sh.DeliveredAt != nil {
+			return fmt.Errorf("%w: shipment was already delivered", ErrInvalidState)
+		}
+		sh.Carrier = &carrier
+		sh.TrackingNo = &trackingNo
+		shipment = sh
+		return shipments.Update(ctx, sh)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// MarkDelivered records the delivery of a shipment and derives the order
+// status again. Marking a delivered shipment again is a no-op.
+func (s *ShipmentService) MarkDelivered(ctx context.Context, id uuid.UUID, in DeliveryInput) (*models.OrderShipment, error) {
+	deliveredAt := s.now()
+	if in.DeliveredAt != nil {
+		deliveredAt = *in.DeliveredAt
+	}
+
+	var shipment *models.OrderShipment
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		shipments := repository.NewShipmentRepository(tx)
+		sh, err := shipments.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		shipment = sh
+		if sh.DeliveredAt != nil {
+			return nil
+		}
+		if sh.ShippedAt != nil && deliveredAt.Before(*sh.ShippedAt) {
+			return fmt.Errorf("%w: delivered_at is before the shipment left", ErrValidation)
+		}
+		if sh.ShippedAt == nil {
+			sh.ShippedAt = &deliveredAt
+		}
+		sh.DeliveredAt = &deliveredAt
+		if err := shipments.Update(ctx, sh); err != nil {
+			return err
+		}
+
+		order, err := repository.NewOrderRepository(tx).GetByIDForUpdate(ctx, sh.OrderID)
+		if err != nil {
+			return err
+		}
+		return deriveFulfillmentStatus(ctx, tx, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// takeStock books the line items of a shipment out of its warehouse. The
+// order's reservations are first lowered from the stock it still needed
+// before the shipment to what it needs after, so the reserved units can
+// leave. If a movement fails, the movements already applied are reversed and
+// the reservations restored so the failed shipment leaves stock untouched.
+func (s *ShipmentService) takeStock(ctx context.Context, sh *models.OrderShipment, orderItems map[uuid.UUID]models.OrderItem, before, after map[stockLine]int) error {
+	var handedOver []stockLine
+	for line, qty := range after {
+		if before[line] == qty {
+			continue
+		}
+		// Orders reserved short of what they need keep shipping from
+		// unreserved stock
+		if err := s.inventory.SetReservation(ctx, sh.OrderID, line.ProductID, line.VariantID, qty); err != nil {
+			log.Printf("failed to release reservation of variant %s for shipment %s: %v", line.VariantID, sh.ID, err)
+			continue
+		}
+		handedOver = append(handedOver, line)
+	}
+
+	refType := shipmentReferenceType
+	type movement struct {
+		itemID uuid.UUID
+		stockQuantity
+	}
+	var done []movement
+	for _, item := range sh.Items {
+		for _, st := range itemStock(orderItems[item.OrderItemID], item.Quantity) {
+			refID := item.ID
+			err := s.inventory.RecordMovement(ctx, clients.StockMovement{
+				ProductID:     st.ProductID,
+				VariantID:     &st.VariantID,
+				WarehouseID:   *sh.WarehouseID,
+				Type:          "out",
+				Quantity:      st.Quantity,
+				ReferenceID:   &refID,
+				ReferenceType: &refType,
+			})
+			if err == nil {
+				done = append(done, movement{itemID: item.ID, stockQuantity: st})
+				continue
+			}
+
+			for _, m := range done {
+				refID := m.itemID
+				reason := "shipment " + sh.ID.String() + " was not created"
+				rerr := s.inventory.RecordMovement(ctx, clients.StockMovement{
+					ProductID:     m.ProductID,
+					VariantID:     &m.VariantID,
+					WarehouseID:   *sh.WarehouseID,
+					Type:          "in",
+					Quantity:      m.Quantity,
+					ReferenceID:   &refID,
+					ReferenceType: &refType,
+					Reason:        &reason,
+				})
+				if rerr != nil {
+					log.Printf("failed to return stock of shipment item %s: %v", m.itemID, rerr)
+				}
+			}
+			for _, line := range handedOver {
+				if rerr := s.inventory.SetReservation(ctx, sh.OrderID, line.ProductID, line.VariantID, before[line]); rerr != nil {
+					log.Printf("failed to restore reservation of variant %s for order %s: %v", line.VariantID, sh.OrderID, rerr)
+				}
+			}
+			return fmt.Errorf("failed to take stock for product %s: %w", st.ProductID, err)
+		}
+	}
+	return nil
+}
+
+// remainingStock sums the stock the unallocated quantities of order items
+// take per product variant
+func remainingStock(items []models.OrderItem, allocated map[uuid.UUID]int) map[stockLine]int {
+	totals := map[stockLine]int{}
+	for _, item := range items {
+		for _, st := range itemStock(item, max(item.Quantity-allocated[item.ID], 0)) {
+			totals[st.stockLine] += st.Quantity
+		}
+	}
+	return totals
+}
+
+// deriveFulfillmentStatus sets the order status from its shipments that have
+// left the warehouse: delivered once every item has shipped and every
+// shipment arrived, shipped once every item has shipped, and partially
+// shipped while items remain
+func deriveFulfillmentStatus(ctx context.Context, tx pgx.Tx, order *models.Order) error {
+	if !shippableStatuses[order.Status] && order.Status != models.OrderStatusShipped {
+		return nil
+	}
+
+	orders := repository.NewOrderRepository(tx)
+	shipments := repository.NewShipmentRepository(tx)
+
+	items, err := orders.ListItems(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+	shipped, err := shipments.DepartedQuantities(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+	if len(shipped) == 0 {
+		return nil
+	}
+	list, err := shipments.ListByOrder(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+
+	status := models.OrderStatusShipped
+	for _, item := range items {
+		if shipped[item.ID] < item.Quantity {
+			status = models.OrderStatusPartiallyShipped
+			break
+		}
+	}
+	if status == models.OrderStatusShipped {
+		delivered := true
+		for _, sh := range list {
+			if sh.CancelledAt == nil && sh.DeliveredAt == nil {
+				delivered = false
+				break
+			}
+		}
+		if delivered {
+			status = models.OrderStatusDelivered
+		}
+	}
+
+	if status == order.Status {
+		return nil
+	}
+	note := fmt.Sprintf("derived from %d shipment(s)", len(list))
+	if err := orders.UpdateStatus(ctx, order.ID, status, &note); err != nil {
+		return err
+	}
+	order.Status = status
+	return nil
+}
+
+// trimmedOrNil returns nil for a missing or blank string
+func trimmedOrNil(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := strings.TrimSpace(*s)
+	if v == "" {
+		return nil
+	}
+	return &v
+}
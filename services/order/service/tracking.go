@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/order/carrier"
+	"main.go/services/order/models"
+	"main.go/services/order/repository"
+)
+
+// trackingPollBatch caps the number of shipments polled per run
+const trackingPollBatch = 100
+
+// LabelInput names the carrier to create a shipping label with
+type LabelInput struct {
+	Carrier string `json:"carrier"`
+}
+
+// CreateLabel creates a shipping label for a shipment that has no tracking
+// number yet. The shipment counts as shipped once the carrier reports it
+// picked up.
+func (s *ShipmentService) CreateLabel(ctx context.Context, id uuid.UUID, in LabelInput) (*models.OrderShipment, error) {
+	c, ok := s.carriers[strings.TrimSpace(in.Carrier)]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown carrier %q", ErrValidation, in.Carrier)
+	}
+
+	var shipment *models.OrderShipment
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		shipments := repository.NewShipmentRepository(tx)
+		sh, err := shipments.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if sh.TrackingNo != nil {
+			return fmt.Errorf("%w: shipment already has tracking number %s", ErrInvalidState, *sh.TrackingNo)
+		}
+		if sh.ShippedAt != nil {
+			return fmt.Errorf("%w: shipment has already left", ErrInvalidState)
+		}
+		order, err := repository.NewOrderRepository(tx).GetByID(ctx, sh.OrderID)
+		if err != nil {
+			return err
+		}
+		shipment = sh
+		return s.attachLabel(ctx, shipments, sh, order, c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// CancelLabel voids the label of a shipment that has not been picked up yet
+// so a new one can be created
+func (s *ShipmentService) CancelLabel(ctx context.Context, id uuid.UUID) (*models.OrderShipment, error) {
+	var shipment *models.OrderShipment
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		shipments := repository.NewShipmentRepository(tx)
+		sh, err := shipments.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if sh.TrackingNo == nil || sh.Carrier == nil {
+			return fmt.Errorf("%w: shipment has no label", ErrInvalidState)
+		}
+		if sh.ShippedAt != nil {
+			return fmt.Errorf("%w: shipment has already left", ErrInvalidState)
+		}
+		c, ok := s.carriers[*sh.Carrier]
+		if !ok {
+			return fmt.Errorf("%w: carrier %s is not integrated", ErrInvalidState, *sh.Carrier)
+		}
+		if err := c.CancelLabel(ctx, *sh.TrackingNo); err != nil {
+			return fmt.Errorf("%w: carrier refused to cancel label: %v", ErrInvalidState, err)
+		}
+
+		sh.TrackingNo = nil
+		sh.LabelURL = nil
+		shipment = sh
+		return shipments.Update(ctx, sh)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// ListTrackingEvents returns the tracking events of a shipment
+func (s *ShipmentService) ListTrackingEvents(ctx context.Context, id uuid.UUID) ([]models.ShipmentTrackingEvent, error) {
+	shipments := repository.NewShipmentRepository(s.pool)
+	if _, err := shipments.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return shipments.ListTrackingEvents(ctx, id)
+}
+
+// PollTracking fetches tracking events for undelivered shipments of the
+// registered carriers and applies them. It returns the number of shipments
+// whose shipped or delivered time changed.
+func (s *ShipmentService) PollTracking(ctx context.Context) (int, error) {
+	if len(s.carriers) == 0 {
+		return 0, nil
+	}
+	list, err := repository.NewShipmentRepository(s.pool).ListAwaitingDelivery(ctx, s.carriers.Names(), trackingPollBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	advanced := 0
+	for _, sh := range list {
+		events, err := s.carriers[*sh.Carrier].Track(ctx, *sh.TrackingNo)
+		if err != nil {
+			log.Printf("failed to track shipment %s (%s %s): %v", sh.ID, *sh.Carrier, *sh.TrackingNo, err)
+			continue
+		}
+		changed := false
+		err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+			locked, err := repository.NewShipmentRepository(tx).GetByIDForUpdate(ctx, sh.ID)
+			if err != nil {
+				return err
+			}
+			changed, err = applyTrackingEvents(ctx, tx, locked, events, models.TrackingSourcePoll)
+			return err
+		})
+		if err != nil {
+			log.Printf("failed to apply tracking events to shipment %s: %v", sh.ID, err)
+			continue
+		}
+		if changed {
+			advanced++
+		}
+	}
+	return advanced, nil
+}
+
+// RunTrackingPoller calls PollTracking every interval until ctx is done
+func (s *ShipmentService) RunTrackingPoller(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.PollTracking(ctx)
+			if err != nil {
+				log.Printf("tracking poll failed: %v", err)
+			} else if n > 0 {
+				log.Printf("advanced %d shipments from carrier tracking", n)
+			}
+		}
+	}
+}
+
+// HandleWebhook applies tracking events pushed by a carrier to the shipment
+// with the given tracking number
+func (s *ShipmentService) HandleWebhook(ctx context.Context, carrierName string, payload carrier.WebhookPayload) (*models.OrderShipment, error) {
+	if _, ok := s.carriers[carrierName]; !ok {
+		return nil, fmt.Errorf("%w: unknown carrier %q", ErrValidation, carrierName)
+	}
+	trackingNo := strings.TrimSpace(payload.TrackingNo)
+	if trackingNo == "" {
+		return nil, fmt.Errorf("%w: tracking_no is required", ErrValidation)
+	}
+
+	var shipment *models.OrderShipment
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		sh, err := repository.NewShipmentRepository(tx).GetByTrackingForUpdate(ctx, carrierName, trackingNo)
+		if err != nil {
+			return err
+		}
+		shipment = sh
+		_, err = applyTrackingEvents(ctx, tx, sh, payload.Events, models.TrackingSourceWebhook)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// attachLabel creates a label with c and stores it on the shipment. The label
+// is cancelled again if it cannot be stored.
+func (s *ShipmentService) attachLabel(ctx context.Context, shipments *repository.ShipmentRepository, sh *models.OrderShipment, order *models.Order, c carrier.Carrier) error {
+	address, err := json.Marshal(order.ShippingAddress)
+	if err != nil {
+		return fmt.Errorf("failed to encode shipping address: %w", err)
+	}
+	label, err := c.CreateLabel(ctx, carrier.LabelRequest{
+		Reference: sh.ID.String(),
+		Address:   address,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create %s label: %w", c.Name(), err)
+	}
+
+	name := c.Name()
+	sh.Carrier = &name
+	sh.TrackingNo = &label.TrackingNo
+	sh.LabelURL = &label.LabelURL
+	if err := shipments.Update(ctx, sh); err != nil {
+		if cerr := c.CancelLabel(ctx, label.TrackingNo); cerr != nil {
+			log.Printf("failed to cancel orphaned %s label %s: %v", name, label.TrackingNo, cerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// applyTrackingEvents stores new events for a locked shipment and moves its
+// shipped and delivered times forward. When either changes the order status
+// is derived again. It reports whether the shipment advanced.
+func applyTrackingEvents(ctx context.Context, tx pgx.Tx, sh *models.OrderShipment, events []carrier.Event, source models.TrackingEventSource) (bool, error) {
+	shipments := repository.NewShipmentRepository(tx)
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].OccurredAt.Before(events[j].OccurredAt) })
+	advanced := false
+	for _, e := range events {
+		if strings.TrimSpace(e.ExternalID) == "" || e.Code == "" || e.OccurredAt.IsZero() {
+			return false, fmt.Errorf("%w: tracking events need external_id, code and occurred_at", ErrValidation)
+		}
+		_, err := shipments.AddTrackingEvent(ctx, &models.ShipmentTrackingEvent{
+			ShipmentID:  sh.ID,
+			Carrier:     *sh.Carrier,
+			ExternalID:  e.ExternalID,
+			Code:        string(e.Code),
+			Description: optionalString(e.Description),
+			Location:    optionalString(e.Location),
+			OccurredAt:  e.OccurredAt,
+			Source:      source,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		at := e.OccurredAt
+		if e.Code.Departed() && sh.ShippedAt == nil {
+			sh.ShippedAt = &at
+			advanced = true
+		}
+		if e.Code == carrier.EventDelivered && sh.DeliveredAt == nil {
+			sh.DeliveredAt = &at
+			advanced = true
+		}
+	}
+
+	// Always touch the shipment so the poller rotates through every shipment
+	if err := shipments.Update(ctx, sh); err != nil {
+		return false, err
+	}
+	if !advanced {
+		return false, nil
+	}
+
+	order, err := repository.NewOrderRepository(tx).GetByIDForUpdate(ctx, sh.OrderID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, deriveFulfillmentStatus(ctx, tx, order)
+}
+
+// optionalString returns nil for an empty string
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,71 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// OrderClient talks to the order service
+type OrderClient struct {
+	http httpClient
+}
+
+// NewOrderClient creates a client for the order service at baseURL
+func NewOrderClient(baseURL string) *OrderClient {
+	return &OrderClient{http: newHTTPClient(baseURL)}
+}
+
+// SetFlag raises a support-visible flag on an order
+func (c *OrderClient) SetFlag(ctx context.Context, orderID uuid.UUID, flag, note string) error {
+	body := map[string]string{"flag": flag, "note": note, "source": "payment"}
+	return c.http.do(ctx, http.MethodPost, "/orders/"+orderID.String()+"/flags", body, nil)
+}
+
+// ClearFlag clears a previously raised flag on an order
+func (c *OrderClient) ClearFlag(ctx context.Context, orderID uuid.UUID, flag string) error {
+	return c.http.do(ctx, http.MethodDelete, "/orders/"+orderID.String()+"/flags/"+flag, nil, nil)
+}
+
+// Address is the subset of an order address used by the payment service
+type Address struct {
+	Country string  `json:"country"`
+	Email   *string `json:"email,omitempty"`
+}
+
+// Order is the order service's view of an order
+type Order struct {
+	ID              uuid.UUID     `json:"id"`
+	UserID          uuid.UUID     `json:"user_id"`
+	OrderNumber     string        `json:"order_number"`
+	Status          string        `json:"status"`
+	Total           float64       `json:"total"`
+	Currency        string        `json:"currency"`
+	ShippingAddress *Address      `json:"shipping_address"`
+	BillingAddress  *Address      `json:"billing_address"`
+	FXRates         []OrderFXRate `json:"fx_rates,omitempty"`
+}
+
+// GetOrder fetches an order by its ID
+func (c *OrderClient) GetOrder(ctx context.Context, orderID uuid.UUID) (*Order, error) {
+	var order Order
+	if err := c.http.do(ctx, http.MethodGet, "/orders/"+orderID.String(), nil, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CancelOrder cancels an order with the given reason
+func (c *OrderClient) CancelOrder(ctx context.Context, orderID uuid.UUID, reason string) error {
+	body := map[string]string{"reason": reason}
+	return c.http.do(ctx, http.MethodPost, "/orders/"+orderID.String()+"/cancel", body, nil)
+}
+
+// OrderFXRate is an FX rate locked on an order at checkout
+type OrderFXRate struct {
+	BaseCurrency  string    `json:"base_currency"`
+	QuoteCurrency string    `json:"quote_currency"`
+	Rate          float64   `json:"rate"`
+	RateID        uuid.UUID `json:"rate_id"`
+}
@@ -58,3 +58,77 @@ CREATE INDEX idx_payments_status ON payments(status);
 CREATE INDEX idx_payment_methods_user ON payment_methods(user_id);
 CREATE INDEX idx_payment_transactions_payment ON payment_transactions(payment_id);
 CREATE INDEX idx_refunds_payment ON refunds(payment_id);
+
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS fx_conversion JSONB;  -- order fx rates and base-currency amounts at payment time
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS currency VARCHAR(3);
+UPDATE refunds r SET currency = p.currency FROM payments p WHERE p.id = r.payment_id AND r.currency IS NULL;
+ALTER TABLE refunds ALTER COLUMN currency SET NOT NULL;
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS fx_conversion JSONB;
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(255);
+
+-- A retried refund with the same key returns the earlier refund unless it failed
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refunds_idempotency ON refunds(payment_id, idempotency_key)
+    WHERE idempotency_key IS NOT NULL AND status <> 'failed';
+
+CREATE TABLE IF NOT EXISTS disputes (
+    id                    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    payment_id            UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    order_id              UUID NOT NULL,  -- references order service (external ID)
+    amount                DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    currency              VARCHAR(3) NOT NULL,
+    reason                VARCHAR(255),
+    status                VARCHAR(30) NOT NULL DEFAULT 'opened' CHECK (status IN (
+        'opened', 'evidence_required', 'won', 'lost'
+    )),
+    gateway_dispute_id    VARCHAR(255) UNIQUE,
+    evidence_due_by       TIMESTAMPTZ,
+    evidence_submitted_at TIMESTAMPTZ,
+    resolved_at           TIMESTAMPTZ,
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at            TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS dispute_evidence (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    dispute_id   UUID NOT NULL REFERENCES disputes(id) ON DELETE CASCADE,
+    file_name    VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes   BIGINT NOT NULL CHECK (size_bytes >= 0),
+    content      BYTEA NOT NULL,
+    description  TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_disputes_payment ON disputes(payment_id);
+CREATE INDEX idx_disputes_order ON disputes(order_id);
+CREATE INDEX idx_disputes_status_due ON disputes(status, evidence_due_by);
+CREATE INDEX idx_dispute_evidence_dispute ON dispute_evidence(dispute_id);
+
+CREATE TABLE IF NOT EXISTS risk_assessments (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    payment_id    UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    order_id      UUID NOT NULL,  -- references order service (external ID)
+    user_id       UUID NOT NULL,
+    score         INT NOT NULL CHECK (score >= 0),
+    decision      VARCHAR(20) NOT NULL CHECK (decision IN ('allow', 'review', 'deny')),
+    signals       JSONB NOT NULL DEFAULT '[]',
+    review_status VARCHAR(20) CHECK (review_status IN ('pending', 'released', 'cancelled')),
+    reviewed_by   VARCHAR(255),
+    review_note   TEXT,
+    reviewed_at   TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS risk_blocklist (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    type       VARCHAR(30) NOT NULL CHECK (type IN ('user', 'payment_method', 'email', 'country')),
+    value      VARCHAR(255) NOT NULL,
+    reason     VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(type, value)
+);
+
+CREATE INDEX idx_risk_assessments_payment ON risk_assessments(payment_id);
+CREATE INDEX idx_risk_assessments_review ON risk_assessments(review_status, created_at);
+CREATE INDEX idx_payments_user_created ON payments(user_id, created_at);
+CREATE INDEX idx_payments_method_created ON payments(payment_method_id, created_at);
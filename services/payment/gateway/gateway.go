@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// Request describes a gateway operation on a payment. Processors treat
+// requests with the same non-empty IdempotencyKey as one operation.
+type Request struct {
+	PaymentID      uuid.UUID
+	GatewayRef     string
+	Amount         float64
+	Currency       string
+	IdempotencyKey string
+}
+
+// Result is the gateway's response to an operation
+type Result struct {
+	Success  bool
+	TxnID    string
+	Ref      string
+	Message  string
+	Response json.RawMessage
+}
+
+// Gateway is implemented by payment processors
+type Gateway interface {
+	Name() string
+	Authorize(ctx context.Context, req Request) (*Result, error)
+	Capture(ctx context.Context, req Request) (*Result, error)
+	Void(ctx context.Context, req Request) (*Result, error)
+	Refund(ctx context.Context, req Request) (*Result, error)
+}
@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Simulated is an in-process gateway for local development. It approves
+// every operation except authorizations above DeclineAbove when set.
+type Simulated struct {
+	DeclineAbove float64
+}
+
+// NewSimulated creates a Simulated gateway that approves everything
+func NewSimulated() *Simulated {
+	return &Simulated{}
+}
+
+// Name implements Gateway
+func (g *Simulated) Name() string { return "simulated" }
+
+// Authorize implements Gateway
+func (g *Simulated) Authorize(_ context.Context, req Request) (*Result, error) {
+	if g.DeclineAbove > 0 && req.Amount > g.DeclineAbove {
+		return g.result(false, "auth", req, uuid.NewString(), "amount exceeds simulated limit"), nil
+	}
+	return g.result(true, "auth", req, "sim_"+uuid.NewString(), "approved"), nil
+}
+
+// Capture implements Gateway
+func (g *Simulated) Capture(_ context.Context, req Request) (*Result, error) {
+	return g.result(true, "capture", req, req.GatewayRef, "captured"), nil
+}
+
+// Void implements Gateway
+func (g *Simulated) Void(_ context.Context, req Request) (*Result, error) {
+	return g.result(true, "void", req, req.GatewayRef, "voided"), nil
+}
+
+// Refund implements Gateway
+func (g *Simulated) Refund(_ context.Context, req Request) (*Result, error) {
+	return g.result(true, "refund", req, req.GatewayRef, "refunded"), nil
+}
+
+func (g *Simulated) result(success bool, op string, req Request, ref, msg string) *Result {
+	txnID := fmt.Sprintf("sim_%s_%s", op, uuid.NewString())
+	resp, _ := json.Marshal(map[string]any{
+		"operation": op,
+		"success":   success,
+		"amount":    req.Amount,
+		"currency":  req.Currency,
+		"message":   msg,
+	})
+	return &Result{Success: success, TxnID: txnID, Ref: ref, Message: msg, Response: resp}
+}
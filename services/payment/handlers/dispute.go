@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"main.go/services/payment/models"
+	"main.go/services/payment/service"
+)
+
+// DisputeHandler exposes dispute management over HTTP
+type DisputeHandler struct {
+	disputes *service.DisputeService
+}
+
+// NewDisputeHandler creates a new DisputeHandler
+func NewDisputeHandler(disputes *service.DisputeService) *DisputeHandler {
+	return &DisputeHandler{disputes: disputes}
+}
+
+// RegisterRoutes registers the dispute routes on mux
+func (h *DisputeHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /payments/{id}/disputes", h.open)
+	mux.HandleFunc("GET /payments/{id}/disputes", h.listByPayment)
+	mux.HandleFunc("GET /disputes/{id}", h.get)
+	mux.HandleFunc("POST /disputes/{id}/request-evidence", h.requestEvidence)
+	mux.HandleFunc("POST /disputes/{id}/evidence", h.addEvidence)
+	mux.HandleFunc("GET /disputes/{id}/evidence", h.listEvidence)
+	mux.HandleFunc("GET /disputes/{id}/evidence/{evidenceID}", h.downloadEvidence)
+	mux.HandleFunc("POST /disputes/{id}/submit", h.submitEvidence)
+	mux.HandleFunc("POST /disputes/{id}/resolve", h.resolve)
+}
+
+func (h *DisputeHandler) open(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.OpenDisputeInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	in.PaymentID = paymentID
+
+	dispute, err := h.disputes.Open(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, dispute)
+}
+
+func (h *DisputeHandler) listByPayment(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	disputes, err := h.disputes.ListByPayment(r.Context(), paymentID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, disputes)
+}
+
+func (h *DisputeHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	dispute, err := h.disputes.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, dispute)
+}
+
+func (h *DisputeHandler) requestEvidence(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var body struct {
+		EvidenceDueBy *time.Time `json:"evidence_due_by"`
+	}
+	if err := decodeJSON(w, r, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+	dispute, err := h.disputes.RequestEvidence(r.Context(), id, body.EvidenceDueBy)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, dispute)
+}
+
+// addEvidence accepts a multipart upload with a "file" part and an optional
+// "description" field
+func (h *DisputeHandler) addEvidence(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxEvidenceSize+maxBodySize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: file is required: %v", service.ErrValidation, err))
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: failed to read file: %v", service.ErrValidation, err))
+		return
+	}
+
+	in := service.AddEvidenceInput{
+		DisputeID:   id,
+		FileName:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Content:     content,
+	}
+	if in.ContentType == "" {
+		in.ContentType = http.DetectContentType(content)
+	}
+	if desc := r.FormValue("description"); desc != "" {
+		in.Description = &desc
+	}
+
+	evidence, err := h.disputes.AddEvidence(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, evidence)
+}
+
+func (h *DisputeHandler) listEvidence(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	evidence, err := h.disputes.ListEvidence(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, evidence)
+}
+
+func (h *DisputeHandler) downloadEvidence(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	evidenceID, err := pathUUID(r, "evidenceID")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	evidence, err := h.disputes.GetEvidence(r.Context(), id, evidenceID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", evidence.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(evidence.SizeBytes, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", evidence.FileName))
+	w.Write(evidence.Content)
+}
+
+func (h *DisputeHandler) submitEvidence(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	dispute, err := h.disputes.SubmitEvidence(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, dispute)
+}
+
+func (h *DisputeHandler) resolve(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var body struct {
+		Outcome models.DisputeStatus `json:"outcome"`
+	}
+	if err := decodeJSON(w, r, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+	dispute, err := h.disputes.Resolve(r.Context(), id, body.Outcome)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, dispute)
+}
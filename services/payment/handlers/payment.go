@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"main.go/services/payment/models"
+	"main.go/services/payment/service"
+)
+
+// PaymentHandler exposes payments over HTTP
+type PaymentHandler struct {
+	payments *service.PaymentService
+}
+
+// NewPaymentHandler creates a new PaymentHandler
+func NewPaymentHandler(payments *service.PaymentService) *PaymentHandler {
+	return &PaymentHandler{payments: payments}
+}
+
+// RegisterRoutes registers the payment routes on mux
+func (h *PaymentHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /payments", h.create)
+	mux.HandleFunc("GET /payments", h.listByOrder)
+	mux.HandleFunc("GET /payments/{id}", h.get)
+	mux.HandleFunc("GET /payments/{id}/transactions", h.listTransactions)
+	mux.HandleFunc("GET /payments/{id}/risk", h.listAssessments)
+	mux.HandleFunc("POST /payments/{id}/authorize", h.authorize)
+	mux.HandleFunc("POST /payments/{id}/capture", h.capture)
+	mux.HandleFunc("POST /payments/{id}/void", h.void)
+	mux.HandleFunc("GET /payments/{id}/refunds", h.listRefunds)
+	mux.HandleFunc("POST /payments/{id}/refunds", h.refund)
+}
+
+func (h *PaymentHandler) create(w http.ResponseWriter, r *http.Request) {
+	var in service.CreatePaymentInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	payment, err := h.payments.Create(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, payment)
+}
+
+// listByOrder lists the payments of the order given by ?order_id=
+func (h *PaymentHandler) listByOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(r.URL.Query().Get("order_id"))
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: order_id is required", service.ErrValidation))
+		return
+	}
+	payments, err := h.payments.ListByOrder(r.Context(), orderID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, payments)
+}
+
+func (h *PaymentHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	payment, err := h.payments.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, payment)
+}
+
+func (h *PaymentHandler) listTransactions(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	txns, err := h.payments.ListTransactions(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, txns)
+}
+
+func (h *PaymentHandler) listAssessments(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	assessments, err := h.payments.ListAssessments(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, assessments)
+}
+
+// authorize responds 202 when the payment is held for manual review
+func (h *PaymentHandler) authorize(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := h.payments.Authorize(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	status := http.StatusOK
+	if result.Assessment.Decision == models.RiskDecisionReview {
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, result)
+}
+
+func (h *PaymentHandler) capture(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	payment, err := h.payments.Capture(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, payment)
+}
+
+func (h *PaymentHandler) void(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	payment, err := h.payments.Void(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, payment)
+}
+
+func (h *PaymentHandler) listRefunds(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	refunds, err := h.payments.ListRefunds(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, refunds)
+}
+
+func (h *PaymentHandler) refund(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.RefundInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	refund, err := h.payments.Refund(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, refund)
+}
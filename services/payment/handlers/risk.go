@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"main.go/services/payment/models"
+	"main.go/services/payment/service"
+)
+
+// RiskHandler exposes the manual review queue and blocklist over HTTP
+type RiskHandler struct {
+	payments *service.PaymentService
+}
+
+// NewRiskHandler creates a new RiskHandler
+func NewRiskHandler(payments *service.PaymentService) *RiskHandler {
+	return &RiskHandler{payments: payments}
+}
+
+// RegisterRoutes registers the risk routes on mux
+func (h *RiskHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /risk/reviews", h.listReviews)
+	mux.HandleFunc("POST /risk/reviews/{id}/release", h.release)
+	mux.HandleFunc("POST /risk/reviews/{id}/cancel", h.cancel)
+	mux.HandleFunc("GET /risk/blocklist", h.listBlocklist)
+	mux.HandleFunc("POST /risk/blocklist", h.addBlocklist)
+	mux.HandleFunc("DELETE /risk/blocklist/{id}", h.removeBlocklist)
+}
+
+func (h *RiskHandler) listReviews(w http.ResponseWriter, r *http.Request) {
+	status := models.RiskReviewStatus(r.URL.Query().Get("status"))
+	reviews, err := h.payments.ListReviews(r.Context(), status)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reviews)
+}
+
+func (h *RiskHandler) release(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.payments.ReleaseReview)
+}
+
+func (h *RiskHandler) cancel(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.payments.CancelReview)
+}
+
+// decide applies a release or cancel decision to a held payment
+func (h *RiskHandler) decide(w http.ResponseWriter, r *http.Request, fn func(context.Context, uuid.UUID, service.ReviewInput) (*service.AuthorizationResult, error)) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.ReviewInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := fn(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *RiskHandler) listBlocklist(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.payments.ListBlocklist(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (h *RiskHandler) addBlocklist(w http.ResponseWriter, r *http.Request) {
+	var in service.AddBlocklistInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	entry, err := h.payments.AddBlocklistEntry(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, entry)
+}
+
+func (h *RiskHandler) removeBlocklist(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.payments.RemoveBlocklistEntry(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
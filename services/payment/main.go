@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"main.go/services/payment/clients"
+	"main.go/services/payment/db"
+	"main.go/services/payment/gateway"
+	"main.go/services/payment/handlers"
+	"main.go/services/payment/repository"
+	"main.go/services/payment/risk"
+	"main.go/services/payment/service"
+)
+
+func main() {
+	// Load database configuration from environment variables
+	config := db.LoadConfig()
+
+	// Connect to the database
+	if err := db.Connect(config); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := db.GetDB()
+	orders := clients.NewOrderClient(getEnv("ORDER_SERVICE_URL", "http://localhost:8083"))
+
+	engine := risk.NewEngine(risk.DefaultRules(repository.NewRiskRepository(pool))...)
+	payments := service.NewPaymentService(pool, gateway.NewSimulated(), engine, orders)
+
+	disputes := service.NewDisputeService(pool, orders)
+	go disputes.RunDeadlineSweeper(ctx, time.Hour)
+
+	mux := http.NewServeMux()
+	handlers.NewPaymentHandler(payments).RegisterRoutes(mux)
+	handlers.NewRiskHandler(payments).RegisterRoutes(mux)
+	handlers.NewDisputeHandler(disputes).RegisterRoutes(mux)
+
+	addr := getEnv("PAYMENT_HTTP_ADDR", ":8084")
+	log.Printf("Payment service listening on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Fatalf("Payment service stopped: %v", err)
+	}
+}
+
+// getEnv gets an environment variable or returns a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -57,6 +57,7 @@ type Payment struct {
 	Gateway         *string         `json:"gateway,omitempty" db:"gateway" validate:"omitempty,max=50"`
 	GatewayRef      *string         `json:"gateway_ref,omitempty" db:"gateway_ref" validate:"omitempty,max=255"`
 	GatewayResponse json.RawMessage `json:"gateway_response,omitempty" db:"gateway_response"`
+	FXConversion    json.RawMessage `json:"fx_conversion,omitempty" db:"fx_conversion"`
 	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
 }
@@ -104,11 +105,106 @@ const (
 
 // Refund represents a refund transaction
 type Refund struct {
-	ID        uuid.UUID   `json:"id" db:"id"`
-	PaymentID uuid.UUID   `json:"payment_id" db:"payment_id" validate:"required"`
-	Amount    float64     `json:"amount" db:"amount" validate:"required,min=0.01"`
-	Reason    *string     `json:"reason,omitempty" db:"reason" validate:"omitempty,max=255"`
-	Status    RefundStatus `json:"status" db:"status" validate:"required,oneof=pending completed failed"`
-	CreatedAt time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at" db:"updated_at"`
+	ID             uuid.UUID       `json:"id" db:"id"`
+	PaymentID      uuid.UUID       `json:"payment_id" db:"payment_id" validate:"required"`
+	Amount         float64         `json:"amount" db:"amount" validate:"required,min=0.01"`
+	Currency       string          `json:"currency" db:"currency" validate:"required,len=3"`
+	Reason         *string         `json:"reason,omitempty" db:"reason" validate:"omitempty,max=255"`
+	Status         RefundStatus    `json:"status" db:"status" validate:"required,oneof=pending completed failed"`
+	FXConversion   json.RawMessage `json:"fx_conversion,omitempty" db:"fx_conversion"`
+	IdempotencyKey *string         `json:"idempotency_key,omitempty" db:"idempotency_key" validate:"omitempty,max=255"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// DisputeStatus represents the status of a payment dispute
+type DisputeStatus string
+
+const (
+	DisputeStatusOpened           DisputeStatus = "opened"
+	DisputeStatusEvidenceRequired DisputeStatus = "evidence_required"
+	DisputeStatusWon              DisputeStatus = "won"
+	DisputeStatusLost             DisputeStatus = "lost"
+)
+
+// Dispute represents a chargeback or dispute raised against a payment
+type Dispute struct {
+	ID                  uuid.UUID     `json:"id" db:"id"`
+	PaymentID           uuid.UUID     `json:"payment_id" db:"payment_id" validate:"required"`
+	OrderID             uuid.UUID     `json:"order_id" db:"order_id" validate:"required"`
+	Amount              float64       `json:"amount" db:"amount" validate:"required,min=0.01"`
+	Currency            string        `json:"currency" db:"currency" validate:"required,len=3"`
+	Reason              *string       `json:"reason,omitempty" db:"reason" validate:"omitempty,max=255"`
+	Status              DisputeStatus `json:"status" db:"status" validate:"required,oneof=opened evidence_required won lost"`
+	GatewayDisputeID    *string       `json:"gateway_dispute_id,omitempty" db:"gateway_dispute_id" validate:"omitempty,max=255"`
+	EvidenceDueBy       *time.Time    `json:"evidence_due_by,omitempty" db:"evidence_due_by"`
+	EvidenceSubmittedAt *time.Time    `json:"evidence_submitted_at,omitempty" db:"evidence_submitted_at"`
+	ResolvedAt          *time.Time    `json:"resolved_at,omitempty" db:"resolved_at"`
+	CreatedAt           time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// DisputeEvidence represents a document attached to a dispute as evidence
+type DisputeEvidence struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	DisputeID   uuid.UUID `json:"dispute_id" db:"dispute_id" validate:"required"`
+	FileName    string    `json:"file_name" db:"file_name" validate:"required,min=1,max=255"`
+	ContentType string    `json:"content_type" db:"content_type" validate:"required,max=100"`
+	SizeBytes   int64     `json:"size_bytes" db:"size_bytes"`
+	Content     []byte    `json:"-" db:"content"`
+	Description *string   `json:"description,omitempty" db:"description"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// RiskDecision represents the outcome of a fraud risk assessment
+type RiskDecision string
+
+const (
+	RiskDecisionAllow  RiskDecision = "allow"
+	RiskDecisionReview RiskDecision = "review"
+	RiskDecisionDeny   RiskDecision = "deny"
+)
+
+// RiskReviewStatus represents the state of a manual risk review
+type RiskReviewStatus string
+
+const (
+	RiskReviewPending   RiskReviewStatus = "pending"
+	RiskReviewReleased  RiskReviewStatus = "released"
+	RiskReviewCancelled RiskReviewStatus = "cancelled"
+)
+
+// RiskAssessment represents a persisted risk decision for a payment
+type RiskAssessment struct {
+	ID           uuid.UUID         `json:"id" db:"id"`
+	PaymentID    uuid.UUID         `json:"payment_id" db:"payment_id" validate:"required"`
+	OrderID      uuid.UUID         `json:"order_id" db:"order_id" validate:"required"`
+	UserID       uuid.UUID         `json:"user_id" db:"user_id" validate:"required"`
+	Score        int               `json:"score" db:"score" validate:"min=0"`
+	Decision     RiskDecision      `json:"decision" db:"decision" validate:"required,oneof=allow review deny"`
+	Signals      json.RawMessage   `json:"signals" db:"signals"`
+	ReviewStatus *RiskReviewStatus `json:"review_status,omitempty" db:"review_status" validate:"omitempty,oneof=pending released cancelled"`
+	ReviewedBy   *string           `json:"reviewed_by,omitempty" db:"reviewed_by" validate:"omitempty,max=255"`
+	ReviewNote   *string           `json:"review_note,omitempty" db:"review_note"`
+	ReviewedAt   *time.Time        `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	CreatedAt    time.Time         `json:"created_at" db:"created_at"`
+}
+
+// BlocklistType represents the kind of value held in a risk blocklist entry
+type BlocklistType string
+
+const (
+	BlocklistUser          BlocklistType = "user"
+	BlocklistPaymentMethod BlocklistType = "payment_method"
+	BlocklistEmail         BlocklistType = "email"
+	BlocklistCountry       BlocklistType = "country"
+)
+
+// BlocklistEntry represents a value that is always denied by the risk engine
+type BlocklistEntry struct {
+	ID        uuid.UUID     `json:"id" db:"id"`
+	Type      BlocklistType `json:"type" db:"type" validate:"required,oneof=user payment_method email country"`
+	Value     string        `json:"value" db:"value" validate:"required,min=1,max=255"`
+	Reason    *string       `json:"reason,omitempty" db:"reason" validate:"omitempty,max=255"`
+	CreatedAt time.Time     `json:"created_at" db:"created_at"`
 }
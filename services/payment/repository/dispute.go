@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"main.go/services/payment/models"
+)
+
+const disputeColumns = `id, payment_id, order_id, amount, currency, reason, status, gateway_dispute_id,
+	evidence_due_by, evidence_submitted_at, resolved_at, created_at, updated_at`
+
+// DisputeRepository provides access to disputes and their evidence
+type DisputeRepository struct {
+	db DBTX
+}
+
+// NewDisputeRepository creates a new DisputeRepository
+func NewDisputeRepository(db DBTX) *DisputeRepository {
+	return &DisputeRepository{db: db}
+}
+
+// Create inserts a new dispute
+func (r *DisputeRepository) Create(ctx context.Context, d *models.Dispute) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO disputes (payment_id, order_id, amount, currency, reason, status, gateway_dispute_id, evidence_due_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`,
+		d.PaymentID, d.OrderID, d.Amount, d.Currency, d.Reason, d.Status, d.GatewayDisputeID, d.EvidenceDueBy,
+	).Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create dispute: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns a dispute by its ID
+func (r *DisputeRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Dispute, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+disputeColumns+` FROM disputes WHERE id = $1`, id)
+	d, err := collectOne[models.Dispute](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dispute: %w", err)
+	}
+	return d, nil
+}
+
+// GetByIDForUpdate returns a dispute by its ID and locks the row until the
+// surrounding transaction ends
+func (r *DisputeRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*models.Dispute, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+disputeColumns+` FROM disputes WHERE id = $1 FOR UPDATE`, id)
+	d, err := collectOne[models.Dispute](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dispute: %w", err)
+	}
+	return d, nil
+}
+
+// ListByPayment returns all disputes raised against a payment, newest first
+func (r *DisputeRepository) ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]models.Dispute, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+disputeColumns+` FROM disputes WHERE payment_id = $1 ORDER BY created_at DESC`, paymentID)
+	disputes, err := collectAll[models.Dispute](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list disputes: %w", err)
+	}
+	return disputes, nil
+}
+
+// ListByStatus returns disputes in the given status, oldest first
+func (r *DisputeRepository) ListByStatus(ctx context.Context, status models.DisputeStatus, limit int) ([]models.Dispute, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+disputeColumns+` FROM disputes WHERE status = $1 ORDER BY created_at LIMIT $2`, status, limit)
+	disputes, err := collectAll[models.Dispute](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list disputes: %w", err)
+	}
+	return disputes, nil
+}
+
+// ListOverdue returns unresolved disputes whose evidence deadline has passed
+// without any evidence being submitted
+func (r *DisputeRepository) ListOverdue(ctx context.Context, now time.Time) ([]models.Dispute, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+disputeColumns+` FROM disputes
+		WHERE status = $1 AND evidence_submitted_at IS NULL AND evidence_due_by < $2
+		ORDER BY evidence_due_by`, models.DisputeStatusEvidenceRequired, now)
+	disputes, err := collectAll[models.Dispute](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list overdue disputes: %w", err)
+	}
+	return disputes, nil
+}
+
+// SumDisputed returns the total amount of a payment's disputes that are
+// unresolved or were lost, the part of it the cardholder is taking back
+func (r *DisputeRepository) SumDisputed(ctx context.Context, paymentID uuid.UUID) (float64, error) {
+	var total float64
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM disputes WHERE payment_id = $1 AND status <> $2`,
+		paymentID, models.DisputeStatusWon,
+	).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum disputes: %w", err)
+	}
+	return total, nil
+}
+
+// SumLost returns the total amount of lost disputes on a payment, the money
+// already returned to the cardholder through chargebacks
+func (r *DisputeRepository) SumLost(ctx context.Context, paymentID uuid.UUID) (float64, error) {
+	var total float64
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM disputes WHERE payment_id = $1 AND status = $2`,
+		paymentID, models.DisputeStatusLost,
+	).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum lost disputes: %w", err)
+	}
+	return total, nil
+}
+
+// CountUnresolvedByOrder returns the number of disputes on an order's
+// payments that are still opened or waiting for evidence
+func (r *DisputeRepository) CountUnresolvedByOrder(ctx context.Context, orderID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM disputes WHERE order_id = $1 AND status IN ($2, $3)`,
+		orderID, models.DisputeStatusOpened, models.DisputeStatusEvidenceRequired,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count disputes: %w", err)
+	}
+	return n, nil
+}
+
+// Update persists the mutable fields of a dispute
+func (r *DisputeRepository) Update(ctx context.Context, d *models.Dispute) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE disputes
+		SET status = $2, reason = $3, evidence_due_by = $4, evidence_submitted_at = $5, resolved_at = $6, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at`,
+		d.ID, d.Status, d.Reason, d.EvidenceDueBy, d.EvidenceSubmittedAt, d.ResolvedAt,
+	).Scan(&d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update dispute: %w", err)
+	}
+	return nil
+}
+
+// AddEvidence stores an evidence document for a dispute
+func (r *DisputeRepository) AddEvidence(ctx context.Context, e *models.DisputeEvidence) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO dispute_evidence (dispute_id, file_name, content_type, size_bytes, content, description)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		e.DisputeID, e.FileName, e.ContentType, e.SizeBytes, e.Content, e.Description,
+	).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add dispute evidence: %w", err)
+	}
+	return nil
+}
+
+// ListEvidence returns the evidence attached to a dispute without the
+// document contents
+func (r *DisputeRepository) ListEvidence(ctx context.Context, disputeID uuid.UUID) ([]models.DisputeEvidence, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, dispute_id, file_name, content_type, size_bytes, NULL::bytea AS content, description, created_at
+		FROM dispute_evidence WHERE dispute_id = $1 ORDER BY created_at`, disputeID)
+	evidence, err := collectAll[models.DisputeEvidence](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dispute evidence: %w", err)
+	}
+	return evidence, nil
+}
+
+// GetEvidence returns a single evidence document including its contents
+func (r *DisputeRepository) GetEvidence(ctx context.Context, disputeID, evidenceID uuid.UUID) (*models.DisputeEvidence, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, dispute_id, file_name, content_type, size_bytes, content, description, created_at
+		FROM dispute_evidence WHERE dispute_id = $1 AND id = $2`, disputeID, evidenceID)
+	e, err := collectOne[models.DisputeEvidence](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dispute evidence: %w", err)
+	}
+	return e, nil
+}
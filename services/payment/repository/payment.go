@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/payment/models"
+)
+
+const paymentColumns = `id, order_id, user_id, amount, currency, status, payment_method_id,
+	gateway, gateway_ref, gateway_response, fx_conversion, created_at, updated_at`
+
+// PaymentRepository provides access to payments and their transactions
+type PaymentRepository struct {
+	db DBTX
+}
+
+// NewPaymentRepository creates a new PaymentRepository
+func NewPaymentRepository(db DBTX) *PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+// GetByID returns a payment by its ID
+func (r *PaymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Payment, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+paymentColumns+` FROM payments WHERE id = $1`, id)
+	payment, err := collectOne[models.Payment](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+	return payment, nil
+}
+
+// GetByIDForUpdate returns a payment by its ID and locks the row until the
+// surrounding transaction ends
+func (r *PaymentRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*models.Payment, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+paymentColumns+` FROM payments WHERE id = $1 FOR UPDATE`, id)
+	payment, err := collectOne[models.Payment](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+	return payment, nil
+}
+
+// ListByOrder returns all payments for an order, oldest first
+func (r *PaymentRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.Payment, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+paymentColumns+` FROM payments WHERE order_id = $1 ORDER BY created_at`, orderID)
+	payments, err := collectAll[models.Payment](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payments: %w", err)
+	}
+	return payments, nil
+}
+
+// UpdateStatus sets the status of a payment
+func (r *PaymentRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status models.PaymentStatus) error {
+	tag, err := r.db.Exec(ctx, `UPDATE payments SET status = $2, updated_at = NOW() WHERE id = $1`, id, status)
+	if err != nil {
+		return fmt.Errorf("failed to update payment status: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateTransaction records a payment transaction
+func (r *PaymentRepository) CreateTransaction(ctx context.Context, txn *models.PaymentTransaction) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO payment_transactions (payment_id, type, amount, status, gateway_txn_id, gateway_response)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		txn.PaymentID, txn.Type, txn.Amount, txn.Status, txn.GatewayTxnID, txn.GatewayResponse,
+	).Scan(&txn.ID, &txn.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create payment transaction: %w", err)
+	}
+	return nil
+}
+
+// UpdateTransaction stores the outcome of a pending payment transaction
+func (r *PaymentRepository) UpdateTransaction(ctx context.Context, txn *models.PaymentTransaction) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE payment_transactions SET status = $2, gateway_txn_id = $3, gateway_response = $4
+		WHERE id = $1`,
+		txn.ID, txn.Status, txn.GatewayTxnID, txn.GatewayResponse)
+	if err != nil {
+		return fmt.Errorf("failed to update payment transaction: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// HasPendingTransaction reports whether a gateway operation on a payment is
+// still waiting for its outcome
+func (r *PaymentRepository) HasPendingTransaction(ctx context.Context, paymentID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM payment_transactions WHERE payment_id = $1 AND status = $2)`,
+		paymentID, models.TransactionStatusPending,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check pending transactions: %w", err)
+	}
+	return exists, nil
+}
+
+// ListTransactions returns all transactions for a payment, oldest first
+func (r *PaymentRepository) ListTransactions(ctx context.Context, paymentID uuid.UUID) ([]models.PaymentTransaction, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, payment_id, type, amount, status, gateway_txn_id, gateway_response, created_at
+		FROM payment_transactions WHERE payment_id = $1 ORDER BY created_at`, paymentID)
+	txns, err := collectAll[models.PaymentTransaction](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payment transactions: %w", err)
+	}
+	return txns, nil
+}
+
+// Create inserts a new payment
+func (r *PaymentRepository) Create(ctx context.Context, p *models.Payment) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO payments (order_id, user_id, amount, currency, status, payment_method_id, gateway, fx_conversion)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`,
+		p.OrderID, p.UserID, p.Amount, p.Currency, p.Status, p.PaymentMethodID, p.Gateway, p.FXConversion,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create payment: %w", err)
+	}
+	return nil
+}
+
+// UpdateGatewayResult stores the status and gateway details of a payment
+func (r *PaymentRepository) UpdateGatewayResult(ctx context.Context, p *models.Payment) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE payments SET status = $2, gateway = $3, gateway_ref = $4, gateway_response = $5, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at`,
+		p.ID, p.Status, p.Gateway, p.GatewayRef, p.GatewayResponse,
+	).Scan(&p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	return nil
+}
+
+// GetMethod returns a payment method by its ID
+func (r *PaymentRepository) GetMethod(ctx context.Context, id uuid.UUID) (*models.PaymentMethod, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, user_id, type, provider, last_four, expiry, is_default, metadata, created_at, updated_at
+		FROM payment_methods WHERE id = $1`, id)
+	method, err := collectOne[models.PaymentMethod](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment method: %w", err)
+	}
+	return method, nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/payment/models"
+)
+
+const refundColumns = `id, payment_id, amount, currency, reason, status, fx_conversion, idempotency_key,
+	created_at, updated_at`
+
+// RefundRepository provides access to refunds
+type RefundRepository struct {
+	db DBTX
+}
+
+// NewRefundRepository creates a new RefundRepository
+func NewRefundRepository(db DBTX) *RefundRepository {
+	return &RefundRepository{db: db}
+}
+
+// Create inserts a new refund
+func (r *RefundRepository) Create(ctx context.Context, refund *models.Refund) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO refunds (payment_id, amount, currency, reason, status, fx_conversion, idempotency_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`,
+		refund.PaymentID, refund.Amount, refund.Currency, refund.Reason, refund.Status, refund.FXConversion,
+		refund.IdempotencyKey,
+	).Scan(&refund.ID, &refund.CreatedAt, &refund.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refund: %w", err)
+	}
+	return nil
+}
+
+// UpdateStatus sets the status of a refund
+func (r *RefundRepository) UpdateStatus(ctx context.Context, refund *models.Refund) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE refunds SET status = $2, updated_at = NOW() WHERE id = $1 RETURNING updated_at`,
+		refund.ID, refund.Status,
+	).Scan(&refund.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update refund: %w", err)
+	}
+	return nil
+}
+
+// GetByIdempotencyKey returns the refund of a payment created with key that
+// did not fail
+func (r *RefundRepository) GetByIdempotencyKey(ctx context.Context, paymentID uuid.UUID, key string) (*models.Refund, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+refundColumns+` FROM refunds
+		WHERE payment_id = $1 AND idempotency_key = $2 AND status <> $3`,
+		paymentID, key, models.RefundStatusFailed)
+	refund, err := collectOne[models.Refund](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refund: %w", err)
+	}
+	return refund, nil
+}
+
+// ListByPayment returns the refunds of a payment, oldest first
+func (r *RefundRepository) ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]models.Refund, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+refundColumns+` FROM refunds WHERE payment_id = $1 ORDER BY created_at`, paymentID)
+	refunds, err := collectAll[models.Refund](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refunds: %w", err)
+	}
+	return refunds, nil
+}
+
+// SumCompleted returns the total amount of completed refunds for a payment
+func (r *RefundRepository) SumCompleted(ctx context.Context, paymentID uuid.UUID) (float64, error) {
+	var total float64
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM refunds WHERE payment_id = $1 AND status = $2`,
+		paymentID, models.RefundStatusCompleted,
+	).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum refunds: %w", err)
+	}
+	return total, nil
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"main.go/services/payment/models"
+)
+
+const riskAssessmentColumns = `id, payment_id, order_id, user_id, score, decision, signals,
+	review_status, reviewed_by, review_note, reviewed_at, created_at`
+
+// RiskRepository provides access to risk assessments, the blocklist and the
+// payment history used by risk rules
+type RiskRepository struct {
+	db DBTX
+}
+
+// NewRiskRepository creates a new RiskRepository
+func NewRiskRepository(db DBTX) *RiskRepository {
+	return &RiskRepository{db: db}
+}
+
+// CreateAssessment persists a risk assessment
+func (r *RiskRepository) CreateAssessment(ctx context.Context, a *models.RiskAssessment) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO risk_assessments (payment_id, order_id, user_id, score, decision, signals, review_status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`,
+		a.PaymentID, a.OrderID, a.UserID, a.Score, a.Decision, a.Signals, a.ReviewStatus,
+	).Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create risk assessment: %w", err)
+	}
+	return nil
+}
+
+// GetAssessmentForUpdate returns a risk assessment and locks it until the
+// surrounding transaction ends
+func (r *RiskRepository) GetAssessmentForUpdate(ctx context.Context, id uuid.UUID) (*models.RiskAssessment, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+riskAssessmentColumns+` FROM risk_assessments WHERE id = $1 FOR UPDATE`, id)
+	a, err := collectOne[models.RiskAssessment](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get risk assessment: %w", err)
+	}
+	return a, nil
+}
+
+// ListByPayment returns the risk assessments of a payment, newest first
+func (r *RiskRepository) ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]models.RiskAssessment, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+riskAssessmentColumns+` FROM risk_assessments WHERE payment_id = $1 ORDER BY created_at DESC`, paymentID)
+	assessments, err := collectAll[models.RiskAssessment](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list risk assessments: %w", err)
+	}
+	return assessments, nil
+}
+
+// ListByReviewStatus returns assessments in a review status, oldest first
+func (r *RiskRepository) ListByReviewStatus(ctx context.Context, status models.RiskReviewStatus, limit int) ([]models.RiskAssessment, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+riskAssessmentColumns+` FROM risk_assessments
+		WHERE review_status = $1 ORDER BY created_at LIMIT $2`, status, limit)
+	assessments, err := collectAll[models.RiskAssessment](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list risk reviews: %w", err)
+	}
+	return assessments, nil
+}
+
+// UpdateReview records the outcome of a manual review
+func (r *RiskRepository) UpdateReview(ctx context.Context, a *models.RiskAssessment) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE risk_assessments SET review_status = $2, reviewed_by = $3, review_note = $4, reviewed_at = $5
+		WHERE id = $1`,
+		a.ID, a.ReviewStatus, a.ReviewedBy, a.ReviewNote, a.ReviewedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update risk review: %w", err)
+	}
+	return nil
+}
+
+// CountPaymentsByUser counts payments created by a user since the given time
+func (r *RiskRepository) CountPaymentsByUser(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM payments WHERE user_id = $1 AND created_at >= $2`, userID, since).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count payments by user: %w", err)
+	}
+	return n, nil
+}
+
+// CountPaymentsByMethod counts payments made with a payment method since the
+// given time
+func (r *RiskRepository) CountPaymentsByMethod(ctx context.Context, methodID uuid.UUID, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM payments WHERE payment_method_id = $1 AND created_at >= $2`, methodID, since).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count payments by method: %w", err)
+	}
+	return n, nil
+}
+
+// CountSuccessfulPaymentsByUser counts a user's captured payments
+func (r *RiskRepository) CountSuccessfulPaymentsByUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM payments
+		WHERE user_id = $1 AND status IN ('captured', 'partially_refunded', 'refunded')`, userID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count successful payments: %w", err)
+	}
+	return n, nil
+}
+
+// FindBlocked returns the blocklist entries matching any of the given values
+func (r *RiskRepository) FindBlocked(ctx context.Context, values map[models.BlocklistType]string) ([]models.BlocklistEntry, error) {
+	types := make([]string, 0, len(values))
+	vals := make([]string, 0, len(values))
+	for t, v := range values {
+		types = append(types, string(t))
+		vals = append(vals, v)
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT b.id, b.type, b.value, b.reason, b.created_at
+		FROM risk_blocklist b
+		JOIN UNNEST($1::text[], $2::text[]) AS q(type, value) ON q.type = b.type AND q.value = b.value`,
+		types, vals)
+	entries, err := collectAll[models.BlocklistEntry](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check blocklist: %w", err)
+	}
+	return entries, nil
+}
+
+// ListBlocklist returns every blocklist entry
+func (r *RiskRepository) ListBlocklist(ctx context.Context) ([]models.BlocklistEntry, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, type, value, reason, created_at FROM risk_blocklist ORDER BY type, value`)
+	entries, err := collectAll[models.BlocklistEntry](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blocklist: %w", err)
+	}
+	return entries, nil
+}
+
+// AddBlocklistEntry adds a value to the blocklist
+func (r *RiskRepository) AddBlocklistEntry(ctx context.Context, e *models.BlocklistEntry) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO risk_blocklist (type, value, reason) VALUES ($1, $2, $3)
+		ON CONFLICT (type, value) DO UPDATE SET reason = EXCLUDED.reason
+		RETURNING id, created_at`,
+		e.Type, e.Value, e.Reason,
+	).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add blocklist entry: %w", err)
+	}
+	return nil
+}
+
+// DeleteBlocklistEntry removes a blocklist entry
+func (r *RiskRepository) DeleteBlocklistEntry(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM risk_blocklist WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete blocklist entry: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
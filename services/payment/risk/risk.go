@@ -0,0 +1,109 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"main.go/services/payment/models"
+)
+
+const (
+	// DefaultReviewThreshold is the score at which a payment is held for review
+	DefaultReviewThreshold = 40
+	// DefaultDenyThreshold is the score at which a payment is denied
+	DefaultDenyThreshold = 80
+)
+
+// Input holds everything known about a payment at authorization time
+type Input struct {
+	PaymentID         uuid.UUID
+	OrderID           uuid.UUID
+	UserID            uuid.UUID
+	PaymentMethodID   *uuid.UUID
+	PaymentMethodType *models.PaymentMethodType
+	Amount            float64
+	Currency          string
+	Email             string
+	BillingCountry    string
+	ShippingCountry   string
+	Now               time.Time
+}
+
+// Signal is a single finding produced by a rule
+type Signal struct {
+	Rule   string `json:"rule"`
+	Score  int    `json:"score"`
+	Reason string `json:"reason"`
+	// Deny forces a deny decision regardless of the total score
+	Deny bool `json:"deny,omitempty"`
+}
+
+// Rule scores one aspect of a payment
+type Rule interface {
+	Name() string
+	Evaluate(ctx context.Context, in *Input) ([]Signal, error)
+}
+
+// Assessment is the combined result of running every rule
+type Assessment struct {
+	Score    int
+	Decision models.RiskDecision
+	Signals  []Signal
+}
+
+// Engine runs a set of rules and turns their signals into a decision
+type Engine struct {
+	rules           []Rule
+	reviewThreshold int
+	denyThreshold   int
+}
+
+// NewEngine creates an Engine with the default thresholds
+func NewEngine(rules ...Rule) *Engine {
+	return &Engine{
+		rules:           rules,
+		reviewThreshold: DefaultReviewThreshold,
+		denyThreshold:   DefaultDenyThreshold,
+	}
+}
+
+// WithThresholds returns a copy of the engine using the given thresholds
+func (e *Engine) WithThresholds(review, deny int) *Engine {
+	c := *e
+	c.reviewThreshold = review
+	c.denyThreshold = deny
+	return &c
+}
+
+// Evaluate runs every rule against in and returns the combined assessment
+func (e *Engine) Evaluate(ctx context.Context, in *Input) (*Assessment, error) {
+	if in.Now.IsZero() {
+		in.Now = time.Now()
+	}
+
+	a := &Assessment{Signals: []Signal{}}
+	forceDeny := false
+	for _, rule := range e.rules {
+		signals, err := rule.Evaluate(ctx, in)
+		if err != nil {
+			return nil, fmt.Errorf("risk rule %s failed: %w", rule.Name(), err)
+		}
+		for _, s := range signals {
+			a.Score += s.Score
+			forceDeny = forceDeny || s.Deny
+		}
+		a.Signals = append(a.Signals, signals...)
+	}
+
+	switch {
+	case forceDeny || a.Score >= e.denyThreshold:
+		a.Decision = models.RiskDecisionDeny
+	case a.Score >= e.reviewThreshold:
+		a.Decision = models.RiskDecisionReview
+	default:
+		a.Decision = models.RiskDecisionAllow
+	}
+	return a, nil
+}
@@ -0,0 +1,195 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"main.go/services/payment/models"
+)
+
+// Store provides the payment history and blocklist lookups used by rules
+type Store interface {
+	CountPaymentsByUser(ctx context.Context, userID uuid.UUID, since time.Time) (int, error)
+	CountPaymentsByMethod(ctx context.Context, methodID uuid.UUID, since time.Time) (int, error)
+	CountSuccessfulPaymentsByUser(ctx context.Context, userID uuid.UUID) (int, error)
+	FindBlocked(ctx context.Context, values map[models.BlocklistType]string) ([]models.BlocklistEntry, error)
+}
+
+// DefaultRules returns the standard rule set backed by store
+func DefaultRules(store Store) []Rule {
+	return []Rule{
+		&VelocityRule{Store: store, Window: time.Hour, MaxPerUser: 5, MaxPerMethod: 3, Score: 30},
+		&CountryMismatchRule{Score: 25},
+		&AmountRule{ReviewAbove: 1000, DenyAbove: 10000, Score: 30},
+		&NewAccountRule{Store: store, HighValue: 250, Score: 20},
+		&BlocklistRule{Store: store},
+	}
+}
+
+// VelocityRule flags bursts of payment attempts per user and payment method
+type VelocityRule struct {
+	Store        Store
+	Window       time.Duration
+	MaxPerUser   int
+	MaxPerMethod int
+	Score        int
+}
+
+// Name implements Rule
+func (r *VelocityRule) Name() string { return "velocity" }
+
+// Evaluate implements Rule
+func (r *VelocityRule) Evaluate(ctx context.Context, in *Input) ([]Signal, error) {
+	since := in.Now.Add(-r.Window)
+	var signals []Signal
+
+	n, err := r.Store.CountPaymentsByUser(ctx, in.UserID, since)
+	if err != nil {
+		return nil, err
+	}
+	if n > r.MaxPerUser {
+		signals = append(signals, Signal{
+			Rule:   r.Name(),
+			Score:  r.Score,
+			Reason: fmt.Sprintf("%d payments by user in the last %s", n, r.Window),
+		})
+	}
+
+	if in.PaymentMethodID != nil {
+		n, err := r.Store.CountPaymentsByMethod(ctx, *in.PaymentMethodID, since)
+		if err != nil {
+			return nil, err
+		}
+		if n > r.MaxPerMethod {
+			signals = append(signals, Signal{
+				Rule:   r.Name(),
+				Score:  r.Score,
+				Reason: fmt.Sprintf("%d payments with payment method in the last %s", n, r.Window),
+			})
+		}
+	}
+	return signals, nil
+}
+
+// CountryMismatchRule flags billing and shipping addresses in different countries
+type CountryMismatchRule struct {
+	Score int
+}
+
+// Name implements Rule
+func (r *CountryMismatchRule) Name() string { return "country_mismatch" }
+
+// Evaluate implements Rule
+func (r *CountryMismatchRule) Evaluate(_ context.Context, in *Input) ([]Signal, error) {
+	if in.BillingCountry == "" || in.ShippingCountry == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(in.BillingCountry, in.ShippingCountry) {
+		return nil, nil
+	}
+	return []Signal{{
+		Rule:   r.Name(),
+		Score:  r.Score,
+		Reason: fmt.Sprintf("billing country %s differs from shipping country %s", in.BillingCountry, in.ShippingCountry),
+	}}, nil
+}
+
+// AmountRule flags high value payments
+type AmountRule struct {
+	ReviewAbove float64
+	DenyAbove   float64
+	Score       int
+}
+
+// Name implements Rule
+func (r *AmountRule) Name() string { return "amount" }
+
+// Evaluate implements Rule
+func (r *AmountRule) Evaluate(_ context.Context, in *Input) ([]Signal, error) {
+	switch {
+	case r.DenyAbove > 0 && in.Amount > r.DenyAbove:
+		return []Signal{{
+			Rule:   r.Name(),
+			Score:  r.Score,
+			Reason: fmt.Sprintf("amount %.2f exceeds limit %.2f", in.Amount, r.DenyAbove),
+			Deny:   true,
+		}}, nil
+	case in.Amount > r.ReviewAbove:
+		return []Signal{{
+			Rule:   r.Name(),
+			Score:  r.Score,
+			Reason: fmt.Sprintf("amount %.2f exceeds review threshold %.2f", in.Amount, r.ReviewAbove),
+		}}, nil
+	}
+	return nil, nil
+}
+
+// NewAccountRule flags high value payments from users with no payment history
+type NewAccountRule struct {
+	Store     Store
+	HighValue float64
+	Score     int
+}
+
+// Name implements Rule
+func (r *NewAccountRule) Name() string { return "new_account" }
+
+// Evaluate implements Rule
+func (r *NewAccountRule) Evaluate(ctx context.Context, in *Input) ([]Signal, error) {
+	if in.Amount < r.HighValue {
+		return nil, nil
+	}
+	n, err := r.Store.CountSuccessfulPaymentsByUser(ctx, in.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		return nil, nil
+	}
+	return []Signal{{
+		Rule:   r.Name(),
+		Score:  r.Score,
+		Reason: fmt.Sprintf("first successful payment for user is %.2f", in.Amount),
+	}}, nil
+}
+
+// BlocklistRule denies payments matching a blocklist entry
+type BlocklistRule struct {
+	Store Store
+}
+
+// Name implements Rule
+func (r *BlocklistRule) Name() string { return "blocklist" }
+
+// Evaluate implements Rule
+func (r *BlocklistRule) Evaluate(ctx context.Context, in *Input) ([]Signal, error) {
+	values := map[models.BlocklistType]string{
+		models.BlocklistUser: in.UserID.String(),
+	}
+	if in.PaymentMethodID != nil {
+		values[models.BlocklistPaymentMethod] = in.PaymentMethodID.String()
+	}
+	if in.Email != "" {
+		values[models.BlocklistEmail] = strings.ToLower(in.Email)
+	}
+	if in.BillingCountry != "" {
+		values[models.BlocklistCountry] = strings.ToUpper(in.BillingCountry)
+	}
+
+	entries, err := r.Store.FindBlocked(ctx, values)
+	if err != nil {
+		return nil, err
+	}
+	signals := make([]Signal, 0, len(entries))
+	for _, e := range entries {
+		signals = append(signals, Signal{
+			Rule:   r.Name(),
+			Reason: fmt.Sprintf("%s %s is blocklisted", e.Type, e.Value),
+			Deny:   true,
+		})
+	}
+	return signals, nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/google/uuid"
+	"main.go/services/payment/clients"
+)
+
+// fxConversion records how a payment or refund amount in the order currency
+// relates to the product base currencies through the rates locked at checkout
+type fxConversion struct {
+	Currency string            `json:"currency"`
+	Amount   float64           `json:"amount"`
+	Rates    []convertedAmount `json:"rates"`
+}
+
+// convertedAmount is an amount expressed in a base currency
+type convertedAmount struct {
+	BaseCurrency string    `json:"base_currency"`
+	Rate         float64   `json:"rate"`
+	RateID       uuid.UUID `json:"rate_id"`
+	BaseAmount   float64   `json:"base_amount"`
+}
+
+// checkOrderCurrency rejects amounts not denominated in the order currency
+func checkOrderCurrency(order *clients.Order, currency string) error {
+	if !strings.EqualFold(strings.TrimSpace(currency), order.Currency) {
+		return fmt.Errorf("%w: currency %q does not match order currency %s", ErrValidation, currency, order.Currency)
+	}
+	return nil
+}
+
+// fxConversionFor returns the conversion metadata for amount, or nil when the
+// order has no converted prices
+func fxConversionFor(order *clients.Order, amount float64) (json.RawMessage, error) {
+	if len(order.FXRates) == 0 {
+		return nil, nil
+	}
+	conv := fxConversion{Currency: order.Currency, Amount: amount}
+	for _, r := range order.FXRates {
+		conv.Rates = append(conv.Rates, convertedAmount{
+			BaseCurrency: r.BaseCurrency,
+			Rate:         r.Rate,
+			RateID:       r.RateID,
+			BaseAmount:   math.Round(amount/r.Rate*100) / 100,
+		})
+	}
+	data, err := json.Marshal(conv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode fx conversion: %w", err)
+	}
+	return data, nil
+}
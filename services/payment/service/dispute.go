@@ -0,0 +1,429 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/payment/models"
+	"main.go/services/payment/repository"
+)
+
+const (
+	// DefaultEvidenceWindow is used when a gateway does not supply an
+	// evidence deadline
+	DefaultEvidenceWindow = 7 * 24 * time.Hour
+	// MaxEvidenceSize is the largest evidence document accepted
+	MaxEvidenceSize = 10 << 20
+
+	// OrderFlagDisputed marks an order whose payment is under dispute
+	OrderFlagDisputed = "disputed"
+	// OrderFlagChargeback marks an order whose dispute was lost
+	OrderFlagChargeback = "chargeback"
+)
+
+// allowedEvidenceTypes lists the document types accepted as dispute evidence
+var allowedEvidenceTypes = map[string]bool{
+	"application/pdf": true,
+	"image/png":       true,
+	"image/jpeg":      true,
+	"text/plain":      true,
+}
+
+// OrderFlagger raises and clears flags on orders in the order service
+type OrderFlagger interface {
+	SetFlag(ctx context.Context, orderID uuid.UUID, flag, note string) error
+	ClearFlag(ctx context.Context, orderID uuid.UUID, flag string) error
+}
+
+// OpenDisputeInput holds the details of a newly reported dispute
+type OpenDisputeInput struct {
+	PaymentID        uuid.UUID  `json:"-"`
+	Amount           *float64   `json:"amount,omitempty"`
+	Reason           *string    `json:"reason,omitempty"`
+	GatewayDisputeID *string    `json:"gateway_dispute_id,omitempty"`
+	EvidenceRequired bool       `json:"evidence_required"`
+	EvidenceDueBy    *time.Time `json:"evidence_due_by,omitempty"`
+}
+
+// AddEvidenceInput holds an evidence document to attach to a dispute
+type AddEvidenceInput struct {
+	DisputeID   uuid.UUID
+	FileName    string
+	ContentType string
+	Content     []byte
+	Description *string
+}
+
+// DisputeService manages the lifecycle of payment disputes
+type DisputeService struct {
+	pool   *pgxpool.Pool
+	orders OrderFlagger
+	now    func() time.Time
+}
+
+// NewDisputeService creates a new DisputeService
+func NewDisputeService(pool *pgxpool.Pool, orders OrderFlagger) *DisputeService {
+	return &DisputeService{pool: pool, orders: orders, now: time.Now}
+}
+
+// Open records a new dispute against a captured payment and flags the order.
+// The disputed amount cannot exceed what is left of the payment after
+// completed refunds and its other unresolved or lost disputes.
+func (s *DisputeService) Open(ctx context.Context, in OpenDisputeInput) (*models.Dispute, error) {
+	var dispute *models.Dispute
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		payment, err := repository.NewPaymentRepository(tx).GetByIDForUpdate(ctx, in.PaymentID)
+		if err != nil {
+			return err
+		}
+		if payment.Status != models.PaymentStatusCaptured && payment.Status != models.PaymentStatusPartiallyRefunded {
+			return fmt.Errorf("%w: payment in status %s cannot be disputed", ErrInvalidState, payment.Status)
+		}
+
+		refunded, err := repository.NewRefundRepository(tx).SumCompleted(ctx, payment.ID)
+		if err != nil {
+			return err
+		}
+		disputes := repository.NewDisputeRepository(tx)
+		disputed, err := disputes.SumDisputed(ctx, payment.ID)
+		if err != nil {
+			return err
+		}
+		remaining := roundCents(payment.Amount - refunded - disputed)
+		if remaining <= 0 {
+			return fmt.Errorf("%w: payment has no undisputed amount left", ErrInvalidState)
+		}
+
+		amount := remaining
+		if in.Amount != nil {
+			amount = *in.Amount
+		}
+		if amount <= 0 || amount > remaining {
+			return fmt.Errorf("%w: dispute amount must be between 0 and %.2f", ErrValidation, remaining)
+		}
+
+		dispute = &models.Dispute{
+			PaymentID:        payment.ID,
+			OrderID:          payment.OrderID,
+			Amount:           amount,
+			Currency:         payment.Currency,
+			Reason:           in.Reason,
+			Status:           models.DisputeStatusOpened,
+			GatewayDisputeID: in.GatewayDisputeID,
+		}
+		if in.EvidenceRequired || in.EvidenceDueBy != nil {
+			dispute.Status = models.DisputeStatusEvidenceRequired
+			dispute.EvidenceDueBy = s.evidenceDeadline(in.EvidenceDueBy)
+		}
+		return disputes.Create(ctx, dispute)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.flagOrder(ctx, dispute.OrderID, OrderFlagDisputed, fmt.Sprintf("dispute %s opened for %.2f %s", dispute.ID, dispute.Amount, dispute.Currency))
+	return dispute, nil
+}
+
+// Get returns a dispute by its ID
+func (s *DisputeService) Get(ctx context.Context, id uuid.UUID) (*models.Dispute, error) {
+	return repository.NewDisputeRepository(s.pool).GetByID(ctx, id)
+}
+
+// ListByPayment returns all disputes raised against a payment
+func (s *DisputeService) ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]models.Dispute, error) {
+	return repository.NewDisputeRepository(s.pool).ListByPayment(ctx, paymentID)
+}
+
+// RequestEvidence moves an opened dispute to evidence_required with a deadline
+func (s *DisputeService) RequestEvidence(ctx context.Context, id uuid.UUID, dueBy *time.Time) (*models.Dispute, error) {
+	return s.update(ctx, id, func(_ pgx.Tx, d *models.Dispute) error {
+		if d.Status != models.DisputeStatusOpened {
+			return fmt.Errorf("%w: dispute is %s", ErrInvalidState, d.Status)
+		}
+		d.Status = models.DisputeStatusEvidenceRequired
+		d.EvidenceDueBy = s.evidenceDeadline(dueBy)
+		return nil
+	})
+}
+
+// AddEvidence attaches a document to an unresolved dispute
+func (s *DisputeService) AddEvidence(ctx context.Context, in AddEvidenceInput) (*models.DisputeEvidence, error) {
+	if in.FileName == "" {
+		return nil, fmt.Errorf("%w: file name is required", ErrValidation)
+	}
+	if len(in.Content) == 0 || len(in.Content) > MaxEvidenceSize {
+		return nil, fmt.Errorf("%w: evidence must be between 1 byte and %d bytes", ErrValidation, MaxEvidenceSize)
+	}
+	if !allowedEvidenceTypes[in.ContentType] {
+		return nil, fmt.Errorf("%w: unsupported evidence type %q", ErrValidation, in.ContentType)
+	}
+
+	evidence := &models.DisputeEvidence{
+		DisputeID:   in.DisputeID,
+		FileName:    in.FileName,
+		ContentType: in.ContentType,
+		SizeBytes:   int64(len(in.Content)),
+		Content:     in.Content,
+		Description: in.Description,
+	}
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		disputes := repository.NewDisputeRepository(tx)
+		d, err := disputes.GetByIDForUpdate(ctx, in.DisputeID)
+		if err != nil {
+			return err
+		}
+		if err := s.checkAcceptingEvidence(d); err != nil {
+			return err
+		}
+		return disputes.AddEvidence(ctx, evidence)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return evidence, nil
+}
+
+// ListEvidence returns the evidence attached to a dispute
+func (s *DisputeService) ListEvidence(ctx context.Context, disputeID uuid.UUID) ([]models.DisputeEvidence, error) {
+	return repository.NewDisputeRepository(s.pool).ListEvidence(ctx, disputeID)
+}
+
+// GetEvidence returns a single evidence document including its contents
+func (s *DisputeService) GetEvidence(ctx context.Context, disputeID, evidenceID uuid.UUID) (*models.DisputeEvidence, error) {
+	return repository.NewDisputeRepository(s.pool).GetEvidence(ctx, disputeID, evidenceID)
+}
+
+// SubmitEvidence marks the attached evidence as submitted to the gateway
+func (s *DisputeService) SubmitEvidence(ctx context.Context, id uuid.UUID) (*models.Dispute, error) {
+	return s.update(ctx, id, func(tx pgx.Tx, d *models.Dispute) error {
+		if err := s.checkAcceptingEvidence(d); err != nil {
+			return err
+		}
+		evidence, err := repository.NewDisputeRepository(tx).ListEvidence(ctx, d.ID)
+		if err != nil {
+			return err
+		}
+		if len(evidence) == 0 {
+			return fmt.Errorf("%w: at least one evidence document is required", ErrValidation)
+		}
+		now := s.now()
+		d.EvidenceSubmittedAt = &now
+		return nil
+	})
+}
+
+// Resolve closes a dispute as won or lost. A lost dispute reverses the
+// disputed funds with an adjustment transaction, moves the payment to
+// partially refunded or refunded and marks the order as charged back. The order's dispute flag is cleared once none of its disputes
+// remain unresolved.
+func (s *DisputeService) Resolve(ctx context.Context, id uuid.UUID, outcome models.DisputeStatus) (*models.Dispute, error) {
+	if outcome != models.DisputeStatusWon && outcome != models.DisputeStatusLost {
+		return nil, fmt.Errorf("%w: outcome must be won or lost", ErrValidation)
+	}
+
+	var dispute *models.Dispute
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		var err error
+		dispute, err = s.resolveTx(ctx, tx, id, outcome)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.syncOrderFlags(ctx, dispute)
+	return dispute, nil
+}
+
+// ExpireOverdue resolves as lost every dispute whose evidence deadline passed
+// without a submission and returns the number of disputes closed
+func (s *DisputeService) ExpireOverdue(ctx context.Context) (int, error) {
+	overdue, err := repository.NewDisputeRepository(s.pool).ListOverdue(ctx, s.now())
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, d := range overdue {
+		if _, err := s.Resolve(ctx, d.ID, models.DisputeStatusLost); err != nil {
+			log.Printf("failed to expire dispute %s: %v", d.ID, err)
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// RunDeadlineSweeper calls ExpireOverdue every interval until ctx is done
+func (s *DisputeService) RunDeadlineSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.ExpireOverdue(ctx)
+			if err != nil {
+				log.Printf("dispute deadline sweep failed: %v", err)
+			} else if n > 0 {
+				log.Printf("expired %d overdue disputes", n)
+			}
+		}
+	}
+}
+
+func (s *DisputeService) resolveTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, outcome models.DisputeStatus) (*models.Dispute, error) {
+	disputes := repository.NewDisputeRepository(tx)
+	d, err := disputes.GetByIDForUpdate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if isResolved(d.Status) {
+		return nil, fmt.Errorf("%w: dispute is already %s", ErrInvalidState, d.Status)
+	}
+
+	now := s.now()
+	d.Status = outcome
+	d.ResolvedAt = &now
+	if err := disputes.Update(ctx, d); err != nil {
+		return nil, err
+	}
+
+	if outcome == models.DisputeStatusLost {
+		// Reverse the disputed funds so the payment's transaction ledger
+		// reflects the money returned to the cardholder
+		meta, err := json.Marshal(map[string]any{
+			"dispute_id":         d.ID,
+			"gateway_dispute_id": d.GatewayDisputeID,
+			"reason":             "chargeback",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode adjustment metadata: %w", err)
+		}
+		adjustment := &models.PaymentTransaction{
+			PaymentID:       d.PaymentID,
+			Type:            models.TransactionTypeAdjustment,
+			Amount:          -d.Amount,
+			Status:          models.TransactionStatusSuccess,
+			GatewayTxnID:    d.GatewayDisputeID,
+			GatewayResponse: meta,
+		}
+		if err := repository.NewPaymentRepository(tx).CreateTransaction(ctx, adjustment); err != nil {
+			return nil, err
+		}
+		if err := s.reversePayment(ctx, tx, d.PaymentID); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// reversePayment moves a payment that lost a dispute to partially refunded,
+// or to refunded once refunds and lost disputes cover its amount
+func (s *DisputeService) reversePayment(ctx context.Context, tx pgx.Tx, paymentID uuid.UUID) error {
+	payments := repository.NewPaymentRepository(tx)
+	payment, err := payments.GetByIDForUpdate(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+	refunded, err := repository.NewRefundRepository(tx).SumCompleted(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+	lost, err := repository.NewDisputeRepository(tx).SumLost(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+	payment.Status = reversedStatus(payment, refunded+lost)
+	return payments.UpdateGatewayResult(ctx, payment)
+}
+
+// update loads a dispute for update, applies fn within the same transaction
+// and persists the result
+func (s *DisputeService) update(ctx context.Context, id uuid.UUID, fn func(pgx.Tx, *models.Dispute) error) (*models.Dispute, error) {
+	var dispute *models.Dispute
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		disputes := repository.NewDisputeRepository(tx)
+		d, err := disputes.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx, d); err != nil {
+			return err
+		}
+		dispute = d
+		return disputes.Update(ctx, d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+func (s *DisputeService) checkAcceptingEvidence(d *models.Dispute) error {
+	if isResolved(d.Status) {
+		return fmt.Errorf("%w: dispute is already %s", ErrInvalidState, d.Status)
+	}
+	if d.EvidenceSubmittedAt != nil {
+		return fmt.Errorf("%w: evidence was already submitted", ErrInvalidState)
+	}
+	if d.EvidenceDueBy != nil && s.now().After(*d.EvidenceDueBy) {
+		return fmt.Errorf("%w: evidence deadline passed at %s", ErrInvalidState, d.EvidenceDueBy.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (s *DisputeService) evidenceDeadline(dueBy *time.Time) *time.Time {
+	if dueBy != nil {
+		return dueBy
+	}
+	deadline := s.now().Add(DefaultEvidenceWindow)
+	return &deadline
+}
+
+// syncOrderFlags mirrors a resolved dispute onto the order's flags. The
+// disputed flag stays while other disputes on the order are unresolved.
+func (s *DisputeService) syncOrderFlags(ctx context.Context, d *models.Dispute) {
+	unresolved, err := repository.NewDisputeRepository(s.pool).CountUnresolvedByOrder(ctx, d.OrderID)
+	if err != nil {
+		log.Printf("failed to count unresolved disputes of order %s: %v", d.OrderID, err)
+	} else if unresolved == 0 {
+		s.clearOrderFlag(ctx, d.OrderID, OrderFlagDisputed)
+	}
+	if d.Status == models.DisputeStatusLost {
+		s.flagOrder(ctx, d.OrderID, OrderFlagChargeback, fmt.Sprintf("dispute %s lost, %.2f %s reversed", d.ID, d.Amount, d.Currency))
+	}
+}
+
+// flagOrder is best effort: the dispute record is the source of truth, so a
+// failure to reach the order service is logged rather than returned
+func (s *DisputeService) flagOrder(ctx context.Context, orderID uuid.UUID, flag, note string) {
+	if s.orders == nil {
+		return
+	}
+	if err := s.orders.SetFlag(ctx, orderID, flag, note); err != nil {
+		log.Printf("failed to flag order %s as %s: %v", orderID, flag, err)
+	}
+}
+
+func (s *DisputeService) clearOrderFlag(ctx context.Context, orderID uuid.UUID, flag string) {
+	if s.orders == nil {
+		return
+	}
+	if err := s.orders.ClearFlag(ctx, orderID, flag); err != nil {
+		log.Printf("failed to clear %s flag on order %s: %v", flag, orderID, err)
+	}
+}
+
+func isResolved(status models.DisputeStatus) bool {
+	return status == models.DisputeStatusWon || status == models.DisputeStatusLost
+}
@@ -0,0 +1,504 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/payment/clients"
+	"main.go/services/payment/gateway"
+	"main.go/services/payment/models"
+	"main.go/services/payment/repository"
+	"main.go/services/payment/risk"
+)
+
+const (
+	// OrderFlagRiskReview marks an order held for manual fraud review
+	OrderFlagRiskReview = "risk_review"
+	// OrderFlagRiskDenied marks an order whose payment was denied by the
+	// risk engine
+	OrderFlagRiskDenied = "risk_denied"
+)
+
+// OrderClient is the subset of the order service used by payments
+type OrderClient interface {
+	OrderFlagger
+	GetOrder(ctx context.Context, orderID uuid.UUID) (*clients.Order, error)
+	CancelOrder(ctx context.Context, orderID uuid.UUID, reason string) error
+}
+
+// CreatePaymentInput holds the details of a new payment
+type CreatePaymentInput struct {
+	OrderID         uuid.UUID  `json:"order_id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	Amount          float64    `json:"amount"`
+	Currency        string     `json:"currency"`
+	PaymentMethodID *uuid.UUID `json:"payment_method_id,omitempty"`
+}
+
+// AuthorizationResult is the outcome of an authorization attempt
+type AuthorizationResult struct {
+	Payment    *models.Payment        `json:"payment"`
+	Assessment *models.RiskAssessment `json:"risk_assessment,omitempty"`
+}
+
+// PaymentService creates payments and moves them through the gateway
+type PaymentService struct {
+	pool    *pgxpool.Pool
+	gateway gateway.Gateway
+	risk    *risk.Engine
+	orders  OrderClient
+	now     func() time.Time
+}
+
+// NewPaymentService creates a new PaymentService
+func NewPaymentService(pool *pgxpool.Pool, gw gateway.Gateway, engine *risk.Engine, orders OrderClient) *PaymentService {
+	return &PaymentService{pool: pool, gateway: gw, risk: engine, orders: orders, now: time.Now}
+}
+
+// Create records a new pending payment for an order
+func (s *PaymentService) Create(ctx context.Context, in CreatePaymentInput) (*models.Payment, error) {
+	if in.OrderID == uuid.Nil || in.UserID == uuid.Nil {
+		return nil, fmt.Errorf("%w: order_id and user_id are required", ErrValidation)
+	}
+	if in.Amount < 0.01 {
+		return nil, fmt.Errorf("%w: amount must be at least 0.01", ErrValidation)
+	}
+
+	order, err := s.orders.GetOrder(ctx, in.OrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order: %w", err)
+	}
+	if order.UserID != in.UserID {
+		return nil, fmt.Errorf("%w: order belongs to a different user", ErrValidation)
+	}
+	if err := checkOrderCurrency(order, in.Currency); err != nil {
+		return nil, err
+	}
+	if in.Amount > order.Total {
+		return nil, fmt.Errorf("%w: amount %.2f exceeds order total %.2f", ErrValidation, in.Amount, order.Total)
+	}
+	conversion, err := fxConversionFor(order, in.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	gatewayName := s.gateway.Name()
+	payment := &models.Payment{
+		OrderID:         in.OrderID,
+		UserID:          in.UserID,
+		Amount:          in.Amount,
+		Currency:        order.Currency,
+		Status:          models.PaymentStatusPending,
+		PaymentMethodID: in.PaymentMethodID,
+		Gateway:         &gatewayName,
+		FXConversion:    conversion,
+	}
+	if err := repository.NewPaymentRepository(s.pool).Create(ctx, payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// Get returns a payment by its ID
+func (s *PaymentService) Get(ctx context.Context, id uuid.UUID) (*models.Payment, error) {
+	return repository.NewPaymentRepository(s.pool).GetByID(ctx, id)
+}
+
+// ListByOrder returns the payments of an order
+func (s *PaymentService) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.Payment, error) {
+	return repository.NewPaymentRepository(s.pool).ListByOrder(ctx, orderID)
+}
+
+// ListTransactions returns the transaction ledger of a payment
+func (s *PaymentService) ListTransactions(ctx context.Context, id uuid.UUID) ([]models.PaymentTransaction, error) {
+	return repository.NewPaymentRepository(s.pool).ListTransactions(ctx, id)
+}
+
+// ListAssessments returns the risk assessments recorded for a payment
+func (s *PaymentService) ListAssessments(ctx context.Context, id uuid.UUID) ([]models.RiskAssessment, error) {
+	return repository.NewRiskRepository(s.pool).ListByPayment(ctx, id)
+}
+
+// Authorize scores a pending payment with the risk engine and, if allowed,
+// authorizes it with the gateway. Payments that need review stay pending in
+// the review queue; denied payments are marked failed. The order is loaded and
+// scored before the payment is locked, and the gateway is called after the
+// assessment is committed, so no remote call runs while the row is locked.
+func (s *PaymentService) Authorize(ctx context.Context, id uuid.UUID) (*AuthorizationResult, error) {
+	payment, err := repository.NewPaymentRepository(s.pool).GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != models.PaymentStatusPending {
+		return nil, fmt.Errorf("%w: payment is %s", ErrInvalidState, payment.Status)
+	}
+	if err := s.checkNoPendingReview(ctx, s.pool, payment.ID); err != nil {
+		return nil, err
+	}
+	input, err := s.riskInput(ctx, s.pool, payment)
+	if err != nil {
+		return nil, err
+	}
+	assessment, err := s.risk.Evaluate(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AuthorizationResult{}
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		payments := repository.NewPaymentRepository(tx)
+		payment, err := payments.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if payment.Status != models.PaymentStatusPending {
+			return fmt.Errorf("%w: payment is %s", ErrInvalidState, payment.Status)
+		}
+		if err := s.checkNoPendingReview(ctx, tx, payment.ID); err != nil {
+			return err
+		}
+		record, err := s.saveAssessment(ctx, tx, payment, assessment)
+		if err != nil {
+			return err
+		}
+		result.Assessment = record
+		result.Payment = payment
+
+		if assessment.Decision == models.RiskDecisionDeny {
+			payment.Status = models.PaymentStatusFailed
+			payment.GatewayResponse = record.Signals
+			return payments.UpdateGatewayResult(ctx, payment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch result.Assessment.Decision {
+	case models.RiskDecisionAllow:
+		if result.Payment, err = s.authorizeWithGateway(ctx, result.Payment); err != nil {
+			return nil, err
+		}
+	case models.RiskDecisionReview:
+		s.flagOrder(ctx, result.Payment.OrderID, OrderFlagRiskReview, fmt.Sprintf("payment %s held for review (score %d)", result.Payment.ID, result.Assessment.Score))
+	case models.RiskDecisionDeny:
+		s.flagOrder(ctx, result.Payment.OrderID, OrderFlagRiskDenied, fmt.Sprintf("payment %s denied (score %d)", result.Payment.ID, result.Assessment.Score))
+	}
+	return result, nil
+}
+
+// Capture captures the full amount of an authorized payment. The gateway is
+// called between two short transactions, as for every gateway operation.
+func (s *PaymentService) Capture(ctx context.Context, id uuid.UUID) (*models.Payment, error) {
+	var (
+		payment *models.Payment
+		txn     *models.PaymentTransaction
+	)
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		var err error
+		payment, err = repository.NewPaymentRepository(tx).GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if payment.Status != models.PaymentStatusAuthorized {
+			return fmt.Errorf("%w: payment is %s", ErrInvalidState, payment.Status)
+		}
+		txn, err = s.beginGatewayOperation(ctx, tx, payment, models.TransactionTypeCapture, payment.Amount)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, gwErr := s.gateway.Capture(ctx, s.operationRequest(payment, txn, payment.Amount))
+	return s.finishGatewayOperation(ctx, txn, res, gwErr, func(_ pgx.Tx, payment *models.Payment, ok bool) error {
+		if ok {
+			payment.Status = models.PaymentStatusCaptured
+		}
+		return nil
+	})
+}
+
+// Void cancels a payment before it is captured. Authorized payments release
+// the hold with the gateway; pending payments are cancelled directly.
+func (s *PaymentService) Void(ctx context.Context, id uuid.UUID) (*models.Payment, error) {
+	var (
+		payment *models.Payment
+		txn     *models.PaymentTransaction
+	)
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		payments := repository.NewPaymentRepository(tx)
+		var err error
+		payment, err = payments.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		switch payment.Status {
+		case models.PaymentStatusPending:
+			payment.Status = models.PaymentStatusCancelled
+			return payments.UpdateGatewayResult(ctx, payment)
+		case models.PaymentStatusAuthorized:
+		default:
+			return fmt.Errorf("%w: payment is %s", ErrInvalidState, payment.Status)
+		}
+		txn, err = s.beginGatewayOperation(ctx, tx, payment, models.TransactionTypeVoid, payment.Amount)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if txn == nil {
+		return payment, nil
+	}
+
+	res, gwErr := s.gateway.Void(ctx, s.operationRequest(payment, txn, payment.Amount))
+	return s.finishGatewayOperation(ctx, txn, res, gwErr, func(_ pgx.Tx, payment *models.Payment, ok bool) error {
+		if ok {
+			payment.Status = models.PaymentStatusCancelled
+		}
+		return nil
+	})
+}
+
+// beginGatewayOperation records a pending transaction for a gateway operation
+// on a payment locked by tx. Only one operation per payment can be in flight,
+// so a payment cannot be captured, voided or refunded twice concurrently.
+func (s *PaymentService) beginGatewayOperation(ctx context.Context, tx pgx.Tx, payment *models.Payment, txnType models.PaymentTransactionType, amount float64) (*models.PaymentTransaction, error) {
+	payments := repository.NewPaymentRepository(tx)
+	pending, err := payments.HasPendingTransaction(ctx, payment.ID)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, fmt.Errorf("%w: another gateway operation on the payment is in progress", ErrInvalidState)
+	}
+	if txnType == models.TransactionTypeRefund {
+		amount = -amount
+	}
+	txn := &models.PaymentTransaction{
+		PaymentID: payment.ID,
+		Type:      txnType,
+		Amount:    amount,
+		Status:    models.TransactionStatusPending,
+	}
+	if err := payments.CreateTransaction(ctx, txn); err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// finishGatewayOperation records the outcome of an operation started with
+// beginGatewayOperation under a fresh lock on the payment. apply updates the
+// payment, and anything tied to the operation, with ok telling whether the
+// gateway accepted it. A gateway error marks the operation failed and is
+// returned.
+func (s *PaymentService) finishGatewayOperation(ctx context.Context, txn *models.PaymentTransaction, res *gateway.Result, gwErr error, apply func(tx pgx.Tx, payment *models.Payment, ok bool) error) (*models.Payment, error) {
+	var payment *models.Payment
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		payments := repository.NewPaymentRepository(tx)
+		var err error
+		payment, err = payments.GetByIDForUpdate(ctx, txn.PaymentID)
+		if err != nil {
+			return err
+		}
+
+		ok := gwErr == nil && res.Success
+		txn.Status = models.TransactionStatusFailed
+		if ok {
+			txn.Status = models.TransactionStatusSuccess
+		}
+		if gwErr == nil {
+			txn.GatewayTxnID = &res.TxnID
+			txn.GatewayResponse = res.Response
+			payment.GatewayResponse = res.Response
+		}
+		if err := apply(tx, payment, ok); err != nil {
+			return err
+		}
+		if err := payments.UpdateTransaction(ctx, txn); err != nil {
+			return err
+		}
+		return payments.UpdateGatewayResult(ctx, payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if gwErr != nil {
+		return nil, fmt.Errorf("gateway %s failed: %w", txn.Type, gwErr)
+	}
+	return payment, nil
+}
+
+// operationRequest builds the gateway request of an operation started with
+// beginGatewayOperation, keyed by its transaction so a retried call is not
+// applied twice
+func (s *PaymentService) operationRequest(payment *models.Payment, txn *models.PaymentTransaction, amount float64) gateway.Request {
+	req := s.gatewayRequest(payment, amount)
+	req.IdempotencyKey = string(txn.Type) + ":" + txn.ID.String()
+	return req
+}
+
+// gatewayRequest builds a gateway request for an existing payment
+func (s *PaymentService) gatewayRequest(payment *models.Payment, amount float64) gateway.Request {
+	req := gateway.Request{PaymentID: payment.ID, Amount: amount, Currency: payment.Currency}
+	if payment.GatewayRef != nil {
+		req.GatewayRef = *payment.GatewayRef
+	}
+	return req
+}
+
+// authorizeWithGateway sends an authorization for a pending payment to the
+// gateway and records the outcome in a short transaction of its own. The
+// request is keyed by payment ID so a retried authorization is not placed
+// twice, and the outcome is only recorded while the payment is still pending.
+func (s *PaymentService) authorizeWithGateway(ctx context.Context, payment *models.Payment) (*models.Payment, error) {
+	req := s.gatewayRequest(payment, payment.Amount)
+	req.IdempotencyKey = "auth:" + payment.ID.String()
+	res, err := s.gateway.Authorize(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("gateway authorization failed: %w", err)
+	}
+
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		var err error
+		payment, err = repository.NewPaymentRepository(tx).GetByIDForUpdate(ctx, payment.ID)
+		if err != nil {
+			return err
+		}
+		switch payment.Status {
+		case models.PaymentStatusPending:
+		case models.PaymentStatusAuthorized:
+			// a concurrent attempt with the same key already recorded it
+			return nil
+		default:
+			return fmt.Errorf("%w: payment is %s", ErrInvalidState, payment.Status)
+		}
+
+		gatewayName := s.gateway.Name()
+		payment.Gateway = &gatewayName
+		payment.GatewayResponse = res.Response
+		payment.Status = models.PaymentStatusFailed
+		if res.Success {
+			payment.Status = models.PaymentStatusAuthorized
+			payment.GatewayRef = &res.Ref
+		}
+		return s.recordGatewayResult(ctx, tx, payment, models.TransactionTypeAuth, payment.Amount, res)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// recordGatewayResult stores a gateway transaction and the updated payment
+func (s *PaymentService) recordGatewayResult(ctx context.Context, tx pgx.Tx, payment *models.Payment, txnType models.PaymentTransactionType, amount float64, res *gateway.Result) error {
+	payments := repository.NewPaymentRepository(tx)
+	status := models.TransactionStatusFailed
+	if res.Success {
+		status = models.TransactionStatusSuccess
+	}
+	txn := &models.PaymentTransaction{
+		PaymentID:       payment.ID,
+		Type:            txnType,
+		Amount:          amount,
+		Status:          status,
+		GatewayTxnID:    &res.TxnID,
+		GatewayResponse: res.Response,
+	}
+	if err := payments.CreateTransaction(ctx, txn); err != nil {
+		return err
+	}
+	return payments.UpdateGatewayResult(ctx, payment)
+}
+
+func (s *PaymentService) checkNoPendingReview(ctx context.Context, db repository.DBTX, paymentID uuid.UUID) error {
+	assessments, err := repository.NewRiskRepository(db).ListByPayment(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+	for _, a := range assessments {
+		if a.ReviewStatus != nil && *a.ReviewStatus == models.RiskReviewPending {
+			return fmt.Errorf("%w: payment is awaiting manual review", ErrInvalidState)
+		}
+	}
+	return nil
+}
+
+// riskInput gathers the order and payment method details scored by the engine
+func (s *PaymentService) riskInput(ctx context.Context, db repository.DBTX, payment *models.Payment) (*risk.Input, error) {
+	in := &risk.Input{
+		PaymentID:       payment.ID,
+		OrderID:         payment.OrderID,
+		UserID:          payment.UserID,
+		PaymentMethodID: payment.PaymentMethodID,
+		Amount:          payment.Amount,
+		Currency:        payment.Currency,
+		Now:             s.now(),
+	}
+
+	if payment.PaymentMethodID != nil {
+		method, err := repository.NewPaymentRepository(db).GetMethod(ctx, *payment.PaymentMethodID)
+		if err != nil {
+			return nil, err
+		}
+		in.PaymentMethodType = &method.Type
+	}
+
+	order, err := s.orders.GetOrder(ctx, payment.OrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order for risk scoring: %w", err)
+	}
+	if order.BillingAddress != nil {
+		in.BillingCountry = order.BillingAddress.Country
+		if order.BillingAddress.Email != nil {
+			in.Email = *order.BillingAddress.Email
+		}
+	}
+	if order.ShippingAddress != nil {
+		in.ShippingCountry = order.ShippingAddress.Country
+	}
+	return in, nil
+}
+
+func (s *PaymentService) saveAssessment(ctx context.Context, tx pgx.Tx, payment *models.Payment, a *risk.Assessment) (*models.RiskAssessment, error) {
+	signals, err := json.Marshal(a.Signals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode risk signals: %w", err)
+	}
+	record := &models.RiskAssessment{
+		PaymentID: payment.ID,
+		OrderID:   payment.OrderID,
+		UserID:    payment.UserID,
+		Score:     a.Score,
+		Decision:  a.Decision,
+		Signals:   signals,
+	}
+	if a.Decision == models.RiskDecisionReview {
+		pending := models.RiskReviewPending
+		record.ReviewStatus = &pending
+	}
+	if err := repository.NewRiskRepository(tx).CreateAssessment(ctx, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// flagOrder is best effort; the payment record is the source of truth
+func (s *PaymentService) flagOrder(ctx context.Context, orderID uuid.UUID, flag, note string) {
+	if err := s.orders.SetFlag(ctx, orderID, flag, note); err != nil {
+		log.Printf("failed to flag order %s as %s: %v", orderID, flag, err)
+	}
+}
+
+func (s *PaymentService) clearOrderFlag(ctx context.Context, orderID uuid.UUID, flag string) {
+	if err := s.orders.ClearFlag(ctx, orderID, flag); err != nil {
+		log.Printf("failed to clear %s flag on order %s: %v", flag, orderID, err)
+	}
+}
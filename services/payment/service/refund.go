@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/payment/models"
+	"main.go/services/payment/repository"
+)
+
+// RefundInput holds the details of a refund request
+type RefundInput struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+	Reason   *string `json:"reason,omitempty"`
+	// IdempotencyKey makes retries safe: a refund of the payment with the
+	// same key that did not fail is returned instead of refunding again
+	IdempotencyKey *string `json:"idempotency_key,omitempty"`
+}
+
+// Refund returns part or all of a captured payment. The amount must be in the
+// order currency and must not exceed what remains after completed refunds
+// and disputes that were not won, so disputed money is not paid out twice.
+// The gateway is called outside the payment lock; a refund that cannot reach
+// the gateway is marked failed and may be retried.
+func (s *PaymentService) Refund(ctx context.Context, paymentID uuid.UUID, in RefundInput) (*models.Refund, error) {
+	if in.Amount < 0.01 {
+		return nil, fmt.Errorf("%w: refund amount must be at least 0.01", ErrValidation)
+	}
+	if in.IdempotencyKey != nil && (*in.IdempotencyKey == "" || len(*in.IdempotencyKey) > 255) {
+		return nil, fmt.Errorf("%w: idempotency_key must be between 1 and 255 characters", ErrValidation)
+	}
+
+	payment, err := repository.NewPaymentRepository(s.pool).GetByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.orders.GetOrder(ctx, payment.OrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order: %w", err)
+	}
+	if err := checkOrderCurrency(order, in.Currency); err != nil {
+		return nil, err
+	}
+	conversion, err := fxConversionFor(order, in.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := &models.Refund{
+		PaymentID:      paymentID,
+		Amount:         in.Amount,
+		Currency:       order.Currency,
+		Reason:         in.Reason,
+		Status:         models.RefundStatusPending,
+		FXConversion:   conversion,
+		IdempotencyKey: in.IdempotencyKey,
+	}
+	var txn *models.PaymentTransaction
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		payments := repository.NewPaymentRepository(tx)
+		refunds := repository.NewRefundRepository(tx)
+
+		payment, err = payments.GetByIDForUpdate(ctx, paymentID)
+		if err != nil {
+			return err
+		}
+		if in.IdempotencyKey != nil {
+			existing, err := refunds.GetByIdempotencyKey(ctx, paymentID, *in.IdempotencyKey)
+			if err == nil {
+				refund = existing
+				return nil
+			}
+			if !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+		}
+		if payment.Status != models.PaymentStatusCaptured && payment.Status != models.PaymentStatusPartiallyRefunded {
+			return fmt.Errorf("%w: payment is %s", ErrInvalidState, payment.Status)
+		}
+		refunded, err := refunds.SumCompleted(ctx, paymentID)
+		if err != nil {
+			return err
+		}
+		disputed, err := repository.NewDisputeRepository(tx).SumDisputed(ctx, paymentID)
+		if err != nil {
+			return err
+		}
+		remaining := roundCents(payment.Amount - refunded - disputed)
+		if in.Amount > remaining {
+			return fmt.Errorf("%w: refund %.2f exceeds refundable amount %.2f", ErrValidation, in.Amount, remaining)
+		}
+		txn, err = s.beginGatewayOperation(ctx, tx, payment, models.TransactionTypeRefund, in.Amount)
+		if err != nil {
+			return err
+		}
+		return refunds.Create(ctx, refund)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if txn == nil {
+		return refund, nil
+	}
+
+	res, gwErr := s.gateway.Refund(ctx, s.operationRequest(payment, txn, in.Amount))
+	_, err = s.finishGatewayOperation(ctx, txn, res, gwErr, func(tx pgx.Tx, payment *models.Payment, ok bool) error {
+		refunds := repository.NewRefundRepository(tx)
+		refund.Status = models.RefundStatusFailed
+		if ok {
+			refund.Status = models.RefundStatusCompleted
+		}
+		if err := refunds.UpdateStatus(ctx, refund); err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		// Sums are read again under the lock: a dispute may have been lost
+		// while the gateway was called
+		refunded, err := refunds.SumCompleted(ctx, payment.ID)
+		if err != nil {
+			return err
+		}
+		lost, err := repository.NewDisputeRepository(tx).SumLost(ctx, payment.ID)
+		if err != nil {
+			return err
+		}
+		payment.Status = reversedStatus(payment, refunded+lost)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// ListRefunds returns the refunds of a payment
+func (s *PaymentService) ListRefunds(ctx context.Context, paymentID uuid.UUID) ([]models.Refund, error) {
+	return repository.NewRefundRepository(s.pool).ListByPayment(ctx, paymentID)
+}
+
+// reversedStatus is the status of a captured payment of which reversed has
+// gone back to the customer through refunds and lost disputes
+func reversedStatus(payment *models.Payment, reversed float64) models.PaymentStatus {
+	if roundCents(reversed) >= payment.Amount {
+		return models.PaymentStatusRefunded
+	}
+	return models.PaymentStatusPartiallyRefunded
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/payment/models"
+	"main.go/services/payment/repository"
+)
+
+// maxReviewQueue caps the number of reviews returned by ListReviews
+const maxReviewQueue = 200
+
+// ReviewInput holds the reviewer's decision on a held payment
+type ReviewInput struct {
+	Reviewer string  `json:"reviewer"`
+	Note     *string `json:"note,omitempty"`
+}
+
+// ListReviews returns risk assessments in the given review status, defaulting
+// to the pending queue
+func (s *PaymentService) ListReviews(ctx context.Context, status models.RiskReviewStatus) ([]models.RiskAssessment, error) {
+	if status == "" {
+		status = models.RiskReviewPending
+	}
+	return repository.NewRiskRepository(s.pool).ListByReviewStatus(ctx, status, maxReviewQueue)
+}
+
+// ReleaseReview approves a held payment and authorizes it with the gateway
+// once the review is committed
+func (s *PaymentService) ReleaseReview(ctx context.Context, assessmentID uuid.UUID, in ReviewInput) (*AuthorizationResult, error) {
+	result, err := s.review(ctx, assessmentID, in, models.RiskReviewReleased, func(pgx.Tx, *models.Payment) error {
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Payment, err = s.authorizeWithGateway(ctx, result.Payment); err != nil {
+		return nil, err
+	}
+	s.clearOrderFlag(ctx, result.Payment.OrderID, OrderFlagRiskReview)
+	return result, nil
+}
+
+// CancelReview rejects a held payment, cancels it and cancels its order
+func (s *PaymentService) CancelReview(ctx context.Context, assessmentID uuid.UUID, in ReviewInput) (*AuthorizationResult, error) {
+	result, err := s.review(ctx, assessmentID, in, models.RiskReviewCancelled, func(tx pgx.Tx, payment *models.Payment) error {
+		payment.Status = models.PaymentStatusCancelled
+		return repository.NewPaymentRepository(tx).UpdateGatewayResult(ctx, payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	orderID := result.Payment.OrderID
+	s.clearOrderFlag(ctx, orderID, OrderFlagRiskReview)
+	reason := "payment cancelled after fraud review"
+	if in.Note != nil {
+		reason += ": " + *in.Note
+	}
+	if err := s.orders.CancelOrder(ctx, orderID, reason); err != nil {
+		log.Printf("failed to cancel order %s after fraud review: %v", orderID, err)
+	}
+	return result, nil
+}
+
+// review records a reviewer's decision on a pending assessment and applies
+// fn to the still-pending payment in the same transaction
+func (s *PaymentService) review(ctx context.Context, assessmentID uuid.UUID, in ReviewInput, outcome models.RiskReviewStatus, fn func(pgx.Tx, *models.Payment) error) (*AuthorizationResult, error) {
+	if strings.TrimSpace(in.Reviewer) == "" {
+		return nil, fmt.Errorf("%w: reviewer is required", ErrValidation)
+	}
+
+	result := &AuthorizationResult{}
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		risks := repository.NewRiskRepository(tx)
+		a, err := risks.GetAssessmentForUpdate(ctx, assessmentID)
+		if err != nil {
+			return err
+		}
+		if a.ReviewStatus == nil || *a.ReviewStatus != models.RiskReviewPending {
+			return fmt.Errorf("%w: assessment is not awaiting review", ErrInvalidState)
+		}
+
+		payment, err := repository.NewPaymentRepository(tx).GetByIDForUpdate(ctx, a.PaymentID)
+		if err != nil {
+			return err
+		}
+		if payment.Status != models.PaymentStatusPending {
+			return fmt.Errorf("%w: payment is %s", ErrInvalidState, payment.Status)
+		}
+
+		now := s.now()
+		a.ReviewStatus = &outcome
+		a.ReviewedBy = &in.Reviewer
+		a.ReviewNote = in.Note
+		a.ReviewedAt = &now
+		if err := risks.UpdateReview(ctx, a); err != nil {
+			return err
+		}
+		if err := fn(tx, payment); err != nil {
+			return err
+		}
+		result.Payment = payment
+		result.Assessment = a
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AddBlocklistInput holds a value to add to the risk blocklist
+type AddBlocklistInput struct {
+	Type   models.BlocklistType `json:"type"`
+	Value  string               `json:"value"`
+	Reason *string              `json:"reason,omitempty"`
+}
+
+// ListBlocklist returns every blocklist entry
+func (s *PaymentService) ListBlocklist(ctx context.Context) ([]models.BlocklistEntry, error) {
+	return repository.NewRiskRepository(s.pool).ListBlocklist(ctx)
+}
+
+// AddBlocklistEntry adds a normalized value to the blocklist
+func (s *PaymentService) AddBlocklistEntry(ctx context.Context, in AddBlocklistInput) (*models.BlocklistEntry, error) {
+	value := strings.TrimSpace(in.Value)
+	switch in.Type {
+	case models.BlocklistUser, models.BlocklistPaymentMethod:
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s blocklist value must be a UUID", ErrValidation, in.Type)
+		}
+		value = id.String()
+	case models.BlocklistEmail:
+		value = strings.ToLower(value)
+	case models.BlocklistCountry:
+		value = strings.ToUpper(value)
+		if len(value) != 2 {
+			return nil, fmt.Errorf("%w: country must be a 2 letter ISO code", ErrValidation)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown blocklist type %q", ErrValidation, in.Type)
+	}
+	if value == "" {
+		return nil, fmt.Errorf("%w: value is required", ErrValidation)
+	}
+
+	entry := &models.BlocklistEntry{Type: in.Type, Value: value, Reason: in.Reason}
+	if err := repository.NewRiskRepository(s.pool).AddBlocklistEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// RemoveBlocklistEntry deletes a blocklist entry
+func (s *PaymentService) RemoveBlocklistEntry(ctx context.Context, id uuid.UUID) error {
+	return repository.NewRiskRepository(s.pool).DeleteBlocklistEntry(ctx, id)
+}
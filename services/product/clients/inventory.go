@@ -0,0 +1,44 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InventoryClient talks to the inventory service
+type InventoryClient struct {
+	http httpClient
+}
+
+// NewInventoryClient creates a client for the inventory service at baseURL
+func NewInventoryClient(baseURL string) *InventoryClient {
+	return &InventoryClient{http: newHTTPClient(baseURL)}
+}
+
+// ProductAvailability is the unreserved stock of a product variant across
+// active warehouses as reported by the inventory service
+type ProductAvailability struct {
+	ProductID uuid.UUID `json:"product_id"`
+	VariantID uuid.UUID `json:"variant_id"`
+	Available int       `json:"available"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AvailabilityChanges returns up to limit variants whose stock changed after
+// the (since, afterID) position, oldest change first
+func (c *InventoryClient) AvailabilityChanges(ctx context.Context, since time.Time, afterID uuid.UUID, limit int) ([]ProductAvailability, error) {
+	q := url.Values{}
+	q.Set("since", since.UTC().Format(time.RFC3339Nano))
+	q.Set("after", afterID.String())
+	q.Set("limit", strconv.Itoa(limit))
+	var changes []ProductAvailability
+	if err := c.http.do(ctx, http.MethodGet, "/stock/availability?"+q.Encode(), nil, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
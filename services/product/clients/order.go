@@ -0,0 +1,65 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrderClient talks to the order service
+type OrderClient struct {
+	http httpClient
+}
+
+// NewOrderClient creates a client for the order service at baseURL
+func NewOrderClient(baseURL string) *OrderClient {
+	return &OrderClient{http: newHTTPClient(baseURL)}
+}
+
+// ProductPurchase tells whether a user received a product they ordered
+type ProductPurchase struct {
+	UserID      uuid.UUID  `json:"user_id"`
+	ProductID   uuid.UUID  `json:"product_id"`
+	Delivered   bool       `json:"delivered"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+// ProductPurchase reports whether an order of userID containing productID
+// was delivered
+func (c *OrderClient) ProductPurchase(ctx context.Context, userID, productID uuid.UUID) (*ProductPurchase, error) {
+	var purchase ProductPurchase
+	path := "/users/" + userID.String() + "/purchases/" + productID.String()
+	if err := c.http.do(ctx, http.MethodGet, path, nil, &purchase); err != nil {
+		return nil, err
+	}
+	return &purchase, nil
+}
+
+// CoPurchase counts the orders that contained both a product and a related
+// product
+type CoPurchase struct {
+	ProductID uuid.UUID `json:"product_id"`
+	RelatedID uuid.UUID `json:"related_id"`
+	Orders    int       `json:"orders"`
+}
+
+// CoPurchases returns up to limit pairs of products bought together in at
+// least minOrders orders placed since since, after the (afterProduct,
+// afterRelated) position
+func (c *OrderClient) CoPurchases(ctx context.Context, since time.Time, minOrders int, afterProduct, afterRelated uuid.UUID, limit int) ([]CoPurchase, error) {
+	q := url.Values{}
+	q.Set("since", since.UTC().Format(time.RFC3339Nano))
+	q.Set("min_orders", strconv.Itoa(minOrders))
+	q.Set("after_product", afterProduct.String())
+	q.Set("after_related", afterRelated.String())
+	q.Set("limit", strconv.Itoa(limit))
+	var pairs []CoPurchase
+	if err := c.http.do(ctx, http.MethodGet, "/co-purchases?"+q.Encode(), nil, &pairs); err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
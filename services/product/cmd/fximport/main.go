@@ -0,0 +1,130 @@
+// Command fximport loads FX rates from a CSV file into the product database.
+//
+// The file must have a header row with the columns
+//
+//	base_currency,quote_currency,rate,effective_at
+//
+// where effective_at is an RFC 3339 timestamp or a YYYY-MM-DD date (midnight
+// UTC). An optional fifth column named source overrides the -source flag.
+//
+// Usage:
+//
+//	go run ./services/product/cmd/fximport -file rates.csv -source ecb
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"main.go/services/product/db"
+	"main.go/services/product/service"
+)
+
+func main() {
+	file := flag.String("file", "", "CSV file of FX rates to import")
+	source := flag.String("source", "import", "source recorded on imported rates")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *file, err)
+	}
+	defer f.Close()
+
+	rates, err := readRates(f, *source)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *file, err)
+	}
+
+	// Load database configuration from environment variables
+	config := db.LoadConfig()
+
+	// Connect to the database
+	if err := db.Connect(config); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	imported, err := service.NewPricingService(db.GetDB()).ImportRates(context.Background(), rates)
+	if err != nil {
+		log.Fatalf("Failed to import rates: %v", err)
+	}
+	log.Printf("Imported %d FX rates", len(imported))
+}
+
+// readRates parses the CSV rows into rate inputs
+func readRates(r io.Reader, defaultSource string) ([]service.AddRateInput, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"base_currency", "quote_currency", "rate", "effective_at"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing column %q", required)
+		}
+	}
+
+	var rates []service.AddRateInput
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		rate, err := strconv.ParseFloat(record[columns["rate"]], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rate: %w", line, err)
+		}
+		effectiveAt, err := parseEffectiveAt(record[columns["effective_at"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		src := defaultSource
+		if i, ok := columns["source"]; ok && record[i] != "" {
+			src = record[i]
+		}
+
+		rates = append(rates, service.AddRateInput{
+			BaseCurrency:  record[columns["base_currency"]],
+			QuoteCurrency: record[columns["quote_currency"]],
+			Rate:          rate,
+			EffectiveAt:   &effectiveAt,
+			Source:        &src,
+		})
+	}
+	return rates, nil
+}
+
+func parseEffectiveAt(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid effective_at %q", v)
+	}
+	return t, nil
+}
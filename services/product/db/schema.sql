@@ -48,3 +48,442 @@ CREATE INDEX idx_products_category ON products(category_id);
 CREATE INDEX idx_products_status ON products(status);
 CREATE INDEX idx_products_sku ON products(sku);
 CREATE INDEX idx_categories_parent ON categories(parent_id);
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'USD';  -- currency of price, compare_at_price and cost
+
+CREATE TABLE IF NOT EXISTS product_prices (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id       UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    currency         VARCHAR(3) NOT NULL,
+    price            DECIMAL(12, 2) NOT NULL CHECK (price >= 0),
+    compare_at_price DECIMAL(12, 2),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(product_id, currency)
+);
+
+CREATE TABLE IF NOT EXISTS fx_rates (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    base_currency  VARCHAR(3) NOT NULL,
+    quote_currency VARCHAR(3) NOT NULL,
+    rate           DECIMAL(18, 8) NOT NULL CHECK (rate > 0),  -- 1 base = rate quote
+    effective_at   TIMESTAMPTZ NOT NULL,
+    source         VARCHAR(100),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(base_currency, quote_currency, effective_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_fx_rates_pair ON fx_rates(base_currency, quote_currency, effective_at DESC);
+
+-- Product variants: a product defines options (e.g. Size with S, M, L) and
+-- sells variants, each with one value per option, its own SKU and optional
+-- price override. Every product has a default variant; for products that
+-- predate variants its ID equals the product ID, which lets the inventory and
+-- order services migrate their rows without a lookup.
+CREATE TABLE IF NOT EXISTS product_options (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    name       VARCHAR(100) NOT NULL,
+    option_values TEXT[] NOT NULL CHECK (cardinality(option_values) > 0),
+    position   INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(product_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS product_variants (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id       UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku              VARCHAR(100) UNIQUE,
+    title            VARCHAR(500) NOT NULL DEFAULT '',
+    options          JSONB NOT NULL DEFAULT '{}',  -- option name -> value
+    price            DECIMAL(12, 2) CHECK (price >= 0),  -- overrides the product price when set
+    compare_at_price DECIMAL(12, 2) CHECK (compare_at_price >= 0),
+    is_default       BOOLEAN NOT NULL DEFAULT false,
+    position         INT NOT NULL DEFAULT 0,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(product_id, options)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_options_product ON product_options(product_id, position);
+CREATE INDEX IF NOT EXISTS idx_product_variants_product ON product_variants(product_id, position);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_default ON product_variants(product_id) WHERE is_default;
+
+ALTER TABLE product_images ADD COLUMN IF NOT EXISTS variant_id UUID REFERENCES product_variants(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_product_images_variant ON product_images(variant_id);
+
+-- Migrate single-SKU products to a default variant carrying their SKU
+INSERT INTO product_variants (id, product_id, sku, is_default)
+SELECT p.id, p.id, p.sku, true FROM products p
+WHERE NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id);
+
+-- New products get their default variant the same way. A default variant's
+-- SKU follows the SKU of the product it was created for, so bulk imports that
+-- change product SKUs also change the SKU new orders record.
+CREATE OR REPLACE FUNCTION products_default_variant_trigger() RETURNS TRIGGER LANGUAGE plpgsql AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO product_variants (id, product_id, sku, is_default)
+        VALUES (NEW.id, NEW.id, NEW.sku, true);
+    ELSE
+        UPDATE product_variants SET sku = NEW.sku, updated_at = NOW()
+        WHERE id = NEW.id AND sku IS NOT DISTINCT FROM OLD.sku;
+    END IF;
+    RETURN NULL;
+END;
+$$;
+
+DROP TRIGGER IF EXISTS trg_products_default_variant ON products;
+CREATE TRIGGER trg_products_default_variant
+    AFTER INSERT OR UPDATE OF sku ON products
+    FOR EACH ROW EXECUTE FUNCTION products_default_variant_trigger();
+
+-- Full-text search: products.search_vector combines the name, product SKU
+-- and variant SKUs (weight A), attribute values (B) and description (C).
+-- Triggers keep it in sync with writes to products, product_attributes and
+-- product_variants.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+CREATE OR REPLACE FUNCTION product_search_vector(p_id UUID, p_name TEXT, p_sku TEXT, p_description TEXT)
+RETURNS TSVECTOR LANGUAGE sql STABLE AS $$
+    SELECT setweight(to_tsvector('english', COALESCE(p_name, '') || ' ' || COALESCE(p_sku, '') || ' ' || COALESCE(
+            (SELECT string_agg(sku, ' ') FROM product_variants WHERE product_id = p_id AND sku IS NOT NULL), '')), 'A')
+        || setweight(to_tsvector('english', COALESCE(
+            (SELECT string_agg(key || ' ' || value, ' ') FROM product_attributes WHERE product_id = p_id), '')), 'B')
+        || setweight(to_tsvector('english', COALESCE(p_description, '')), 'C')
+$$;
+
+CREATE OR REPLACE FUNCTION products_search_vector_trigger() RETURNS TRIGGER LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.search_vector := product_search_vector(NEW.id, NEW.name, NEW.sku, NEW.description);
+    RETURN NEW;
+END
+$$;
+
+CREATE OR REPLACE FUNCTION product_attributes_search_vector_trigger() RETURNS TRIGGER LANGUAGE plpgsql AS $$
+DECLARE
+    pid UUID;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        pid := OLD.product_id;
+    ELSE
+        pid := NEW.product_id;
+    END IF;
+    UPDATE products SET search_vector = product_search_vector(id, name, sku, description) WHERE id = pid;
+    IF TG_OP = 'UPDATE' AND OLD.product_id <> NEW.product_id THEN
+        UPDATE products SET search_vector = product_search_vector(id, name, sku, description) WHERE id = OLD.product_id;
+    END IF;
+    RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS trg_products_search_vector ON products;
+CREATE TRIGGER trg_products_search_vector
+    BEFORE INSERT OR UPDATE OF name, sku, description ON products
+    FOR EACH ROW EXECUTE FUNCTION products_search_vector_trigger();
+
+DROP TRIGGER IF EXISTS trg_product_attributes_search_vector ON product_attributes;
+CREATE TRIGGER trg_product_attributes_search_vector
+    AFTER INSERT OR UPDATE OR DELETE ON product_attributes
+    FOR EACH ROW EXECUTE FUNCTION product_attributes_search_vector_trigger();
+
+DROP TRIGGER IF EXISTS trg_product_variants_search_vector ON product_variants;
+CREATE TRIGGER trg_product_variants_search_vector
+    AFTER INSERT OR UPDATE OF sku, product_id OR DELETE ON product_variants
+    FOR EACH ROW EXECUTE FUNCTION product_attributes_search_vector_trigger();
+
+UPDATE products SET search_vector = product_search_vector(id, name, sku, description) WHERE search_vector IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_product_attributes_key_value ON product_attributes(key, value);
+
+-- Unreserved stock per product mirrored from the inventory service for the
+-- in-stock search filter, summed over the product's variants
+CREATE TABLE IF NOT EXISTS product_stock (
+    product_id UUID PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    available  INT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,  -- latest inventory-side change of any variant
+    synced_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_stock_updated ON product_stock(updated_at, product_id);
+
+-- Category tree: siblings are ordered by sort_order, and a category cannot be
+-- its own parent. Deeper cycles are rejected by the service, which serializes
+-- tree changes with an advisory lock.
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS sort_order INT NOT NULL DEFAULT 0;
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_parent_not_self;
+ALTER TABLE categories ADD CONSTRAINT categories_parent_not_self CHECK (parent_id <> id);
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent_sort ON categories(parent_id, sort_order);
+
+-- Bulk catalog imports. The uploaded file is kept with the job and processed
+-- in batches; next_row is the checkpoint a crashed or failed import resumes
+-- from.
+CREATE TABLE IF NOT EXISTS catalog_imports (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    format        VARCHAR(10) NOT NULL CHECK (format IN ('csv', 'jsonl')),
+    dry_run       BOOLEAN NOT NULL DEFAULT false,
+    status        VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    content       BYTEA NOT NULL,
+    total_rows    INT NOT NULL CHECK (total_rows >= 0),
+    next_row      INT NOT NULL DEFAULT 0 CHECK (next_row >= 0),
+    created_count INT NOT NULL DEFAULT 0,
+    updated_count INT NOT NULL DEFAULT 0,
+    failed_count  INT NOT NULL DEFAULT 0,
+    error         TEXT,
+    locked_until  TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at  TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS catalog_import_errors (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    import_id  UUID NOT NULL REFERENCES catalog_imports(id) ON DELETE CASCADE,
+    row_number INT NOT NULL,
+    sku        VARCHAR(100),
+    slug       VARCHAR(500),
+    message    TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(import_id, row_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_catalog_imports_status ON catalog_imports(status, created_at);
+
+-- Former slugs of products and categories. Looking up a former slug
+-- redirects to the record's current slug; generated slugs avoid them so
+-- existing redirects keep working.
+CREATE TABLE IF NOT EXISTS product_slug_history (
+    slug       VARCHAR(500) PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS category_slug_history (
+    slug        VARCHAR(255) PRIMARY KEY,
+    category_id UUID NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_slug_history_product ON product_slug_history(product_id);
+CREATE INDEX IF NOT EXISTS idx_category_slug_history_category ON category_slug_history(category_id);
+
+-- Audit trail of product status changes. from_status is NULL for the status
+-- a product was created with; source is manual, schedule or import.
+CREATE TABLE IF NOT EXISTS product_status_history (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id  UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    from_status VARCHAR(20),
+    to_status   VARCHAR(20) NOT NULL,
+    source      VARCHAR(20) NOT NULL CHECK (source IN ('manual', 'schedule', 'import')),
+    note        TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Status changes planned for a point in time and applied by a background
+-- worker
+CREATE TABLE IF NOT EXISTS product_status_schedules (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id   UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    status       VARCHAR(20) NOT NULL CHECK (status IN ('draft', 'active', 'archived')),
+    run_at       TIMESTAMPTZ NOT NULL,
+    state        VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (state IN ('pending', 'applied', 'failed', 'cancelled')),
+    error        TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_status_history_product ON product_status_history(product_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_product_status_schedules_product ON product_status_schedules(product_id, run_at);
+CREATE INDEX IF NOT EXISTS idx_product_status_schedules_due ON product_status_schedules(run_at) WHERE state = 'pending';
+
+-- Every change of a product's base price, compare-at price or cost, with the
+-- values before it. The old values are NULL for the prices a product was
+-- created with. The price at a point in time is the old price of the first
+-- change after it, or the current price when there is none.
+CREATE TABLE IF NOT EXISTS product_price_history (
+    id                   UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id           UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    old_price            DECIMAL(12, 2),
+    new_price            DECIMAL(12, 2) NOT NULL,
+    old_compare_at_price DECIMAL(12, 2),
+    new_compare_at_price DECIMAL(12, 2),
+    old_cost             DECIMAL(12, 2),
+    new_cost             DECIMAL(12, 2),
+    source               VARCHAR(20) NOT NULL CHECK (source IN ('manual', 'sale', 'import')),
+    actor                VARCHAR(100),
+    reason               TEXT,
+    below_cost           BOOLEAN NOT NULL DEFAULT false,
+    changed_at           TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Timed sales. While a sale is active the product's price is the sale price
+-- and its compare-at price the regular price, which is restored when it ends.
+CREATE TABLE IF NOT EXISTS product_sales (
+    id                       UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id               UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sale_price               DECIMAL(12, 2) NOT NULL CHECK (sale_price >= 0),
+    starts_at                TIMESTAMPTZ NOT NULL,
+    ends_at                  TIMESTAMPTZ NOT NULL,
+    state                    VARCHAR(20) NOT NULL DEFAULT 'scheduled' CHECK (state IN ('scheduled', 'active', 'ended', 'cancelled', 'failed')),
+    regular_price            DECIMAL(12, 2),
+    regular_compare_at_price DECIMAL(12, 2),
+    actor                    VARCHAR(100),
+    reason                   TEXT,
+    error                    TEXT,
+    created_at               TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at               TIMESTAMPTZ,
+    finished_at              TIMESTAMPTZ,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_price_history_product ON product_price_history(product_id, changed_at);
+CREATE INDEX IF NOT EXISTS idx_product_sales_product ON product_sales(product_id, starts_at);
+CREATE INDEX IF NOT EXISTS idx_product_sales_due ON product_sales(starts_at) WHERE state = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_product_sales_ending ON product_sales(ends_at) WHERE state = 'active';
+
+-- Uploaded image files, stored once per content hash (SHA-256) and shared by
+-- every product image with the same content. The original is stored under
+-- originals/<hash> and each derived size under sizes/<hash>/<name>.
+CREATE TABLE IF NOT EXISTS image_blobs (
+    hash         VARCHAR(64) PRIMARY KEY,
+    content_type VARCHAR(50) NOT NULL,
+    size_bytes   BIGINT NOT NULL CHECK (size_bytes > 0),
+    width        INT NOT NULL CHECK (width > 0),
+    height       INT NOT NULL CHECK (height > 0),
+    sizes        JSONB NOT NULL DEFAULT '[]',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Images with a blob were uploaded; images without one link to an external URL
+ALTER TABLE product_images ADD COLUMN IF NOT EXISTS blob_hash VARCHAR(64) REFERENCES image_blobs(hash);
+CREATE INDEX IF NOT EXISTS idx_product_images_blob ON product_images(blob_hash);
+
+-- Blobs are recorded before their files are written and deleted after the
+-- files are removed, so cleanup finds every file through its blob
+ALTER TABLE image_blobs ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'stored'
+    CHECK (status IN ('storing', 'stored', 'releasing'));
+
+-- Customer reviews, one per user and product. verified_purchase is set when
+-- the order service reported a delivery of the product to the user. Only
+-- approved reviews are shown and counted in the product's rating.
+CREATE TABLE IF NOT EXISTS product_reviews (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id        UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    user_id           UUID NOT NULL,  -- references user/auth service (external ID)
+    rating            SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    title             VARCHAR(200),
+    body              TEXT,
+    verified_purchase BOOLEAN NOT NULL DEFAULT false,
+    status            VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    moderation_note   TEXT,
+    moderated_at      TIMESTAMPTZ,
+    helpful_count     INT NOT NULL DEFAULT 0 CHECK (helpful_count >= 0),
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (product_id, user_id)
+);
+
+-- Users who found a review helpful; each user votes once per review
+CREATE TABLE IF NOT EXISTS product_review_votes (
+    review_id  UUID NOT NULL REFERENCES product_reviews(id) ON DELETE CASCADE,
+    user_id    UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (review_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_reviews_product ON product_reviews(product_id, status, created_at);
+CREATE INDEX IF NOT EXISTS idx_product_reviews_status ON product_reviews(status, created_at);
+
+-- Average and count of the approved ratings of a product, kept up to date
+-- as reviews change so searches can sort and filter by rating
+ALTER TABLE products ADD COLUMN IF NOT EXISTS rating_average DECIMAL(3, 2);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS rating_count INT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_products_rating ON products(rating_average DESC NULLS LAST, rating_count DESC);
+
+-- Bundles (kits) sold as one product made of other products. A fixed bundle
+-- sells at its own price; a components bundle at the sum of its components'
+-- prices less discount_percent. Components are not bundles themselves.
+CREATE TABLE IF NOT EXISTS product_bundles (
+    product_id       UUID PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    pricing          VARCHAR(20) NOT NULL CHECK (pricing IN ('fixed', 'components')),
+    discount_percent DECIMAL(5, 2) NOT NULL DEFAULT 0 CHECK (discount_percent >= 0 AND discount_percent < 100),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Without a variant_id a component is the product's default variant
+CREATE TABLE IF NOT EXISTS product_bundle_components (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    bundle_id  UUID NOT NULL REFERENCES product_bundles(product_id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    variant_id UUID REFERENCES product_variants(id),
+    quantity   INT NOT NULL CHECK (quantity > 0),
+    position   INT NOT NULL DEFAULT 0,
+    CHECK (product_id <> bundle_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_bundle_components_bundle ON product_bundle_components(bundle_id, position);
+CREATE INDEX IF NOT EXISTS idx_product_bundle_components_product ON product_bundle_components(product_id);
+CREATE INDEX IF NOT EXISTS idx_product_bundle_components_variant ON product_bundle_components(variant_id);
+
+-- Unreserved stock per variant mirrored from the inventory service. Its
+-- updated_at is the sync cursor, and product_stock is refreshed from it.
+CREATE TABLE IF NOT EXISTS product_variant_stock (
+    variant_id UUID PRIMARY KEY REFERENCES product_variants(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    available  INT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,  -- inventory-side change time
+    synced_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_variant_stock_updated ON product_variant_stock(updated_at, variant_id);
+CREATE INDEX IF NOT EXISTS idx_product_variant_stock_product ON product_variant_stock(product_id);
+
+-- Availability of every product: the mirrored stock of regular products and,
+-- for bundles, the number of complete bundles the stock of their components'
+-- variants makes up. A component without a variant_id uses its product's
+-- default variant. It is NULL until the stock of every component was synced.
+CREATE OR REPLACE VIEW product_availability AS
+SELECT s.product_id, s.available
+FROM product_stock s
+WHERE NOT EXISTS (SELECT 1 FROM product_bundles b WHERE b.product_id = s.product_id)
+UNION ALL
+SELECT c.bundle_id AS product_id,
+    CASE WHEN COUNT(s.variant_id) < COUNT(*) THEN NULL
+        ELSE MIN(GREATEST(s.available, 0) / c.quantity) END AS available
+FROM product_bundle_components c
+LEFT JOIN product_variants dv ON c.variant_id IS NULL AND dv.product_id = c.product_id AND dv.is_default
+LEFT JOIN product_variant_stock s ON s.variant_id = COALESCE(c.variant_id, dv.id)
+GROUP BY c.bundle_id;
+
+-- Curated links from a product to related products, upsells and cross-sells
+CREATE TABLE IF NOT EXISTS product_links (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    related_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    type       VARCHAR(20) NOT NULL CHECK (type IN ('related', 'upsell', 'cross_sell')),
+    position   INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (product_id, related_id, type),
+    CHECK (product_id <> related_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_links_product ON product_links(product_id, type, position);
+
+-- Products bought together, computed from the order history by a batch job.
+-- Pairs missing from the latest run are removed after it.
+CREATE TABLE IF NOT EXISTS product_co_purchases (
+    product_id  UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    related_id  UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    orders      INT NOT NULL CHECK (orders > 0),
+    computed_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (product_id, related_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_co_purchases_computed ON product_co_purchases(computed_at);
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+
+	"main.go/services/product/service"
+)
+
+// BundleHandler exposes product bundle definitions over HTTP
+type BundleHandler struct {
+	bundles *service.BundleService
+}
+
+// NewBundleHandler creates a new BundleHandler
+func NewBundleHandler(bundles *service.BundleService) *BundleHandler {
+	return &BundleHandler{bundles: bundles}
+}
+
+// RegisterRoutes registers the bundle routes on mux
+func (h *BundleHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /products/{id}/bundle", h.get)
+	mux.HandleFunc("PUT /products/{id}/bundle", h.set)
+	mux.HandleFunc("DELETE /products/{id}/bundle", h.delete)
+}
+
+func (h *BundleHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	bundle, err := h.bundles.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, bundle)
+}
+
+func (h *BundleHandler) set(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.SetBundleInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	bundle, err := h.bundles.Set(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, bundle)
+}
+
+func (h *BundleHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.bundles.Delete(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"main.go/services/product/service"
+)
+
+// maxImportSize limits uploaded catalog files
+const maxImportSize = 64 << 20
+
+// catalogContentTypes maps upload content types to catalog formats
+var catalogContentTypes = map[string]string{
+	"text/csv":             service.CatalogFormatCSV,
+	"application/x-ndjson": service.CatalogFormatJSONL,
+	"application/jsonl":    service.CatalogFormatJSONL,
+}
+
+// CatalogHandler exposes bulk catalog import and export over HTTP
+type CatalogHandler struct {
+	catalog *service.CatalogService
+}
+
+// NewCatalogHandler creates a new CatalogHandler
+func NewCatalogHandler(catalog *service.CatalogService) *CatalogHandler {
+	return &CatalogHandler{catalog: catalog}
+}
+
+// RegisterRoutes registers the catalog routes on mux
+func (h *CatalogHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /catalog/imports", h.createImport)
+	mux.HandleFunc("GET /catalog/imports/{id}", h.getImport)
+	mux.HandleFunc("GET /catalog/imports/{id}/errors", h.listImportErrors)
+	mux.HandleFunc("POST /catalog/imports/{id}/resume", h.resumeImport)
+	mux.HandleFunc("GET /catalog/export", h.export)
+}
+
+// createImport queues the request body as a catalog file. The format comes
+// from ?format= or the Content-Type; ?dry_run=true validates without saving.
+func (h *CatalogHandler) createImport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	in := service.CreateImportInput{Format: q.Get("format")}
+	if in.Format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		in.Format = catalogContentTypes[mediaType]
+	}
+	if in.Format == "" {
+		writeError(w, fmt.Errorf("%w: format must be given as ?format=csv|jsonl or by Content-Type", service.ErrValidation))
+		return
+	}
+	if v := q.Get("dry_run"); v != "" {
+		var err error
+		if in.DryRun, err = strconv.ParseBool(v); err != nil {
+			writeError(w, fmt.Errorf("%w: dry_run must be a boolean", service.ErrValidation))
+			return
+		}
+	}
+
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, fmt.Errorf("%w: file exceeds %d bytes", service.ErrValidation, maxImportSize))
+		return
+	}
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: failed to read file: %v", service.ErrValidation, err))
+		return
+	}
+	in.Content = content
+
+	imp, err := h.catalog.CreateImport(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, imp)
+}
+
+func (h *CatalogHandler) getImport(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	imp, err := h.catalog.GetImport(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, imp)
+}
+
+func (h *CatalogHandler) listImportErrors(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	q := r.URL.Query()
+	limit, offset := 0, 0
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, fmt.Errorf("%w: limit must be an integer", service.ErrValidation))
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil {
+			writeError(w, fmt.Errorf("%w: offset must be an integer", service.ErrValidation))
+			return
+		}
+	}
+	errs, err := h.catalog.ListImportErrors(r.Context(), id, limit, offset)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, errs)
+}
+
+func (h *CatalogHandler) resumeImport(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	imp, err := h.catalog.ResumeImport(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, imp)
+}
+
+// export streams the catalog as CSV or JSON Lines, optionally limited to a
+// status and a category with its descendants
+func (h *CatalogHandler) export(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	in := service.ExportCatalogInput{Format: q.Get("format"), Status: q.Get("status")}
+	var err error
+	if in.CategoryID, err = queryUUID(r, "category_id"); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Errors before the first byte still get a proper error response; once
+	// rows are streamed the response can only be cut short
+	started := false
+	n, err := h.catalog.Export(r.Context(), in, w, func() {
+		started = true
+		contentType, filename := "text/csv", "catalog.csv"
+		if in.Format == service.CatalogFormatJSONL {
+			contentType, filename = "application/x-ndjson", "catalog.jsonl"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.WriteHeader(http.StatusOK)
+	})
+	if err != nil && !started {
+		writeError(w, err)
+		return
+	}
+	if err != nil {
+		log.Printf("catalog export aborted after %d products: %v", n, err)
+	}
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"main.go/services/product/service"
+)
+
+// CategoryHandler exposes the category tree over HTTP
+type CategoryHandler struct {
+	categories *service.CategoryService
+}
+
+// NewCategoryHandler creates a new CategoryHandler
+func NewCategoryHandler(categories *service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{categories: categories}
+}
+
+// RegisterRoutes registers the category routes on mux
+func (h *CategoryHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /categories/tree", h.tree)
+	mux.HandleFunc("POST /categories", h.create)
+	mux.HandleFunc("PUT /categories/order", h.reorder)
+	mux.HandleFunc("GET /categories/{id}", h.get)
+	mux.HandleFunc("PUT /categories/{id}", h.update)
+	mux.HandleFunc("GET /categories/{id}/tree", h.subtree)
+	mux.HandleFunc("POST /categories/{id}/move", h.move)
+	mux.HandleFunc("GET /categories/{id}/products", h.listProducts)
+	mux.HandleFunc("GET /products/{id}/breadcrumbs", h.breadcrumbs)
+	mux.HandleFunc("GET /category-slugs/{slug}", h.getBySlug)
+}
+
+func (h *CategoryHandler) tree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.categories.Tree(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tree)
+}
+
+func (h *CategoryHandler) create(w http.ResponseWriter, r *http.Request) {
+	var in service.CreateCategoryInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	category, err := h.categories.Create(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, category)
+}
+
+func (h *CategoryHandler) reorder(w http.ResponseWriter, r *http.Request) {
+	var in service.ReorderCategoriesInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	children, err := h.categories.Reorder(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, children)
+}
+
+func (h *CategoryHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	category, err := h.categories.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, category)
+}
+
+// getBySlug returns a category by its slug and redirects former slugs to the
+// current one
+func (h *CategoryHandler) getBySlug(w http.ResponseWriter, r *http.Request) {
+	category, moved, err := h.categories.GetBySlug(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if moved {
+		http.Redirect(w, r, "/category-slugs/"+url.PathEscape(category.Slug), http.StatusMovedPermanently)
+		return
+	}
+	writeJSON(w, http.StatusOK, category)
+}
+
+func (h *CategoryHandler) update(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.UpdateCategoryInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	category, err := h.categories.Update(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, category)
+}
+
+// subtree returns a category with its descendants, limited to ?depth= levels
+// below it when given
+func (h *CategoryHandler) subtree(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	depth := -1
+	if v := r.URL.Query().Get("depth"); v != "" {
+		if depth, err = strconv.Atoi(v); err != nil || depth < 0 {
+			writeError(w, fmt.Errorf("%w: depth must be a non-negative integer", service.ErrValidation))
+			return
+		}
+	}
+	node, err := h.categories.Subtree(r.Context(), id, depth)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, node)
+}
+
+func (h *CategoryHandler) move(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.MoveCategoryInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	category, err := h.categories.Move(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, category)
+}
+
+// listProducts pages through the products of a category, including its
+// subcategories unless ?include_descendants=false
+func (h *CategoryHandler) listProducts(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	q := r.URL.Query()
+	in := service.CategoryProductsInput{IncludeDescendants: true, Status: q.Get("status")}
+	if v := q.Get("include_descendants"); v != "" {
+		if in.IncludeDescendants, err = strconv.ParseBool(v); err != nil {
+			writeError(w, fmt.Errorf("%w: include_descendants must be a boolean", service.ErrValidation))
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if in.Limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, fmt.Errorf("%w: limit must be an integer", service.ErrValidation))
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if in.Offset, err = strconv.Atoi(v); err != nil {
+			writeError(w, fmt.Errorf("%w: offset must be an integer", service.ErrValidation))
+			return
+		}
+	}
+	page, err := h.categories.ListProducts(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *CategoryHandler) breadcrumbs(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	crumbs, err := h.categories.Breadcrumbs(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, crumbs)
+}
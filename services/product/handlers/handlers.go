@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"main.go/services/product/repository"
+	"main.go/services/product/service"
+)
+
+// maxBodySize limits JSON request bodies
+const maxBodySize = 1 << 20
+
+// errorResponse is the JSON body returned for failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// writeError maps service and repository errors to HTTP status codes
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrValidation):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrInvalidState):
+		status = http.StatusConflict
+	}
+
+	msg := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Printf("internal error: %v", err)
+		msg = "internal server error"
+	}
+	writeJSON(w, status, errorResponse{Error: msg})
+}
+
+// decodeJSON decodes a JSON request body into v
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid request body: %v", service.ErrValidation, err)
+	}
+	return nil
+}
+
+// pathUUID parses a UUID path parameter
+func pathUUID(r *http.Request, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(r.PathValue(name))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: invalid %s", service.ErrValidation, name)
+	}
+	return id, nil
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"main.go/services/product/service"
+)
+
+// ImageHandler exposes product image uploads and files over HTTP
+type ImageHandler struct {
+	images *service.ImageService
+}
+
+// NewImageHandler creates a new ImageHandler
+func NewImageHandler(images *service.ImageService) *ImageHandler {
+	return &ImageHandler{images: images}
+}
+
+// RegisterRoutes registers the image routes on mux
+func (h *ImageHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /products/{id}/images", h.list)
+	mux.HandleFunc("POST /products/{id}/images", h.upload)
+	mux.HandleFunc("PUT /products/{id}/images/order", h.reorder)
+	mux.HandleFunc("DELETE /images/{id}", h.delete)
+	mux.HandleFunc("GET /image-files/{hash}/{size}", h.file)
+}
+
+func (h *ImageHandler) list(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	images, err := h.images.List(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, images)
+}
+
+// upload stores the request body as a new image of the product, with the
+// optional ?alt_text=. A file the product already has returns its image
+// with 200 instead of 201.
+func (h *ImageHandler) upload(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, service.MaxImageSize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, fmt.Errorf("%w: image exceeds %d bytes", service.ErrValidation, service.MaxImageSize))
+		return
+	}
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: failed to read image: %v", service.ErrValidation, err))
+		return
+	}
+	in := service.UploadImageInput{Content: content}
+	if alt := r.URL.Query().Get("alt_text"); alt != "" {
+		in.AltText = &alt
+	}
+
+	img, created, err := h.images.Upload(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, img)
+}
+
+func (h *ImageHandler) reorder(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.ReorderImagesInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	images, err := h.images.Reorder(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, images)
+}
+
+func (h *ImageHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.images.Delete(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// file serves an uploaded image file. Files are addressed by their content
+// hash, so they can be cached forever.
+func (h *ImageHandler) file(w http.ResponseWriter, r *http.Request) {
+	f, contentType, err := h.images.OpenFile(r.Context(), r.PathValue("hash"), r.PathValue("size"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, f); err != nil {
+		log.Printf("failed to send image file: %v", err)
+	}
+}
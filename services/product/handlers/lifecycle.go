@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+
+	"main.go/services/product/service"
+)
+
+// LifecycleHandler exposes product status changes and their schedules over
+// HTTP
+type LifecycleHandler struct {
+	lifecycle *service.LifecycleService
+}
+
+// NewLifecycleHandler creates a new LifecycleHandler
+func NewLifecycleHandler(lifecycle *service.LifecycleService) *LifecycleHandler {
+	return &LifecycleHandler{lifecycle: lifecycle}
+}
+
+// RegisterRoutes registers the lifecycle routes on mux
+func (h *LifecycleHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /products/{id}/status", h.changeStatus)
+	mux.HandleFunc("GET /products/{id}/status-history", h.history)
+	mux.HandleFunc("GET /products/{id}/status-schedules", h.listSchedules)
+	mux.HandleFunc("POST /products/{id}/status-schedules", h.schedule)
+	mux.HandleFunc("POST /status-schedules/{id}/cancel", h.cancelSchedule)
+}
+
+func (h *LifecycleHandler) changeStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.ChangeStatusInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	product, err := h.lifecycle.ChangeStatus(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, product)
+}
+
+func (h *LifecycleHandler) history(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	changes, err := h.lifecycle.History(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, changes)
+}
+
+func (h *LifecycleHandler) listSchedules(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	schedules, err := h.lifecycle.ListSchedules(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, schedules)
+}
+
+func (h *LifecycleHandler) schedule(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.ScheduleStatusInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	sched, err := h.lifecycle.Schedule(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, sched)
+}
+
+func (h *LifecycleHandler) cancelSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sched, err := h.lifecycle.CancelSchedule(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sched)
+}
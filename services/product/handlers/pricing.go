@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"main.go/services/product/service"
+)
+
+// PricingHandler exposes currency prices and FX rates over HTTP
+type PricingHandler struct {
+	pricing *service.PricingService
+}
+
+// NewPricingHandler creates a new PricingHandler
+func NewPricingHandler(pricing *service.PricingService) *PricingHandler {
+	return &PricingHandler{pricing: pricing}
+}
+
+// RegisterRoutes registers the pricing routes on mux
+func (h *PricingHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /products/{id}/price", h.resolve)
+	mux.HandleFunc("PUT /products/{id}/price", h.setBasePrice)
+	mux.HandleFunc("GET /products/{id}/price-history", h.priceHistory)
+	mux.HandleFunc("GET /products/{id}/prices", h.listPrices)
+	mux.HandleFunc("PUT /products/{id}/prices/{currency}", h.setPrice)
+	mux.HandleFunc("DELETE /products/{id}/prices/{currency}", h.deletePrice)
+	mux.HandleFunc("GET /fx/rates", h.listRates)
+	mux.HandleFunc("GET /fx/rate", h.rateAt)
+	mux.HandleFunc("POST /fx/rates", h.addRate)
+}
+
+// resolve returns the price in ?currency= as of the optional RFC 3339 ?at=,
+// for the ?variant_id= variant or the default one
+func (h *PricingHandler) resolve(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	at, err := queryTime(r, "at")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	variantID, err := queryUUID(r, "variant_id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	price, err := h.pricing.Resolve(r.Context(), id, variantID, r.URL.Query().Get("currency"), at)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, price)
+}
+
+// setBasePrice changes the prices of a product in its base currency and
+// reports warnings such as a price below cost
+func (h *PricingHandler) setBasePrice(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.SetBasePriceInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	update, err := h.pricing.SetBasePrice(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, update)
+}
+
+func (h *PricingHandler) priceHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	changes, err := h.pricing.PriceHistory(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, changes)
+}
+
+func (h *PricingHandler) listPrices(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	prices, err := h.pricing.ListPrices(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, prices)
+}
+
+func (h *PricingHandler) setPrice(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.SetPriceInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	price, err := h.pricing.SetPrice(r.Context(), id, r.PathValue("currency"), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, price)
+}
+
+func (h *PricingHandler) deletePrice(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.pricing.DeletePrice(r.Context(), id, r.PathValue("currency")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *PricingHandler) listRates(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	rates, err := h.pricing.ListRates(r.Context(), q.Get("base"), q.Get("quote"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rates)
+}
+
+func (h *PricingHandler) rateAt(w http.ResponseWriter, r *http.Request) {
+	at, err := queryTime(r, "at")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	q := r.URL.Query()
+	rate, err := h.pricing.RateAt(r.Context(), q.Get("base"), q.Get("quote"), at)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rate)
+}
+
+func (h *PricingHandler) addRate(w http.ResponseWriter, r *http.Request) {
+	var in service.AddRateInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	rate, err := h.pricing.AddRate(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, rate)
+}
+
+// queryTime parses an optional RFC 3339 query parameter
+func queryTime(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s must be an RFC 3339 timestamp", service.ErrValidation, name)
+	}
+	return t, nil
+}
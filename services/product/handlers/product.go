@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"main.go/services/product/service"
+)
+
+// ProductHandler exposes the product catalog over HTTP
+type ProductHandler struct {
+	products *service.ProductService
+}
+
+// NewProductHandler creates a new ProductHandler
+func NewProductHandler(products *service.ProductService) *ProductHandler {
+	return &ProductHandler{products: products}
+}
+
+// RegisterRoutes registers the product routes on mux
+func (h *ProductHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /products/{id}", h.get)
+	mux.HandleFunc("GET /product-slugs/{slug}", h.getBySlug)
+}
+
+func (h *ProductHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	product, err := h.products.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, product)
+}
+
+// getBySlug returns a product by its slug and redirects former slugs to the
+// current one
+func (h *ProductHandler) getBySlug(w http.ResponseWriter, r *http.Request) {
+	product, moved, err := h.products.GetBySlug(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if moved {
+		http.Redirect(w, r, "/product-slugs/"+url.PathEscape(product.Slug), http.StatusMovedPermanently)
+		return
+	}
+	writeJSON(w, http.StatusOK, product)
+}
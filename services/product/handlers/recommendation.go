@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"main.go/services/product/service"
+)
+
+// RecommendationHandler exposes curated product links and recommendations
+// over HTTP
+type RecommendationHandler struct {
+	recommendations *service.RecommendationService
+}
+
+// NewRecommendationHandler creates a new RecommendationHandler
+func NewRecommendationHandler(recommendations *service.RecommendationService) *RecommendationHandler {
+	return &RecommendationHandler{recommendations: recommendations}
+}
+
+// RegisterRoutes registers the recommendation routes on mux
+func (h *RecommendationHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /products/{id}/links", h.listLinks)
+	mux.HandleFunc("POST /products/{id}/links", h.addLink)
+	mux.HandleFunc("DELETE /product-links/{id}", h.deleteLink)
+	mux.HandleFunc("GET /products/{id}/recommendations", h.forProduct)
+	mux.HandleFunc("GET /recommendations", h.forCart)
+}
+
+func (h *RecommendationHandler) listLinks(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	links, err := h.recommendations.ListLinks(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, links)
+}
+
+func (h *RecommendationHandler) addLink(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.AddLinkInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	link, err := h.recommendations.AddLink(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, link)
+}
+
+func (h *RecommendationHandler) deleteLink(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.recommendations.DeleteLink(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *RecommendationHandler) forProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	in, err := recommendInput(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	recs, err := h.recommendations.ForProduct(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, recs)
+}
+
+// forCart recommends products for the cart given as repeated ?product_id=
+// parameters
+func (h *RecommendationHandler) forCart(w http.ResponseWriter, r *http.Request) {
+	var ids []uuid.UUID
+	for _, v := range r.URL.Query()["product_id"] {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			writeError(w, fmt.Errorf("%w: product_id must be a UUID", service.ErrValidation))
+			return
+		}
+		ids = append(ids, id)
+	}
+	in, err := recommendInput(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	recs, err := h.recommendations.ForCart(r.Context(), ids, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, recs)
+}
+
+// recommendInput parses the query parameters of a recommendation list
+func recommendInput(r *http.Request) (service.RecommendInput, error) {
+	q := r.URL.Query()
+	in := service.RecommendInput{Type: q.Get("type")}
+	if v := q.Get("limit"); v != "" {
+		var err error
+		if in.Limit, err = strconv.Atoi(v); err != nil {
+			return in, fmt.Errorf("%w: limit must be an integer", service.ErrValidation)
+		}
+	}
+	return in, nil
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"main.go/services/product/service"
+)
+
+// ReviewHandler exposes product reviews, their moderation and helpful votes
+// over HTTP
+type ReviewHandler struct {
+	reviews *service.ReviewService
+}
+
+// NewReviewHandler creates a new ReviewHandler
+func NewReviewHandler(reviews *service.ReviewService) *ReviewHandler {
+	return &ReviewHandler{reviews: reviews}
+}
+
+// RegisterRoutes registers the review routes on mux
+func (h *ReviewHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /products/{id}/reviews", h.listByProduct)
+	mux.HandleFunc("POST /products/{id}/reviews", h.create)
+	mux.HandleFunc("GET /products/{id}/rating", h.summary)
+	mux.HandleFunc("GET /reviews", h.list)
+	mux.HandleFunc("GET /reviews/{id}", h.get)
+	mux.HandleFunc("PUT /reviews/{id}", h.update)
+	mux.HandleFunc("DELETE /reviews/{id}", h.delete)
+	mux.HandleFunc("POST /reviews/{id}/status", h.moderate)
+	mux.HandleFunc("POST /reviews/{id}/votes", h.vote)
+	mux.HandleFunc("DELETE /reviews/{id}/votes/{user_id}", h.unvote)
+}
+
+// voteRequest is the body of a helpful vote
+type voteRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (h *ReviewHandler) listByProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	in, err := reviewListInput(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	page, err := h.reviews.ListByProduct(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *ReviewHandler) create(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.ReviewInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	review, err := h.reviews.Create(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, review)
+}
+
+func (h *ReviewHandler) summary(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	summary, err := h.reviews.Summary(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+func (h *ReviewHandler) list(w http.ResponseWriter, r *http.Request) {
+	in, err := reviewListInput(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	page, err := h.reviews.List(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *ReviewHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	review, err := h.reviews.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, review)
+}
+
+func (h *ReviewHandler) update(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.ReviewInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	review, err := h.reviews.Update(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, review)
+}
+
+func (h *ReviewHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.reviews.Delete(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ReviewHandler) moderate(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.ModerateReviewInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	review, err := h.reviews.Moderate(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, review)
+}
+
+func (h *ReviewHandler) vote(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var req voteRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	review, err := h.reviews.Vote(r.Context(), id, req.UserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, review)
+}
+
+func (h *ReviewHandler) unvote(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	userID, err := pathUUID(r, "user_id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.reviews.Unvote(r.Context(), id, userID); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reviewListInput parses the query parameters of a review listing
+func reviewListInput(r *http.Request) (service.ListReviewsInput, error) {
+	q := r.URL.Query()
+	in := service.ListReviewsInput{
+		Status: q.Get("status"),
+		Sort:   q.Get("sort"),
+	}
+	var err error
+	if v := q.Get("rating"); v != "" {
+		if in.Rating, err = strconv.Atoi(v); err != nil {
+			return in, fmt.Errorf("%w: rating must be an integer", service.ErrValidation)
+		}
+	}
+	if v := q.Get("verified"); v != "" {
+		if in.VerifiedOnly, err = strconv.ParseBool(v); err != nil {
+			return in, fmt.Errorf("%w: verified must be a boolean", service.ErrValidation)
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if in.Limit, err = strconv.Atoi(v); err != nil {
+			return in, fmt.Errorf("%w: limit must be an integer", service.ErrValidation)
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if in.Offset, err = strconv.Atoi(v); err != nil {
+			return in, fmt.Errorf("%w: offset must be an integer", service.ErrValidation)
+		}
+	}
+	return in, nil
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+
+	"main.go/services/product/service"
+)
+
+// SaleHandler exposes timed product sales over HTTP
+type SaleHandler struct {
+	sales *service.SaleService
+}
+
+// NewSaleHandler creates a new SaleHandler
+func NewSaleHandler(sales *service.SaleService) *SaleHandler {
+	return &SaleHandler{sales: sales}
+}
+
+// RegisterRoutes registers the sale routes on mux
+func (h *SaleHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /products/{id}/sales", h.list)
+	mux.HandleFunc("POST /products/{id}/sales", h.create)
+	mux.HandleFunc("POST /sales/{id}/cancel", h.cancel)
+}
+
+func (h *SaleHandler) list(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sales, err := h.sales.List(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sales)
+}
+
+func (h *SaleHandler) create(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.CreateSaleInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	sale, err := h.sales.Create(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, sale)
+}
+
+func (h *SaleHandler) cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sale, err := h.sales.Cancel(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sale)
+}
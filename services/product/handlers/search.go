@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"main.go/services/product/service"
+)
+
+// attributeParamPrefix marks query parameters that filter on an attribute,
+// e.g. ?attr.color=red,blue
+const attributeParamPrefix = "attr."
+
+// SearchHandler exposes product search over HTTP
+type SearchHandler struct {
+	search *service.SearchService
+}
+
+// NewSearchHandler creates a new SearchHandler
+func NewSearchHandler(search *service.SearchService) *SearchHandler {
+	return &SearchHandler{search: search}
+}
+
+// RegisterRoutes registers the search routes on mux
+func (h *SearchHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /products/search", h.list)
+}
+
+func (h *SearchHandler) list(w http.ResponseWriter, r *http.Request) {
+	in, err := searchInput(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := h.search.Search(r.Context(), in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// searchInput parses the query parameters of a product search
+func searchInput(r *http.Request) (service.SearchProductsInput, error) {
+	q := r.URL.Query()
+	in := service.SearchProductsInput{
+		Query:    q.Get("q"),
+		Currency: q.Get("currency"),
+		Sort:     q.Get("sort"),
+	}
+	for name, values := range q {
+		key, ok := strings.CutPrefix(name, attributeParamPrefix)
+		if !ok {
+			continue
+		}
+		if in.Attributes == nil {
+			in.Attributes = map[string][]string{}
+		}
+		for _, v := range values {
+			for _, value := range strings.Split(v, ",") {
+				if value = strings.TrimSpace(value); value != "" {
+					in.Attributes[key] = append(in.Attributes[key], value)
+				}
+			}
+		}
+	}
+
+	var err error
+	if in.CategoryID, err = queryUUID(r, "category_id"); err != nil {
+		return in, err
+	}
+	if in.MinPrice, err = queryFloat(r, "min_price"); err != nil {
+		return in, err
+	}
+	if in.MaxPrice, err = queryFloat(r, "max_price"); err != nil {
+		return in, err
+	}
+	if in.MinRating, err = queryFloat(r, "min_rating"); err != nil {
+		return in, err
+	}
+	if v := q.Get("in_stock"); v != "" {
+		if in.InStock, err = strconv.ParseBool(v); err != nil {
+			return in, fmt.Errorf("%w: in_stock must be a boolean", service.ErrValidation)
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if in.Limit, err = strconv.Atoi(v); err != nil {
+			return in, fmt.Errorf("%w: limit must be an integer", service.ErrValidation)
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if in.Offset, err = strconv.Atoi(v); err != nil {
+			return in, fmt.Errorf("%w: offset must be an integer", service.ErrValidation)
+		}
+	}
+	return in, nil
+}
+
+// queryUUID parses an optional UUID query parameter
+func queryUUID(r *http.Request, name string) (*uuid.UUID, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be a UUID", service.ErrValidation, name)
+	}
+	return &id, nil
+}
+
+// queryFloat parses an optional decimal query parameter
+func queryFloat(r *http.Request, name string) (*float64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be a number", service.ErrValidation, name)
+	}
+	return &f, nil
+}
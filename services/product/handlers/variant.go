@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"main.go/services/product/service"
+)
+
+// VariantHandler exposes product options and variants over HTTP
+type VariantHandler struct {
+	variants *service.VariantService
+}
+
+// NewVariantHandler creates a new VariantHandler
+func NewVariantHandler(variants *service.VariantService) *VariantHandler {
+	return &VariantHandler{variants: variants}
+}
+
+// RegisterRoutes registers the variant routes on mux
+func (h *VariantHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /products/{id}/options", h.listOptions)
+	mux.HandleFunc("PUT /products/{id}/options", h.setOptions)
+	mux.HandleFunc("GET /products/{id}/variants", h.list)
+	mux.HandleFunc("POST /products/{id}/variants", h.create)
+	mux.HandleFunc("GET /variants/{id}", h.get)
+	mux.HandleFunc("PUT /variants/{id}", h.update)
+	mux.HandleFunc("DELETE /variants/{id}", h.delete)
+	mux.HandleFunc("PUT /variants/{id}/images", h.setImages)
+}
+
+func (h *VariantHandler) listOptions(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	options, err := h.variants.ListOptions(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, options)
+}
+
+func (h *VariantHandler) setOptions(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in []service.OptionInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	options, err := h.variants.SetOptions(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, options)
+}
+
+func (h *VariantHandler) list(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	variants, err := h.variants.ListVariants(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, variants)
+}
+
+func (h *VariantHandler) create(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.VariantInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	variant, err := h.variants.CreateVariant(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, variant)
+}
+
+func (h *VariantHandler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	variant, err := h.variants.GetVariant(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, variant)
+}
+
+func (h *VariantHandler) update(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in service.VariantInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	variant, err := h.variants.UpdateVariant(r.Context(), id, in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, variant)
+}
+
+func (h *VariantHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.variants.DeleteVariant(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *VariantHandler) setImages(w http.ResponseWriter, r *http.Request) {
+	id, err := pathUUID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in struct {
+		ImageIDs []uuid.UUID `json:"image_ids"`
+	}
+	if err := decodeJSON(w, r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	variant, err := h.variants.SetVariantImages(r.Context(), id, in.ImageIDs)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, variant)
+}
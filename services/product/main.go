@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"main.go/services/product/clients"
+	"main.go/services/product/db"
+	"main.go/services/product/handlers"
+	"main.go/services/product/service"
+	"main.go/services/product/storage"
+)
+
+func main() {
+	// Load database configuration from environment variables
+	config := db.LoadConfig()
+
+	// Connect to the database
+	if err := db.Connect(config); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := db.GetDB()
+	inventory := clients.NewInventoryClient(getEnv("INVENTORY_SERVICE_URL", "http://localhost:8082"))
+	orders := clients.NewOrderClient(getEnv("ORDER_SERVICE_URL", "http://localhost:8083"))
+
+	stockSync := service.NewStockSyncService(pool, inventory)
+	go stockSync.RunStockSync(ctx, time.Minute)
+
+	catalog := service.NewCatalogService(pool)
+	go catalog.RunCatalogImports(ctx, 5*time.Second)
+
+	lifecycle := service.NewLifecycleService(pool)
+	go lifecycle.RunStatusSchedules(ctx, 30*time.Second)
+
+	sales := service.NewSaleService(pool)
+	go sales.RunSales(ctx, 30*time.Second)
+
+	coPurchaseWindow := service.DefaultCoPurchaseWindow
+	if days, err := strconv.Atoi(getEnv("RECOMMENDATION_WINDOW_DAYS", "")); err == nil && days > 0 {
+		coPurchaseWindow = time.Duration(days) * 24 * time.Hour
+	}
+	recommendations := service.NewRecommendationService(pool, orders, coPurchaseWindow)
+	go recommendations.RunCoPurchases(ctx, time.Hour)
+
+	store, err := storage.NewLocal(getEnv("PRODUCT_IMAGE_DIR", "data/images"))
+	if err != nil {
+		log.Fatalf("Failed to open image storage: %v", err)
+	}
+	images := service.NewImageService(pool, store, getEnv("PRODUCT_IMAGE_BASE_URL", "http://localhost:8081"))
+	go images.RunBlobCleanup(ctx, 10*time.Minute)
+
+	mux := http.NewServeMux()
+	handlers.NewProductHandler(service.NewProductService(pool)).RegisterRoutes(mux)
+	handlers.NewPricingHandler(service.NewPricingService(pool)).RegisterRoutes(mux)
+	handlers.NewSearchHandler(service.NewSearchService(pool)).RegisterRoutes(mux)
+	handlers.NewCategoryHandler(service.NewCategoryService(pool)).RegisterRoutes(mux)
+	handlers.NewVariantHandler(service.NewVariantService(pool)).RegisterRoutes(mux)
+	handlers.NewCatalogHandler(catalog).RegisterRoutes(mux)
+	handlers.NewLifecycleHandler(lifecycle).RegisterRoutes(mux)
+	handlers.NewSaleHandler(sales).RegisterRoutes(mux)
+	handlers.NewImageHandler(images).RegisterRoutes(mux)
+	handlers.NewReviewHandler(service.NewReviewService(pool, orders)).RegisterRoutes(mux)
+	handlers.NewBundleHandler(service.NewBundleService(pool)).RegisterRoutes(mux)
+	handlers.NewRecommendationHandler(recommendations).RegisterRoutes(mux)
+
+	addr := getEnv("PRODUCT_HTTP_ADDR", ":8081")
+	log.Printf("Product service listening on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Fatalf("Product service stopped: %v", err)
+	}
+}
+
+// getEnv gets an environment variable or returns a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
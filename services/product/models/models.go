@@ -13,10 +13,17 @@ type Category struct {
 	Slug        string     `json:"slug" db:"slug" validate:"required,min=1,max=255"`
 	Description *string    `json:"description,omitempty" db:"description"`
 	ParentID    *uuid.UUID `json:"parent_id,omitempty" db:"parent_id"`
+	SortOrder   int        `json:"sort_order" db:"sort_order"`
 	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// CategoryNode is a category with its subcategories in sibling order
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
+
 // Product represents a product in the catalog
 type Product struct {
 	ID             uuid.UUID  `json:"id" db:"id"`
@@ -28,7 +35,10 @@ type Product struct {
 	Price          float64    `json:"price" db:"price" validate:"required,min=0"`
 	CompareAtPrice *float64   `json:"compare_at_price,omitempty" db:"compare_at_price" validate:"omitempty,min=0"`
 	Cost           *float64   `json:"cost,omitempty" db:"cost" validate:"omitempty,min=0"`
+	Currency       string     `json:"currency" db:"currency" validate:"required,len=3"`
 	Status         string     `json:"status" db:"status" validate:"required,oneof=draft active archived"`
+	RatingAverage  *float64   `json:"rating_average,omitempty" db:"rating_average"`
+	RatingCount    int        `json:"rating_count" db:"rating_count"`
 	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
 }
@@ -42,12 +52,352 @@ type ProductAttribute struct {
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
-// ProductImage represents a product image
+// ProductImage represents a product image, optionally shown for a single
+// variant. Uploaded images have a ContentHash and URLs of their derived
+// Sizes; other images link to an external URL.
 type ProductImage struct {
+	ID          uuid.UUID         `json:"id" db:"id"`
+	ProductID   uuid.UUID         `json:"product_id" db:"product_id" validate:"required"`
+	VariantID   *uuid.UUID        `json:"variant_id,omitempty" db:"variant_id"`
+	URL         string            `json:"url" db:"url" validate:"required,url,max=1000"`
+	AltText     *string           `json:"alt_text,omitempty" db:"alt_text" validate:"omitempty,max=255"`
+	SortOrder   int               `json:"sort_order" db:"sort_order"`
+	ContentHash *string           `json:"content_hash,omitempty" db:"blob_hash"`
+	Sizes       map[string]string `json:"sizes,omitempty" db:"-"`
+	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+}
+
+// ImageBlobStatus is the storage state of an image file. A blob is recorded
+// before its files are written and removed after they are deleted, so every
+// file in the store belongs to a blob.
+type ImageBlobStatus string
+
+const (
+	ImageBlobStoring   ImageBlobStatus = "storing"
+	ImageBlobStored    ImageBlobStatus = "stored"
+	ImageBlobReleasing ImageBlobStatus = "releasing"
+)
+
+// ImageBlob is an uploaded image file, shared by the product images with the
+// same content
+type ImageBlob struct {
+	Hash        string          `json:"hash" db:"hash"`
+	ContentType string          `json:"content_type" db:"content_type"`
+	SizeBytes   int64           `json:"size_bytes" db:"size_bytes"`
+	Width       int             `json:"width" db:"width"`
+	Height      int             `json:"height" db:"height"`
+	Sizes       []ImageSize     `json:"sizes" db:"sizes"`
+	Status      ImageBlobStatus `json:"status" db:"status"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
+
+// ImageSize is a derived size of an uploaded image
+type ImageSize struct {
+	Name        string `json:"name"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	ContentType string `json:"content_type"`
+}
+
+// ProductOption is an option a product is sold in, such as Size, with its
+// values in display order
+type ProductOption struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	ProductID uuid.UUID `json:"product_id" db:"product_id" validate:"required"`
+	Name      string    `json:"name" db:"name" validate:"required,min=1,max=100"`
+	Values    []string  `json:"values" db:"option_values" validate:"required,min=1"`
+	Position  int       `json:"position" db:"position"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ProductVariant is a sellable version of a product with one value for each
+// of the product's options. Price and CompareAtPrice override the product's
+// when set. Stock and order items refer to variants.
+type ProductVariant struct {
+	ID             uuid.UUID         `json:"id" db:"id"`
+	ProductID      uuid.UUID         `json:"product_id" db:"product_id" validate:"required"`
+	SKU            *string           `json:"sku,omitempty" db:"sku" validate:"omitempty,max=100"`
+	Title          string            `json:"title" db:"title"`
+	Options        map[string]string `json:"options" db:"options"`
+	Price          *float64          `json:"price,omitempty" db:"price" validate:"omitempty,min=0"`
+	CompareAtPrice *float64          `json:"compare_at_price,omitempty" db:"compare_at_price" validate:"omitempty,min=0"`
+	IsDefault      bool              `json:"is_default" db:"is_default"`
+	Position       int               `json:"position" db:"position"`
+	Images         []ProductImage    `json:"images,omitempty" db:"-"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// ProductPrice represents an explicit price for a product in a currency,
+// overriding the price derived from the base price and FX rates
+type ProductPrice struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	ProductID      uuid.UUID `json:"product_id" db:"product_id" validate:"required"`
+	Currency       string    `json:"currency" db:"currency" validate:"required,len=3"`
+	Price          float64   `json:"price" db:"price" validate:"min=0"`
+	CompareAtPrice *float64  `json:"compare_at_price,omitempty" db:"compare_at_price" validate:"omitempty,min=0"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// FXRate represents an exchange rate effective from a point in time, where
+// one unit of BaseCurrency equals Rate units of QuoteCurrency
+type FXRate struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	BaseCurrency  string    `json:"base_currency" db:"base_currency" validate:"required,len=3"`
+	QuoteCurrency string    `json:"quote_currency" db:"quote_currency" validate:"required,len=3"`
+	Rate          float64   `json:"rate" db:"rate" validate:"required,gt=0"`
+	EffectiveAt   time.Time `json:"effective_at" db:"effective_at" validate:"required"`
+	Source        *string   `json:"source,omitempty" db:"source" validate:"omitempty,max=100"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// ProductStock is the unreserved stock of a product, the sum of the stock
+// mirrored for its variants. UpdatedAt is when any of it last changed.
+type ProductStock struct {
+	ProductID uuid.UUID `json:"product_id" db:"product_id"`
+	Available int       `json:"available" db:"available"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	SyncedAt  time.Time `json:"synced_at" db:"synced_at"`
+}
+
+// VariantStock is the unreserved stock of a product variant mirrored from the
+// inventory service. UpdatedAt is when the stock last changed there.
+type VariantStock struct {
+	VariantID uuid.UUID `json:"variant_id" db:"variant_id"`
+	ProductID uuid.UUID `json:"product_id" db:"product_id"`
+	Available int       `json:"available" db:"available"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	SyncedAt  time.Time `json:"synced_at" db:"synced_at"`
+}
+
+// CatalogImportStatus is the processing state of a catalog import
+type CatalogImportStatus string
+
+const (
+	CatalogImportPending   CatalogImportStatus = "pending"
+	CatalogImportRunning   CatalogImportStatus = "running"
+	CatalogImportCompleted CatalogImportStatus = "completed"
+	CatalogImportFailed    CatalogImportStatus = "failed"
+)
+
+// CatalogImport is a bulk import of a CSV or JSON Lines catalog file. Rows
+// before NextRow have been processed; a dry run validates and counts rows
+// without keeping any change.
+type CatalogImport struct {
+	ID           uuid.UUID           `json:"id" db:"id"`
+	Format       string              `json:"format" db:"format" validate:"required,oneof=csv jsonl"`
+	DryRun       bool                `json:"dry_run" db:"dry_run"`
+	Status       CatalogImportStatus `json:"status" db:"status"`
+	TotalRows    int                 `json:"total_rows" db:"total_rows"`
+	NextRow      int                 `json:"next_row" db:"next_row"`
+	CreatedCount int                 `json:"created_count" db:"created_count"`
+	UpdatedCount int                 `json:"updated_count" db:"updated_count"`
+	FailedCount  int                 `json:"failed_count" db:"failed_count"`
+	Error        *string             `json:"error,omitempty" db:"error"`
+	LockedUntil  *time.Time          `json:"-" db:"locked_until"`
+	CreatedAt    time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at" db:"updated_at"`
+	CompletedAt  *time.Time          `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// CatalogImportError is a row of a catalog import that could not be applied.
+// Row numbers start at 1 with the first data row.
+type CatalogImportError struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	ImportID  uuid.UUID `json:"import_id" db:"import_id"`
+	RowNumber int       `json:"row_number" db:"row_number"`
+	SKU       *string   `json:"sku,omitempty" db:"sku"`
+	Slug      *string   `json:"slug,omitempty" db:"slug"`
+	Message   string    `json:"message" db:"message"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Product statuses. Only active products are listed in search and can be
+// ordered; archived ones stay readable for the orders that refer to them.
+const (
+	ProductStatusDraft    = "draft"
+	ProductStatusActive   = "active"
+	ProductStatusArchived = "archived"
+)
+
+// Sources of a product status change
+const (
+	StatusSourceManual   = "manual"
+	StatusSourceSchedule = "schedule"
+	StatusSourceImport   = "import"
+)
+
+// ProductStatusChange is an entry in the audit trail of a product's status.
+// FromStatus is nil for the status the product was created with.
+type ProductStatusChange struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	ProductID  uuid.UUID `json:"product_id" db:"product_id"`
+	FromStatus *string   `json:"from_status,omitempty" db:"from_status"`
+	ToStatus   string    `json:"to_status" db:"to_status"`
+	Source     string    `json:"source" db:"source"`
+	Note       *string   `json:"note,omitempty" db:"note"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// ScheduleState is the processing state of a scheduled status change
+type ScheduleState string
+
+const (
+	SchedulePending   ScheduleState = "pending"
+	ScheduleApplied   ScheduleState = "applied"
+	ScheduleFailed    ScheduleState = "failed"
+	ScheduleCancelled ScheduleState = "cancelled"
+)
+
+// ProductStatusSchedule is a status change planned for RunAt. Error explains
+// why a failed change could not be applied.
+type ProductStatusSchedule struct {
+	ID          uuid.UUID     `json:"id" db:"id"`
+	ProductID   uuid.UUID     `json:"product_id" db:"product_id"`
+	Status      string        `json:"status" db:"status" validate:"required,oneof=draft active archived"`
+	RunAt       time.Time     `json:"run_at" db:"run_at" validate:"required"`
+	State       ScheduleState `json:"state" db:"state"`
+	Error       *string       `json:"error,omitempty" db:"error"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	ProcessedAt *time.Time    `json:"processed_at,omitempty" db:"processed_at"`
+}
+
+// Sources of a product price change
+const (
+	PriceChangeManual = "manual"
+	PriceChangeSale   = "sale"
+	PriceChangeImport = "import"
+)
+
+// PriceChange is an entry in the price history of a product. The old values
+// are what the product cost before the change; OldPrice is nil for the prices
+// it was created with. BelowCost flags a new price under the cost.
+type PriceChange struct {
+	ID                uuid.UUID `json:"id" db:"id"`
+	ProductID         uuid.UUID `json:"product_id" db:"product_id"`
+	OldPrice          *float64  `json:"old_price,omitempty" db:"old_price"`
+	NewPrice          float64   `json:"new_price" db:"new_price"`
+	OldCompareAtPrice *float64  `json:"old_compare_at_price,omitempty" db:"old_compare_at_price"`
+	NewCompareAtPrice *float64  `json:"new_compare_at_price,omitempty" db:"new_compare_at_price"`
+	OldCost           *float64  `json:"old_cost,omitempty" db:"old_cost"`
+	NewCost           *float64  `json:"new_cost,omitempty" db:"new_cost"`
+	Source            string    `json:"source" db:"source"`
+	Actor             *string   `json:"actor,omitempty" db:"actor" validate:"omitempty,max=100"`
+	Reason            *string   `json:"reason,omitempty" db:"reason"`
+	BelowCost         bool      `json:"below_cost" db:"below_cost"`
+	ChangedAt         time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// SaleState is the state of a timed sale
+type SaleState string
+
+const (
+	SaleScheduled SaleState = "scheduled"
+	SaleActive    SaleState = "active"
+	SaleEnded     SaleState = "ended"
+	SaleCancelled SaleState = "cancelled"
+	SaleFailed    SaleState = "failed"
+)
+
+// ProductSale is a timed sale of a product. The regular prices are captured
+// when the sale starts and restored when it ends.
+type ProductSale struct {
+	ID                    uuid.UUID  `json:"id" db:"id"`
+	ProductID             uuid.UUID  `json:"product_id" db:"product_id"`
+	SalePrice             float64    `json:"sale_price" db:"sale_price" validate:"min=0"`
+	StartsAt              time.Time  `json:"starts_at" db:"starts_at" validate:"required"`
+	EndsAt                time.Time  `json:"ends_at" db:"ends_at" validate:"required,gtfield=StartsAt"`
+	State                 SaleState  `json:"state" db:"state"`
+	RegularPrice          *float64   `json:"regular_price,omitempty" db:"regular_price"`
+	RegularCompareAtPrice *float64   `json:"regular_compare_at_price,omitempty" db:"regular_compare_at_price"`
+	Actor                 *string    `json:"actor,omitempty" db:"actor" validate:"omitempty,max=100"`
+	Reason                *string    `json:"reason,omitempty" db:"reason"`
+	Error                 *string    `json:"error,omitempty" db:"error"`
+	CreatedAt             time.Time  `json:"created_at" db:"created_at"`
+	StartedAt             *time.Time `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt            *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// ReviewStatus is the moderation state of a product review
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "pending"
+	ReviewApproved ReviewStatus = "approved"
+	ReviewRejected ReviewStatus = "rejected"
+)
+
+// ProductReview is a user's star rating and review of a product. Verified
+// purchases are reviews by users who received the product.
+type ProductReview struct {
+	ID               uuid.UUID    `json:"id" db:"id"`
+	ProductID        uuid.UUID    `json:"product_id" db:"product_id"`
+	UserID           uuid.UUID    `json:"user_id" db:"user_id"`
+	Rating           int          `json:"rating" db:"rating" validate:"required,min=1,max=5"`
+	Title            *string      `json:"title,omitempty" db:"title" validate:"omitempty,max=200"`
+	Body             *string      `json:"body,omitempty" db:"body"`
+	VerifiedPurchase bool         `json:"verified_purchase" db:"verified_purchase"`
+	Status           ReviewStatus `json:"status" db:"status"`
+	ModerationNote   *string      `json:"moderation_note,omitempty" db:"moderation_note"`
+	ModeratedAt      *time.Time   `json:"moderated_at,omitempty" db:"moderated_at"`
+	HelpfulCount     int          `json:"helpful_count" db:"helpful_count"`
+	CreatedAt        time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// Bundle pricing modes
+const (
+	BundlePricingFixed      = "fixed"
+	BundlePricingComponents = "components"
+)
+
+// ProductBundle makes a product a bundle (kit) of other products. A fixed
+// bundle sells at its own price; a components bundle at the sum of its
+// components' prices less DiscountPercent.
+type ProductBundle struct {
+	ProductID       uuid.UUID         `json:"product_id" db:"product_id"`
+	Pricing         string            `json:"pricing" db:"pricing" validate:"required,oneof=fixed components"`
+	DiscountPercent float64           `json:"discount_percent" db:"discount_percent" validate:"min=0,lt=100"`
+	CreatedAt       time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at" db:"updated_at"`
+	Components      []BundleComponent `json:"components" db:"-"`
+	Available       *int              `json:"available,omitempty" db:"-"`
+}
+
+// BundleComponent is a quantity of a product contained in each unit of a
+// bundle. Without a VariantID it is the product's default variant.
+type BundleComponent struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	BundleID  uuid.UUID  `json:"bundle_id" db:"bundle_id"`
+	ProductID uuid.UUID  `json:"product_id" db:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty" db:"variant_id"`
+	Quantity  int        `json:"quantity" db:"quantity" validate:"required,min=1"`
+	Position  int        `json:"position" db:"position"`
+}
+
+// Product link types
+const (
+	ProductLinkRelated   = "related"
+	ProductLinkUpsell    = "upsell"
+	ProductLinkCrossSell = "cross_sell"
+)
+
+// ProductLink is a curated recommendation of a related product
+type ProductLink struct {
 	ID        uuid.UUID `json:"id" db:"id"`
 	ProductID uuid.UUID `json:"product_id" db:"product_id" validate:"required"`
-	URL       string    `json:"url" db:"url" validate:"required,url,max=1000"`
-	AltText   *string   `json:"alt_text,omitempty" db:"alt_text" validate:"omitempty,max=255"`
-	SortOrder int       `json:"sort_order" db:"sort_order"`
+	RelatedID uuid.UUID `json:"related_id" db:"related_id" validate:"required"`
+	Type      string    `json:"type" db:"type" validate:"required,oneof=related upsell cross_sell"`
+	Position  int       `json:"position" db:"position"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
+
+// ProductCoPurchase counts the orders that contained both products
+type ProductCoPurchase struct {
+	ProductID  uuid.UUID `json:"product_id" db:"product_id"`
+	RelatedID  uuid.UUID `json:"related_id" db:"related_id"`
+	Orders     int       `json:"orders" db:"orders"`
+	ComputedAt time.Time `json:"computed_at" db:"computed_at"`
+}
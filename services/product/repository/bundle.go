@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/product/models"
+)
+
+const bundleColumns = `product_id, pricing, discount_percent, created_at, updated_at`
+
+const bundleComponentColumns = `id, bundle_id, product_id, variant_id, quantity, position`
+
+// BundleRepository provides access to product bundles and their components
+type BundleRepository struct {
+	db DBTX
+}
+
+// NewBundleRepository creates a new BundleRepository
+func NewBundleRepository(db DBTX) *BundleRepository {
+	return &BundleRepository{db: db}
+}
+
+// Get returns the bundle definition of a product, or ErrNotFound when the
+// product is not a bundle
+func (r *BundleRepository) Get(ctx context.Context, productID uuid.UUID) (*models.ProductBundle, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+bundleColumns+` FROM product_bundles WHERE product_id = $1`, productID)
+	b, err := collectOne[models.ProductBundle](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle: %w", err)
+	}
+	return b, nil
+}
+
+// Upsert creates or replaces the pricing of a bundle
+func (r *BundleRepository) Upsert(ctx context.Context, b *models.ProductBundle) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO product_bundles (product_id, pricing, discount_percent)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (product_id) DO UPDATE
+		SET pricing = EXCLUDED.pricing, discount_percent = EXCLUDED.discount_percent, updated_at = NOW()
+		RETURNING created_at, updated_at`,
+		b.ProductID, b.Pricing, b.DiscountPercent,
+	).Scan(&b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save bundle: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the bundle definition of a product and its components
+func (r *BundleRepository) Delete(ctx context.Context, productID uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM product_bundles WHERE product_id = $1`, productID)
+	if err != nil {
+		return fmt.Errorf("failed to delete bundle: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListComponents returns the components of a bundle in display order
+func (r *BundleRepository) ListComponents(ctx context.Context, bundleID uuid.UUID) ([]models.BundleComponent, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+bundleComponentColumns+` FROM product_bundle_components
+		WHERE bundle_id = $1 ORDER BY position, id`, bundleID)
+	components, err := collectAll[models.BundleComponent](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bundle components: %w", err)
+	}
+	return components, nil
+}
+
+// ReplaceComponents replaces the components of a bundle, numbering their
+// positions in the given order
+func (r *BundleRepository) ReplaceComponents(ctx context.Context, bundleID uuid.UUID, components []models.BundleComponent) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM product_bundle_components WHERE bundle_id = $1`, bundleID); err != nil {
+		return fmt.Errorf("failed to clear bundle components: %w", err)
+	}
+	for i := range components {
+		c := &components[i]
+		c.BundleID, c.Position = bundleID, i
+		err := r.db.QueryRow(ctx, `
+			INSERT INTO product_bundle_components (bundle_id, product_id, variant_id, quantity, position)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id`,
+			c.BundleID, c.ProductID, c.VariantID, c.Quantity, c.Position,
+		).Scan(&c.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create bundle component: %w", err)
+		}
+	}
+	return nil
+}
+
+// IsComponent reports whether a product is a component of any bundle
+func (r *BundleRepository) IsComponent(ctx context.Context, productID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM product_bundle_components WHERE product_id = $1)`, productID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check bundle components: %w", err)
+	}
+	return exists, nil
+}
+
+// IsVariantComponent reports whether a variant is a component of any bundle
+func (r *BundleRepository) IsVariantComponent(ctx context.Context, variantID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM product_bundle_components WHERE variant_id = $1)`, variantID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check bundle components: %w", err)
+	}
+	return exists, nil
+}
+
+// Available returns the number of complete bundles the mirrored stock of
+// their components makes up, or nil until every component's stock was synced
+func (r *BundleRepository) Available(ctx context.Context, bundleID uuid.UUID) (*int, error) {
+	var available *int
+	err := r.db.QueryRow(ctx, `SELECT available FROM product_availability WHERE product_id = $1`, bundleID).Scan(&available)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle availability: %w", err)
+	}
+	return available, nil
+}
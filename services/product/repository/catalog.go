@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/product/models"
+)
+
+const catalogImportColumns = `id, format, dry_run, status, total_rows, next_row, created_count, updated_count,
+	failed_count, error, locked_until, created_at, updated_at, completed_at`
+
+const catalogImportErrorColumns = `id, import_id, row_number, sku, slug, message, created_at`
+
+// CatalogImage is an image of an exported catalog entry
+type CatalogImage struct {
+	URL     string  `json:"url"`
+	AltText *string `json:"alt_text,omitempty"`
+}
+
+// CatalogEntry is a product with its category path, attributes and images as
+// written to a catalog export
+type CatalogEntry struct {
+	models.Product
+	CategoryPath *string           `db:"category_path"`
+	Attributes   map[string]string `db:"attributes"`
+	Images       []CatalogImage    `db:"images"`
+}
+
+// CatalogFilter narrows a catalog export. Empty fields match every product.
+type CatalogFilter struct {
+	Status      string
+	CategoryIDs []uuid.UUID
+}
+
+// CatalogRepository provides access to catalog imports and exports
+type CatalogRepository struct {
+	db DBTX
+}
+
+// NewCatalogRepository creates a new CatalogRepository
+func NewCatalogRepository(db DBTX) *CatalogRepository {
+	return &CatalogRepository{db: db}
+}
+
+// CreateImport inserts a new import together with its file
+func (r *CatalogRepository) CreateImport(ctx context.Context, imp *models.CatalogImport, content []byte) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO catalog_imports (format, dry_run, status, content, total_rows)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`,
+		imp.Format, imp.DryRun, imp.Status, content, imp.TotalRows,
+	).Scan(&imp.ID, &imp.CreatedAt, &imp.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create catalog import: %w", err)
+	}
+	return nil
+}
+
+// GetImport returns an import by its ID
+func (r *CatalogRepository) GetImport(ctx context.Context, id uuid.UUID) (*models.CatalogImport, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+catalogImportColumns+` FROM catalog_imports WHERE id = $1`, id)
+	imp, err := collectOne[models.CatalogImport](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog import: %w", err)
+	}
+	return imp, nil
+}
+
+// GetImportForUpdate returns an import by its ID and locks it until the
+// surrounding transaction ends
+func (r *CatalogRepository) GetImportForUpdate(ctx context.Context, id uuid.UUID) (*models.CatalogImport, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+catalogImportColumns+` FROM catalog_imports WHERE id = $1 FOR UPDATE`, id)
+	imp, err := collectOne[models.CatalogImport](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog import: %w", err)
+	}
+	return imp, nil
+}
+
+// GetImportContent returns the file of an import
+func (r *CatalogRepository) GetImportContent(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	var content []byte
+	err := r.db.QueryRow(ctx, `SELECT content FROM catalog_imports WHERE id = $1`, id).Scan(&content)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get catalog import content: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog import content: %w", err)
+	}
+	return content, nil
+}
+
+// ClaimImport marks the oldest pending import, or a running one whose lock
+// expired, as running and locks it for lease. It returns ErrNotFound when
+// there is nothing to process.
+func (r *CatalogRepository) ClaimImport(ctx context.Context, lease time.Duration) (*models.CatalogImport, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE catalog_imports
+		SET status = 'running', locked_until = NOW() + $1::float8 * INTERVAL '1 second', updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM catalog_imports
+			WHERE status IN ('pending', 'running') AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY created_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+catalogImportColumns, lease.Seconds())
+	imp, err := collectOne[models.CatalogImport](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim catalog import: %w", err)
+	}
+	return imp, nil
+}
+
+// UpdateImport persists the progress and status of an import that was at
+// row from. Running imports stay locked for lease; other states release the
+// lock. It returns ErrNotFound when the import moved on in the meantime.
+func (r *CatalogRepository) UpdateImport(ctx context.Context, imp *models.CatalogImport, from int, lease time.Duration) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE catalog_imports
+		SET status = $3, next_row = $4, created_count = $5, updated_count = $6, failed_count = $7,
+			error = $8, completed_at = $9, updated_at = NOW(),
+			locked_until = CASE WHEN $3 = 'running' THEN NOW() + $10::float8 * INTERVAL '1 second' END
+		WHERE id = $1 AND next_row = $2
+		RETURNING locked_until, updated_at`,
+		imp.ID, from, imp.Status, imp.NextRow, imp.CreatedCount, imp.UpdatedCount, imp.FailedCount,
+		imp.Error, imp.CompletedAt, lease.Seconds(),
+	).Scan(&imp.LockedUntil, &imp.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to update catalog import: %w", ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update catalog import: %w", err)
+	}
+	return nil
+}
+
+// CreateImportErrors records rows of an import that could not be applied.
+// Rows already recorded are skipped.
+func (r *CatalogRepository) CreateImportErrors(ctx context.Context, importID uuid.UUID, errs []models.CatalogImportError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	rowNumbers := make([]int, len(errs))
+	skus := make([]*string, len(errs))
+	slugs := make([]*string, len(errs))
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		rowNumbers[i], skus[i], slugs[i], messages[i] = e.RowNumber, e.SKU, e.Slug, e.Message
+	}
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO catalog_import_errors (import_id, row_number, sku, slug, message)
+		SELECT $1, n, sku, slug, message
+		FROM unnest($2::int[], $3::text[], $4::text[], $5::text[]) AS e(n, sku, slug, message)
+		ON CONFLICT (import_id, row_number) DO NOTHING`,
+		importID, rowNumbers, skus, slugs, messages)
+	if err != nil {
+		return fmt.Errorf("failed to record catalog import errors: %w", err)
+	}
+	return nil
+}
+
+// DeleteImportErrors removes the recorded row errors of an import
+func (r *CatalogRepository) DeleteImportErrors(ctx context.Context, importID uuid.UUID) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM catalog_import_errors WHERE import_id = $1`, importID); err != nil {
+		return fmt.Errorf("failed to delete catalog import errors: %w", err)
+	}
+	return nil
+}
+
+// ListImportErrors returns a page of the row errors of an import in row order
+func (r *CatalogRepository) ListImportErrors(ctx context.Context, importID uuid.UUID, limit, offset int) ([]models.CatalogImportError, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+catalogImportErrorColumns+` FROM catalog_import_errors
+		WHERE import_id = $1 ORDER BY row_number
+		LIMIT $2 OFFSET $3`, importID, limit, offset)
+	errs, err := collectAll[models.CatalogImportError](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list catalog import errors: %w", err)
+	}
+	return errs, nil
+}
+
+// ListExportAttributeKeys returns every attribute key used by the products
+// matching f, in key order
+func (r *CatalogRepository) ListExportAttributeKeys(ctx context.Context, f CatalogFilter) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT DISTINCT a.key FROM product_attributes a
+		JOIN products p ON p.id = a.product_id
+		WHERE ($1::text = '' OR p.status = $1) AND ($2::uuid[] IS NULL OR p.category_id = ANY($2))
+		ORDER BY a.key`, f.Status, f.CategoryIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attribute keys: %w", err)
+	}
+	keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attribute keys: %w", err)
+	}
+	return keys, nil
+}
+
+// MaxExportImages returns the largest number of images of a product
+// matching f
+func (r *CatalogRepository) MaxExportImages(ctx context.Context, f CatalogFilter) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(MAX(n), 0) FROM (
+			SELECT COUNT(*) AS n FROM product_images i
+			JOIN products p ON p.id = i.product_id
+			WHERE ($1::text = '' OR p.status = $1) AND ($2::uuid[] IS NULL OR p.category_id = ANY($2))
+			GROUP BY i.product_id
+		) counts`, f.Status, f.CategoryIDs).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count product images: %w", err)
+	}
+	return n, nil
+}
+
+// StreamExport calls fn for every product matching f in slug order, reading
+// rows from the database as fn consumes them
+func (r *CatalogRepository) StreamExport(ctx context.Context, f CatalogFilter, fn func(*CatalogEntry) error) error {
+	rows, err := r.db.Query(ctx, `
+		WITH RECURSIVE category_paths AS (
+			SELECT id, name::text AS path FROM categories WHERE parent_id IS NULL
+			UNION ALL
+			SELECT c.id, cp.path || ' > ' || c.name FROM categories c
+			JOIN category_paths cp ON c.parent_id = cp.id
+		)
+		SELECT `+productColumns+`,
+			(SELECT path FROM category_paths WHERE category_paths.id = products.category_id) AS category_path,
+			(SELECT COALESCE(jsonb_object_agg(key, value), '{}') FROM product_attributes
+				WHERE product_id = products.id) AS attributes,
+			(SELECT COALESCE(jsonb_agg(jsonb_build_object('url', url, 'alt_text', alt_text) ORDER BY sort_order, created_at, id), '[]')
+				FROM product_images WHERE product_id = products.id) AS images
+		FROM products
+		WHERE ($1::text = '' OR status = $1) AND ($2::uuid[] IS NULL OR category_id = ANY($2))
+		ORDER BY slug`, f.Status, f.CategoryIDs)
+	if err != nil {
+		return fmt.Errorf("failed to export catalog: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := pgx.RowToStructByName[CatalogEntry](rows)
+		if err != nil {
+			return fmt.Errorf("failed to read catalog entry: %w", err)
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to export catalog: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/product/models"
+)
+
+const categoryColumns = `id, name, slug, description, parent_id, sort_order, created_at, updated_at`
+
+// categoryTreeLock is the advisory lock key that serializes changes to the
+// shape of the category tree
+const categoryTreeLock = "product.categories.tree"
+
+// CategoryRepository provides access to categories and their hierarchy
+type CategoryRepository struct {
+	db DBTX
+}
+
+// NewCategoryRepository creates a new CategoryRepository
+func NewCategoryRepository(db DBTX) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// LockTree takes a transaction-scoped lock serializing tree changes, so
+// concurrent moves cannot combine into a cycle
+func (r *CategoryRepository) LockTree(ctx context.Context) error {
+	if _, err := r.db.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, categoryTreeLock); err != nil {
+		return fmt.Errorf("failed to lock category tree: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns a category by its ID
+func (r *CategoryRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Category, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+categoryColumns+` FROM categories WHERE id = $1`, id)
+	category, err := collectOne[models.Category](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+	return category, nil
+}
+
+// GetBySlug returns a category by its slug
+func (r *CategoryRepository) GetBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+categoryColumns+` FROM categories WHERE slug = $1`, slug)
+	category, err := collectOne[models.Category](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+	return category, nil
+}
+
+// ListAll returns every category in sibling order
+func (r *CategoryRepository) ListAll(ctx context.Context) ([]models.Category, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+categoryColumns+` FROM categories ORDER BY sort_order, name, id`)
+	categories, err := collectAll[models.Category](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+	return categories, nil
+}
+
+// ListSubtree returns a category and its descendants down to maxDepth levels
+// below it, or all of them when maxDepth is negative, in sibling order
+func (r *CategoryRepository) ListSubtree(ctx context.Context, id uuid.UUID, maxDepth int) ([]models.Category, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH RECURSIVE tree AS (
+			SELECT c.id, 0 AS depth, ARRAY[c.id] AS path FROM categories c WHERE c.id = $1
+			UNION ALL
+			SELECT c.id, t.depth + 1, t.path || c.id
+			FROM categories c JOIN tree t ON c.parent_id = t.id
+			WHERE NOT c.id = ANY(t.path) AND ($2 < 0 OR t.depth < $2)
+		)
+		SELECT c.id, c.name, c.slug, c.description, c.parent_id, c.sort_order, c.created_at, c.updated_at
+		FROM tree t JOIN categories c ON c.id = t.id
+		ORDER BY t.depth, c.sort_order, c.name, c.id`, id, maxDepth)
+	categories, err := collectAll[models.Category](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list category subtree: %w", err)
+	}
+	return categories, nil
+}
+
+// ListSubtreeIDs returns the IDs of a category and all of its descendants
+func (r *CategoryRepository) ListSubtreeIDs(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH RECURSIVE tree AS (
+			SELECT id, ARRAY[id] AS path FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, t.path || c.id
+			FROM categories c JOIN tree t ON c.parent_id = t.id
+			WHERE NOT c.id = ANY(t.path)
+		)
+		SELECT id FROM tree`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list category subtree: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to read category id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list category subtree: %w", err)
+	}
+	return ids, nil
+}
+
+// ListAncestors returns the path from the root down to and including the
+// given category
+func (r *CategoryRepository) ListAncestors(ctx context.Context, id uuid.UUID) ([]models.Category, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH RECURSIVE chain AS (
+			SELECT id, parent_id, 0 AS depth, ARRAY[id] AS path FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.parent_id, ch.depth + 1, ch.path || c.id
+			FROM categories c JOIN chain ch ON c.id = ch.parent_id
+			WHERE NOT c.id = ANY(ch.path)
+		)
+		SELECT c.id, c.name, c.slug, c.description, c.parent_id, c.sort_order, c.created_at, c.updated_at
+		FROM chain ch JOIN categories c ON c.id = ch.id
+		ORDER BY ch.depth DESC`, id)
+	categories, err := collectAll[models.Category](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list category ancestors: %w", err)
+	}
+	return categories, nil
+}
+
+// IsDescendant reports whether candidate is id itself or lies below it
+func (r *CategoryRepository) IsDescendant(ctx context.Context, id, candidate uuid.UUID) (bool, error) {
+	var found bool
+	err := r.db.QueryRow(ctx, `
+		WITH RECURSIVE chain AS (
+			SELECT id, parent_id, ARRAY[id] AS path FROM categories WHERE id = $2
+			UNION ALL
+			SELECT c.id, c.parent_id, ch.path || c.id
+			FROM categories c JOIN chain ch ON c.id = ch.parent_id
+			WHERE NOT c.id = ANY(ch.path)
+		)
+		SELECT EXISTS (SELECT 1 FROM chain WHERE id = $1)`, id, candidate).Scan(&found)
+	if err != nil {
+		return false, fmt.Errorf("failed to check category ancestry: %w", err)
+	}
+	return found, nil
+}
+
+// ListChildrenForUpdate returns the direct children of parentID, or the root
+// categories when it is nil, in sibling order and locks them until the
+// surrounding transaction ends
+func (r *CategoryRepository) ListChildrenForUpdate(ctx context.Context, parentID *uuid.UUID) ([]models.Category, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+categoryColumns+` FROM categories
+		WHERE parent_id IS NOT DISTINCT FROM $1
+		ORDER BY sort_order, name, id
+		FOR UPDATE`, parentID)
+	categories, err := collectAll[models.Category](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list child categories: %w", err)
+	}
+	return categories, nil
+}
+
+// Create inserts a new category
+func (r *CategoryRepository) Create(ctx context.Context, c *models.Category) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO categories (name, slug, description, parent_id, sort_order)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`,
+		c.Name, c.Slug, c.Description, c.ParentID, c.SortOrder,
+	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create category: %w", err)
+	}
+	return nil
+}
+
+// Update persists the name, slug, description, parent and sort order of a
+// category
+func (r *CategoryRepository) Update(ctx context.Context, c *models.Category) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE categories
+		SET name = $2, slug = $3, description = $4, parent_id = $5, sort_order = $6, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`,
+		c.ID, c.Name, c.Slug, c.Description, c.ParentID, c.SortOrder,
+	).Scan(&c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update category: %w", err)
+	}
+	return nil
+}
+
+// SetSortOrders renumbers the given categories 0..n-1 in slice order
+func (r *CategoryRepository) SetSortOrders(ctx context.Context, ids []uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE categories c SET sort_order = o.n - 1, updated_at = NOW()
+		FROM unnest($1::uuid[]) WITH ORDINALITY AS o(id, n)
+		WHERE c.id = o.id AND c.sort_order <> o.n - 1`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to reorder categories: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/product/models"
+)
+
+const imageBlobColumns = `hash, content_type, size_bytes, width, height, sizes, status, created_at`
+
+// ImageRepository provides access to uploaded image files and the product
+// images using them
+type ImageRepository struct {
+	db DBTX
+}
+
+// NewImageRepository creates a new ImageRepository
+func NewImageRepository(db DBTX) *ImageRepository {
+	return &ImageRepository{db: db}
+}
+
+// LockBlob takes a transaction-scoped lock on a content hash, serializing
+// the upload of a file with its removal
+func (r *ImageRepository) LockBlob(ctx context.Context, hash string) error {
+	if _, err := r.db.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "image_blob:"+hash); err != nil {
+		return fmt.Errorf("failed to lock image blob: %w", err)
+	}
+	return nil
+}
+
+// GetBlob returns an image file by its content hash
+func (r *ImageRepository) GetBlob(ctx context.Context, hash string) (*models.ImageBlob, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+imageBlobColumns+` FROM image_blobs WHERE hash = $1`, hash)
+	blob, err := collectOne[models.ImageBlob](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image blob: %w", err)
+	}
+	return blob, nil
+}
+
+// CreateBlob inserts an image file
+func (r *ImageRepository) CreateBlob(ctx context.Context, b *models.ImageBlob) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO image_blobs (hash, content_type, size_bytes, width, height, sizes, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at`,
+		b.Hash, b.ContentType, b.SizeBytes, b.Width, b.Height, b.Sizes, b.Status,
+	).Scan(&b.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create image blob: %w", err)
+	}
+	return nil
+}
+
+// UpdateBlobStatus sets the storage state of an image file
+func (r *ImageRepository) UpdateBlobStatus(ctx context.Context, hash string, status models.ImageBlobStatus) error {
+	tag, err := r.db.Exec(ctx, `UPDATE image_blobs SET status = $2 WHERE hash = $1`, hash, status)
+	if err != nil {
+		return fmt.Errorf("failed to update image blob status: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteBlob removes an image file that no product image uses anymore
+func (r *ImageRepository) DeleteBlob(ctx context.Context, hash string) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM image_blobs WHERE hash = $1`, hash); err != nil {
+		return fmt.Errorf("failed to delete image blob: %w", err)
+	}
+	return nil
+}
+
+// CountBlobUses returns the number of product images using an image file
+func (r *ImageRepository) CountBlobUses(ctx context.Context, hash string) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM product_images WHERE blob_hash = $1`, hash).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count image blob uses: %w", err)
+	}
+	return n, nil
+}
+
+// ListUnusedBlobs returns up to limit image files no product image uses,
+// such as those of images replaced by a catalog import. Files recorded after
+// createdBefore are left to the upload that may still be adding them, unless
+// their removal already started.
+func (r *ImageRepository) ListUnusedBlobs(ctx context.Context, createdBefore time.Time, limit int) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT b.hash FROM image_blobs b
+		WHERE NOT EXISTS (SELECT 1 FROM product_images i WHERE i.blob_hash = b.hash)
+			AND (b.status = $1 OR b.created_at < $2)
+		ORDER BY b.created_at
+		LIMIT $3`, models.ImageBlobReleasing, createdBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unused image blobs: %w", err)
+	}
+	hashes, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unused image blobs: %w", err)
+	}
+	return hashes, nil
+}
+
+// GetImage returns a product image by its ID
+func (r *ImageRepository) GetImage(ctx context.Context, id uuid.UUID) (*models.ProductImage, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+imageColumns+` FROM product_images WHERE id = $1`, id)
+	img, err := collectOne[models.ProductImage](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product image: %w", err)
+	}
+	return img, nil
+}
+
+// GetImageByHash returns the image of a product with the given content
+func (r *ImageRepository) GetImageByHash(ctx context.Context, productID uuid.UUID, hash string) (*models.ProductImage, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+imageColumns+` FROM product_images
+		WHERE product_id = $1 AND blob_hash = $2
+		ORDER BY sort_order LIMIT 1`, productID, hash)
+	img, err := collectOne[models.ProductImage](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product image: %w", err)
+	}
+	return img, nil
+}
+
+// CreateImage inserts a product image
+func (r *ImageRepository) CreateImage(ctx context.Context, img *models.ProductImage) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO product_images (product_id, url, alt_text, sort_order, blob_hash)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		img.ProductID, img.URL, img.AltText, img.SortOrder, img.ContentHash,
+	).Scan(&img.ID, &img.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create product image: %w", err)
+	}
+	return nil
+}
+
+// DeleteImage removes a product image
+func (r *ImageRepository) DeleteImage(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM product_images WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete product image: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetImageOrder renumbers the given images 0..n-1 in slice order
+func (r *ImageRepository) SetImageOrder(ctx context.Context, ids []uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE product_images i SET sort_order = o.n - 1
+		FROM unnest($1::uuid[]) WITH ORDINALITY AS o(id, n)
+		WHERE i.id = o.id AND i.sort_order <> o.n - 1`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to reorder product images: %w", err)
+	}
+	return nil
+}
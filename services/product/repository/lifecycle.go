@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/product/models"
+)
+
+const statusChangeColumns = `id, product_id, from_status, to_status, source, note, created_at`
+
+const statusScheduleColumns = `id, product_id, status, run_at, state, error, created_at, processed_at`
+
+// LifecycleRepository provides access to the status history and scheduled
+// status changes of products
+type LifecycleRepository struct {
+	db DBTX
+}
+
+// NewLifecycleRepository creates a new LifecycleRepository
+func NewLifecycleRepository(db DBTX) *LifecycleRepository {
+	return &LifecycleRepository{db: db}
+}
+
+// AddStatusChange records a status change in a product's audit trail
+func (r *LifecycleRepository) AddStatusChange(ctx context.Context, c *models.ProductStatusChange) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO product_status_history (product_id, from_status, to_status, source, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		c.ProductID, c.FromStatus, c.ToStatus, c.Source, c.Note,
+	).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record product status change: %w", err)
+	}
+	return nil
+}
+
+// ListStatusChanges returns the status changes of a product, oldest first
+func (r *LifecycleRepository) ListStatusChanges(ctx context.Context, productID uuid.UUID) ([]models.ProductStatusChange, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+statusChangeColumns+` FROM product_status_history
+		WHERE product_id = $1 ORDER BY created_at, id`, productID)
+	changes, err := collectAll[models.ProductStatusChange](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list product status changes: %w", err)
+	}
+	return changes, nil
+}
+
+// CreateSchedule inserts a scheduled status change
+func (r *LifecycleRepository) CreateSchedule(ctx context.Context, s *models.ProductStatusSchedule) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO product_status_schedules (product_id, status, run_at, state)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		s.ProductID, s.Status, s.RunAt, s.State,
+	).Scan(&s.ID, &s.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create status schedule: %w", err)
+	}
+	return nil
+}
+
+// ListSchedules returns the scheduled status changes of a product by time
+func (r *LifecycleRepository) ListSchedules(ctx context.Context, productID uuid.UUID) ([]models.ProductStatusSchedule, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+statusScheduleColumns+` FROM product_status_schedules
+		WHERE product_id = $1 ORDER BY run_at, created_at`, productID)
+	schedules, err := collectAll[models.ProductStatusSchedule](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list status schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// GetScheduleForUpdate returns a scheduled status change and locks it until
+// the surrounding transaction ends
+func (r *LifecycleRepository) GetScheduleForUpdate(ctx context.Context, id uuid.UUID) (*models.ProductStatusSchedule, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+statusScheduleColumns+` FROM product_status_schedules WHERE id = $1 FOR UPDATE`, id)
+	s, err := collectOne[models.ProductStatusSchedule](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status schedule: %w", err)
+	}
+	return s, nil
+}
+
+// ClaimDueSchedule returns the earliest pending status change due by now and
+// locks it, skipping changes other workers hold. It returns ErrNotFound when
+// none is due.
+func (r *LifecycleRepository) ClaimDueSchedule(ctx context.Context) (*models.ProductStatusSchedule, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+statusScheduleColumns+` FROM product_status_schedules
+		WHERE state = 'pending' AND run_at <= NOW()
+		ORDER BY run_at, created_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`)
+	s, err := collectOne[models.ProductStatusSchedule](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim status schedule: %w", err)
+	}
+	return s, nil
+}
+
+// UpdateSchedule persists the state of a scheduled status change
+func (r *LifecycleRepository) UpdateSchedule(ctx context.Context, s *models.ProductStatusSchedule) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE product_status_schedules SET state = $2, error = $3, processed_at = $4 WHERE id = $1`,
+		s.ID, s.State, s.Error, s.ProcessedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update status schedule: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"main.go/services/product/models"
+)
+
+const priceChangeColumns = `id, product_id, old_price, new_price, old_compare_at_price, new_compare_at_price,
+	old_cost, new_cost, source, actor, reason, below_cost, changed_at`
+
+// PriceRepository provides access to per-currency prices and FX rates
+type PriceRepository struct {
+	db DBTX
+}
+
+// NewPriceRepository creates a new PriceRepository
+func NewPriceRepository(db DBTX) *PriceRepository {
+	return &PriceRepository{db: db}
+}
+
+// ListPrices returns the explicit currency prices of a product
+func (r *PriceRepository) ListPrices(ctx context.Context, productID uuid.UUID) ([]models.ProductPrice, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, product_id, currency, price, compare_at_price, created_at, updated_at
+		FROM product_prices WHERE product_id = $1 ORDER BY currency`, productID)
+	prices, err := collectAll[models.ProductPrice](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list product prices: %w", err)
+	}
+	return prices, nil
+}
+
+// GetPrice returns the explicit price of a product in a currency
+func (r *PriceRepository) GetPrice(ctx context.Context, productID uuid.UUID, currency string) (*models.ProductPrice, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, product_id, currency, price, compare_at_price, created_at, updated_at
+		FROM product_prices WHERE product_id = $1 AND currency = $2`, productID, currency)
+	price, err := collectOne[models.ProductPrice](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product price: %w", err)
+	}
+	return price, nil
+}
+
+// UpsertPrice creates or replaces the explicit price of a product in a currency
+func (r *PriceRepository) UpsertPrice(ctx context.Context, p *models.ProductPrice) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO product_prices (product_id, currency, price, compare_at_price)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (product_id, currency)
+		DO UPDATE SET price = EXCLUDED.price, compare_at_price = EXCLUDED.compare_at_price, updated_at = NOW()
+		RETURNING id, created_at, updated_at`,
+		p.ProductID, p.Currency, p.Price, p.CompareAtPrice,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save product price: %w", err)
+	}
+	return nil
+}
+
+// DeletePrice removes the explicit price of a product in a currency
+func (r *PriceRepository) DeletePrice(ctx context.Context, productID uuid.UUID, currency string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM product_prices WHERE product_id = $1 AND currency = $2`, productID, currency)
+	if err != nil {
+		return fmt.Errorf("failed to delete product price: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddPriceChange records a change of a product's base prices
+func (r *PriceRepository) AddPriceChange(ctx context.Context, c *models.PriceChange) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO product_price_history (product_id, old_price, new_price, old_compare_at_price,
+			new_compare_at_price, old_cost, new_cost, source, actor, reason, below_cost)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, changed_at`,
+		c.ProductID, c.OldPrice, c.NewPrice, c.OldCompareAtPrice, c.NewCompareAtPrice,
+		c.OldCost, c.NewCost, c.Source, c.Actor, c.Reason, c.BelowCost,
+	).Scan(&c.ID, &c.ChangedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record price change: %w", err)
+	}
+	return nil
+}
+
+// ListPriceChanges returns the price history of a product, newest first
+func (r *PriceRepository) ListPriceChanges(ctx context.Context, productID uuid.UUID, limit int) ([]models.PriceChange, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+priceChangeColumns+` FROM product_price_history
+		WHERE product_id = $1
+		ORDER BY changed_at DESC, id DESC
+		LIMIT $2`, productID, limit)
+	changes, err := collectAll[models.PriceChange](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list price changes: %w", err)
+	}
+	return changes, nil
+}
+
+// GetPriceChangeAfter returns the first change of a product's prices after
+// the given time
+func (r *PriceRepository) GetPriceChangeAfter(ctx context.Context, productID uuid.UUID, at time.Time) (*models.PriceChange, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+priceChangeColumns+` FROM product_price_history
+		WHERE product_id = $1 AND changed_at > $2
+		ORDER BY changed_at, id
+		LIMIT 1`, productID, at)
+	change, err := collectOne[models.PriceChange](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price change: %w", err)
+	}
+	return change, nil
+}
+
+// CreateRate stores an FX rate. Re-importing a rate for the same pair and
+// effective time replaces the stored value.
+func (r *PriceRepository) CreateRate(ctx context.Context, rate *models.FXRate) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO fx_rates (base_currency, quote_currency, rate, effective_at, source)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (base_currency, quote_currency, effective_at)
+		DO UPDATE SET rate = EXCLUDED.rate, source = EXCLUDED.source
+		RETURNING id, created_at`,
+		rate.BaseCurrency, rate.QuoteCurrency, rate.Rate, rate.EffectiveAt, rate.Source,
+	).Scan(&rate.ID, &rate.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save fx rate: %w", err)
+	}
+	return nil
+}
+
+// GetRateAt returns the rate for a currency pair in effect at the given time
+func (r *PriceRepository) GetRateAt(ctx context.Context, base, quote string, at time.Time) (*models.FXRate, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, base_currency, quote_currency, rate, effective_at, source, created_at
+		FROM fx_rates
+		WHERE base_currency = $1 AND quote_currency = $2 AND effective_at <= $3
+		ORDER BY effective_at DESC
+		LIMIT 1`, base, quote, at)
+	rate, err := collectOne[models.FXRate](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fx rate: %w", err)
+	}
+	return rate, nil
+}
+
+// ListRates returns the rate history of a currency pair, newest first
+func (r *PriceRepository) ListRates(ctx context.Context, base, quote string, limit int) ([]models.FXRate, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, base_currency, quote_currency, rate, effective_at, source, created_at
+		FROM fx_rates
+		WHERE base_currency = $1 AND quote_currency = $2
+		ORDER BY effective_at DESC
+		LIMIT $3`, base, quote, limit)
+	rates, err := collectAll[models.FXRate](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fx rates: %w", err)
+	}
+	return rates, nil
+}
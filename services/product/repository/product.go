@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/product/models"
+)
+
+const productColumns = `id, category_id, name, slug, description, sku, price, compare_at_price,
+	cost, currency, status, rating_average, rating_count, created_at, updated_at`
+
+// ProductRepository provides access to products
+type ProductRepository struct {
+	db DBTX
+}
+
+// NewProductRepository creates a new ProductRepository
+func NewProductRepository(db DBTX) *ProductRepository {
+	return &ProductRepository{db: db}
+}
+
+// GetByID returns a product by its ID
+func (r *ProductRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+productColumns+` FROM products WHERE id = $1`, id)
+	product, err := collectOne[models.Product](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	return product, nil
+}
+
+// GetBySlug returns a product by its slug
+func (r *ProductRepository) GetBySlug(ctx context.Context, slug string) (*models.Product, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+productColumns+` FROM products WHERE slug = $1`, slug)
+	product, err := collectOne[models.Product](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	return product, nil
+}
+
+// ListByCategories returns a page of the products in any of the given
+// categories, optionally limited to a status, newest first
+func (r *ProductRepository) ListByCategories(ctx context.Context, categoryIDs []uuid.UUID, status string, limit, offset int) ([]models.Product, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+productColumns+` FROM products
+		WHERE category_id = ANY($1) AND ($2::text = '' OR status = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4`, categoryIDs, status, limit, offset)
+	products, err := collectAll[models.Product](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+	return products, nil
+}
+
+// CountByCategories returns the number of products in any of the given
+// categories, optionally limited to a status
+func (r *ProductRepository) CountByCategories(ctx context.Context, categoryIDs []uuid.UUID, status string) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM products
+		WHERE category_id = ANY($1) AND ($2::text = '' OR status = $2)`, categoryIDs, status).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count products: %w", err)
+	}
+	return n, nil
+}
+
+// GetByIDForUpdate returns a product by its ID and locks it until the
+// surrounding transaction ends
+func (r *ProductRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+productColumns+` FROM products WHERE id = $1 FOR UPDATE`, id)
+	product, err := collectOne[models.Product](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	return product, nil
+}
+
+// GetBySKUForUpdate returns the product with the given SKU and locks it until
+// the surrounding transaction ends
+func (r *ProductRepository) GetBySKUForUpdate(ctx context.Context, sku string) (*models.Product, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+productColumns+` FROM products WHERE sku = $1 FOR UPDATE`, sku)
+	product, err := collectOne[models.Product](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	return product, nil
+}
+
+// GetBySlugForUpdate returns the product with the given slug and locks it
+// until the surrounding transaction ends
+func (r *ProductRepository) GetBySlugForUpdate(ctx context.Context, slug string) (*models.Product, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+productColumns+` FROM products WHERE slug = $1 FOR UPDATE`, slug)
+	product, err := collectOne[models.Product](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	return product, nil
+}
+
+// Create inserts a new product
+func (r *ProductRepository) Create(ctx context.Context, p *models.Product) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO products (category_id, name, slug, description, sku, price, compare_at_price, cost, currency, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at`,
+		p.CategoryID, p.Name, p.Slug, p.Description, p.SKU, p.Price, p.CompareAtPrice, p.Cost, p.Currency, p.Status,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create product: %w", err)
+	}
+	return nil
+}
+
+// Update persists the editable fields of a product
+func (r *ProductRepository) Update(ctx context.Context, p *models.Product) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE products
+		SET category_id = $2, name = $3, slug = $4, description = $5, sku = $6, price = $7,
+			compare_at_price = $8, cost = $9, currency = $10, status = $11, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`,
+		p.ID, p.CategoryID, p.Name, p.Slug, p.Description, p.SKU, p.Price, p.CompareAtPrice, p.Cost, p.Currency, p.Status,
+	).Scan(&p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update product: %w", err)
+	}
+	return nil
+}
+
+// UpdateStatus sets the status of a product
+func (r *ProductRepository) UpdateStatus(ctx context.Context, p *models.Product) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE products SET status = $2, updated_at = NOW() WHERE id = $1 RETURNING updated_at`,
+		p.ID, p.Status,
+	).Scan(&p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update product status: %w", err)
+	}
+	return nil
+}
+
+// UpdatePrices sets the base price, compare-at price and cost of a product
+func (r *ProductRepository) UpdatePrices(ctx context.Context, p *models.Product) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE products SET price = $2, compare_at_price = $3, cost = $4, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`,
+		p.ID, p.Price, p.CompareAtPrice, p.Cost,
+	).Scan(&p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update product prices: %w", err)
+	}
+	return nil
+}
+
+// UpdateRating recomputes the rating average and count of a product from
+// its approved reviews. The product's updated_at is left alone since its
+// own data did not change.
+func (r *ProductRepository) UpdateRating(ctx context.Context, p *models.Product) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE products SET
+			rating_average = (SELECT ROUND(AVG(rating), 2) FROM product_reviews WHERE product_id = $1 AND status = 'approved'),
+			rating_count = (SELECT COUNT(*) FROM product_reviews WHERE product_id = $1 AND status = 'approved')
+		WHERE id = $1 RETURNING rating_average, rating_count`,
+		p.ID,
+	).Scan(&p.RatingAverage, &p.RatingCount)
+	if err != nil {
+		return fmt.Errorf("failed to update product rating: %w", err)
+	}
+	return nil
+}
+
+// CountImages returns the number of images of a product
+func (r *ProductRepository) CountImages(ctx context.Context, productID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM product_images WHERE product_id = $1`, productID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count product images: %w", err)
+	}
+	return n, nil
+}
+
+// ReplaceAttributes makes attrs the attributes of a product, keeping the rows
+// of keys that remain
+func (r *ProductRepository) ReplaceAttributes(ctx context.Context, productID uuid.UUID, attrs map[string]string) error {
+	keys := make([]string, 0, len(attrs))
+	values := make([]string, 0, len(attrs))
+	for k, v := range attrs {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	_, err := r.db.Exec(ctx, `DELETE FROM product_attributes WHERE product_id = $1 AND key <> ALL($2)`, productID, keys)
+	if err != nil {
+		return fmt.Errorf("failed to delete product attributes: %w", err)
+	}
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO product_attributes (product_id, key, value)
+		SELECT $1, k, v FROM unnest($2::text[], $3::text[]) AS a(k, v)
+		ON CONFLICT (product_id, key) DO UPDATE SET value = EXCLUDED.value
+		WHERE product_attributes.value <> EXCLUDED.value`, productID, keys, values)
+	if err != nil {
+		return fmt.Errorf("failed to store product attributes: %w", err)
+	}
+	return nil
+}
+
+// SyncImages makes images the images of a product in slice order. Images
+// matched by URL keep their ID and variant assignment.
+func (r *ProductRepository) SyncImages(ctx context.Context, productID uuid.UUID, images []models.ProductImage) error {
+	urls := make([]string, len(images))
+	for i, img := range images {
+		urls[i] = img.URL
+	}
+	_, err := r.db.Exec(ctx, `DELETE FROM product_images WHERE product_id = $1 AND url <> ALL($2)`, productID, urls)
+	if err != nil {
+		return fmt.Errorf("failed to delete product images: %w", err)
+	}
+	for i := range images {
+		img := &images[i]
+		img.ProductID, img.SortOrder = productID, i
+		tag, err := r.db.Exec(ctx, `
+			UPDATE product_images SET alt_text = $3, sort_order = $4
+			WHERE product_id = $1 AND url = $2`, productID, img.URL, img.AltText, img.SortOrder)
+		if err != nil {
+			return fmt.Errorf("failed to update product image: %w", err)
+		}
+		if tag.RowsAffected() > 0 {
+			continue
+		}
+		_, err = r.db.Exec(ctx, `
+			INSERT INTO product_images (product_id, url, alt_text, sort_order)
+			VALUES ($1, $2, $3, $4)`, productID, img.URL, img.AltText, img.SortOrder)
+		if err != nil {
+			return fmt.Errorf("failed to create product image: %w", err)
+		}
+	}
+	return nil
+}
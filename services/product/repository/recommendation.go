@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/product/models"
+)
+
+const productLinkColumns = `id, product_id, related_id, type, position, created_at`
+
+// Recommendation is a product recommended for a set of products with the
+// curated link type that suggested it, the number of orders that bought it
+// together with them and the mirrored stock level
+type Recommendation struct {
+	models.Product
+	LinkType    *string `json:"link_type,omitempty" db:"link_type"`
+	CoPurchases int     `json:"co_purchases" db:"co_purchases"`
+	Available   *int    `json:"available,omitempty" db:"available"`
+}
+
+// RecommendationRepository provides access to curated product links and the
+// co-purchase counts recommendations are ranked by
+type RecommendationRepository struct {
+	db DBTX
+}
+
+// NewRecommendationRepository creates a new RecommendationRepository
+func NewRecommendationRepository(db DBTX) *RecommendationRepository {
+	return &RecommendationRepository{db: db}
+}
+
+// ListLinks returns the curated links of a product by type and position
+func (r *RecommendationRepository) ListLinks(ctx context.Context, productID uuid.UUID) ([]models.ProductLink, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+productLinkColumns+` FROM product_links
+		WHERE product_id = $1 ORDER BY type, position, created_at`, productID)
+	links, err := collectAll[models.ProductLink](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list product links: %w", err)
+	}
+	return links, nil
+}
+
+// CreateLink inserts a curated link, placing it last among the product's
+// links of its type. It returns false if the products were already linked
+// that way.
+func (r *RecommendationRepository) CreateLink(ctx context.Context, l *models.ProductLink) (bool, error) {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO product_links (product_id, related_id, type, position)
+		SELECT $1, $2, $3, COALESCE(MAX(position) + 1, 0)
+		FROM product_links WHERE product_id = $1 AND type = $3
+		ON CONFLICT (product_id, related_id, type) DO NOTHING
+		RETURNING id, position, created_at`,
+		l.ProductID, l.RelatedID, l.Type,
+	).Scan(&l.ID, &l.Position, &l.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create product link: %w", err)
+	}
+	return true, nil
+}
+
+// DeleteLink removes a curated link
+func (r *RecommendationRepository) DeleteLink(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM product_links WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete product link: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpsertCoPurchases stores co-purchase counts computed at computedAt,
+// skipping pairs with a product that does not exist here. It returns the
+// number of pairs stored.
+func (r *RecommendationRepository) UpsertCoPurchases(ctx context.Context, pairs []models.ProductCoPurchase, computedAt time.Time) (int, error) {
+	productIDs := make([]uuid.UUID, len(pairs))
+	relatedIDs := make([]uuid.UUID, len(pairs))
+	orders := make([]int32, len(pairs))
+	for i, p := range pairs {
+		productIDs[i], relatedIDs[i], orders[i] = p.ProductID, p.RelatedID, int32(p.Orders)
+	}
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO product_co_purchases (product_id, related_id, orders, computed_at)
+		SELECT c.product_id, c.related_id, c.orders, $4
+		FROM unnest($1::uuid[], $2::uuid[], $3::int[]) AS c(product_id, related_id, orders)
+		WHERE EXISTS (SELECT 1 FROM products p WHERE p.id = c.product_id)
+			AND EXISTS (SELECT 1 FROM products p WHERE p.id = c.related_id)
+		ON CONFLICT (product_id, related_id) DO UPDATE
+		SET orders = EXCLUDED.orders, computed_at = EXCLUDED.computed_at`,
+		productIDs, relatedIDs, orders, computedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to upsert co-purchases: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// DeleteCoPurchasesBefore removes co-purchase counts computed before t and
+// returns how many were removed
+func (r *RecommendationRepository) DeleteCoPurchasesBefore(ctx context.Context, t time.Time) (int, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM product_co_purchases WHERE computed_at < $1`, t)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete co-purchases: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// Recommend returns up to limit active products recommended for productIDs,
+// leaving out the products themselves and products without synced stock to
+// sell, including bundles whose components' stock is not synced yet. Curated
+// links come first by position, followed by the products bought together
+// with productIDs in the most orders. With a linkType only curated links of
+// that type are returned.
+func (r *RecommendationRepository) Recommend(ctx context.Context, productIDs []uuid.UUID, linkType string, limit int) ([]Recommendation, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH candidates AS (
+			SELECT related_id, type::text AS link_type, position, 0 AS orders
+			FROM product_links
+			WHERE product_id = ANY($1) AND ($2::text = '' OR type = $2)
+			UNION ALL
+			SELECT related_id, NULL, NULL, orders
+			FROM product_co_purchases
+			WHERE product_id = ANY($1) AND $2::text = ''
+		), ranked AS (
+			SELECT related_id,
+				MIN(position) AS position,
+				(ARRAY_AGG(link_type ORDER BY position) FILTER (WHERE link_type IS NOT NULL))[1] AS link_type,
+				SUM(orders)::int AS co_purchases
+			FROM candidates
+			WHERE related_id <> ALL($1)
+			GROUP BY related_id
+		)
+		SELECT p.id, p.category_id, p.name, p.slug, p.description, p.sku, p.price, p.compare_at_price,
+			p.cost, p.currency, p.status, p.rating_average, p.rating_count, p.created_at, p.updated_at,
+			r.link_type, r.co_purchases, ps.available
+		FROM ranked r
+		JOIN products p ON p.id = r.related_id
+		LEFT JOIN product_availability ps ON ps.product_id = p.id
+		WHERE p.status = 'active' AND ps.available > 0
+		ORDER BY r.position NULLS LAST, r.co_purchases DESC, p.id
+		LIMIT $3`, productIDs, linkType, limit)
+	recs, err := collectAll[Recommendation](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recommend products: %w", err)
+	}
+	return recs, nil
+}
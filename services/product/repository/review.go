@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/product/models"
+)
+
+const reviewColumns = `id, product_id, user_id, rating, title, body, verified_purchase, status,
+	moderation_note, moderated_at, helpful_count, created_at, updated_at`
+
+// ReviewSort names an ordering of product reviews
+type ReviewSort string
+
+const (
+	ReviewSortNewest     ReviewSort = "newest"
+	ReviewSortHelpful    ReviewSort = "helpful"
+	ReviewSortRatingDesc ReviewSort = "rating_desc"
+	ReviewSortRatingAsc  ReviewSort = "rating_asc"
+)
+
+// reviewSortOrders maps sort names to ORDER BY clauses; the review ID breaks
+// ties so pages are stable
+var reviewSortOrders = map[ReviewSort]string{
+	ReviewSortNewest:     "created_at DESC, id",
+	ReviewSortHelpful:    "helpful_count DESC, created_at DESC, id",
+	ReviewSortRatingDesc: "rating DESC, created_at DESC, id",
+	ReviewSortRatingAsc:  "rating ASC, created_at DESC, id",
+}
+
+// ValidReviewSort reports whether reviews can be sorted by s
+func ValidReviewSort(s ReviewSort) bool {
+	_, ok := reviewSortOrders[s]
+	return ok
+}
+
+// ReviewFilter narrows a review listing. Empty fields do not filter.
+type ReviewFilter struct {
+	ProductID    *uuid.UUID
+	Status       models.ReviewStatus
+	Rating       int
+	VerifiedOnly bool
+}
+
+// RatingCount counts the approved reviews of a product with a star rating
+type RatingCount struct {
+	Rating int `db:"rating"`
+	Count  int `db:"count"`
+}
+
+// ReviewRepository provides access to product reviews and their helpful votes
+type ReviewRepository struct {
+	db DBTX
+}
+
+// NewReviewRepository creates a new ReviewRepository
+func NewReviewRepository(db DBTX) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+// Create inserts a new review
+func (r *ReviewRepository) Create(ctx context.Context, rv *models.ProductReview) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO product_reviews (product_id, user_id, rating, title, body, verified_purchase, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, helpful_count, created_at, updated_at`,
+		rv.ProductID, rv.UserID, rv.Rating, rv.Title, rv.Body, rv.VerifiedPurchase, rv.Status,
+	).Scan(&rv.ID, &rv.HelpfulCount, &rv.CreatedAt, &rv.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create review: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns a review by its ID
+func (r *ReviewRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ProductReview, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+reviewColumns+` FROM product_reviews WHERE id = $1`, id)
+	rv, err := collectOne[models.ProductReview](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+	return rv, nil
+}
+
+// GetForUpdate returns a review by its ID and locks it until the surrounding
+// transaction ends
+func (r *ReviewRepository) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.ProductReview, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+reviewColumns+` FROM product_reviews WHERE id = $1 FOR UPDATE`, id)
+	rv, err := collectOne[models.ProductReview](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+	return rv, nil
+}
+
+// GetByUser returns the review a user wrote for a product
+func (r *ReviewRepository) GetByUser(ctx context.Context, productID, userID uuid.UUID) (*models.ProductReview, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+reviewColumns+` FROM product_reviews
+		WHERE product_id = $1 AND user_id = $2`, productID, userID)
+	rv, err := collectOne[models.ProductReview](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+	return rv, nil
+}
+
+// List returns a page of the reviews matching f in the given order
+func (r *ReviewRepository) List(ctx context.Context, f ReviewFilter, sortBy ReviewSort, limit, offset int) ([]models.ProductReview, error) {
+	order, ok := reviewSortOrders[sortBy]
+	if !ok {
+		order = reviewSortOrders[ReviewSortNewest]
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT `+reviewColumns+` FROM product_reviews
+		WHERE ($1::uuid IS NULL OR product_id = $1)
+			AND ($2::text = '' OR status = $2)
+			AND ($3::int = 0 OR rating = $3)
+			AND (NOT $4 OR verified_purchase)
+		ORDER BY `+order+`
+		LIMIT $5 OFFSET $6`,
+		f.ProductID, f.Status, f.Rating, f.VerifiedOnly, limit, offset)
+	reviews, err := collectAll[models.ProductReview](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reviews: %w", err)
+	}
+	return reviews, nil
+}
+
+// Count returns the number of reviews matching f
+func (r *ReviewRepository) Count(ctx context.Context, f ReviewFilter) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM product_reviews
+		WHERE ($1::uuid IS NULL OR product_id = $1)
+			AND ($2::text = '' OR status = $2)
+			AND ($3::int = 0 OR rating = $3)
+			AND (NOT $4 OR verified_purchase)`,
+		f.ProductID, f.Status, f.Rating, f.VerifiedOnly).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reviews: %w", err)
+	}
+	return n, nil
+}
+
+// RatingCounts counts the approved reviews of a product per star rating,
+// leaving out ratings nobody gave
+func (r *ReviewRepository) RatingCounts(ctx context.Context, productID uuid.UUID) ([]RatingCount, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT rating::int AS rating, COUNT(*)::int AS count FROM product_reviews
+		WHERE product_id = $1 AND status = 'approved'
+		GROUP BY rating
+		ORDER BY rating DESC`, productID)
+	counts, err := collectAll[RatingCount](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count ratings: %w", err)
+	}
+	return counts, nil
+}
+
+// Update saves the content, verification and moderation of a review
+func (r *ReviewRepository) Update(ctx context.Context, rv *models.ProductReview) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE product_reviews
+		SET rating = $2, title = $3, body = $4, verified_purchase = $5, status = $6,
+			moderation_note = $7, moderated_at = $8, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`,
+		rv.ID, rv.Rating, rv.Title, rv.Body, rv.VerifiedPurchase, rv.Status, rv.ModerationNote, rv.ModeratedAt,
+	).Scan(&rv.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update review: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a review and its votes
+func (r *ReviewRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM product_reviews WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete review: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddVote records that a user found a review helpful and counts the vote.
+// It returns false if the user had already voted.
+func (r *ReviewRepository) AddVote(ctx context.Context, rv *models.ProductReview, userID uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO product_review_votes (review_id, user_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, rv.ID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to add review vote: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	return true, r.adjustHelpfulCount(ctx, rv, 1)
+}
+
+// DeleteVote removes a user's helpful vote from a review. It returns false
+// if the user had not voted.
+func (r *ReviewRepository) DeleteVote(ctx context.Context, rv *models.ProductReview, userID uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM product_review_votes WHERE review_id = $1 AND user_id = $2`, rv.ID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete review vote: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	return true, r.adjustHelpfulCount(ctx, rv, -1)
+}
+
+func (r *ReviewRepository) adjustHelpfulCount(ctx context.Context, rv *models.ProductReview, delta int) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE product_reviews SET helpful_count = helpful_count + $2
+		WHERE id = $1 RETURNING helpful_count`, rv.ID, delta).Scan(&rv.HelpfulCount)
+	if err != nil {
+		return fmt.Errorf("failed to count review votes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"main.go/services/product/models"
+)
+
+const saleColumns = `id, product_id, sale_price, starts_at, ends_at, state, regular_price,
+	regular_compare_at_price, actor, reason, error, created_at, started_at, finished_at`
+
+// SaleRepository provides access to timed product sales
+type SaleRepository struct {
+	db DBTX
+}
+
+// NewSaleRepository creates a new SaleRepository
+func NewSaleRepository(db DBTX) *SaleRepository {
+	return &SaleRepository{db: db}
+}
+
+// Create inserts a new sale
+func (r *SaleRepository) Create(ctx context.Context, s *models.ProductSale) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO product_sales (product_id, sale_price, starts_at, ends_at, state, actor, reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`,
+		s.ProductID, s.SalePrice, s.StartsAt, s.EndsAt, s.State, s.Actor, s.Reason,
+	).Scan(&s.ID, &s.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create sale: %w", err)
+	}
+	return nil
+}
+
+// GetForUpdate returns a sale by its ID and locks it until the surrounding
+// transaction ends
+func (r *SaleRepository) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.ProductSale, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+saleColumns+` FROM product_sales WHERE id = $1 FOR UPDATE`, id)
+	s, err := collectOne[models.ProductSale](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sale: %w", err)
+	}
+	return s, nil
+}
+
+// GetActive returns the active sale of a product
+func (r *SaleRepository) GetActive(ctx context.Context, productID uuid.UUID) (*models.ProductSale, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+saleColumns+` FROM product_sales
+		WHERE product_id = $1 AND state = 'active'
+		ORDER BY starts_at DESC
+		LIMIT 1`, productID)
+	s, err := collectOne[models.ProductSale](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active sale: %w", err)
+	}
+	return s, nil
+}
+
+// ListByProduct returns the sales of a product by start time
+func (r *SaleRepository) ListByProduct(ctx context.Context, productID uuid.UUID) ([]models.ProductSale, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+saleColumns+` FROM product_sales
+		WHERE product_id = $1 ORDER BY starts_at, created_at`, productID)
+	sales, err := collectAll[models.ProductSale](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sales: %w", err)
+	}
+	return sales, nil
+}
+
+// Overlaps reports whether a scheduled or active sale of a product overlaps
+// the period from start to end
+func (r *SaleRepository) Overlaps(ctx context.Context, productID uuid.UUID, start, end time.Time) (bool, error) {
+	var overlaps bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM product_sales
+			WHERE product_id = $1 AND state IN ('scheduled', 'active') AND starts_at < $3 AND ends_at > $2
+		)`, productID, start, end).Scan(&overlaps)
+	if err != nil {
+		return false, fmt.Errorf("failed to check overlapping sales: %w", err)
+	}
+	return overlaps, nil
+}
+
+// ClaimDue returns the sale that is due to start or end the earliest and
+// locks it, skipping sales other workers hold. It returns ErrNotFound when
+// none is due.
+func (r *SaleRepository) ClaimDue(ctx context.Context, now time.Time) (*models.ProductSale, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+saleColumns+` FROM product_sales
+		WHERE (state = 'scheduled' AND starts_at <= $1) OR (state = 'active' AND ends_at <= $1)
+		ORDER BY CASE WHEN state = 'scheduled' THEN starts_at ELSE ends_at END, created_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`, now)
+	s, err := collectOne[models.ProductSale](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim sale: %w", err)
+	}
+	return s, nil
+}
+
+// Update persists the state and captured regular prices of a sale
+func (r *SaleRepository) Update(ctx context.Context, s *models.ProductSale) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE product_sales
+		SET state = $2, regular_price = $3, regular_compare_at_price = $4, error = $5,
+			started_at = $6, finished_at = $7
+		WHERE id = $1`,
+		s.ID, s.State, s.RegularPrice, s.RegularCompareAtPrice, s.Error, s.StartedAt, s.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update sale: %w", err)
+	}
+	return nil
+}
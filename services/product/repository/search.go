@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"main.go/services/product/models"
+)
+
+// ProductSort names an ordering of product search results
+type ProductSort string
+
+const (
+	ProductSortRelevance ProductSort = "relevance"
+	ProductSortPriceAsc  ProductSort = "price_asc"
+	ProductSortPriceDesc ProductSort = "price_desc"
+	ProductSortNewest    ProductSort = "newest"
+	ProductSortRating    ProductSort = "rating"
+)
+
+// productSortOrders maps sort names to ORDER BY clauses; the product ID
+// breaks ties so pages are stable
+var productSortOrders = map[ProductSort]string{
+	ProductSortRelevance: "rank DESC, p.id",
+	ProductSortPriceAsc:  "p.price ASC, p.id",
+	ProductSortPriceDesc: "p.price DESC, p.id",
+	ProductSortNewest:    "p.created_at DESC, p.id",
+	ProductSortRating:    "p.rating_average DESC NULLS LAST, p.rating_count DESC, p.id",
+}
+
+// ValidProductSort reports whether products can be sorted by s
+func ValidProductSort(s ProductSort) bool {
+	_, ok := productSortOrders[s]
+	return ok
+}
+
+// ProductFilter narrows a product search to active products. Empty fields
+// do not filter. Attributes match products having, for every key, one of
+// the listed values.
+type ProductFilter struct {
+	Query      string
+	CategoryID *uuid.UUID
+	MinPrice   *float64
+	MaxPrice   *float64
+	Currency   string
+	InStock    bool
+	MinRating  *float64
+	Attributes map[string][]string
+}
+
+// ProductHit is a product matching a search with its relevance and the
+// mirrored stock level, which is nil until the product's stock was synced
+type ProductHit struct {
+	models.Product
+	Rank      float64 `json:"rank" db:"rank"`
+	Available *int    `json:"available,omitempty" db:"available"`
+}
+
+// CategoryFacet counts the matching products of a category
+type CategoryFacet struct {
+	CategoryID uuid.UUID `json:"category_id" db:"category_id"`
+	Name       string    `json:"name" db:"name"`
+	Count      int       `json:"count" db:"count"`
+}
+
+// PriceBucketCount counts the matching products in a price bucket. Bucket i
+// holds prices from bound i-1 up to but excluding bound i; bucket 0 is below
+// the first bound and the last bucket is at or above the last bound.
+type PriceBucketCount struct {
+	Bucket int `db:"bucket"`
+	Count  int `db:"count"`
+}
+
+// RatingStarCount counts the matching rated products whose average rating
+// rounds down to Stars
+type RatingStarCount struct {
+	Stars int `db:"stars"`
+	Count int `db:"count"`
+}
+
+// AttributeValueCount counts the matching products with an attribute value
+type AttributeValueCount struct {
+	Key   string `db:"key"`
+	Value string `db:"value"`
+	Count int    `db:"count"`
+}
+
+// facetOmit names the filter left out when counting a facet, so a facet
+// shows the alternatives to its own selection
+type facetOmit struct {
+	category  bool
+	price     bool
+	rating    bool
+	attribute string
+}
+
+// productSearch accumulates the conditions and arguments of a search query
+type productSearch struct {
+	args []any
+}
+
+func (s *productSearch) arg(v any) string {
+	s.args = append(s.args, v)
+	return fmt.Sprintf("$%d", len(s.args))
+}
+
+// where renders the conditions of f, leaving out the filter named by omit
+func (s *productSearch) where(f ProductFilter, omit facetOmit) string {
+	conds := []string{"p.status = 'active'"}
+	if f.Query != "" {
+		q := s.arg(f.Query)
+		conds = append(conds, "(p.search_vector @@ websearch_to_tsquery('english', "+q+") OR "+q+" <% p.name)")
+	}
+	if f.CategoryID != nil && !omit.category {
+		conds = append(conds, "p.category_id = "+s.arg(*f.CategoryID))
+	}
+	if !omit.price {
+		if f.MinPrice != nil {
+			conds = append(conds, "p.price >= "+s.arg(*f.MinPrice))
+		}
+		if f.MaxPrice != nil {
+			conds = append(conds, "p.price <= "+s.arg(*f.MaxPrice))
+		}
+	}
+	if f.Currency != "" {
+		conds = append(conds, "p.currency = "+s.arg(f.Currency))
+	}
+	if f.InStock {
+		conds = append(conds, "ps.available > 0")
+	}
+	if f.MinRating != nil && !omit.rating {
+		conds = append(conds, "p.rating_average >= "+s.arg(*f.MinRating))
+	}
+	for _, key := range attributeKeys(f.Attributes) {
+		if key == omit.attribute {
+			continue
+		}
+		conds = append(conds, "EXISTS (SELECT 1 FROM product_attributes pa WHERE pa.product_id = p.id AND pa.key = "+
+			s.arg(key)+" AND pa.value = ANY("+s.arg(f.Attributes[key])+"))")
+	}
+	return strings.Join(conds, "\n\t\t\tAND ")
+}
+
+// attributeKeys returns the keys of attrs in a stable order
+func attributeKeys(attrs map[string][]string) []string {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// productSearchFrom joins the availability derived from the mirrored stock,
+// which covers bundles, so the in-stock filter and the hits can use it
+const productSearchFrom = `FROM products p LEFT JOIN product_availability ps ON ps.product_id = p.id`
+
+// SearchProducts returns a page of active products matching f in the given
+// order
+func (r *ProductRepository) SearchProducts(ctx context.Context, f ProductFilter, sortBy ProductSort, limit, offset int) ([]ProductHit, error) {
+	s := &productSearch{}
+	rank := "0::float8"
+	if f.Query != "" {
+		q := s.arg(f.Query)
+		rank = "(ts_rank_cd(p.search_vector, websearch_to_tsquery('english', " + q + ")) + word_similarity(" + q + ", p.name))::float8"
+	}
+	order, ok := productSortOrders[sortBy]
+	if !ok {
+		order = productSortOrders[ProductSortNewest]
+	}
+	where := s.where(f, facetOmit{})
+	sql := `SELECT p.id, p.category_id, p.name, p.slug, p.description, p.sku, p.price, p.compare_at_price,
+			p.cost, p.currency, p.status, p.rating_average, p.rating_count, p.created_at, p.updated_at, ` + rank + ` AS rank, ps.available
+		` + productSearchFrom + `
+		WHERE ` + where + `
+		ORDER BY ` + order + `
+		LIMIT ` + s.arg(limit) + ` OFFSET ` + s.arg(offset)
+	rows, err := r.db.Query(ctx, sql, s.args...)
+	hits, err := collectAll[ProductHit](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+	return hits, nil
+}
+
+// CountProducts returns the number of active products matching f
+func (r *ProductRepository) CountProducts(ctx context.Context, f ProductFilter) (int, error) {
+	s := &productSearch{}
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) `+productSearchFrom+` WHERE `+s.where(f, facetOmit{}), s.args...).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count products: %w", err)
+	}
+	return n, nil
+}
+
+// CategoryFacets counts the products matching f per category, ignoring the
+// category filter itself
+func (r *ProductRepository) CategoryFacets(ctx context.Context, f ProductFilter) ([]CategoryFacet, error) {
+	s := &productSearch{}
+	where := s.where(f, facetOmit{category: true})
+	rows, err := r.db.Query(ctx, `
+		SELECT c.id AS category_id, c.name, COUNT(*)::int AS count
+		`+productSearchFrom+` JOIN categories c ON c.id = p.category_id
+		WHERE `+where+`
+		GROUP BY c.id, c.name
+		ORDER BY count DESC, c.name`, s.args...)
+	facets, err := collectAll[CategoryFacet](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count category facets: %w", err)
+	}
+	return facets, nil
+}
+
+// PriceFacets counts the products matching f per price bucket delimited by
+// the ascending bounds, ignoring the price filter itself. Empty buckets are
+// left out.
+func (r *ProductRepository) PriceFacets(ctx context.Context, f ProductFilter, bounds []float64) ([]PriceBucketCount, error) {
+	s := &productSearch{}
+	where := s.where(f, facetOmit{price: true})
+	rows, err := r.db.Query(ctx, `
+		SELECT width_bucket(p.price, `+s.arg(bounds)+`::numeric[]) AS bucket, COUNT(*)::int AS count
+		`+productSearchFrom+`
+		WHERE `+where+`
+		GROUP BY bucket
+		ORDER BY bucket`, s.args...)
+	counts, err := collectAll[PriceBucketCount](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count price facets: %w", err)
+	}
+	return counts, nil
+}
+
+// RatingFacets counts the rated products matching f per whole star of their
+// average rating, ignoring the rating filter itself
+func (r *ProductRepository) RatingFacets(ctx context.Context, f ProductFilter) ([]RatingStarCount, error) {
+	s := &productSearch{}
+	where := s.where(f, facetOmit{rating: true})
+	rows, err := r.db.Query(ctx, `
+		SELECT FLOOR(p.rating_average)::int AS stars, COUNT(*)::int AS count
+		`+productSearchFrom+`
+		WHERE `+where+` AND p.rating_average IS NOT NULL
+		GROUP BY stars
+		ORDER BY stars DESC`, s.args...)
+	counts, err := collectAll[RatingStarCount](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rating facets: %w", err)
+	}
+	return counts, nil
+}
+
+// AttributeFacets counts the products matching f per attribute value,
+// keeping the perKey most frequent values of each key. A key that f filters
+// on is counted without its own filter so its other values stay visible.
+func (r *ProductRepository) AttributeFacets(ctx context.Context, f ProductFilter, perKey int) ([]AttributeValueCount, error) {
+	selected := attributeKeys(f.Attributes)
+	var counts []AttributeValueCount
+
+	s := &productSearch{}
+	where := s.where(f, facetOmit{})
+	page, err := r.attributeFacets(ctx, s, where+"\n\t\t\tAND a.key <> ALL("+s.arg(selected)+")", perKey)
+	if err != nil {
+		return nil, err
+	}
+	counts = append(counts, page...)
+
+	for _, key := range selected {
+		s := &productSearch{}
+		where := s.where(f, facetOmit{attribute: key})
+		page, err := r.attributeFacets(ctx, s, where+"\n\t\t\tAND a.key = "+s.arg(key), perKey)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, page...)
+	}
+
+	sort.SliceStable(counts, func(i, j int) bool { return counts[i].Key < counts[j].Key })
+	return counts, nil
+}
+
+func (r *ProductRepository) attributeFacets(ctx context.Context, s *productSearch, where string, perKey int) ([]AttributeValueCount, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT key, value, count FROM (
+			SELECT a.key, a.value, COUNT(*)::int AS count,
+				ROW_NUMBER() OVER (PARTITION BY a.key ORDER BY COUNT(*) DESC, a.value) AS n
+			`+productSearchFrom+` JOIN product_attributes a ON a.product_id = p.id
+			WHERE `+where+`
+			GROUP BY a.key, a.value
+		) f
+		WHERE n <= `+s.arg(perKey)+`
+		ORDER BY key, count DESC, value`, s.args...)
+	counts, err := collectAll[AttributeValueCount](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count attribute facets: %w", err)
+	}
+	return counts, nil
+}
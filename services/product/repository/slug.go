@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// SlugRepository provides access to the current and former slugs of one kind
+// of record, products or categories
+type SlugRepository struct {
+	db      DBTX
+	kind    string
+	table   string
+	history string
+	column  string
+}
+
+// NewProductSlugRepository creates a SlugRepository for product slugs
+func NewProductSlugRepository(db DBTX) *SlugRepository {
+	return &SlugRepository{db: db, kind: "product", table: "products", history: "product_slug_history", column: "product_id"}
+}
+
+// NewCategorySlugRepository creates a SlugRepository for category slugs
+func NewCategorySlugRepository(db DBTX) *SlugRepository {
+	return &SlugRepository{db: db, kind: "category", table: "categories", history: "category_slug_history", column: "category_id"}
+}
+
+// ListTaken returns the current and former slugs that equal base or extend
+// it with a hyphen
+func (r *SlugRepository) ListTaken(ctx context.Context, base string) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT slug FROM `+r.table+` WHERE slug = $1 OR slug LIKE $1 || '-%'
+		UNION
+		SELECT slug FROM `+r.history+` WHERE slug = $1 OR slug LIKE $1 || '-%'`, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s slugs: %w", r.kind, err)
+	}
+	slugs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s slugs: %w", r.kind, err)
+	}
+	return slugs, nil
+}
+
+// Resolve returns the ID of the record that formerly used slug
+func (r *SlugRepository) Resolve(ctx context.Context, slug string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRow(ctx, `SELECT `+r.column+` FROM `+r.history+` WHERE slug = $1`, slug).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("failed to resolve %s slug: %w", r.kind, ErrNotFound)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to resolve %s slug: %w", r.kind, err)
+	}
+	return id, nil
+}
+
+// Claim removes slug from the history once a record uses it again
+func (r *SlugRepository) Claim(ctx context.Context, slug string) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM `+r.history+` WHERE slug = $1`, slug); err != nil {
+		return fmt.Errorf("failed to claim %s slug: %w", r.kind, err)
+	}
+	return nil
+}
+
+// Retire records slug as a former slug of the record id
+func (r *SlugRepository) Retire(ctx context.Context, id uuid.UUID, slug string) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO `+r.history+` (slug, `+r.column+`) VALUES ($1, $2)
+		ON CONFLICT (slug) DO UPDATE SET `+r.column+` = EXCLUDED.`+r.column+`, created_at = NOW()`, slug, id)
+	if err != nil {
+		return fmt.Errorf("failed to record former %s slug: %w", r.kind, err)
+	}
+	return nil
+}
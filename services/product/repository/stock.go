@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/product/models"
+)
+
+// StockRepository provides access to the stock levels mirrored from the
+// inventory service
+type StockRepository struct {
+	db DBTX
+}
+
+// NewStockRepository creates a new StockRepository
+func NewStockRepository(db DBTX) *StockRepository {
+	return &StockRepository{db: db}
+}
+
+// Latest returns the position of the most recent variant stock change
+// mirrored so far, or the zero time when nothing was synced yet
+func (r *StockRepository) Latest(ctx context.Context) (time.Time, uuid.UUID, error) {
+	var (
+		at time.Time
+		id uuid.UUID
+	)
+	err := r.db.QueryRow(ctx, `
+		SELECT updated_at, variant_id FROM product_variant_stock
+		ORDER BY updated_at DESC, variant_id DESC LIMIT 1`).Scan(&at, &id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, uuid.Nil, nil
+	}
+	if err != nil {
+		return time.Time{}, uuid.Nil, fmt.Errorf("failed to get latest stock change: %w", err)
+	}
+	return at, id, nil
+}
+
+// Upsert stores the given variant stock levels, skipping variants unknown to
+// the catalog and changes older than the stored ones. It returns the IDs of
+// the products whose variants were written, once per written row.
+func (r *StockRepository) Upsert(ctx context.Context, levels []models.VariantStock) ([]uuid.UUID, error) {
+	variantIDs := make([]uuid.UUID, len(levels))
+	productIDs := make([]uuid.UUID, len(levels))
+	available := make([]int32, len(levels))
+	updated := make([]time.Time, len(levels))
+	for i, l := range levels {
+		variantIDs[i], productIDs[i], available[i], updated[i] = l.VariantID, l.ProductID, int32(l.Available), l.UpdatedAt
+	}
+	rows, err := r.db.Query(ctx, `
+		INSERT INTO product_variant_stock (variant_id, product_id, available, updated_at)
+		SELECT l.variant_id, l.product_id, l.available, l.updated_at
+		FROM unnest($1::uuid[], $2::uuid[], $3::int[], $4::timestamptz[]) AS l(variant_id, product_id, available, updated_at)
+		WHERE EXISTS (SELECT 1 FROM product_variants v WHERE v.id = l.variant_id AND v.product_id = l.product_id)
+		ON CONFLICT (variant_id) DO UPDATE
+		SET available = EXCLUDED.available, updated_at = EXCLUDED.updated_at, synced_at = NOW()
+		WHERE product_variant_stock.updated_at <= EXCLUDED.updated_at
+		RETURNING product_id`, variantIDs, productIDs, available, updated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert variant stock: %w", err)
+	}
+	written, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert variant stock: %w", err)
+	}
+	return written, nil
+}
+
+// RefreshProducts recomputes the stock of the given products from the
+// mirrored stock of their variants
+func (r *StockRepository) RefreshProducts(ctx context.Context, productIDs []uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO product_stock (product_id, available, updated_at)
+		SELECT product_id, SUM(available)::int, MAX(updated_at)
+		FROM product_variant_stock WHERE product_id = ANY($1)
+		GROUP BY product_id
+		ON CONFLICT (product_id) DO UPDATE
+		SET available = EXCLUDED.available, updated_at = EXCLUDED.updated_at, synced_at = NOW()`, productIDs)
+	if err != nil {
+		return fmt.Errorf("failed to refresh product stock: %w", err)
+	}
+	return nil
+}
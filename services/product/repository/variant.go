@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"main.go/services/product/models"
+)
+
+const optionColumns = `id, product_id, name, option_values, position, created_at, updated_at`
+
+const variantColumns = `id, product_id, sku, title, options, price, compare_at_price, is_default,
+	position, created_at, updated_at`
+
+const imageColumns = `id, product_id, variant_id, url, alt_text, sort_order, blob_hash, created_at`
+
+// VariantRepository provides access to product options, variants and the
+// images assigned to variants
+type VariantRepository struct {
+	db DBTX
+}
+
+// NewVariantRepository creates a new VariantRepository
+func NewVariantRepository(db DBTX) *VariantRepository {
+	return &VariantRepository{db: db}
+}
+
+// ListOptions returns the options of a product in display order
+func (r *VariantRepository) ListOptions(ctx context.Context, productID uuid.UUID) ([]models.ProductOption, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+optionColumns+` FROM product_options
+		WHERE product_id = $1 ORDER BY position, name`, productID)
+	options, err := collectAll[models.ProductOption](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list product options: %w", err)
+	}
+	return options, nil
+}
+
+// ReplaceOptions replaces the options of a product with the given ones,
+// numbering their positions in slice order
+func (r *VariantRepository) ReplaceOptions(ctx context.Context, productID uuid.UUID, options []models.ProductOption) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM product_options WHERE product_id = $1`, productID); err != nil {
+		return fmt.Errorf("failed to delete product options: %w", err)
+	}
+	for i := range options {
+		o := &options[i]
+		o.ProductID, o.Position = productID, i
+		err := r.db.QueryRow(ctx, `
+			INSERT INTO product_options (product_id, name, option_values, position)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at, updated_at`,
+			o.ProductID, o.Name, o.Values, o.Position,
+		).Scan(&o.ID, &o.CreatedAt, &o.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create product option: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetVariant returns a variant by its ID
+func (r *VariantRepository) GetVariant(ctx context.Context, id uuid.UUID) (*models.ProductVariant, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+variantColumns+` FROM product_variants WHERE id = $1`, id)
+	variant, err := collectOne[models.ProductVariant](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get variant: %w", err)
+	}
+	return variant, nil
+}
+
+// GetVariantForUpdate returns a variant by its ID and locks it until the
+// surrounding transaction ends
+func (r *VariantRepository) GetVariantForUpdate(ctx context.Context, id uuid.UUID) (*models.ProductVariant, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+variantColumns+` FROM product_variants WHERE id = $1 FOR UPDATE`, id)
+	variant, err := collectOne[models.ProductVariant](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get variant: %w", err)
+	}
+	return variant, nil
+}
+
+// GetDefaultVariant returns the default variant of a product
+func (r *VariantRepository) GetDefaultVariant(ctx context.Context, productID uuid.UUID) (*models.ProductVariant, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+variantColumns+` FROM product_variants
+		WHERE product_id = $1 AND is_default`, productID)
+	variant, err := collectOne[models.ProductVariant](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default variant: %w", err)
+	}
+	return variant, nil
+}
+
+// GetVariantBySKU returns the variant with the given SKU
+func (r *VariantRepository) GetVariantBySKU(ctx context.Context, sku string) (*models.ProductVariant, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+variantColumns+` FROM product_variants WHERE sku = $1`, sku)
+	variant, err := collectOne[models.ProductVariant](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get variant: %w", err)
+	}
+	return variant, nil
+}
+
+// ListVariants returns the variants of a product in display order
+func (r *VariantRepository) ListVariants(ctx context.Context, productID uuid.UUID) ([]models.ProductVariant, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+variantColumns+` FROM product_variants
+		WHERE product_id = $1 ORDER BY position, created_at, id`, productID)
+	variants, err := collectAll[models.ProductVariant](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list variants: %w", err)
+	}
+	return variants, nil
+}
+
+// ListVariantsForUpdate returns the variants of a product and locks them
+// until the surrounding transaction ends
+func (r *VariantRepository) ListVariantsForUpdate(ctx context.Context, productID uuid.UUID) ([]models.ProductVariant, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+variantColumns+` FROM product_variants
+		WHERE product_id = $1 ORDER BY position, created_at, id FOR UPDATE`, productID)
+	variants, err := collectAll[models.ProductVariant](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list variants: %w", err)
+	}
+	return variants, nil
+}
+
+// CreateVariant inserts a new variant. A zero ID lets the database assign
+// one.
+func (r *VariantRepository) CreateVariant(ctx context.Context, v *models.ProductVariant) error {
+	id := &v.ID
+	if v.ID == uuid.Nil {
+		id = nil
+	}
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO product_variants (id, product_id, sku, title, options, price, compare_at_price, is_default, position)
+		VALUES (COALESCE($1, gen_random_uuid()), $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at`,
+		id, v.ProductID, v.SKU, v.Title, v.Options, v.Price, v.CompareAtPrice, v.IsDefault, v.Position,
+	).Scan(&v.ID, &v.CreatedAt, &v.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create variant: %w", err)
+	}
+	return nil
+}
+
+// UpdateVariant persists the editable fields of a variant
+func (r *VariantRepository) UpdateVariant(ctx context.Context, v *models.ProductVariant) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE product_variants
+		SET sku = $2, title = $3, options = $4, price = $5, compare_at_price = $6, is_default = $7,
+			position = $8, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`,
+		v.ID, v.SKU, v.Title, v.Options, v.Price, v.CompareAtPrice, v.IsDefault, v.Position,
+	).Scan(&v.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update variant: %w", err)
+	}
+	return nil
+}
+
+// ClearDefault unsets the default flag on every variant of a product, so
+// another one can take it
+func (r *VariantRepository) ClearDefault(ctx context.Context, productID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE product_variants SET is_default = false, updated_at = NOW()
+		WHERE product_id = $1 AND is_default`, productID)
+	if err != nil {
+		return fmt.Errorf("failed to clear default variant: %w", err)
+	}
+	return nil
+}
+
+// DeleteVariant deletes a variant
+func (r *VariantRepository) DeleteVariant(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM product_variants WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete variant: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to delete variant: %w", ErrNotFound)
+	}
+	return nil
+}
+
+// ListImages returns the images of a product in display order
+func (r *VariantRepository) ListImages(ctx context.Context, productID uuid.UUID) ([]models.ProductImage, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+imageColumns+` FROM product_images
+		WHERE product_id = $1 ORDER BY sort_order, created_at, id`, productID)
+	images, err := collectAll[models.ProductImage](rows, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list product images: %w", err)
+	}
+	return images, nil
+}
+
+// AssignImages makes the given images of a product the images of a variant,
+// releasing any other image the variant had back to the product
+func (r *VariantRepository) AssignImages(ctx context.Context, productID, variantID uuid.UUID, imageIDs []uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE product_images
+		SET variant_id = CASE WHEN id = ANY($3) THEN $2::uuid END
+		WHERE product_id = $1 AND (variant_id = $2 OR id = ANY($3))`, productID, variantID, imageIDs)
+	if err != nil {
+		return fmt.Errorf("failed to assign variant images: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/product/models"
+	"main.go/services/product/repository"
+)
+
+// Bundle limits
+const (
+	maxBundleComponents = 50
+	maxComponentQty     = 1000
+)
+
+// BundleComponentInput is a quantity of a product variant in each unit of a
+// bundle. Without a VariantID the product's default variant is used.
+type BundleComponentInput struct {
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Quantity  int        `json:"quantity"`
+}
+
+// SetBundleInput defines a product as a bundle of other products
+type SetBundleInput struct {
+	Pricing         string                 `json:"pricing"`
+	DiscountPercent float64                `json:"discount_percent"`
+	Components      []BundleComponentInput `json:"components"`
+}
+
+// ResolvedComponent is a component of a bundle as it is ordered: the variant
+// and quantity contained in each unit of the bundle
+type ResolvedComponent struct {
+	ProductID uuid.UUID `json:"product_id"`
+	VariantID uuid.UUID `json:"variant_id"`
+	Name      string    `json:"name"`
+	SKU       *string   `json:"sku,omitempty"`
+	Status    string    `json:"status"`
+	Quantity  int       `json:"quantity"`
+}
+
+// BundleService makes products bundles of other products
+type BundleService struct {
+	pool *pgxpool.Pool
+}
+
+// NewBundleService creates a new BundleService
+func NewBundleService(pool *pgxpool.Pool) *BundleService {
+	return &BundleService{pool: pool}
+}
+
+// Get returns the bundle definition of a product with the number of complete
+// bundles in stock
+func (s *BundleService) Get(ctx context.Context, productID uuid.UUID) (*models.ProductBundle, error) {
+	bundles := repository.NewBundleRepository(s.pool)
+	b, err := bundles.Get(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if b.Components, err = bundles.ListComponents(ctx, productID); err != nil {
+		return nil, err
+	}
+	if b.Available, err = bundles.Available(ctx, productID); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Set makes a product a bundle of the given components, replacing an
+// earlier definition. Components cannot be bundles themselves, and a product
+// that is a component of another bundle cannot become one. Components of a
+// bundle priced from them must share its currency.
+func (s *BundleService) Set(ctx context.Context, productID uuid.UUID, in SetBundleInput) (*models.ProductBundle, error) {
+	if in.Pricing != models.BundlePricingFixed && in.Pricing != models.BundlePricingComponents {
+		return nil, fmt.Errorf("%w: pricing must be fixed or components", ErrValidation)
+	}
+	if in.DiscountPercent < 0 || in.DiscountPercent >= 100 {
+		return nil, fmt.Errorf("%w: discount_percent must be at least 0 and below 100", ErrValidation)
+	}
+	if in.Pricing == models.BundlePricingFixed && in.DiscountPercent != 0 {
+		return nil, fmt.Errorf("%w: discount_percent only applies to bundles priced from their components", ErrValidation)
+	}
+	if len(in.Components) == 0 {
+		return nil, fmt.Errorf("%w: a bundle needs at least one component", ErrValidation)
+	}
+	if len(in.Components) > maxBundleComponents {
+		return nil, fmt.Errorf("%w: a bundle can have at most %d components", ErrValidation, maxBundleComponents)
+	}
+
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		products := repository.NewProductRepository(tx)
+		bundles := repository.NewBundleRepository(tx)
+		bundle, err := products.GetByIDForUpdate(ctx, productID)
+		if err != nil {
+			return err
+		}
+		isComponent, err := bundles.IsComponent(ctx, productID)
+		if err != nil {
+			return err
+		}
+		if isComponent {
+			return fmt.Errorf("%w: product is a component of another bundle", ErrInvalidState)
+		}
+
+		// Locking components in a stable order keeps them from becoming
+		// bundles meanwhile without deadlocking concurrent definitions
+		ids := make([]uuid.UUID, 0, len(in.Components))
+		for _, c := range in.Components {
+			if c.Quantity < 1 || c.Quantity > maxComponentQty {
+				return fmt.Errorf("%w: component quantity must be between 1 and %d", ErrValidation, maxComponentQty)
+			}
+			if c.ProductID == productID {
+				return fmt.Errorf("%w: a bundle cannot contain itself", ErrValidation)
+			}
+			ids = append(ids, c.ProductID)
+		}
+		slices.SortFunc(ids, func(a, b uuid.UUID) int { return strings.Compare(a.String(), b.String()) })
+		locked := map[uuid.UUID]*models.Product{}
+		for _, id := range slices.Compact(ids) {
+			p, err := products.GetByIDForUpdate(ctx, id)
+			if errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("%w: component product %s does not exist", ErrValidation, id)
+			}
+			if err != nil {
+				return err
+			}
+			locked[id] = p
+		}
+
+		components := make([]models.BundleComponent, 0, len(in.Components))
+		seen := map[uuid.UUID]bool{}
+		variants := repository.NewVariantRepository(tx)
+		for _, c := range in.Components {
+			p := locked[c.ProductID]
+			if _, err := bundles.Get(ctx, p.ID); err == nil {
+				return fmt.Errorf("%w: component %s is a bundle itself", ErrValidation, p.ID)
+			} else if !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+			if in.Pricing == models.BundlePricingComponents && p.Currency != bundle.Currency {
+				return fmt.Errorf("%w: component %s is priced in %s, not %s", ErrValidation, p.ID, p.Currency, bundle.Currency)
+			}
+			key := p.ID
+			if c.VariantID != nil {
+				v, err := variants.GetVariant(ctx, *c.VariantID)
+				if errors.Is(err, repository.ErrNotFound) || err == nil && v.ProductID != p.ID {
+					return fmt.Errorf("%w: variant %s does not belong to product %s", ErrValidation, *c.VariantID, p.ID)
+				}
+				if err != nil {
+					return err
+				}
+				key = v.ID
+			}
+			if seen[key] {
+				return fmt.Errorf("%w: component %s is listed twice", ErrValidation, key)
+			}
+			seen[key] = true
+			components = append(components, models.BundleComponent{ProductID: p.ID, VariantID: c.VariantID, Quantity: c.Quantity})
+		}
+
+		b := &models.ProductBundle{ProductID: productID, Pricing: in.Pricing, DiscountPercent: in.DiscountPercent}
+		if err := bundles.Upsert(ctx, b); err != nil {
+			return err
+		}
+		return bundles.ReplaceComponents(ctx, productID, components)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, productID)
+}
+
+// Delete turns a bundle back into a regular product
+func (s *BundleService) Delete(ctx context.Context, productID uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := repository.NewProductRepository(tx).GetByIDForUpdate(ctx, productID); err != nil {
+			return err
+		}
+		return repository.NewBundleRepository(tx).Delete(ctx, productID)
+	})
+}
+
+// resolveBundle returns the components of a bundle as they are ordered, the
+// bundle definition and the total base price of its components as of at. It
+// returns nil components when the product is not a bundle.
+func resolveBundle(ctx context.Context, db repository.DBTX, productID uuid.UUID, at time.Time, historic bool) ([]ResolvedComponent, *models.ProductBundle, float64, error) {
+	bundles := repository.NewBundleRepository(db)
+	b, err := bundles.Get(ctx, productID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, 0, nil
+	}
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	components, err := bundles.ListComponents(ctx, productID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	products := repository.NewProductRepository(db)
+	variants := repository.NewVariantRepository(db)
+	resolved := make([]ResolvedComponent, 0, len(components))
+	total := 0.0
+	for _, c := range components {
+		p, err := products.GetByID(ctx, c.ProductID)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if historic {
+			if err := applyPriceAt(ctx, repository.NewPriceRepository(db), p, at); err != nil {
+				return nil, nil, 0, err
+			}
+		}
+		var v *models.ProductVariant
+		if c.VariantID != nil {
+			v, err = variants.GetVariant(ctx, *c.VariantID)
+		} else {
+			v, err = variants.GetDefaultVariant(ctx, c.ProductID)
+		}
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		rc := ResolvedComponent{ProductID: p.ID, VariantID: v.ID, Name: p.Name, SKU: p.SKU, Status: p.Status, Quantity: c.Quantity}
+		if v.Title != "" {
+			rc.Name += " - " + v.Title
+		}
+		if v.SKU != nil {
+			rc.SKU = v.SKU
+		}
+		resolved = append(resolved, rc)
+
+		unit := p.Price
+		if v.Price != nil {
+			unit = *v.Price
+		}
+		total += unit * float64(c.Quantity)
+	}
+	return resolved, b, total, nil
+}
@@ -0,0 +1,727 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/product/models"
+	"main.go/services/product/repository"
+)
+
+// Catalog import limits
+const (
+	catalogImportBatch      = 200
+	catalogImportLease      = 5 * time.Minute
+	defaultImportErrorLimit = 100
+	maxImportErrorLimit     = 1000
+)
+
+// errDryRun rolls back the changes of a dry run
+var errDryRun = errors.New("dry run")
+
+// CreateImportInput describes an uploaded catalog file
+type CreateImportInput struct {
+	Format  string
+	DryRun  bool
+	Content []byte
+}
+
+// ExportCatalogInput selects the products of a catalog export. A category
+// includes its descendants.
+type ExportCatalogInput struct {
+	Format     string
+	Status     string
+	CategoryID *uuid.UUID
+}
+
+// CatalogService imports and exports the product catalog in bulk
+type CatalogService struct {
+	pool *pgxpool.Pool
+	now  func() time.Time
+}
+
+// NewCatalogService creates a new CatalogService
+func NewCatalogService(pool *pgxpool.Pool) *CatalogService {
+	return &CatalogService{pool: pool, now: time.Now}
+}
+
+// CreateImport checks that a catalog file can be read and queues it for
+// import. Rows are validated when the import runs.
+func (s *CatalogService) CreateImport(ctx context.Context, in CreateImportInput) (*models.CatalogImport, error) {
+	total, err := countCatalogRows(in.Format, in.Content)
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%w: file has no rows", ErrValidation)
+	}
+	imp := &models.CatalogImport{
+		Format:    in.Format,
+		DryRun:    in.DryRun,
+		Status:    models.CatalogImportPending,
+		TotalRows: total,
+	}
+	if err := repository.NewCatalogRepository(s.pool).CreateImport(ctx, imp, in.Content); err != nil {
+		return nil, err
+	}
+	return imp, nil
+}
+
+// GetImport returns an import and its progress
+func (s *CatalogService) GetImport(ctx context.Context, id uuid.UUID) (*models.CatalogImport, error) {
+	return repository.NewCatalogRepository(s.pool).GetImport(ctx, id)
+}
+
+// ListImportErrors returns a page of the rows an import could not apply
+func (s *CatalogService) ListImportErrors(ctx context.Context, id uuid.UUID, limit, offset int) ([]models.CatalogImportError, error) {
+	if limit == 0 {
+		limit = defaultImportErrorLimit
+	}
+	if limit < 0 || limit > maxImportErrorLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrValidation, maxImportErrorLimit)
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrValidation)
+	}
+	catalog := repository.NewCatalogRepository(s.pool)
+	if _, err := catalog.GetImport(ctx, id); err != nil {
+		return nil, err
+	}
+	errs, err := catalog.ListImportErrors(ctx, id, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if errs == nil {
+		errs = []models.CatalogImportError{}
+	}
+	return errs, nil
+}
+
+// ResumeImport queues a failed import again. It continues after the last
+// batch it completed.
+func (s *CatalogService) ResumeImport(ctx context.Context, id uuid.UUID) (*models.CatalogImport, error) {
+	var imp *models.CatalogImport
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		catalog := repository.NewCatalogRepository(tx)
+		var err error
+		if imp, err = catalog.GetImportForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if imp.Status != models.CatalogImportFailed {
+			return fmt.Errorf("%w: only failed imports can be resumed, import is %s", ErrInvalidState, imp.Status)
+		}
+		imp.Status = models.CatalogImportPending
+		imp.Error = nil
+		return catalog.UpdateImport(ctx, imp, imp.NextRow, 0)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imp, nil
+}
+
+// ProcessNext claims the next queued import, or one abandoned by a stopped
+// worker, and runs it to the end. It returns false when there was nothing to
+// process.
+func (s *CatalogService) ProcessNext(ctx context.Context) (bool, error) {
+	catalog := repository.NewCatalogRepository(s.pool)
+	imp, err := catalog.ClaimImport(ctx, catalogImportLease)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := s.run(ctx, imp); err != nil {
+		if ctx.Err() != nil {
+			// Shutting down: the lock expires and another worker resumes
+			return true, err
+		}
+		msg := err.Error()
+		imp.Status, imp.Error = models.CatalogImportFailed, &msg
+		if uerr := catalog.UpdateImport(ctx, imp, imp.NextRow, 0); uerr != nil {
+			log.Printf("failed to mark catalog import %s as failed: %v", imp.ID, uerr)
+		}
+		return true, fmt.Errorf("catalog import %s failed at row %d: %w", imp.ID, imp.NextRow+1, err)
+	}
+	return true, nil
+}
+
+// RunCatalogImports processes queued imports every interval until ctx is done
+func (s *CatalogService) RunCatalogImports(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				ok, err := s.ProcessNext(ctx)
+				if err != nil {
+					log.Printf("catalog import: %v", err)
+				}
+				if !ok {
+					break
+				}
+			}
+		}
+	}
+}
+
+// catalogRow is a record read from an import file with its row number, or
+// the reason it could not be read
+type catalogRow struct {
+	number int
+	rec    *CatalogRecord
+	err    error
+}
+
+// batchResult counts what a batch of rows did
+type batchResult struct {
+	created int
+	updated int
+	errs    []models.CatalogImportError
+}
+
+// run imports the rows of a claimed import from its checkpoint on, one
+// batch per transaction. A dry run applies every batch in a single
+// transaction that is rolled back at the end, so its results account for the
+// products earlier rows would have created; an interrupted dry run starts
+// over since its changes were not kept.
+func (s *CatalogService) run(ctx context.Context, imp *models.CatalogImport) error {
+	if !imp.DryRun {
+		return s.runBatches(ctx, imp, nil)
+	}
+	if imp.NextRow > 0 {
+		if err := s.restartImport(ctx, imp); err != nil {
+			return err
+		}
+	}
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if err := s.runBatches(ctx, imp, tx); err != nil {
+			return err
+		}
+		return errDryRun
+	})
+	if !errors.Is(err, errDryRun) {
+		return err
+	}
+	return nil
+}
+
+// restartImport clears the progress and row errors of an import
+func (s *CatalogService) restartImport(ctx context.Context, imp *models.CatalogImport) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		catalog := repository.NewCatalogRepository(tx)
+		if err := catalog.DeleteImportErrors(ctx, imp.ID); err != nil {
+			return err
+		}
+		from := imp.NextRow
+		next := *imp
+		next.NextRow, next.CreatedCount, next.UpdatedCount, next.FailedCount = 0, 0, 0, 0
+		if err := catalog.UpdateImport(ctx, &next, from, catalogImportLease); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("%w: import was taken over by another worker", ErrInvalidState)
+			}
+			return err
+		}
+		*imp = next
+		return nil
+	})
+}
+
+// runBatches applies the rows of an import batch by batch from its
+// checkpoint on. Changes of a dry run go to dry, which the caller rolls back.
+func (s *CatalogService) runBatches(ctx context.Context, imp *models.CatalogImport, dry pgx.Tx) error {
+	catalog := repository.NewCatalogRepository(s.pool)
+	content, err := catalog.GetImportContent(ctx, imp.ID)
+	if err != nil {
+		return err
+	}
+	reader, err := newCatalogReader(imp.Format, content)
+	if err != nil {
+		return err
+	}
+	for range imp.NextRow {
+		if _, err := reader.Next(); err != nil && !errors.Is(err, ErrValidation) {
+			return fmt.Errorf("failed to skip processed rows: %w", err)
+		}
+	}
+
+	for {
+		rows, eof, err := readCatalogBatch(reader, imp.NextRow)
+		if err != nil {
+			return err
+		}
+		if err := s.applyBatch(ctx, imp, dry, rows, eof); err != nil {
+			return err
+		}
+		if eof {
+			log.Printf("catalog import %s completed: %d created, %d updated, %d failed",
+				imp.ID, imp.CreatedCount, imp.UpdatedCount, imp.FailedCount)
+			return nil
+		}
+	}
+}
+
+// readCatalogBatch reads up to a batch of rows following row after
+func readCatalogBatch(reader catalogReader, after int) ([]catalogRow, bool, error) {
+	rows := make([]catalogRow, 0, catalogImportBatch)
+	for len(rows) < catalogImportBatch {
+		rec, err := reader.Next()
+		if err == io.EOF {
+			return rows, true, nil
+		}
+		if err != nil && !errors.Is(err, ErrValidation) {
+			return nil, false, err
+		}
+		rows = append(rows, catalogRow{number: after + len(rows) + 1, rec: rec, err: err})
+	}
+	return rows, false, nil
+}
+
+// applyBatch applies rows and moves the checkpoint past them. A real import
+// does both in one transaction, so a batch is never applied twice; a dry run
+// applies rows in dry and records the outcome in a transaction of its own.
+func (s *CatalogService) applyBatch(ctx context.Context, imp *models.CatalogImport, dry pgx.Tx, rows []catalogRow, eof bool) error {
+	var result batchResult
+	record := func(tx pgx.Tx) error {
+		catalog := repository.NewCatalogRepository(tx)
+		if err := catalog.CreateImportErrors(ctx, imp.ID, result.errs); err != nil {
+			return err
+		}
+		from := imp.NextRow
+		next := *imp
+		next.NextRow += len(rows)
+		next.CreatedCount += result.created
+		next.UpdatedCount += result.updated
+		next.FailedCount += len(result.errs)
+		if eof {
+			now := s.now()
+			next.Status, next.CompletedAt = models.CatalogImportCompleted, &now
+		}
+		if err := catalog.UpdateImport(ctx, &next, from, catalogImportLease); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("%w: import was taken over by another worker", ErrInvalidState)
+			}
+			return err
+		}
+		*imp = next
+		return nil
+	}
+
+	if dry != nil {
+		var err error
+		if result, err = s.applyRows(ctx, dry, rows); err != nil {
+			return err
+		}
+		return pgx.BeginFunc(ctx, s.pool, record)
+	}
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		var err error
+		if result, err = s.applyRows(ctx, tx, rows); err != nil {
+			return err
+		}
+		return record(tx)
+	})
+}
+
+// applyRows applies each row in its own savepoint, so a rejected row leaves
+// the rest of the batch intact. Errors other than row errors abort the batch.
+func (s *CatalogService) applyRows(ctx context.Context, tx pgx.Tx, rows []catalogRow) (batchResult, error) {
+	var result batchResult
+	categories, err := repository.NewCategoryRepository(tx).ListAll(ctx)
+	if err != nil {
+		return result, err
+	}
+	paths := categoryPaths(categories)
+
+	for _, row := range rows {
+		err := row.err
+		created := false
+		if err == nil {
+			err = pgx.BeginFunc(ctx, tx, func(sp pgx.Tx) error {
+				var err error
+				created, err = s.applyRecord(ctx, sp, paths, row.rec)
+				return err
+			})
+		}
+		switch {
+		case err == nil && created:
+			result.created++
+		case err == nil:
+			result.updated++
+		case isRowError(err):
+			e := models.CatalogImportError{RowNumber: row.number, Message: err.Error()}
+			if row.rec != nil {
+				e.SKU, e.Slug = row.rec.SKU, row.rec.Slug
+			}
+			result.errs = append(result.errs, e)
+		default:
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// applyRecord creates or updates the product a record describes, matched by
+// SKU or slug. It returns true when the product was created.
+func (s *CatalogService) applyRecord(ctx context.Context, tx pgx.Tx, paths map[string]uuid.UUID, rec *CatalogRecord) (bool, error) {
+	rec.normalize()
+	if rec.SKU == nil && rec.Slug == nil {
+		return false, fmt.Errorf("%w: sku or slug is required", ErrValidation)
+	}
+
+	products := repository.NewProductRepository(tx)
+	var p *models.Product
+	if rec.SKU != nil {
+		found, err := products.GetBySKUForUpdate(ctx, *rec.SKU)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return false, err
+		}
+		p = found
+	}
+	if rec.Slug != nil {
+		found, err := products.GetBySlugForUpdate(ctx, *rec.Slug)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return false, err
+		}
+		if p != nil && found != nil && found.ID != p.ID {
+			return false, fmt.Errorf("%w: sku %q and slug %q belong to different products", ErrValidation, *rec.SKU, *rec.Slug)
+		}
+		if p == nil {
+			p = found
+		}
+	}
+
+	created := p == nil
+	if created {
+		if rec.Name == nil || rec.Price == nil {
+			return false, fmt.Errorf("%w: name and price are required for new products", ErrValidation)
+		}
+		p = &models.Product{Currency: "USD"}
+	}
+	var old *models.Product
+	if !created {
+		snapshot := *p
+		old = &snapshot
+	}
+	oldSlug, oldStatus := p.Slug, p.Status
+	if err := rec.apply(p, paths); err != nil {
+		return false, err
+	}
+	if p.Status == "" {
+		p.Status = models.ProductStatusDraft
+	}
+	if err := validateProduct(p); err != nil {
+		return false, err
+	}
+
+	slugs := repository.NewProductSlugRepository(tx)
+	switch {
+	case created && p.Slug == "":
+		base := slugify(p.Name, "product", maxProductSlug)
+		_, err := createWithSlug(ctx, tx, slugs, base, maxProductSlug, func(sp pgx.Tx, slug string) error {
+			p.Slug = slug
+			return repository.NewProductRepository(sp).Create(ctx, p)
+		})
+		if err != nil {
+			return false, err
+		}
+	case created:
+		if err := slugs.Claim(ctx, p.Slug); err != nil {
+			return false, err
+		}
+		if err := products.Create(ctx, p); err != nil {
+			return false, err
+		}
+	default:
+		if err := changeSlug(ctx, slugs, p.ID, oldSlug, p.Slug); err != nil {
+			return false, err
+		}
+		if err := products.Update(ctx, p); err != nil {
+			return false, err
+		}
+	}
+	if _, err := recordPriceChange(ctx, tx, old, p, models.PriceChangeImport, nil, nil); err != nil {
+		return false, err
+	}
+	if rec.Attributes != nil {
+		if err := products.ReplaceAttributes(ctx, p.ID, rec.Attributes); err != nil {
+			return false, err
+		}
+	}
+	if rec.Images != nil {
+		images := make([]models.ProductImage, len(rec.Images))
+		for i, img := range rec.Images {
+			images[i] = models.ProductImage{URL: img.URL, AltText: img.AltText}
+		}
+		if err := products.SyncImages(ctx, p.ID, images); err != nil {
+			return false, err
+		}
+	}
+
+	// The status is checked last so publishing sees the imported images
+	if p.Status == oldStatus {
+		return created, nil
+	}
+	if err := checkStatusChange(ctx, products, p, oldStatus); err != nil {
+		return false, err
+	}
+	change := &models.ProductStatusChange{ProductID: p.ID, ToStatus: p.Status, Source: models.StatusSourceImport}
+	if !created {
+		change.FromStatus = &oldStatus
+	}
+	if err := repository.NewLifecycleRepository(tx).AddStatusChange(ctx, change); err != nil {
+		return false, err
+	}
+	return created, nil
+}
+
+// normalize trims the text fields of a record, treating blank ones as
+// absent
+func (rec *CatalogRecord) normalize() {
+	for _, field := range []**string{&rec.SKU, &rec.Slug, &rec.Name, &rec.Description, &rec.Currency, &rec.Status, &rec.CategoryPath} {
+		if *field == nil {
+			continue
+		}
+		if v := strings.TrimSpace(**field); v != "" {
+			*field = &v
+		} else {
+			*field = nil
+		}
+	}
+}
+
+// apply copies the fields a record sets onto p and validates its attributes
+// and images
+func (rec *CatalogRecord) apply(p *models.Product, paths map[string]uuid.UUID) error {
+	if rec.SKU != nil {
+		p.SKU = rec.SKU
+	}
+	if rec.Slug != nil {
+		p.Slug = *rec.Slug
+	}
+	if rec.Name != nil {
+		p.Name = *rec.Name
+	}
+	if rec.Description != nil {
+		p.Description = rec.Description
+	}
+	if rec.Price != nil {
+		p.Price = *rec.Price
+	}
+	if rec.CompareAtPrice != nil {
+		p.CompareAtPrice = rec.CompareAtPrice
+	}
+	if rec.Cost != nil {
+		p.Cost = rec.Cost
+	}
+	if rec.Currency != nil {
+		currency, err := normalizeCurrency(*rec.Currency)
+		if err != nil {
+			return err
+		}
+		p.Currency = currency
+	}
+	if rec.Status != nil {
+		p.Status = *rec.Status
+	}
+	if rec.CategoryPath != nil {
+		path := normalizeCategoryPath(*rec.CategoryPath)
+		id, ok := paths[path]
+		if !ok {
+			return fmt.Errorf("%w: unknown category %q", ErrValidation, path)
+		}
+		if id == uuid.Nil {
+			return fmt.Errorf("%w: category path %q matches more than one category", ErrValidation, path)
+		}
+		p.CategoryID = &id
+	}
+
+	for key, value := range rec.Attributes {
+		if key == "" || len(key) > 100 {
+			return fmt.Errorf("%w: attribute keys must be 1 to 100 characters", ErrValidation)
+		}
+		if value == "" {
+			return fmt.Errorf("%w: attribute %q has no value", ErrValidation, key)
+		}
+	}
+	seen := map[string]bool{}
+	for _, img := range rec.Images {
+		u, err := url.Parse(img.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(img.URL) > 1000 {
+			return fmt.Errorf("%w: image %q must be an absolute http(s) URL of at most 1000 characters", ErrValidation, img.URL)
+		}
+		if seen[img.URL] {
+			return fmt.Errorf("%w: image %q is listed twice", ErrValidation, img.URL)
+		}
+		seen[img.URL] = true
+		if img.AltText != nil && len(*img.AltText) > 255 {
+			return fmt.Errorf("%w: image alt text must be at most 255 characters", ErrValidation)
+		}
+	}
+	return nil
+}
+
+func validateProduct(p *models.Product) error {
+	if p.Name == "" || len(p.Name) > 500 {
+		return fmt.Errorf("%w: name must be 1 to 500 characters", ErrValidation)
+	}
+	// An empty slug is generated on create
+	if p.Slug != "" && (len(p.Slug) > maxProductSlug || !slugPattern.MatchString(p.Slug)) {
+		return fmt.Errorf("%w: slug must be lowercase letters, digits and hyphens", ErrValidation)
+	}
+	if p.SKU != nil && len(*p.SKU) > 100 {
+		return fmt.Errorf("%w: sku must be at most 100 characters", ErrValidation)
+	}
+	if p.Price < 0 {
+		return fmt.Errorf("%w: price must not be negative", ErrValidation)
+	}
+	if p.CompareAtPrice != nil && *p.CompareAtPrice < 0 {
+		return fmt.Errorf("%w: compare_at_price must not be negative", ErrValidation)
+	}
+	if p.Cost != nil && *p.Cost < 0 {
+		return fmt.Errorf("%w: cost must not be negative", ErrValidation)
+	}
+	if !slices.Contains([]string{"draft", "active", "archived"}, p.Status) {
+		return fmt.Errorf("%w: status must be draft, active or archived", ErrValidation)
+	}
+	return nil
+}
+
+// isRowError reports whether err rejects a single import row rather than
+// the whole batch: a validation failure or a constraint the row violates
+func isRowError(err error) bool {
+	if errors.Is(err, ErrValidation) || errors.Is(err, ErrInvalidState) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23"))
+}
+
+// categoryPaths maps the path of every category, its ancestors' names and
+// its own joined by " > ", to its ID. Paths shared by several categories map
+// to uuid.Nil.
+func categoryPaths(categories []models.Category) map[string]uuid.UUID {
+	byID := make(map[uuid.UUID]models.Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+	paths := make(map[string]uuid.UUID, len(categories))
+	for _, c := range categories {
+		names := []string{c.Name}
+		for parent := c.ParentID; parent != nil && len(names) <= len(categories); parent = byID[*parent].ParentID {
+			names = append(names, byID[*parent].Name)
+		}
+		slices.Reverse(names)
+		path := normalizeCategoryPath(strings.Join(names, ">"))
+		if _, ok := paths[path]; ok {
+			paths[path] = uuid.Nil
+		} else {
+			paths[path] = c.ID
+		}
+	}
+	return paths
+}
+
+// normalizeCategoryPath trims the names of a category path and joins them
+// with " > "
+func normalizeCategoryPath(path string) string {
+	names := strings.Split(path, ">")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+	return strings.Join(names, " > ")
+}
+
+// Export writes the products matching in to w. begin is called once right
+// before the first byte is written, so errors that happen earlier can still
+// be reported to the caller. It returns the number of products written.
+func (s *CatalogService) Export(ctx context.Context, in ExportCatalogInput, w io.Writer, begin func()) (int, error) {
+	if in.Format == "" {
+		in.Format = CatalogFormatCSV
+	}
+	if in.Format != CatalogFormatCSV && in.Format != CatalogFormatJSONL {
+		return 0, fmt.Errorf("%w: format must be csv or jsonl", ErrValidation)
+	}
+	if in.Status != "" && !slices.Contains([]string{"draft", "active", "archived"}, in.Status) {
+		return 0, fmt.Errorf("%w: unknown status %q", ErrValidation, in.Status)
+	}
+
+	n := 0
+	err := pgx.BeginTxFunc(ctx, s.pool, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		filter := repository.CatalogFilter{Status: in.Status}
+		if in.CategoryID != nil {
+			categories := repository.NewCategoryRepository(tx)
+			if _, err := categories.GetByID(ctx, *in.CategoryID); err != nil {
+				return err
+			}
+			var err error
+			if filter.CategoryIDs, err = categories.ListSubtreeIDs(ctx, *in.CategoryID); err != nil {
+				return err
+			}
+		}
+
+		catalog := repository.NewCatalogRepository(tx)
+		var attrKeys []string
+		images := 0
+		if in.Format == CatalogFormatCSV {
+			var err error
+			if attrKeys, err = catalog.ListExportAttributeKeys(ctx, filter); err != nil {
+				return err
+			}
+			if images, err = catalog.MaxExportImages(ctx, filter); err != nil {
+				return err
+			}
+		}
+
+		begin()
+		cw, err := newCatalogWriter(in.Format, w, attrKeys, images)
+		if err != nil {
+			return err
+		}
+		err = catalog.StreamExport(ctx, filter, func(e *repository.CatalogEntry) error {
+			n++
+			return cw.Write(catalogRecord(e))
+		})
+		if err != nil {
+			return err
+		}
+		return cw.Flush()
+	})
+	return n, err
+}
+
+// catalogRecord converts an exported product to a catalog record
+func catalogRecord(e *repository.CatalogEntry) *CatalogRecord {
+	p := e.Product
+	return &CatalogRecord{
+		SKU:            p.SKU,
+		Slug:           &p.Slug,
+		Name:           &p.Name,
+		Description:    p.Description,
+		Price:          &p.Price,
+		CompareAtPrice: p.CompareAtPrice,
+		Cost:           p.Cost,
+		Currency:       &p.Currency,
+		Status:         &p.Status,
+		CategoryPath:   e.CategoryPath,
+		Attributes:     e.Attributes,
+		Images:         e.Images,
+	}
+}
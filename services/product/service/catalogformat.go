@@ -0,0 +1,350 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"main.go/services/product/repository"
+)
+
+// Catalog file formats
+const (
+	CatalogFormatCSV   = "csv"
+	CatalogFormatJSONL = "jsonl"
+)
+
+// maxCatalogLine is the longest JSON Lines record accepted
+const maxCatalogLine = 1 << 20
+
+// catalogFields are the fixed CSV columns of a catalog file. Attributes are
+// stored in attr.<key> columns and images in image.<n> and image.<n>.alt
+// columns, numbered from 1.
+var catalogFields = []string{
+	"sku", "slug", "name", "description", "price", "compare_at_price", "cost",
+	"currency", "status", "category_path",
+}
+
+// CatalogRecord is a product row of a catalog file. Empty fields leave the
+// product unchanged on import; Attributes and Images replace the product's
+// when present.
+type CatalogRecord struct {
+	SKU            *string                   `json:"sku,omitempty"`
+	Slug           *string                   `json:"slug,omitempty"`
+	Name           *string                   `json:"name,omitempty"`
+	Description    *string                   `json:"description,omitempty"`
+	Price          *float64                  `json:"price,omitempty"`
+	CompareAtPrice *float64                  `json:"compare_at_price,omitempty"`
+	Cost           *float64                  `json:"cost,omitempty"`
+	Currency       *string                   `json:"currency,omitempty"`
+	Status         *string                   `json:"status,omitempty"`
+	CategoryPath   *string                   `json:"category_path,omitempty"`
+	Attributes     map[string]string         `json:"attributes,omitempty"`
+	Images         []repository.CatalogImage `json:"images,omitempty"`
+}
+
+// catalogReader reads the records of a catalog file in order. Next returns
+// io.EOF after the last record; errors wrapping ErrValidation concern a
+// single row and reading can continue.
+type catalogReader interface {
+	Next() (*CatalogRecord, error)
+}
+
+// newCatalogReader returns a reader for content in format, checking the CSV
+// header up front
+func newCatalogReader(format string, content []byte) (catalogReader, error) {
+	switch format {
+	case CatalogFormatCSV:
+		return newCSVCatalogReader(content)
+	case CatalogFormatJSONL:
+		s := bufio.NewScanner(bytes.NewReader(content))
+		s.Buffer(make([]byte, 0, 64*1024), maxCatalogLine)
+		return &jsonlCatalogReader{s: s}, nil
+	default:
+		return nil, fmt.Errorf("%w: format must be csv or jsonl", ErrValidation)
+	}
+}
+
+// countCatalogRows reads a whole catalog file and returns its number of
+// records. Problems that stop the file from being read are validation errors.
+func countCatalogRows(format string, content []byte) (int, error) {
+	r, err := newCatalogReader(format, content)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for {
+		_, err := r.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil && !errors.Is(err, ErrValidation) {
+			return 0, fmt.Errorf("%w: unreadable file after row %d: %v", ErrValidation, n, err)
+		}
+		n++
+	}
+}
+
+// csvColumn describes what a CSV column holds: a fixed field, an attribute
+// key, or the URL or alt text of an image
+type csvColumn struct {
+	field string
+	key   string
+	image int
+	alt   bool
+}
+
+type csvCatalogReader struct {
+	r       *csv.Reader
+	columns []csvColumn
+	attrs   bool
+	images  bool
+}
+
+func newCSVCatalogReader(content []byte) (*csvCatalogReader, error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.ReuseRecord = true
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: file is empty", ErrValidation)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid CSV header: %v", ErrValidation, err)
+	}
+
+	c := &csvCatalogReader{r: r, columns: make([]csvColumn, len(header))}
+	seen := map[string]bool{}
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if seen[name] {
+			return nil, fmt.Errorf("%w: column %q appears twice", ErrValidation, name)
+		}
+		seen[name] = true
+		switch {
+		case slices.Contains(catalogFields, name):
+			c.columns[i] = csvColumn{field: name}
+		case strings.HasPrefix(name, "attr."):
+			key := strings.TrimPrefix(name, "attr.")
+			if key == "" || len(key) > 100 {
+				return nil, fmt.Errorf("%w: column %q needs an attribute key of 1 to 100 characters", ErrValidation, name)
+			}
+			c.columns[i] = csvColumn{key: key}
+			c.attrs = true
+		case strings.HasPrefix(name, "image."):
+			rest, alt := strings.CutSuffix(strings.TrimPrefix(name, "image."), ".alt")
+			n, err := strconv.Atoi(rest)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: column %q must be image.<n> or image.<n>.alt with n from 1", ErrValidation, name)
+			}
+			c.columns[i] = csvColumn{image: n, alt: alt}
+			c.images = true
+		default:
+			return nil, fmt.Errorf("%w: unknown column %q", ErrValidation, name)
+		}
+	}
+	return c, nil
+}
+
+func (c *csvCatalogReader) Next() (*CatalogRecord, error) {
+	cells, err := c.r.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, fmt.Errorf("%w: %v", ErrValidation, parseErr.Err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &CatalogRecord{}
+	if c.attrs {
+		rec.Attributes = map[string]string{}
+	}
+	if c.images {
+		rec.Images = []repository.CatalogImage{}
+	}
+	urls := map[int]string{}
+	alts := map[int]string{}
+	for i, col := range c.columns {
+		v := strings.TrimSpace(cells[i])
+		if v == "" {
+			continue
+		}
+		switch {
+		case col.field != "":
+			if err := rec.set(col.field, v); err != nil {
+				return rec, err
+			}
+		case col.key != "":
+			rec.Attributes[col.key] = v
+		case col.alt:
+			alts[col.image] = v
+		default:
+			urls[col.image] = v
+		}
+	}
+	for n := range alts {
+		if _, ok := urls[n]; !ok {
+			return rec, fmt.Errorf("%w: image.%d.alt is set without image.%d", ErrValidation, n, n)
+		}
+	}
+	for _, n := range slices.Sorted(maps.Keys(urls)) {
+		img := repository.CatalogImage{URL: urls[n]}
+		if alt, ok := alts[n]; ok {
+			img.AltText = &alt
+		}
+		rec.Images = append(rec.Images, img)
+	}
+	return rec, nil
+}
+
+// set parses the value of a fixed CSV column into the record
+func (rec *CatalogRecord) set(field, v string) error {
+	number := func() (*float64, error) {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be a number", ErrValidation, field)
+		}
+		return &f, nil
+	}
+	var err error
+	switch field {
+	case "sku":
+		rec.SKU = &v
+	case "slug":
+		rec.Slug = &v
+	case "name":
+		rec.Name = &v
+	case "description":
+		rec.Description = &v
+	case "price":
+		rec.Price, err = number()
+	case "compare_at_price":
+		rec.CompareAtPrice, err = number()
+	case "cost":
+		rec.Cost, err = number()
+	case "currency":
+		rec.Currency = &v
+	case "status":
+		rec.Status = &v
+	case "category_path":
+		rec.CategoryPath = &v
+	}
+	return err
+}
+
+type jsonlCatalogReader struct {
+	s *bufio.Scanner
+}
+
+func (j *jsonlCatalogReader) Next() (*CatalogRecord, error) {
+	for j.s.Scan() {
+		line := bytes.TrimSpace(j.s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+		rec := &CatalogRecord{}
+		if err := dec.Decode(rec); err != nil {
+			return nil, fmt.Errorf("%w: invalid JSON: %v", ErrValidation, err)
+		}
+		if dec.More() {
+			return nil, fmt.Errorf("%w: invalid JSON: more than one value on the line", ErrValidation)
+		}
+		return rec, nil
+	}
+	if err := j.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// catalogWriter writes catalog records to an export
+type catalogWriter interface {
+	Write(rec *CatalogRecord) error
+	Flush() error
+}
+
+// newCatalogWriter returns a writer of format to w. CSV exports start with
+// a header holding the given attribute keys and image columns.
+func newCatalogWriter(format string, w io.Writer, attrKeys []string, images int) (catalogWriter, error) {
+	if format == CatalogFormatJSONL {
+		return &jsonlCatalogWriter{enc: json.NewEncoder(w)}, nil
+	}
+	header := slices.Clone(catalogFields)
+	for _, key := range attrKeys {
+		header = append(header, "attr."+key)
+	}
+	for n := 1; n <= images; n++ {
+		header = append(header, fmt.Sprintf("image.%d", n), fmt.Sprintf("image.%d.alt", n))
+	}
+	cw := &csvCatalogWriter{w: csv.NewWriter(w), attrKeys: attrKeys, images: images}
+	if err := cw.w.Write(header); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+type csvCatalogWriter struct {
+	w        *csv.Writer
+	attrKeys []string
+	images   int
+}
+
+func (c *csvCatalogWriter) Write(rec *CatalogRecord) error {
+	text := func(v *string) string {
+		if v == nil {
+			return ""
+		}
+		return *v
+	}
+	number := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', 2, 64)
+	}
+	row := []string{
+		text(rec.SKU), text(rec.Slug), text(rec.Name), text(rec.Description), number(rec.Price),
+		number(rec.CompareAtPrice), number(rec.Cost), text(rec.Currency), text(rec.Status), text(rec.CategoryPath),
+	}
+	for _, key := range c.attrKeys {
+		row = append(row, rec.Attributes[key])
+	}
+	for n := 0; n < c.images; n++ {
+		if n < len(rec.Images) {
+			row = append(row, rec.Images[n].URL, text(rec.Images[n].AltText))
+		} else {
+			row = append(row, "", "")
+		}
+	}
+	return c.w.Write(row)
+}
+
+func (c *csvCatalogWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlCatalogWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlCatalogWriter) Write(rec *CatalogRecord) error {
+	return j.enc.Encode(rec)
+}
+
+func (j *jsonlCatalogWriter) Flush() error {
+	return nil
+}
@@ -0,0 +1,480 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/product/models"
+	"main.go/services/product/repository"
+)
+
+// Category listing limits
+const (
+	defaultCategoryProductLimit = 50
+	maxCategoryProductLimit     = 500
+)
+
+// CreateCategoryInput holds a new category. Position is its index among its
+// siblings; it is appended when Position is nil. An empty Slug is generated
+// from the name.
+type CreateCategoryInput struct {
+	Name        string     `json:"name"`
+	Slug        string     `json:"slug"`
+	Description *string    `json:"description,omitempty"`
+	ParentID    *uuid.UUID `json:"parent_id,omitempty"`
+	Position    *int       `json:"position,omitempty"`
+}
+
+// UpdateCategoryInput holds the category fields to change; nil fields are
+// kept, and an empty Slug is generated from the name. The parent is changed
+// with Move.
+type UpdateCategoryInput struct {
+	Name        *string `json:"name,omitempty"`
+	Slug        *string `json:"slug,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+// MoveCategoryInput names the new parent of a category, or the root level
+// when ParentID is nil, and its index among the new siblings
+type MoveCategoryInput struct {
+	ParentID *uuid.UUID `json:"parent_id"`
+	Position *int       `json:"position,omitempty"`
+}
+
+// ReorderCategoriesInput lists every child of a parent, or every root
+// category when ParentID is nil, in the desired order
+type ReorderCategoriesInput struct {
+	ParentID    *uuid.UUID  `json:"parent_id"`
+	CategoryIDs []uuid.UUID `json:"category_ids"`
+}
+
+// CategoryProductsInput pages through the products of a category
+type CategoryProductsInput struct {
+	IncludeDescendants bool
+	Status             string
+	Limit              int
+	Offset             int
+}
+
+// CategoryProductPage is a page of products with the total count
+type CategoryProductPage struct {
+	Products []models.Product `json:"products"`
+	Total    int              `json:"total"`
+}
+
+// CategoryService manages the category tree
+type CategoryService struct {
+	pool *pgxpool.Pool
+}
+
+// NewCategoryService creates a new CategoryService
+func NewCategoryService(pool *pgxpool.Pool) *CategoryService {
+	return &CategoryService{pool: pool}
+}
+
+// Get returns a category by its ID
+func (s *CategoryService) Get(ctx context.Context, id uuid.UUID) (*models.Category, error) {
+	return repository.NewCategoryRepository(s.pool).GetByID(ctx, id)
+}
+
+// GetBySlug returns the category with a slug. When the slug is a former one
+// it returns the category that used it and moved is true.
+func (s *CategoryService) GetBySlug(ctx context.Context, slug string) (c *models.Category, moved bool, err error) {
+	categories := repository.NewCategoryRepository(s.pool)
+	c, err = categories.GetBySlug(ctx, slug)
+	if !errors.Is(err, repository.ErrNotFound) {
+		return c, false, err
+	}
+	id, err := repository.NewCategorySlugRepository(s.pool).Resolve(ctx, slug)
+	if err != nil {
+		return nil, false, err
+	}
+	c, err = categories.GetByID(ctx, id)
+	return c, err == nil, err
+}
+
+// Tree returns every root category with its descendants
+func (s *CategoryService) Tree(ctx context.Context) ([]*models.CategoryNode, error) {
+	categories, err := repository.NewCategoryRepository(s.pool).ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return buildTree(categories, nil), nil
+}
+
+// Subtree returns a category with its descendants down to depth levels
+// below it, or all of them when depth is negative
+func (s *CategoryService) Subtree(ctx context.Context, id uuid.UUID, depth int) (*models.CategoryNode, error) {
+	categories, err := repository.NewCategoryRepository(s.pool).ListSubtree(ctx, id, depth)
+	if err != nil {
+		return nil, err
+	}
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("failed to get category: %w", repository.ErrNotFound)
+	}
+	root := &models.CategoryNode{Category: categories[0], Children: []*models.CategoryNode{}}
+	root.Children = buildTree(categories[1:], &root.ID)
+	return root, nil
+}
+
+// buildTree nests categories, already in sibling order, below rootID. Nodes
+// whose parent is missing from categories are dropped.
+func buildTree(categories []models.Category, rootID *uuid.UUID) []*models.CategoryNode {
+	children := map[uuid.UUID][]*models.CategoryNode{}
+	var roots []*models.CategoryNode
+	nodes := make([]*models.CategoryNode, len(categories))
+	for i := range categories {
+		n := &models.CategoryNode{Category: categories[i], Children: []*models.CategoryNode{}}
+		nodes[i] = n
+		switch {
+		case n.ParentID == nil && rootID == nil, n.ParentID != nil && rootID != nil && *n.ParentID == *rootID:
+			roots = append(roots, n)
+		case n.ParentID != nil:
+			children[*n.ParentID] = append(children[*n.ParentID], n)
+		}
+	}
+	for _, n := range nodes {
+		if c, ok := children[n.ID]; ok {
+			n.Children = c
+		}
+	}
+	if roots == nil {
+		roots = []*models.CategoryNode{}
+	}
+	return roots
+}
+
+// Breadcrumbs returns the path of categories from the root down to the
+// category of a product, which is empty for an uncategorized product
+func (s *CategoryService) Breadcrumbs(ctx context.Context, productID uuid.UUID) ([]models.Category, error) {
+	product, err := repository.NewProductRepository(s.pool).GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if product.CategoryID == nil {
+		return []models.Category{}, nil
+	}
+	return repository.NewCategoryRepository(s.pool).ListAncestors(ctx, *product.CategoryID)
+}
+
+// ListProducts returns a page of the products in a category and, when
+// IncludeDescendants is set, in its subcategories
+func (s *CategoryService) ListProducts(ctx context.Context, id uuid.UUID, in CategoryProductsInput) (*CategoryProductPage, error) {
+	limit := in.Limit
+	if limit == 0 {
+		limit = defaultCategoryProductLimit
+	}
+	if limit < 0 || limit > maxCategoryProductLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrValidation, maxCategoryProductLimit)
+	}
+	if in.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrValidation)
+	}
+	if in.Status != "" && !slices.Contains([]string{"draft", "active", "archived"}, in.Status) {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrValidation, in.Status)
+	}
+
+	categories := repository.NewCategoryRepository(s.pool)
+	if _, err := categories.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	ids := []uuid.UUID{id}
+	var err error
+	if in.IncludeDescendants {
+		if ids, err = categories.ListSubtreeIDs(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	products := repository.NewProductRepository(s.pool)
+	page := &CategoryProductPage{}
+	if page.Products, err = products.ListByCategories(ctx, ids, in.Status, limit, in.Offset); err != nil {
+		return nil, err
+	}
+	if page.Total, err = products.CountByCategories(ctx, ids, in.Status); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Create adds a category at the given position among its siblings
+func (s *CategoryService) Create(ctx context.Context, in CreateCategoryInput) (*models.Category, error) {
+	c := &models.Category{
+		Name:        strings.TrimSpace(in.Name),
+		Slug:        strings.TrimSpace(in.Slug),
+		Description: in.Description,
+		ParentID:    in.ParentID,
+	}
+	if err := validateCategory(c); err != nil {
+		return nil, err
+	}
+
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		categories := repository.NewCategoryRepository(tx)
+		if err := categories.LockTree(ctx); err != nil {
+			return err
+		}
+		if c.ParentID != nil {
+			if _, err := categories.GetByID(ctx, *c.ParentID); err != nil {
+				return parentError(err)
+			}
+		}
+		siblings, err := categories.ListChildrenForUpdate(ctx, c.ParentID)
+		if err != nil {
+			return err
+		}
+		c.SortOrder = len(siblings)
+		slugs := repository.NewCategorySlugRepository(tx)
+		if c.Slug == "" {
+			base := slugify(c.Name, "category", maxCategorySlug)
+			_, err = createWithSlug(ctx, tx, slugs, base, maxCategorySlug, func(sp pgx.Tx, slug string) error {
+				c.Slug = slug
+				return repository.NewCategoryRepository(sp).Create(ctx, c)
+			})
+		} else {
+			err = createCategory(ctx, categories, slugs, c)
+		}
+		if err != nil {
+			return err
+		}
+		return place(ctx, categories, c, siblings, in.Position)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Update changes the name, slug or description of a category
+func (s *CategoryService) Update(ctx context.Context, id uuid.UUID, in UpdateCategoryInput) (*models.Category, error) {
+	var c *models.Category
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		categories := repository.NewCategoryRepository(tx)
+		if err := categories.LockTree(ctx); err != nil {
+			return err
+		}
+		var err error
+		if c, err = categories.GetByID(ctx, id); err != nil {
+			return err
+		}
+		if in.Name != nil {
+			c.Name = strings.TrimSpace(*in.Name)
+		}
+		if in.Description != nil {
+			c.Description = in.Description
+		}
+		slugs := repository.NewCategorySlugRepository(tx)
+		oldSlug := c.Slug
+		if in.Slug != nil {
+			c.Slug = strings.TrimSpace(*in.Slug)
+		}
+		if c.Slug == "" {
+			base := slugify(c.Name, "category", maxCategorySlug)
+			if c.Slug, err = freeSlug(ctx, slugs, base, oldSlug, maxCategorySlug); err != nil {
+				return err
+			}
+		}
+		if err := validateCategory(c); err != nil {
+			return err
+		}
+		if err := checkSlugFree(ctx, categories, c.Slug, c.ID); err != nil {
+			return err
+		}
+		if err := changeSlug(ctx, slugs, c.ID, oldSlug, c.Slug); err != nil {
+			return err
+		}
+		return categories.Update(ctx, c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Move reattaches a category, with its whole subtree, below a new parent at
+// the given position. Moving a category below itself or one of its
+// descendants is rejected, and tree changes are serialized so concurrent
+// moves cannot form a cycle either.
+func (s *CategoryService) Move(ctx context.Context, id uuid.UUID, in MoveCategoryInput) (*models.Category, error) {
+	var c *models.Category
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		categories := repository.NewCategoryRepository(tx)
+		if err := categories.LockTree(ctx); err != nil {
+			return err
+		}
+		var err error
+		if c, err = categories.GetByID(ctx, id); err != nil {
+			return err
+		}
+		if in.ParentID != nil {
+			if _, err := categories.GetByID(ctx, *in.ParentID); err != nil {
+				return parentError(err)
+			}
+			cycle, err := categories.IsDescendant(ctx, c.ID, *in.ParentID)
+			if err != nil {
+				return err
+			}
+			if cycle {
+				return fmt.Errorf("%w: a category cannot be moved below itself or its descendants", ErrValidation)
+			}
+		}
+
+		oldParent := c.ParentID
+		c.ParentID = in.ParentID
+		if !sameParent(oldParent, in.ParentID) {
+			// Close the gap left among the old siblings
+			old, err := categories.ListChildrenForUpdate(ctx, oldParent)
+			if err != nil {
+				return err
+			}
+			if err := categories.SetSortOrders(ctx, categoryIDs(old, c.ID)); err != nil {
+				return err
+			}
+		}
+		siblings, err := categories.ListChildrenForUpdate(ctx, in.ParentID)
+		if err != nil {
+			return err
+		}
+		if !sameParent(oldParent, in.ParentID) {
+			c.SortOrder = len(siblings)
+		}
+		if err := categories.Update(ctx, c); err != nil {
+			return err
+		}
+		return place(ctx, categories, c, siblings, in.Position)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reorder sets the order of the children of a parent. The list must name
+// every child exactly once.
+func (s *CategoryService) Reorder(ctx context.Context, in ReorderCategoriesInput) ([]models.Category, error) {
+	var children []models.Category
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		categories := repository.NewCategoryRepository(tx)
+		if err := categories.LockTree(ctx); err != nil {
+			return err
+		}
+		if in.ParentID != nil {
+			if _, err := categories.GetByID(ctx, *in.ParentID); err != nil {
+				return parentError(err)
+			}
+		}
+		current, err := categories.ListChildrenForUpdate(ctx, in.ParentID)
+		if err != nil {
+			return err
+		}
+		want := map[uuid.UUID]bool{}
+		for _, c := range current {
+			want[c.ID] = true
+		}
+		if len(in.CategoryIDs) != len(current) {
+			return fmt.Errorf("%w: category_ids must list all %d children exactly once", ErrValidation, len(current))
+		}
+		for _, id := range in.CategoryIDs {
+			if !want[id] {
+				return fmt.Errorf("%w: category_ids must list all %d children exactly once", ErrValidation, len(current))
+			}
+			delete(want, id)
+		}
+		if err := categories.SetSortOrders(ctx, in.CategoryIDs); err != nil {
+			return err
+		}
+		children, err = categories.ListChildrenForUpdate(ctx, in.ParentID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return children, nil
+}
+
+// place puts c at position among siblings, which may already contain it, and
+// renumbers them. A nil or out of range position appends c.
+func place(ctx context.Context, categories *repository.CategoryRepository, c *models.Category, siblings []models.Category, position *int) error {
+	ids := categoryIDs(siblings, c.ID)
+	at := len(ids)
+	if position != nil {
+		if *position < 0 {
+			return fmt.Errorf("%w: position must not be negative", ErrValidation)
+		}
+		at = min(*position, len(ids))
+	}
+	ids = slices.Insert(ids, at, c.ID)
+	c.SortOrder = at
+	return categories.SetSortOrders(ctx, ids)
+}
+
+// categoryIDs returns the IDs of categories except skip
+func categoryIDs(categories []models.Category, skip uuid.UUID) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(categories))
+	for _, c := range categories {
+		if c.ID != skip {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+func sameParent(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func validateCategory(c *models.Category) error {
+	if c.Name == "" || len(c.Name) > 255 {
+		return fmt.Errorf("%w: name must be 1 to 255 characters", ErrValidation)
+	}
+	// An empty slug is generated later
+	if c.Slug != "" && (len(c.Slug) > maxCategorySlug || !slugPattern.MatchString(c.Slug)) {
+		return fmt.Errorf("%w: slug must be lowercase letters, digits and hyphens", ErrValidation)
+	}
+	return nil
+}
+
+// createCategory inserts a category with the slug it was given, taking the
+// slug over from the history should it be a former one
+func createCategory(ctx context.Context, categories *repository.CategoryRepository, slugs *repository.SlugRepository, c *models.Category) error {
+	if err := checkSlugFree(ctx, categories, c.Slug, uuid.Nil); err != nil {
+		return err
+	}
+	if err := slugs.Claim(ctx, c.Slug); err != nil {
+		return err
+	}
+	return categories.Create(ctx, c)
+}
+
+// checkSlugFree rejects a slug already used by a category other than self
+func checkSlugFree(ctx context.Context, categories *repository.CategoryRepository, slug string, self uuid.UUID) error {
+	existing, err := categories.GetBySlug(ctx, slug)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != self {
+		return fmt.Errorf("%w: slug %q is already used by another category", ErrInvalidState, slug)
+	}
+	return nil
+}
+
+// parentError reports a missing parent as invalid input rather than as the
+// category itself not being found
+func parentError(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: parent category not found", ErrValidation)
+	}
+	return err
+}
@@ -0,0 +1,460 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/product/models"
+	"main.go/services/product/repository"
+	"main.go/services/product/storage"
+)
+
+// Upload limits
+const (
+	MaxImageSize    = 10 << 20
+	maxImagePixels  = 50_000_000
+	blobCleanupPage = 100
+	// blobUploadTimeout is how long an upload may take to write its files
+	// before CleanupBlobs reclaims them
+	blobUploadTimeout = time.Hour
+)
+
+// imageTypes lists the accepted upload content types
+var imageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// UploadImageInput is an uploaded image file with optional alt text
+type UploadImageInput struct {
+	Content []byte
+	AltText *string
+}
+
+// ReorderImagesInput lists every image of a product in the desired order
+type ReorderImagesInput struct {
+	ImageIDs []uuid.UUID `json:"image_ids"`
+}
+
+// ImageService stores uploaded product images and their derived sizes.
+// Files are stored once per content hash and removed from the store when no
+// image uses them anymore.
+type ImageService struct {
+	pool    *pgxpool.Pool
+	store   storage.Store
+	baseURL string
+}
+
+// NewImageService creates a new ImageService. Image URLs start with baseURL,
+// the public address of the product service.
+func NewImageService(pool *pgxpool.Pool, store storage.Store, baseURL string) *ImageService {
+	return &ImageService{pool: pool, store: store, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Upload adds an image file to the end of a product's images. Uploading a
+// file the product already has returns the existing image and false.
+func (s *ImageService) Upload(ctx context.Context, productID uuid.UUID, in UploadImageInput) (*models.ProductImage, bool, error) {
+	if len(in.Content) == 0 {
+		return nil, false, fmt.Errorf("%w: image is empty", ErrValidation)
+	}
+	if len(in.Content) > MaxImageSize {
+		return nil, false, fmt.Errorf("%w: image exceeds %d bytes", ErrValidation, MaxImageSize)
+	}
+	if in.AltText != nil && len(*in.AltText) > 255 {
+		return nil, false, fmt.Errorf("%w: alt_text must be at most 255 characters", ErrValidation)
+	}
+	contentType := http.DetectContentType(in.Content)
+	if !imageTypes[contentType] {
+		return nil, false, fmt.Errorf("%w: images must be JPEG, PNG or GIF, got %s", ErrValidation, contentType)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(in.Content))
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: invalid image: %v", ErrValidation, err)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, false, fmt.Errorf("%w: image exceeds %d pixels", ErrValidation, maxImagePixels)
+	}
+	sum := sha256.Sum256(in.Content)
+	hash := hex.EncodeToString(sum[:])
+	if err := s.storeBlob(ctx, hash, contentType, in.Content); err != nil {
+		return nil, false, err
+	}
+
+	var img *models.ProductImage
+	created := false
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := repository.NewProductRepository(tx).GetByIDForUpdate(ctx, productID); err != nil {
+			return err
+		}
+		images := repository.NewImageRepository(tx)
+		existing, err := images.GetImageByHash(ctx, productID, hash)
+		if err == nil {
+			img = existing
+			return nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		if err := images.LockBlob(ctx, hash); err != nil {
+			return err
+		}
+		blob, err := images.GetBlob(ctx, hash)
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && blob.Status != models.ImageBlobStored) {
+			return fmt.Errorf("%w: image file is being removed, try again", ErrInvalidState)
+		}
+		if err != nil {
+			return err
+		}
+
+		all, err := repository.NewVariantRepository(tx).ListImages(ctx, productID)
+		if err != nil {
+			return err
+		}
+		img = &models.ProductImage{
+			ProductID:   productID,
+			URL:         s.fileURL(hash, "original"),
+			AltText:     in.AltText,
+			SortOrder:   len(all),
+			ContentHash: &hash,
+		}
+		created = true
+		return images.CreateImage(ctx, img)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	s.addSizes(img)
+	return img, created, nil
+}
+
+// storeBlob makes sure a file and its derived sizes are in the store. The
+// blob is recorded as storing before any file is written and marked stored
+// after, so the files of a failed upload are removed by CleanupBlobs through
+// their blob. A blob still storing, e.g. after a crash, is written again.
+func (s *ImageService) storeBlob(ctx context.Context, hash, contentType string, content []byte) error {
+	var files map[string][]byte
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		images := repository.NewImageRepository(tx)
+		if err := images.LockBlob(ctx, hash); err != nil {
+			return err
+		}
+		existing, err := images.GetBlob(ctx, hash)
+		if err == nil && existing.Status == models.ImageBlobStored {
+			return nil
+		}
+		if err == nil && existing.Status == models.ImageBlobReleasing {
+			return fmt.Errorf("%w: image file is being removed, try again", ErrInvalidState)
+		}
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		blob, rendered, rerr := renderBlob(hash, contentType, content)
+		if rerr != nil {
+			return rerr
+		}
+		files = rendered
+		if err == nil {
+			return nil
+		}
+		return images.CreateBlob(ctx, blob)
+	})
+	if err != nil || files == nil {
+		return err
+	}
+
+	for key, data := range files {
+		if err := s.store.Put(ctx, key, bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		images := repository.NewImageRepository(tx)
+		if err := images.LockBlob(ctx, hash); err != nil {
+			return err
+		}
+		blob, err := images.GetBlob(ctx, hash)
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && blob.Status == models.ImageBlobReleasing) {
+			return fmt.Errorf("%w: image file is being removed, try again", ErrInvalidState)
+		}
+		if err != nil || blob.Status == models.ImageBlobStored {
+			return err
+		}
+		return images.UpdateBlobStatus(ctx, hash, models.ImageBlobStored)
+	})
+}
+
+// renderBlob describes a new file and encodes its derived sizes, returning
+// the contents to store by key
+func renderBlob(hash, contentType string, content []byte) (*models.ImageBlob, map[string][]byte, error) {
+	decoded, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid image: %v", ErrValidation, err)
+	}
+	src := toRGBA(decoded)
+	blob := &models.ImageBlob{
+		Hash:        hash,
+		ContentType: contentType,
+		SizeBytes:   int64(len(content)),
+		Width:       src.Rect.Dx(),
+		Height:      src.Rect.Dy(),
+		Status:      models.ImageBlobStoring,
+	}
+	files := map[string][]byte{originalKey(hash): content}
+	for _, size := range imageSizes {
+		w, h := fit(blob.Width, blob.Height, size.Side)
+		data, sizeType, err := encodeImage(resize(src, w, h), contentType)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode %s image: %w", size.Name, err)
+		}
+		files[sizeKey(hash, size.Name)] = data
+		blob.Sizes = append(blob.Sizes, models.ImageSize{Name: size.Name, Width: w, Height: h, ContentType: sizeType})
+	}
+	return blob, files, nil
+}
+
+// List returns the images of a product in display order
+func (s *ImageService) List(ctx context.Context, productID uuid.UUID) ([]models.ProductImage, error) {
+	if _, err := repository.NewProductRepository(s.pool).GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	images, err := repository.NewVariantRepository(s.pool).ListImages(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if images == nil {
+		images = []models.ProductImage{}
+	}
+	for i := range images {
+		s.addSizes(&images[i])
+	}
+	return images, nil
+}
+
+// Reorder sets the display order of a product's images. The list must name
+// every image exactly once.
+func (s *ImageService) Reorder(ctx context.Context, productID uuid.UUID, in ReorderImagesInput) ([]models.ProductImage, error) {
+	var images []models.ProductImage
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := repository.NewProductRepository(tx).GetByIDForUpdate(ctx, productID); err != nil {
+			return err
+		}
+		variants := repository.NewVariantRepository(tx)
+		current, err := variants.ListImages(ctx, productID)
+		if err != nil {
+			return err
+		}
+		want := map[uuid.UUID]bool{}
+		for _, img := range current {
+			want[img.ID] = true
+		}
+		if len(in.ImageIDs) != len(current) {
+			return fmt.Errorf("%w: image_ids must list all %d images exactly once", ErrValidation, len(current))
+		}
+		for _, id := range in.ImageIDs {
+			if !want[id] {
+				return fmt.Errorf("%w: image_ids must list all %d images exactly once", ErrValidation, len(current))
+			}
+			delete(want, id)
+		}
+		if err := repository.NewImageRepository(tx).SetImageOrder(ctx, in.ImageIDs); err != nil {
+			return err
+		}
+		images, err = variants.ListImages(ctx, productID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range images {
+		s.addSizes(&images[i])
+	}
+	return images, nil
+}
+
+// Delete removes a product image, closes the gap in the order of the rest
+// and removes its file from the store unless another image uses it
+func (s *ImageService) Delete(ctx context.Context, id uuid.UUID) error {
+	var img *models.ProductImage
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		images := repository.NewImageRepository(tx)
+		var err error
+		if img, err = images.GetImage(ctx, id); err != nil {
+			return err
+		}
+		if _, err := repository.NewProductRepository(tx).GetByIDForUpdate(ctx, img.ProductID); err != nil {
+			return err
+		}
+		if err := images.DeleteImage(ctx, id); err != nil {
+			return err
+		}
+		rest, err := repository.NewVariantRepository(tx).ListImages(ctx, img.ProductID)
+		if err != nil {
+			return err
+		}
+		ids := make([]uuid.UUID, len(rest))
+		for i, r := range rest {
+			ids[i] = r.ID
+		}
+		return images.SetImageOrder(ctx, ids)
+	})
+	if err != nil {
+		return err
+	}
+	if img.ContentHash != nil {
+		// The image is gone either way; CleanupBlobs retries a failed removal
+		if err := s.releaseBlob(ctx, *img.ContentHash); err != nil {
+			log.Printf("failed to remove image file %s: %v", *img.ContentHash, err)
+		}
+	}
+	return nil
+}
+
+// OpenFile returns a reader of an uploaded file in a size, or the original
+// when size is "original", with its content type
+func (s *ImageService) OpenFile(ctx context.Context, hash, size string) (io.ReadCloser, string, error) {
+	blob, err := repository.NewImageRepository(s.pool).GetBlob(ctx, hash)
+	if err != nil {
+		return nil, "", err
+	}
+	key, contentType := originalKey(hash), blob.ContentType
+	if size != "original" {
+		found := false
+		for _, sz := range blob.Sizes {
+			if sz.Name == size {
+				key, contentType, found = sizeKey(hash, size), sz.ContentType, true
+			}
+		}
+		if !found {
+			return nil, "", fmt.Errorf("failed to open image size %q: %w", size, repository.ErrNotFound)
+		}
+	}
+	r, err := s.store.Open(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, "", fmt.Errorf("%w: %v", repository.ErrNotFound, err)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return r, contentType, nil
+}
+
+// CleanupBlobs removes the files no image uses anymore, such as those of
+// images a catalog import replaced, and returns how many were removed
+func (s *ImageService) CleanupBlobs(ctx context.Context) (int, error) {
+	hashes, err := repository.NewImageRepository(s.pool).ListUnusedBlobs(ctx, time.Now().Add(-blobUploadTimeout), blobCleanupPage)
+	if err != nil {
+		return 0, err
+	}
+	for i, hash := range hashes {
+		if err := s.releaseBlob(ctx, hash); err != nil {
+			return i, err
+		}
+	}
+	return len(hashes), nil
+}
+
+// RunBlobCleanup removes unused files every interval until ctx is done
+func (s *ImageService) RunBlobCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.CleanupBlobs(ctx); err != nil {
+				log.Printf("image cleanup: %v", err)
+			}
+		}
+	}
+}
+
+// releaseBlob removes a file and its sizes from the store and the database
+// when no image uses it. The blob is marked releasing first, which keeps
+// uploads of the same file from reusing it, and deleted only once its files
+// are gone, so a failed removal is retried through it.
+func (s *ImageService) releaseBlob(ctx context.Context, hash string) error {
+	var blob *models.ImageBlob
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		images := repository.NewImageRepository(tx)
+		if err := images.LockBlob(ctx, hash); err != nil {
+			return err
+		}
+		uses, err := images.CountBlobUses(ctx, hash)
+		if err != nil || uses > 0 {
+			return err
+		}
+		found, err := images.GetBlob(ctx, hash)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		blob = found
+		if blob.Status == models.ImageBlobReleasing {
+			return nil
+		}
+		return images.UpdateBlobStatus(ctx, hash, models.ImageBlobReleasing)
+	})
+	if err != nil || blob == nil {
+		return err
+	}
+
+	for _, size := range blob.Sizes {
+		if err := s.store.Delete(ctx, sizeKey(hash, size.Name)); err != nil {
+			return err
+		}
+	}
+	if err := s.store.Delete(ctx, originalKey(hash)); err != nil {
+		return err
+	}
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		images := repository.NewImageRepository(tx)
+		if err := images.LockBlob(ctx, hash); err != nil {
+			return err
+		}
+		return images.DeleteBlob(ctx, hash)
+	})
+}
+
+// addSizes fills in the URLs of the derived sizes of an uploaded image
+func (s *ImageService) addSizes(img *models.ProductImage) {
+	if img.ContentHash == nil {
+		return
+	}
+	img.Sizes = map[string]string{}
+	for _, size := range imageSizes {
+		img.Sizes[size.Name] = s.fileURL(*img.ContentHash, size.Name)
+	}
+}
+
+// fileURL is where an uploaded file is served in a size
+func (s *ImageService) fileURL(hash, size string) string {
+	return s.baseURL + "/image-files/" + hash + "/" + size
+}
+
+func originalKey(hash string) string {
+	return "originals/" + hash
+}
+
+func sizeKey(hash, size string) string {
+	return "sizes/" + hash + "/" + size
+}
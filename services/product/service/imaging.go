@@ -0,0 +1,101 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	// Registers the GIF decoder with image.Decode
+	_ "image/gif"
+)
+
+// imageSize is a derived size of uploaded images, fitting within Side pixels
+// on the longest side
+type imageSize struct {
+	Name string
+	Side int
+}
+
+// imageSizes are generated for every uploaded image. Images are never
+// enlarged, so a small original yields sizes of its own dimensions.
+var imageSizes = []imageSize{
+	{Name: "thumb", Side: 150},
+	{Name: "small", Side: 400},
+	{Name: "medium", Side: 800},
+}
+
+// jpegQuality is the quality of derived JPEG sizes
+const jpegQuality = 85
+
+// toRGBA returns img as an RGBA image with its origin at 0,0
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// fit returns the dimensions of a w×h image scaled down to fit within side
+// pixels, keeping its aspect ratio
+func fit(w, h, side int) (int, int) {
+	if w <= side && h <= side {
+		return w, h
+	}
+	if w >= h {
+		return side, max(1, h*side/w)
+	}
+	return max(1, w*side/h), side
+}
+
+// resize scales src down to w×h, averaging the source pixels each target
+// pixel covers. RGBA pixels are premultiplied, so transparent pixels do not
+// bleed their color into the result.
+func resize(src *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if w == sw && h == sh {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// encodeImage encodes img as JPEG for JPEG originals and as PNG otherwise,
+// which keeps transparency
+func encodeImage(img image.Image, contentType string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/product/models"
+	"main.go/services/product/repository"
+)
+
+// statusTransitions lists the statuses a product can move to from each
+// status. Archived products return to draft before they are published again.
+var statusTransitions = map[string][]string{
+	models.ProductStatusDraft:    {models.ProductStatusActive, models.ProductStatusArchived},
+	models.ProductStatusActive:   {models.ProductStatusDraft, models.ProductStatusArchived},
+	models.ProductStatusArchived: {models.ProductStatusDraft},
+}
+
+// ChangeStatusInput moves a product to a new status with an optional note for
+// the audit trail
+type ChangeStatusInput struct {
+	Status string  `json:"status"`
+	Note   *string `json:"note,omitempty"`
+}
+
+// ScheduleStatusInput plans a status change for a future time
+type ScheduleStatusInput struct {
+	Status string    `json:"status"`
+	RunAt  time.Time `json:"run_at"`
+}
+
+// LifecycleService publishes, unpublishes and archives products, now or at a
+// scheduled time, and keeps the audit trail of their status
+type LifecycleService struct {
+	pool *pgxpool.Pool
+	now  func() time.Time
+}
+
+// NewLifecycleService creates a new LifecycleService
+func NewLifecycleService(pool *pgxpool.Pool) *LifecycleService {
+	return &LifecycleService{pool: pool, now: time.Now}
+}
+
+// ChangeStatus moves a product to a new status. Publishing requires a price,
+// a category and at least one image.
+func (s *LifecycleService) ChangeStatus(ctx context.Context, id uuid.UUID, in ChangeStatusInput) (*models.Product, error) {
+	var p *models.Product
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		var err error
+		if p, err = repository.NewProductRepository(tx).GetByIDForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if p.Status == in.Status {
+			return fmt.Errorf("%w: product is already %s", ErrInvalidState, p.Status)
+		}
+		return setStatus(ctx, tx, p, in.Status, models.StatusSourceManual, in.Note)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// History returns the status changes of a product, oldest first
+func (s *LifecycleService) History(ctx context.Context, id uuid.UUID) ([]models.ProductStatusChange, error) {
+	if _, err := repository.NewProductRepository(s.pool).GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	changes, err := repository.NewLifecycleRepository(s.pool).ListStatusChanges(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if changes == nil {
+		changes = []models.ProductStatusChange{}
+	}
+	return changes, nil
+}
+
+// Schedule plans a status change of a product. Whether the change is allowed
+// is checked when it is due.
+func (s *LifecycleService) Schedule(ctx context.Context, id uuid.UUID, in ScheduleStatusInput) (*models.ProductStatusSchedule, error) {
+	if _, ok := statusTransitions[in.Status]; !ok {
+		return nil, fmt.Errorf("%w: status must be draft, active or archived", ErrValidation)
+	}
+	if in.RunAt.IsZero() {
+		return nil, fmt.Errorf("%w: run_at is required", ErrValidation)
+	}
+	if !in.RunAt.After(s.now()) {
+		return nil, fmt.Errorf("%w: run_at must be in the future", ErrValidation)
+	}
+	if _, err := repository.NewProductRepository(s.pool).GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	sched := &models.ProductStatusSchedule{
+		ProductID: id,
+		Status:    in.Status,
+		RunAt:     in.RunAt,
+		State:     models.SchedulePending,
+	}
+	if err := repository.NewLifecycleRepository(s.pool).CreateSchedule(ctx, sched); err != nil {
+		return nil, err
+	}
+	return sched, nil
+}
+
+// ListSchedules returns the scheduled status changes of a product by time
+func (s *LifecycleService) ListSchedules(ctx context.Context, id uuid.UUID) ([]models.ProductStatusSchedule, error) {
+	if _, err := repository.NewProductRepository(s.pool).GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	schedules, err := repository.NewLifecycleRepository(s.pool).ListSchedules(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if schedules == nil {
+		schedules = []models.ProductStatusSchedule{}
+	}
+	return schedules, nil
+}
+
+// CancelSchedule cancels a status change that has not been applied yet
+func (s *LifecycleService) CancelSchedule(ctx context.Context, id uuid.UUID) (*models.ProductStatusSchedule, error) {
+	var sched *models.ProductStatusSchedule
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		lifecycle := repository.NewLifecycleRepository(tx)
+		var err error
+		if sched, err = lifecycle.GetScheduleForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if sched.State != models.SchedulePending {
+			return fmt.Errorf("%w: only pending schedules can be cancelled, schedule is %s", ErrInvalidState, sched.State)
+		}
+		now := s.now()
+		sched.State, sched.ProcessedAt = models.ScheduleCancelled, &now
+		return lifecycle.UpdateSchedule(ctx, sched)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sched, nil
+}
+
+// ProcessNextSchedule applies the earliest due status change. A change the
+// product no longer allows is marked failed with the reason. It returns false
+// when nothing was due.
+func (s *LifecycleService) ProcessNextSchedule(ctx context.Context) (bool, error) {
+	found := false
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		lifecycle := repository.NewLifecycleRepository(tx)
+		sched, err := lifecycle.ClaimDueSchedule(ctx)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+
+		err = pgx.BeginFunc(ctx, tx, func(sp pgx.Tx) error {
+			p, err := repository.NewProductRepository(sp).GetByIDForUpdate(ctx, sched.ProductID)
+			if err != nil {
+				return err
+			}
+			if p.Status == sched.Status {
+				return nil
+			}
+			return setStatus(ctx, sp, p, sched.Status, models.StatusSourceSchedule, nil)
+		})
+		switch {
+		case err == nil:
+			sched.State = models.ScheduleApplied
+		case errors.Is(err, ErrValidation), errors.Is(err, ErrInvalidState):
+			msg := err.Error()
+			sched.State, sched.Error = models.ScheduleFailed, &msg
+		default:
+			return err
+		}
+		now := s.now()
+		sched.ProcessedAt = &now
+		return lifecycle.UpdateSchedule(ctx, sched)
+	})
+	return found, err
+}
+
+// RunStatusSchedules applies due status changes every interval until ctx is
+// done
+func (s *LifecycleService) RunStatusSchedules(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				ok, err := s.ProcessNextSchedule(ctx)
+				if err != nil {
+					log.Printf("status schedules: %v", err)
+				}
+				if !ok || err != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// setStatus moves a locked product to a new status if its lifecycle allows
+// it and records the change in the audit trail
+func setStatus(ctx context.Context, tx pgx.Tx, p *models.Product, status, source string, note *string) error {
+	products := repository.NewProductRepository(tx)
+	from := p.Status
+	p.Status = status
+	if err := checkStatusChange(ctx, products, p, from); err != nil {
+		return err
+	}
+	if err := products.UpdateStatus(ctx, p); err != nil {
+		return err
+	}
+	return repository.NewLifecycleRepository(tx).AddStatusChange(ctx, &models.ProductStatusChange{
+		ProductID:  p.ID,
+		FromStatus: &from,
+		ToStatus:   status,
+		Source:     source,
+		Note:       note,
+	})
+}
+
+// checkStatusChange checks that p may have moved to its current status from
+// status from, which is empty for a new product
+func checkStatusChange(ctx context.Context, products *repository.ProductRepository, p *models.Product, from string) error {
+	if _, ok := statusTransitions[p.Status]; !ok {
+		return fmt.Errorf("%w: status must be draft, active or archived", ErrValidation)
+	}
+	if from != "" && !slices.Contains(statusTransitions[from], p.Status) {
+		return fmt.Errorf("%w: a %s product cannot become %s", ErrInvalidState, from, p.Status)
+	}
+	if p.Status != models.ProductStatusActive {
+		return nil
+	}
+
+	var missing []string
+	if p.Price <= 0 {
+		missing = append(missing, "a price")
+	}
+	if p.CategoryID == nil {
+		missing = append(missing, "a category")
+	}
+	images, err := products.CountImages(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	if images == 0 {
+		missing = append(missing, "an image")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: product cannot be published without %s", ErrValidation, strings.Join(missing, ", "))
+	}
+	return nil
+}
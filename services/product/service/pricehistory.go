@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"main.go/services/product/models"
+	"main.go/services/product/repository"
+)
+
+// maxPriceHistory caps the number of price changes returned by PriceHistory
+const maxPriceHistory = 500
+
+// SetBasePriceInput replaces the base price and compare-at price of a
+// product; Cost is kept when nil. Actor and Reason go to the price history.
+type SetBasePriceInput struct {
+	Price          float64  `json:"price"`
+	CompareAtPrice *float64 `json:"compare_at_price,omitempty"`
+	Cost           *float64 `json:"cost,omitempty"`
+	Actor          string   `json:"actor"`
+	Reason         *string  `json:"reason,omitempty"`
+}
+
+// PriceUpdate is a product after a price change, the history entry recording
+// it and warnings about the new prices
+type PriceUpdate struct {
+	Product  *models.Product     `json:"product"`
+	Change   *models.PriceChange `json:"change,omitempty"`
+	Warnings []string            `json:"warnings"`
+}
+
+// SetBasePrice changes the prices of a product in its base currency. Prices
+// below cost are accepted with a warning. A product on sale keeps its sale
+// price until the sale ends or is cancelled.
+func (s *PricingService) SetBasePrice(ctx context.Context, productID uuid.UUID, in SetBasePriceInput) (*PriceUpdate, error) {
+	actor := strings.TrimSpace(in.Actor)
+	if actor == "" {
+		return nil, fmt.Errorf("%w: actor is required", ErrValidation)
+	}
+	if len(actor) > 100 {
+		return nil, fmt.Errorf("%w: actor must be at most 100 characters", ErrValidation)
+	}
+	for _, v := range []*float64{&in.Price, in.CompareAtPrice, in.Cost} {
+		if v != nil && *v < 0 {
+			return nil, fmt.Errorf("%w: prices must not be negative", ErrValidation)
+		}
+	}
+
+	update := &PriceUpdate{}
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		products := repository.NewProductRepository(tx)
+		p, err := products.GetByIDForUpdate(ctx, productID)
+		if err != nil {
+			return err
+		}
+		sale, err := repository.NewSaleRepository(tx).GetActive(ctx, productID)
+		if err == nil {
+			return fmt.Errorf("%w: product is on sale until %s; cancel the sale first", ErrInvalidState, sale.EndsAt.Format(time.RFC3339))
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		old := *p
+		p.Price, p.CompareAtPrice = in.Price, in.CompareAtPrice
+		if in.Cost != nil {
+			p.Cost = in.Cost
+		}
+		if err := products.UpdatePrices(ctx, p); err != nil {
+			return err
+		}
+		update.Product = p
+		update.Change, err = recordPriceChange(ctx, tx, &old, p, models.PriceChangeManual, &actor, in.Reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	update.Warnings = priceWarnings(update.Product.Price, update.Product)
+	return update, nil
+}
+
+// PriceHistory returns the price changes of a product, newest first
+func (s *PricingService) PriceHistory(ctx context.Context, productID uuid.UUID) ([]models.PriceChange, error) {
+	if _, err := repository.NewProductRepository(s.pool).GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	changes, err := repository.NewPriceRepository(s.pool).ListPriceChanges(ctx, productID, maxPriceHistory)
+	if err != nil {
+		return nil, err
+	}
+	if changes == nil {
+		changes = []models.PriceChange{}
+	}
+	return changes, nil
+}
+
+// applyPriceAt sets the prices of p to those it had at the given time, which
+// are the old prices of the first change after it
+func applyPriceAt(ctx context.Context, prices *repository.PriceRepository, p *models.Product, at time.Time) error {
+	change, err := prices.GetPriceChangeAfter(ctx, p.ID, at)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if change.OldPrice == nil {
+		return fmt.Errorf("%w: product %s had no price at %s", ErrValidation, p.ID, at.Format(time.RFC3339))
+	}
+	p.Price, p.CompareAtPrice, p.Cost = *change.OldPrice, change.OldCompareAtPrice, change.OldCost
+	return nil
+}
+
+// recordPriceChange adds an entry to the price history of p when its prices
+// differ from old, which is nil for a new product. It returns nil when
+// nothing changed.
+func recordPriceChange(ctx context.Context, db repository.DBTX, old, p *models.Product, source string, actor, reason *string) (*models.PriceChange, error) {
+	change := &models.PriceChange{
+		ProductID:         p.ID,
+		NewPrice:          p.Price,
+		NewCompareAtPrice: p.CompareAtPrice,
+		NewCost:           p.Cost,
+		Source:            source,
+		Actor:             actor,
+		Reason:            reason,
+		BelowCost:         p.Cost != nil && p.Price < *p.Cost,
+	}
+	if old != nil {
+		if old.Price == p.Price && sameAmount(old.CompareAtPrice, p.CompareAtPrice) && sameAmount(old.Cost, p.Cost) {
+			return nil, nil
+		}
+		change.OldPrice = &old.Price
+		change.OldCompareAtPrice, change.OldCost = old.CompareAtPrice, old.Cost
+	}
+	if err := repository.NewPriceRepository(db).AddPriceChange(ctx, change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// priceWarnings returns the warnings about selling p at price
+func priceWarnings(price float64, p *models.Product) []string {
+	warnings := []string{}
+	if p.Cost != nil && price < *p.Cost {
+		warnings = append(warnings, fmt.Sprintf("price %.2f is below the cost of %.2f", price, *p.Cost))
+	}
+	return warnings
+}
+
+func sameAmount(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
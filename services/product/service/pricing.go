@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/product/models"
+	"main.go/services/product/repository"
+)
+
+// maxRateHistory caps the number of rates returned by ListRates
+const maxRateHistory = 500
+
+// Price sources reported by ResolvedPrice
+const (
+	PriceSourceBase     = "base"
+	PriceSourceVariant  = "variant"
+	PriceSourceOverride = "override"
+	PriceSourceFX       = "fx"
+	PriceSourceBundle   = "bundle"
+)
+
+// ConversionRate is the FX rate applied to a conversion. When only the
+// reverse pair is stored the rate is inverted and Inverted is set.
+type ConversionRate struct {
+	RateID        uuid.UUID `json:"rate_id"`
+	BaseCurrency  string    `json:"base_currency"`
+	QuoteCurrency string    `json:"quote_currency"`
+	Rate          float64   `json:"rate"`
+	EffectiveAt   time.Time `json:"effective_at"`
+	Inverted      bool      `json:"inverted,omitempty"`
+}
+
+// ResolvedPrice is a product's price in a requested currency
+type ResolvedPrice struct {
+	ProductID      uuid.UUID       `json:"product_id"`
+	VariantID      uuid.UUID       `json:"variant_id"`
+	Name           string          `json:"name"`
+	SKU            *string         `json:"sku,omitempty"`
+	Status         string          `json:"status"`
+	Currency       string          `json:"currency"`
+	Price          float64         `json:"price"`
+	CompareAtPrice *float64        `json:"compare_at_price,omitempty"`
+	Source         string          `json:"source"`
+	BaseCurrency   string          `json:"base_currency"`
+	BasePrice      float64         `json:"base_price"`
+	FXRate         *ConversionRate `json:"fx_rate,omitempty"`
+	// Components lists what each unit of a bundle contains
+	Components []ResolvedComponent `json:"components,omitempty"`
+}
+
+// SetPriceInput holds an explicit price for a product in a currency
+type SetPriceInput struct {
+	Price          float64  `json:"price"`
+	CompareAtPrice *float64 `json:"compare_at_price,omitempty"`
+}
+
+// AddRateInput holds a new FX rate
+type AddRateInput struct {
+	BaseCurrency  string     `json:"base_currency"`
+	QuoteCurrency string     `json:"quote_currency"`
+	Rate          float64    `json:"rate"`
+	EffectiveAt   *time.Time `json:"effective_at,omitempty"`
+	Source        *string    `json:"source,omitempty"`
+}
+
+// PricingService resolves product prices across currencies
+type PricingService struct {
+	pool *pgxpool.Pool
+	now  func() time.Time
+}
+
+// NewPricingService creates a new PricingService
+func NewPricingService(pool *pgxpool.Pool) *PricingService {
+	return &PricingService{pool: pool, now: time.Now}
+}
+
+// Resolve returns the price of a product variant in currency as of at; the
+// product's default variant is priced when variantID is nil. The base price
+// is the one recorded in the price history for that time. A variant's own
+// price replaces the product's base price. Otherwise an explicit price for
+// the currency wins, and failing that the base price is converted with the
+// FX rate in effect at that time.
+func (s *PricingService) Resolve(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, currency string, at time.Time) (*ResolvedPrice, error) {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	historic := !at.IsZero() && at.Before(s.now())
+	if at.IsZero() {
+		at = s.now()
+	}
+
+	product, err := repository.NewProductRepository(s.pool).GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if historic {
+		if err := applyPriceAt(ctx, repository.NewPriceRepository(s.pool), product, at); err != nil {
+			return nil, err
+		}
+	}
+	variants := repository.NewVariantRepository(s.pool)
+	var variant *models.ProductVariant
+	if variantID != nil {
+		variant, err = variants.GetVariant(ctx, *variantID)
+	} else {
+		variant, err = variants.GetDefaultVariant(ctx, productID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if variant.ProductID != product.ID {
+		return nil, fmt.Errorf("%w: variant %s does not belong to product %s", ErrValidation, variant.ID, product.ID)
+	}
+
+	resolved := &ResolvedPrice{
+		ProductID:      product.ID,
+		VariantID:      variant.ID,
+		Name:           product.Name,
+		SKU:            product.SKU,
+		Status:         product.Status,
+		Currency:       currency,
+		Price:          product.Price,
+		CompareAtPrice: product.CompareAtPrice,
+		Source:         PriceSourceBase,
+		BaseCurrency:   product.Currency,
+	}
+	if variant.Title != "" {
+		resolved.Name += " - " + variant.Title
+	}
+	if variant.SKU != nil {
+		resolved.SKU = variant.SKU
+	}
+	if variant.Price != nil {
+		resolved.Price = *variant.Price
+		resolved.CompareAtPrice = variant.CompareAtPrice
+		resolved.Source = PriceSourceVariant
+	}
+
+	components, bundle, total, err := resolveBundle(ctx, s.pool, product.ID, at, historic)
+	if err != nil {
+		return nil, err
+	}
+	resolved.Components = components
+	if bundle != nil && bundle.Pricing == models.BundlePricingComponents && variant.Price == nil {
+		resolved.Price = convert(total, 1-bundle.DiscountPercent/100)
+		resolved.CompareAtPrice = nil
+		if bundle.DiscountPercent > 0 {
+			compareAt := convert(total, 1)
+			resolved.CompareAtPrice = &compareAt
+		}
+		resolved.Source = PriceSourceBundle
+	}
+	resolved.BasePrice = resolved.Price
+	if currency == product.Currency {
+		return resolved, nil
+	}
+
+	// Explicit currency prices are set per product, so they only apply to
+	// variants priced like the product, not bundles priced from components
+	if variant.Price == nil && resolved.Source != PriceSourceBundle {
+		override, err := repository.NewPriceRepository(s.pool).GetPrice(ctx, product.ID, currency)
+		if err == nil {
+			resolved.Price = override.Price
+			resolved.CompareAtPrice = override.CompareAtPrice
+			resolved.Source = PriceSourceOverride
+			return resolved, nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	rate, err := s.RateAt(ctx, product.Currency, currency, at)
+	if err != nil {
+		return nil, err
+	}
+	basePrice, baseCompareAt := resolved.Price, resolved.CompareAtPrice
+	resolved.Price = convert(basePrice, rate.Rate)
+	if baseCompareAt != nil {
+		compareAt := convert(*baseCompareAt, rate.Rate)
+		resolved.CompareAtPrice = &compareAt
+	}
+	resolved.Source = PriceSourceFX
+	resolved.FXRate = rate
+	return resolved, nil
+}
+
+// RateAt returns the rate converting base into quote in effect at the given
+// time, falling back to the inverse of the reverse pair
+func (s *PricingService) RateAt(ctx context.Context, base, quote string, at time.Time) (*ConversionRate, error) {
+	base, err := normalizeCurrency(base)
+	if err != nil {
+		return nil, err
+	}
+	quote, err = normalizeCurrency(quote)
+	if err != nil {
+		return nil, err
+	}
+	if at.IsZero() {
+		at = s.now()
+	}
+
+	prices := repository.NewPriceRepository(s.pool)
+	rate, err := prices.GetRateAt(ctx, base, quote, at)
+	if err == nil {
+		return &ConversionRate{
+			RateID:        rate.ID,
+			BaseCurrency:  base,
+			QuoteCurrency: quote,
+			Rate:          rate.Rate,
+			EffectiveAt:   rate.EffectiveAt,
+		}, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	reverse, err := prices.GetRateAt(ctx, quote, base, at)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: no fx rate from %s to %s at %s", ErrValidation, base, quote, at.Format(time.RFC3339))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ConversionRate{
+		RateID:        reverse.ID,
+		BaseCurrency:  base,
+		QuoteCurrency: quote,
+		Rate:          1 / reverse.Rate,
+		EffectiveAt:   reverse.EffectiveAt,
+		Inverted:      true,
+	}, nil
+}
+
+// ListPrices returns the explicit currency prices of a product
+func (s *PricingService) ListPrices(ctx context.Context, productID uuid.UUID) ([]models.ProductPrice, error) {
+	return repository.NewPriceRepository(s.pool).ListPrices(ctx, productID)
+}
+
+// SetPrice sets an explicit price for a product in a currency other than its
+// base currency
+func (s *PricingService) SetPrice(ctx context.Context, productID uuid.UUID, currency string, in SetPriceInput) (*models.ProductPrice, error) {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	if in.Price < 0 || (in.CompareAtPrice != nil && *in.CompareAtPrice < 0) {
+		return nil, fmt.Errorf("%w: prices must not be negative", ErrValidation)
+	}
+
+	price := &models.ProductPrice{
+		ProductID:      productID,
+		Currency:       currency,
+		Price:          in.Price,
+		CompareAtPrice: in.CompareAtPrice,
+	}
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		product, err := repository.NewProductRepository(tx).GetByID(ctx, productID)
+		if err != nil {
+			return err
+		}
+		if product.Currency == currency {
+			return fmt.Errorf("%w: %s is the product's base currency; set the base price instead", ErrValidation, currency)
+		}
+		return repository.NewPriceRepository(tx).UpsertPrice(ctx, price)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return price, nil
+}
+
+// DeletePrice removes an explicit price so the currency falls back to FX
+// conversion
+func (s *PricingService) DeletePrice(ctx context.Context, productID uuid.UUID, currency string) error {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return err
+	}
+	return repository.NewPriceRepository(s.pool).DeletePrice(ctx, productID, currency)
+}
+
+// AddRate stores a single FX rate
+func (s *PricingService) AddRate(ctx context.Context, in AddRateInput) (*models.FXRate, error) {
+	rate, err := s.buildRate(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.NewPriceRepository(s.pool).CreateRate(ctx, rate); err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+// ImportRates stores a batch of FX rates atomically
+func (s *PricingService) ImportRates(ctx context.Context, in []AddRateInput) ([]models.FXRate, error) {
+	rates := make([]models.FXRate, 0, len(in))
+	for i, r := range in {
+		rate, err := s.buildRate(r)
+		if err != nil {
+			return nil, fmt.Errorf("rate %d: %w", i+1, err)
+		}
+		rates = append(rates, *rate)
+	}
+
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		prices := repository.NewPriceRepository(tx)
+		for i := range rates {
+			if err := prices.CreateRate(ctx, &rates[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// ListRates returns the rate history of a currency pair
+func (s *PricingService) ListRates(ctx context.Context, base, quote string) ([]models.FXRate, error) {
+	base, err := normalizeCurrency(base)
+	if err != nil {
+		return nil, err
+	}
+	quote, err = normalizeCurrency(quote)
+	if err != nil {
+		return nil, err
+	}
+	return repository.NewPriceRepository(s.pool).ListRates(ctx, base, quote, maxRateHistory)
+}
+
+func (s *PricingService) buildRate(in AddRateInput) (*models.FXRate, error) {
+	base, err := normalizeCurrency(in.BaseCurrency)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := normalizeCurrency(in.QuoteCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if base == quote {
+		return nil, fmt.Errorf("%w: base and quote currency must differ", ErrValidation)
+	}
+	if in.Rate <= 0 || math.IsInf(in.Rate, 0) || math.IsNaN(in.Rate) {
+		return nil, fmt.Errorf("%w: rate must be positive", ErrValidation)
+	}
+
+	effectiveAt := s.now()
+	if in.EffectiveAt != nil {
+		effectiveAt = *in.EffectiveAt
+	}
+	return &models.FXRate{
+		BaseCurrency:  base,
+		QuoteCurrency: quote,
+		Rate:          in.Rate,
+		EffectiveAt:   effectiveAt,
+		Source:        in.Source,
+	}, nil
+}
+
+// normalizeCurrency upper-cases and validates an ISO 4217 currency code
+func normalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", fmt.Errorf("%w: currency must be a 3 letter ISO code", ErrValidation)
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return "", fmt.Errorf("%w: currency must be a 3 letter ISO code", ErrValidation)
+		}
+	}
+	return code, nil
+}
+
+// convert applies rate to amount and rounds to cents
+func convert(amount, rate float64) float64 {
+	return math.Round(amount*rate*100) / 100
+}
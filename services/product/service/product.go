@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/product/models"
+	"main.go/services/product/repository"
+)
+
+// ProductService manages the product catalog
+type ProductService struct {
+	pool *pgxpool.Pool
+}
+
+// NewProductService creates a new ProductService
+func NewProductService(pool *pgxpool.Pool) *ProductService {
+	return &ProductService{pool: pool}
+}
+
+// Get returns a product by its ID
+func (s *ProductService) Get(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+	return repository.NewProductRepository(s.pool).GetByID(ctx, id)
+}
+
+// GetBySlug returns the product with a slug. When the slug is a former one
+// it returns the product that used it and moved is true.
+func (s *ProductService) GetBySlug(ctx context.Context, slug string) (p *models.Product, moved bool, err error) {
+	products := repository.NewProductRepository(s.pool)
+	p, err = products.GetBySlug(ctx, slug)
+	if !errors.Is(err, repository.ErrNotFound) {
+		return p, false, err
+	}
+	id, err := repository.NewProductSlugRepository(s.pool).Resolve(ctx, slug)
+	if err != nil {
+		return nil, false, err
+	}
+	p, err = products.GetByID(ctx, id)
+	return p, err == nil, err
+}
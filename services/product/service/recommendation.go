@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/product/clients"
+	"main.go/services/product/models"
+	"main.go/services/product/repository"
+)
+
+// DefaultCoPurchaseWindow is how far back orders count towards co-purchases
+const DefaultCoPurchaseWindow = 180 * 24 * time.Hour
+
+// Co-purchase import settings
+const (
+	coPurchasePage      = 1000
+	coPurchaseMinOrders = 2
+)
+
+// Recommendation limits
+const (
+	defaultRecommendations = 10
+	maxRecommendations     = 50
+	maxCartProducts        = 100
+)
+
+// CoPurchaseFeed lists the products bought together in the order history
+type CoPurchaseFeed interface {
+	CoPurchases(ctx context.Context, since time.Time, minOrders int, afterProduct, afterRelated uuid.UUID, limit int) ([]clients.CoPurchase, error)
+}
+
+// AddLinkInput links a product to a related product
+type AddLinkInput struct {
+	RelatedID uuid.UUID `json:"related_id"`
+	Type      string    `json:"type"`
+}
+
+// RecommendInput narrows a recommendation list. An empty Type recommends
+// curated links of every type followed by products bought together.
+type RecommendInput struct {
+	Type  string
+	Limit int
+}
+
+// RecommendationService curates related products and recommends products
+// for a product or a cart from curated links and the order history
+type RecommendationService struct {
+	pool   *pgxpool.Pool
+	orders CoPurchaseFeed
+	window time.Duration
+	now    func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewRecommendationService creates a new RecommendationService counting the
+// orders placed within window
+func NewRecommendationService(pool *pgxpool.Pool, orders CoPurchaseFeed, window time.Duration) *RecommendationService {
+	return &RecommendationService{pool: pool, orders: orders, window: window, now: time.Now}
+}
+
+// ListLinks returns the curated links of a product
+func (s *RecommendationService) ListLinks(ctx context.Context, productID uuid.UUID) ([]models.ProductLink, error) {
+	if _, err := repository.NewProductRepository(s.pool).GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	links, err := repository.NewRecommendationRepository(s.pool).ListLinks(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if links == nil {
+		links = []models.ProductLink{}
+	}
+	return links, nil
+}
+
+// AddLink links a product to a related product as the last link of its type
+func (s *RecommendationService) AddLink(ctx context.Context, productID uuid.UUID, in AddLinkInput) (*models.ProductLink, error) {
+	if !validLinkType(in.Type) {
+		return nil, fmt.Errorf("%w: type must be related, upsell or cross_sell", ErrValidation)
+	}
+	if in.RelatedID == productID {
+		return nil, fmt.Errorf("%w: a product cannot be linked to itself", ErrValidation)
+	}
+	products := repository.NewProductRepository(s.pool)
+	if _, err := products.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	if _, err := products.GetByID(ctx, in.RelatedID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("%w: related product %s does not exist", ErrValidation, in.RelatedID)
+		}
+		return nil, err
+	}
+
+	link := &models.ProductLink{ProductID: productID, RelatedID: in.RelatedID, Type: in.Type}
+	created, err := repository.NewRecommendationRepository(s.pool).CreateLink(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, fmt.Errorf("%w: product %s is already linked as %s", ErrInvalidState, in.RelatedID, in.Type)
+	}
+	return link, nil
+}
+
+// DeleteLink removes a curated link
+func (s *RecommendationService) DeleteLink(ctx context.Context, id uuid.UUID) error {
+	return repository.NewRecommendationRepository(s.pool).DeleteLink(ctx, id)
+}
+
+// ForProduct recommends active, in-stock products for a product
+func (s *RecommendationService) ForProduct(ctx context.Context, productID uuid.UUID, in RecommendInput) ([]repository.Recommendation, error) {
+	if _, err := repository.NewProductRepository(s.pool).GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.recommend(ctx, []uuid.UUID{productID}, in)
+}
+
+// ForCart recommends active, in-stock products for the products in a cart,
+// ranking products bought together with several of them higher
+func (s *RecommendationService) ForCart(ctx context.Context, productIDs []uuid.UUID, in RecommendInput) ([]repository.Recommendation, error) {
+	if len(productIDs) == 0 {
+		return nil, fmt.Errorf("%w: at least one product_id is required", ErrValidation)
+	}
+	if len(productIDs) > maxCartProducts {
+		return nil, fmt.Errorf("%w: a cart can have at most %d products", ErrValidation, maxCartProducts)
+	}
+	return s.recommend(ctx, productIDs, in)
+}
+
+func (s *RecommendationService) recommend(ctx context.Context, productIDs []uuid.UUID, in RecommendInput) ([]repository.Recommendation, error) {
+	if in.Type != "" && !validLinkType(in.Type) {
+		return nil, fmt.Errorf("%w: type must be related, upsell or cross_sell", ErrValidation)
+	}
+	if in.Limit <= 0 {
+		in.Limit = defaultRecommendations
+	}
+	if in.Limit > maxRecommendations {
+		return nil, fmt.Errorf("%w: limit must be at most %d", ErrValidation, maxRecommendations)
+	}
+	recs, err := repository.NewRecommendationRepository(s.pool).Recommend(ctx, productIDs, in.Type, in.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if recs == nil {
+		recs = []repository.Recommendation{}
+	}
+	return recs, nil
+}
+
+// RefreshCoPurchases recomputes which products were bought together from the
+// orders placed within the window and returns the number of pairs stored.
+// Pairs no longer bought together often enough are removed once every page
+// was stored, so a failed refresh keeps serving the previous counts.
+func (s *RecommendationService) RefreshCoPurchases(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := s.now()
+	since := run.Add(-s.window)
+	recommendations := repository.NewRecommendationRepository(s.pool)
+	stored := 0
+	var afterProduct, afterRelated uuid.UUID
+	for {
+		page, err := s.orders.CoPurchases(ctx, since, coPurchaseMinOrders, afterProduct, afterRelated, coPurchasePage)
+		if err != nil {
+			return stored, err
+		}
+		if len(page) > 0 {
+			pairs := make([]models.ProductCoPurchase, len(page))
+			for i, p := range page {
+				pairs[i] = models.ProductCoPurchase{ProductID: p.ProductID, RelatedID: p.RelatedID, Orders: p.Orders}
+			}
+			n, err := recommendations.UpsertCoPurchases(ctx, pairs, run)
+			if err != nil {
+				return stored, err
+			}
+			stored += n
+			last := page[len(page)-1]
+			afterProduct, afterRelated = last.ProductID, last.RelatedID
+		}
+		if len(page) < coPurchasePage {
+			break
+		}
+	}
+	if _, err := recommendations.DeleteCoPurchasesBefore(ctx, run); err != nil {
+		return stored, err
+	}
+	return stored, nil
+}
+
+// RunCoPurchases calls RefreshCoPurchases every interval until ctx is done
+func (s *RecommendationService) RunCoPurchases(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.RefreshCoPurchases(ctx)
+			if err != nil {
+				log.Printf("co-purchase refresh failed: %v", err)
+			} else {
+				log.Printf("refreshed %d co-purchase pairs", n)
+			}
+		}
+	}
+}
+
+func validLinkType(t string) bool {
+	switch t {
+	case models.ProductLinkRelated, models.ProductLinkUpsell, models.ProductLinkCrossSell:
+		return true
+	}
+	return false
+}
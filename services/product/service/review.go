@@ -0,0 +1,406 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/product/clients"
+	"main.go/services/product/models"
+	"main.go/services/product/repository"
+)
+
+// Review limits
+const (
+	maxReviewTitleLength = 200
+	maxReviewBodyLength  = 5000
+	defaultReviewLimit   = 20
+	maxReviewLimit       = 100
+)
+
+// PurchaseChecker tells whether a user received a product they ordered
+type PurchaseChecker interface {
+	ProductPurchase(ctx context.Context, userID, productID uuid.UUID) (*clients.ProductPurchase, error)
+}
+
+// ReviewInput is a user's star rating and review of a product
+type ReviewInput struct {
+	UserID uuid.UUID `json:"user_id"`
+	Rating int       `json:"rating"`
+	Title  *string   `json:"title,omitempty"`
+	Body   *string   `json:"body,omitempty"`
+}
+
+// ModerateReviewInput approves or rejects a review with an optional note
+type ModerateReviewInput struct {
+	Status models.ReviewStatus `json:"status"`
+	Note   *string             `json:"note,omitempty"`
+}
+
+// ListReviewsInput holds the filters and page of a review listing
+type ListReviewsInput struct {
+	Status       string
+	Rating       int
+	VerifiedOnly bool
+	Sort         string
+	Limit        int
+	Offset       int
+}
+
+// ReviewPage is a page of reviews with the total number of matches
+type ReviewPage struct {
+	Reviews []models.ProductReview `json:"reviews"`
+	Total   int                    `json:"total"`
+}
+
+// RatingSummary is the rating of a product with the number of approved
+// reviews giving each star rating
+type RatingSummary struct {
+	ProductID uuid.UUID   `json:"product_id"`
+	Average   *float64    `json:"average,omitempty"`
+	Count     int         `json:"count"`
+	Stars     map[int]int `json:"stars"`
+}
+
+// ReviewService manages product reviews, their moderation and helpful votes,
+// and keeps the rating of each product in step with its approved reviews
+type ReviewService struct {
+	pool   *pgxpool.Pool
+	orders PurchaseChecker
+	now    func() time.Time
+}
+
+// NewReviewService creates a new ReviewService
+func NewReviewService(pool *pgxpool.Pool, orders PurchaseChecker) *ReviewService {
+	return &ReviewService{pool: pool, orders: orders, now: time.Now}
+}
+
+// Create adds a user's review of a product, pending moderation. Users review
+// a product once; the review is a verified purchase when the order service
+// reports that the user received the product.
+func (s *ReviewService) Create(ctx context.Context, productID uuid.UUID, in ReviewInput) (*models.ProductReview, error) {
+	rv := &models.ProductReview{ProductID: productID, Status: models.ReviewPending}
+	if err := applyReviewInput(rv, in); err != nil {
+		return nil, err
+	}
+	if _, err := repository.NewProductRepository(s.pool).GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	verified, err := s.verifyPurchase(ctx, in.UserID, productID)
+	if err != nil {
+		return nil, err
+	}
+	rv.VerifiedPurchase = verified
+
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		// Locking the product serializes reviews by the same user
+		if _, err := repository.NewProductRepository(tx).GetByIDForUpdate(ctx, productID); err != nil {
+			return err
+		}
+		reviews := repository.NewReviewRepository(tx)
+		_, err := reviews.GetByUser(ctx, productID, in.UserID)
+		if err == nil {
+			return fmt.Errorf("%w: user has already reviewed this product", ErrInvalidState)
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		return reviews.Create(ctx, rv)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// Get returns a review by its ID
+func (s *ReviewService) Get(ctx context.Context, id uuid.UUID) (*models.ProductReview, error) {
+	return repository.NewReviewRepository(s.pool).GetByID(ctx, id)
+}
+
+// ListByProduct returns a page of the reviews of a product, by default its
+// approved reviews newest first
+func (s *ReviewService) ListByProduct(ctx context.Context, productID uuid.UUID, in ListReviewsInput) (*ReviewPage, error) {
+	if _, err := repository.NewProductRepository(s.pool).GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	if in.Status == "" {
+		in.Status = string(models.ReviewApproved)
+	}
+	return s.list(ctx, &productID, in)
+}
+
+// List returns a page of the reviews of every product, by default the
+// pending reviews awaiting moderation
+func (s *ReviewService) List(ctx context.Context, in ListReviewsInput) (*ReviewPage, error) {
+	if in.Status == "" {
+		in.Status = string(models.ReviewPending)
+	}
+	return s.list(ctx, nil, in)
+}
+
+func (s *ReviewService) list(ctx context.Context, productID *uuid.UUID, in ListReviewsInput) (*ReviewPage, error) {
+	f := repository.ReviewFilter{
+		ProductID:    productID,
+		Status:       models.ReviewStatus(in.Status),
+		Rating:       in.Rating,
+		VerifiedOnly: in.VerifiedOnly,
+	}
+	if !validReviewStatus(f.Status) {
+		return nil, fmt.Errorf("%w: status must be pending, approved or rejected", ErrValidation)
+	}
+	if f.Rating < 0 || f.Rating > 5 {
+		return nil, fmt.Errorf("%w: rating must be between 1 and 5", ErrValidation)
+	}
+	sortBy := repository.ReviewSort(in.Sort)
+	if sortBy == "" {
+		sortBy = repository.ReviewSortNewest
+	}
+	if !repository.ValidReviewSort(sortBy) {
+		return nil, fmt.Errorf("%w: unknown sort %q", ErrValidation, in.Sort)
+	}
+	limit := in.Limit
+	if limit == 0 {
+		limit = defaultReviewLimit
+	}
+	if limit < 0 || limit > maxReviewLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrValidation, maxReviewLimit)
+	}
+	if in.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrValidation)
+	}
+
+	reviews := repository.NewReviewRepository(s.pool)
+	page := &ReviewPage{}
+	var err error
+	if page.Reviews, err = reviews.List(ctx, f, sortBy, limit, in.Offset); err != nil {
+		return nil, err
+	}
+	if page.Total, err = reviews.Count(ctx, f); err != nil {
+		return nil, err
+	}
+	if page.Reviews == nil {
+		page.Reviews = []models.ProductReview{}
+	}
+	return page, nil
+}
+
+// Summary returns the rating of a product and how many approved reviews
+// gave each star rating
+func (s *ReviewService) Summary(ctx context.Context, productID uuid.UUID) (*RatingSummary, error) {
+	p, err := repository.NewProductRepository(s.pool).GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := repository.NewReviewRepository(s.pool).RatingCounts(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	summary := &RatingSummary{
+		ProductID: productID,
+		Average:   p.RatingAverage,
+		Count:     p.RatingCount,
+		Stars:     map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0},
+	}
+	for _, c := range counts {
+		summary.Stars[c.Rating] = c.Count
+	}
+	return summary, nil
+}
+
+// Update replaces the rating and text of a review by its author. The edited
+// review goes back to moderation and its purchase is verified again.
+func (s *ReviewService) Update(ctx context.Context, id uuid.UUID, in ReviewInput) (*models.ProductReview, error) {
+	current, err := repository.NewReviewRepository(s.pool).GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if in.UserID != current.UserID {
+		return nil, fmt.Errorf("%w: only the author can edit a review", ErrValidation)
+	}
+	verified, err := s.verifyPurchase(ctx, in.UserID, current.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rv *models.ProductReview
+	err = s.withReview(ctx, id, func(tx pgx.Tx, p *models.Product, locked *models.ProductReview) error {
+		rv = locked
+		wasApproved := rv.Status == models.ReviewApproved
+		if err := applyReviewInput(rv, in); err != nil {
+			return err
+		}
+		rv.VerifiedPurchase = verified
+		rv.Status, rv.ModerationNote, rv.ModeratedAt = models.ReviewPending, nil, nil
+		if err := repository.NewReviewRepository(tx).Update(ctx, rv); err != nil {
+			return err
+		}
+		if wasApproved {
+			return repository.NewProductRepository(tx).UpdateRating(ctx, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// Moderate approves or rejects a review and updates the product's rating
+func (s *ReviewService) Moderate(ctx context.Context, id uuid.UUID, in ModerateReviewInput) (*models.ProductReview, error) {
+	if in.Status != models.ReviewApproved && in.Status != models.ReviewRejected {
+		return nil, fmt.Errorf("%w: status must be approved or rejected", ErrValidation)
+	}
+	var rv *models.ProductReview
+	err := s.withReview(ctx, id, func(tx pgx.Tx, p *models.Product, locked *models.ProductReview) error {
+		rv = locked
+		if rv.Status == in.Status {
+			return fmt.Errorf("%w: review is already %s", ErrInvalidState, rv.Status)
+		}
+		now := s.now()
+		rv.Status, rv.ModerationNote, rv.ModeratedAt = in.Status, in.Note, &now
+		if err := repository.NewReviewRepository(tx).Update(ctx, rv); err != nil {
+			return err
+		}
+		return repository.NewProductRepository(tx).UpdateRating(ctx, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// Delete removes a review and its votes and updates the product's rating
+func (s *ReviewService) Delete(ctx context.Context, id uuid.UUID) error {
+	return s.withReview(ctx, id, func(tx pgx.Tx, p *models.Product, rv *models.ProductReview) error {
+		if err := repository.NewReviewRepository(tx).Delete(ctx, id); err != nil {
+			return err
+		}
+		if rv.Status == models.ReviewApproved {
+			return repository.NewProductRepository(tx).UpdateRating(ctx, p)
+		}
+		return nil
+	})
+}
+
+// Vote records that a user found an approved review helpful. Voting again
+// has no effect and authors cannot vote on their own review.
+func (s *ReviewService) Vote(ctx context.Context, id, userID uuid.UUID) (*models.ProductReview, error) {
+	if userID == uuid.Nil {
+		return nil, fmt.Errorf("%w: user_id is required", ErrValidation)
+	}
+	var rv *models.ProductReview
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		reviews := repository.NewReviewRepository(tx)
+		var err error
+		if rv, err = reviews.GetForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if rv.Status != models.ReviewApproved {
+			return fmt.Errorf("%w: only approved reviews can be voted on", ErrInvalidState)
+		}
+		if rv.UserID == userID {
+			return fmt.Errorf("%w: authors cannot vote on their own review", ErrValidation)
+		}
+		_, err = reviews.AddVote(ctx, rv, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// Unvote removes a user's helpful vote from a review
+func (s *ReviewService) Unvote(ctx context.Context, id, userID uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		reviews := repository.NewReviewRepository(tx)
+		rv, err := reviews.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		removed, err := reviews.DeleteVote(ctx, rv, userID)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return repository.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// withReview runs fn in a transaction holding the locks of a review and its
+// product, taken in the order reviews are created in
+func (s *ReviewService) withReview(ctx context.Context, id uuid.UUID, fn func(tx pgx.Tx, p *models.Product, rv *models.ProductReview) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		reviews := repository.NewReviewRepository(tx)
+		rv, err := reviews.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		p, err := repository.NewProductRepository(tx).GetByIDForUpdate(ctx, rv.ProductID)
+		if err != nil {
+			return err
+		}
+		if rv, err = reviews.GetForUpdate(ctx, id); err != nil {
+			return err
+		}
+		return fn(tx, p, rv)
+	})
+}
+
+// verifyPurchase asks the order service whether the user received the
+// product
+func (s *ReviewService) verifyPurchase(ctx context.Context, userID, productID uuid.UUID) (bool, error) {
+	purchase, err := s.orders.ProductPurchase(ctx, userID, productID)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify purchase: %w", err)
+	}
+	return purchase.Delivered, nil
+}
+
+// applyReviewInput validates in and copies it onto rv
+func applyReviewInput(rv *models.ProductReview, in ReviewInput) error {
+	if in.UserID == uuid.Nil {
+		return fmt.Errorf("%w: user_id is required", ErrValidation)
+	}
+	if in.Rating < 1 || in.Rating > 5 {
+		return fmt.Errorf("%w: rating must be between 1 and 5", ErrValidation)
+	}
+	title, body := trimOptional(in.Title), trimOptional(in.Body)
+	if title != nil && len(*title) > maxReviewTitleLength {
+		return fmt.Errorf("%w: title must be at most %d characters", ErrValidation, maxReviewTitleLength)
+	}
+	if body != nil && len(*body) > maxReviewBodyLength {
+		return fmt.Errorf("%w: body must be at most %d characters", ErrValidation, maxReviewBodyLength)
+	}
+	rv.UserID, rv.Rating, rv.Title, rv.Body = in.UserID, in.Rating, title, body
+	return nil
+}
+
+// trimOptional trims s and returns nil when nothing is left
+func trimOptional(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	t := strings.TrimSpace(*s)
+	if t == "" {
+		return nil
+	}
+	return &t
+}
+
+// validReviewStatus reports whether status is a review moderation state
+func validReviewStatus(status models.ReviewStatus) bool {
+	switch status {
+	case models.ReviewPending, models.ReviewApproved, models.ReviewRejected:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/product/models"
+	"main.go/services/product/repository"
+)
+
+// CreateSaleInput schedules a sale of a product at SalePrice from StartsAt
+// until EndsAt
+type CreateSaleInput struct {
+	SalePrice float64   `json:"sale_price"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Actor     string    `json:"actor"`
+	Reason    *string   `json:"reason,omitempty"`
+}
+
+// ScheduledSale is a new sale with warnings about its price
+type ScheduledSale struct {
+	Sale     *models.ProductSale `json:"sale"`
+	Warnings []string            `json:"warnings"`
+}
+
+// SaleService schedules timed sales and starts and ends them on time. While
+// a sale runs the product's price is the sale price and its compare-at price
+// the regular price.
+type SaleService struct {
+	pool *pgxpool.Pool
+	now  func() time.Time
+}
+
+// NewSaleService creates a new SaleService
+func NewSaleService(pool *pgxpool.Pool) *SaleService {
+	return &SaleService{pool: pool, now: time.Now}
+}
+
+// Create schedules a sale. Sales of a product cannot overlap, and the sale
+// price must be below the current price; a sale price below cost is accepted
+// with a warning.
+func (s *SaleService) Create(ctx context.Context, productID uuid.UUID, in CreateSaleInput) (*ScheduledSale, error) {
+	actor := strings.TrimSpace(in.Actor)
+	if actor == "" {
+		return nil, fmt.Errorf("%w: actor is required", ErrValidation)
+	}
+	if len(actor) > 100 {
+		return nil, fmt.Errorf("%w: actor must be at most 100 characters", ErrValidation)
+	}
+	if in.SalePrice < 0 {
+		return nil, fmt.Errorf("%w: sale_price must not be negative", ErrValidation)
+	}
+	if in.StartsAt.IsZero() || in.EndsAt.IsZero() {
+		return nil, fmt.Errorf("%w: starts_at and ends_at are required", ErrValidation)
+	}
+	if !in.EndsAt.After(in.StartsAt) {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrValidation)
+	}
+	if !in.EndsAt.After(s.now()) {
+		return nil, fmt.Errorf("%w: ends_at must be in the future", ErrValidation)
+	}
+
+	result := &ScheduledSale{}
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		// Locking the product serializes the overlap check
+		p, err := repository.NewProductRepository(tx).GetByIDForUpdate(ctx, productID)
+		if err != nil {
+			return err
+		}
+		if in.SalePrice >= p.Price {
+			return fmt.Errorf("%w: sale_price must be below the current price of %.2f", ErrValidation, p.Price)
+		}
+		sales := repository.NewSaleRepository(tx)
+		overlaps, err := sales.Overlaps(ctx, productID, in.StartsAt, in.EndsAt)
+		if err != nil {
+			return err
+		}
+		if overlaps {
+			return fmt.Errorf("%w: product already has a sale during that time", ErrInvalidState)
+		}
+		result.Sale = &models.ProductSale{
+			ProductID: productID,
+			SalePrice: in.SalePrice,
+			StartsAt:  in.StartsAt,
+			EndsAt:    in.EndsAt,
+			State:     models.SaleScheduled,
+			Actor:     &actor,
+			Reason:    in.Reason,
+		}
+		result.Warnings = priceWarnings(in.SalePrice, p)
+		return sales.Create(ctx, result.Sale)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// List returns the sales of a product by start time
+func (s *SaleService) List(ctx context.Context, productID uuid.UUID) ([]models.ProductSale, error) {
+	if _, err := repository.NewProductRepository(s.pool).GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	sales, err := repository.NewSaleRepository(s.pool).ListByProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if sales == nil {
+		sales = []models.ProductSale{}
+	}
+	return sales, nil
+}
+
+// Cancel cancels a scheduled sale, or ends an active one now and restores
+// the regular price
+func (s *SaleService) Cancel(ctx context.Context, id uuid.UUID) (*models.ProductSale, error) {
+	var sale *models.ProductSale
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		sales := repository.NewSaleRepository(tx)
+		var err error
+		if sale, err = sales.GetForUpdate(ctx, id); err != nil {
+			return err
+		}
+		now := s.now()
+		switch sale.State {
+		case models.SaleScheduled:
+			sale.State, sale.FinishedAt = models.SaleCancelled, &now
+			return sales.Update(ctx, sale)
+		case models.SaleActive:
+			return s.finish(ctx, tx, sale, models.SaleCancelled, now)
+		default:
+			return fmt.Errorf("%w: sale is already %s", ErrInvalidState, sale.State)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sale, nil
+}
+
+// ProcessNextSale starts or ends the sale that is due the earliest. It
+// returns false when no sale was due.
+func (s *SaleService) ProcessNextSale(ctx context.Context) (bool, error) {
+	found := false
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		now := s.now()
+		sale, err := repository.NewSaleRepository(tx).ClaimDue(ctx, now)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		if sale.State == models.SaleScheduled {
+			return s.start(ctx, tx, sale, now)
+		}
+		return s.finish(ctx, tx, sale, models.SaleEnded, now)
+	})
+	return found, err
+}
+
+// RunSales starts and ends due sales every interval until ctx is done
+func (s *SaleService) RunSales(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				ok, err := s.ProcessNextSale(ctx)
+				if err != nil {
+					log.Printf("sales: %v", err)
+				}
+				if !ok || err != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// start puts a product on sale, keeping its regular prices on the sale. A
+// sale whose end has passed, or whose price is no longer below the current
+// price, fails instead.
+func (s *SaleService) start(ctx context.Context, tx pgx.Tx, sale *models.ProductSale, now time.Time) error {
+	products := repository.NewProductRepository(tx)
+	p, err := products.GetByIDForUpdate(ctx, sale.ProductID)
+	if err != nil {
+		return err
+	}
+	sales := repository.NewSaleRepository(tx)
+	fail := func(msg string) error {
+		sale.State, sale.Error, sale.FinishedAt = models.SaleFailed, &msg, &now
+		return sales.Update(ctx, sale)
+	}
+	if !sale.EndsAt.After(now) {
+		return fail("sale ended before it could start")
+	}
+	if sale.SalePrice >= p.Price {
+		return fail(fmt.Sprintf("sale price %.2f is not below the current price %.2f", sale.SalePrice, p.Price))
+	}
+
+	old := *p
+	regular := p.Price
+	sale.RegularPrice, sale.RegularCompareAtPrice = &regular, p.CompareAtPrice
+	p.Price, p.CompareAtPrice = sale.SalePrice, &regular
+	if err := products.UpdatePrices(ctx, p); err != nil {
+		return err
+	}
+	if _, err := recordPriceChange(ctx, tx, &old, p, models.PriceChangeSale, sale.Actor, saleReason("sale started", sale)); err != nil {
+		return err
+	}
+	sale.State, sale.StartedAt = models.SaleActive, &now
+	return sales.Update(ctx, sale)
+}
+
+// finish ends an active sale in state and restores the regular prices. When
+// the prices were changed during the sale they are left as they are.
+func (s *SaleService) finish(ctx context.Context, tx pgx.Tx, sale *models.ProductSale, state models.SaleState, now time.Time) error {
+	products := repository.NewProductRepository(tx)
+	p, err := products.GetByIDForUpdate(ctx, sale.ProductID)
+	if err != nil {
+		return err
+	}
+	if p.Price == sale.SalePrice && sameAmount(p.CompareAtPrice, sale.RegularPrice) {
+		old := *p
+		p.Price, p.CompareAtPrice = *sale.RegularPrice, sale.RegularCompareAtPrice
+		if err := products.UpdatePrices(ctx, p); err != nil {
+			return err
+		}
+		if _, err := recordPriceChange(ctx, tx, &old, p, models.PriceChangeSale, sale.Actor, saleReason("sale ended", sale)); err != nil {
+			return err
+		}
+	} else {
+		msg := "prices were changed during the sale and left as they are"
+		sale.Error = &msg
+	}
+	sale.State, sale.FinishedAt = state, &now
+	return repository.NewSaleRepository(tx).Update(ctx, sale)
+}
+
+// saleReason describes a price change made by a sale in the price history
+func saleReason(event string, sale *models.ProductSale) *string {
+	reason := event
+	if sale.Reason != nil {
+		reason += ": " + *sale.Reason
+	}
+	return &reason
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/product/repository"
+)
+
+// Search limits
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchOffset    = 10000
+	maxQueryLength     = 200
+	attributeFacetSize = 20
+)
+
+// DefaultPriceBuckets are the bounds of the price range facet
+var DefaultPriceBuckets = []float64{0, 25, 50, 100, 250, 500, 1000}
+
+// SearchProductsInput holds the query, filters and page of a product search.
+// Prices are compared in each product's base currency, so Currency should be
+// set when MinPrice or MaxPrice is.
+type SearchProductsInput struct {
+	Query      string
+	CategoryID *uuid.UUID
+	MinPrice   *float64
+	MaxPrice   *float64
+	Currency   string
+	InStock    bool
+	MinRating  *float64
+	Attributes map[string][]string
+	Sort       string
+	Limit      int
+	Offset     int
+}
+
+// PriceRangeFacet counts the matching products priced from Min up to but
+// excluding Max; the last range is open-ended
+type PriceRangeFacet struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count"`
+}
+
+// RatingFacet counts the matching products rated MinRating stars or more on
+// average
+type RatingFacet struct {
+	MinRating int `json:"min_rating"`
+	Count     int `json:"count"`
+}
+
+// AttributeValueFacet counts the matching products with an attribute value
+type AttributeValueFacet struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// AttributeFacet lists the most frequent values of an attribute key among
+// the matching products
+type AttributeFacet struct {
+	Key    string                `json:"key"`
+	Values []AttributeValueFacet `json:"values"`
+}
+
+// ProductFacets summarizes the matching products. Each facet ignores its
+// own filter so the alternatives to a selection remain visible.
+type ProductFacets struct {
+	Categories  []repository.CategoryFacet `json:"categories"`
+	PriceRanges []PriceRangeFacet          `json:"price_ranges"`
+	Ratings     []RatingFacet              `json:"ratings"`
+	Attributes  []AttributeFacet           `json:"attributes"`
+}
+
+// ProductSearchResult is a page of search hits with the total number of
+// matches and the facets over all of them
+type ProductSearchResult struct {
+	Products []repository.ProductHit `json:"products"`
+	Total    int                     `json:"total"`
+	Facets   ProductFacets           `json:"facets"`
+}
+
+// SearchService runs full-text product searches
+type SearchService struct {
+	pool *pgxpool.Pool
+}
+
+// NewSearchService creates a new SearchService
+func NewSearchService(pool *pgxpool.Pool) *SearchService {
+	return &SearchService{pool: pool}
+}
+
+// Search returns the active products matching in ranked by relevance, or by
+// the requested sort, together with facet counts. Words are matched with
+// stemming against names, SKUs, attributes and descriptions; names also
+// match by trigram similarity so misspelled queries still find products.
+func (s *SearchService) Search(ctx context.Context, in SearchProductsInput) (*ProductSearchResult, error) {
+	filter, sortBy, err := searchFilter(in)
+	if err != nil {
+		return nil, err
+	}
+	limit := in.Limit
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	if limit < 0 || limit > maxSearchLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrValidation, maxSearchLimit)
+	}
+	if in.Offset < 0 || in.Offset > maxSearchOffset {
+		return nil, fmt.Errorf("%w: offset must be between 0 and %d", ErrValidation, maxSearchOffset)
+	}
+
+	result := &ProductSearchResult{}
+	// A read-only snapshot keeps the hits, total and facets consistent
+	err = pgx.BeginTxFunc(ctx, s.pool, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		products := repository.NewProductRepository(tx)
+		var err error
+		if result.Products, err = products.SearchProducts(ctx, filter, sortBy, limit, in.Offset); err != nil {
+			return err
+		}
+		if result.Total, err = products.CountProducts(ctx, filter); err != nil {
+			return err
+		}
+		if result.Facets.Categories, err = products.CategoryFacets(ctx, filter); err != nil {
+			return err
+		}
+		buckets, err := products.PriceFacets(ctx, filter, DefaultPriceBuckets)
+		if err != nil {
+			return err
+		}
+		result.Facets.PriceRanges = priceRanges(buckets, DefaultPriceBuckets)
+		stars, err := products.RatingFacets(ctx, filter)
+		if err != nil {
+			return err
+		}
+		result.Facets.Ratings = ratingFacets(stars)
+		counts, err := products.AttributeFacets(ctx, filter, attributeFacetSize)
+		if err != nil {
+			return err
+		}
+		result.Facets.Attributes = attributeFacets(counts)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Products == nil {
+		result.Products = []repository.ProductHit{}
+	}
+	return result, nil
+}
+
+// searchFilter validates in and converts it to a repository filter and sort
+func searchFilter(in SearchProductsInput) (repository.ProductFilter, repository.ProductSort, error) {
+	f := repository.ProductFilter{
+		Query:      strings.TrimSpace(in.Query),
+		CategoryID: in.CategoryID,
+		MinPrice:   in.MinPrice,
+		MaxPrice:   in.MaxPrice,
+		Currency:   strings.ToUpper(strings.TrimSpace(in.Currency)),
+		InStock:    in.InStock,
+		MinRating:  in.MinRating,
+	}
+	if len(f.Query) > maxQueryLength {
+		return f, "", fmt.Errorf("%w: query must be at most %d characters", ErrValidation, maxQueryLength)
+	}
+	if f.Currency != "" && len(f.Currency) != 3 {
+		return f, "", fmt.Errorf("%w: currency must be a 3-letter code", ErrValidation)
+	}
+	if f.MinPrice != nil && *f.MinPrice < 0 || f.MaxPrice != nil && *f.MaxPrice < 0 {
+		return f, "", fmt.Errorf("%w: price bounds must not be negative", ErrValidation)
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		return f, "", fmt.Errorf("%w: min_price must not exceed max_price", ErrValidation)
+	}
+	if f.MinRating != nil && (*f.MinRating < 1 || *f.MinRating > 5) {
+		return f, "", fmt.Errorf("%w: min_rating must be between 1 and 5", ErrValidation)
+	}
+	for key, values := range in.Attributes {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return f, "", fmt.Errorf("%w: attribute keys must not be empty", ErrValidation)
+		}
+		if f.Attributes == nil {
+			f.Attributes = map[string][]string{}
+		}
+		f.Attributes[key] = append(f.Attributes[key], values...)
+	}
+
+	sortBy := repository.ProductSort(in.Sort)
+	switch {
+	case sortBy == "" && f.Query != "":
+		sortBy = repository.ProductSortRelevance
+	case sortBy == "" || sortBy == repository.ProductSortRelevance && f.Query == "":
+		sortBy = repository.ProductSortNewest
+	case !repository.ValidProductSort(sortBy):
+		return f, "", fmt.Errorf("%w: unknown sort %q", ErrValidation, in.Sort)
+	}
+	return f, sortBy, nil
+}
+
+// priceRanges turns bucket counts into price ranges delimited by bounds
+func priceRanges(buckets []repository.PriceBucketCount, bounds []float64) []PriceRangeFacet {
+	ranges := []PriceRangeFacet{}
+	for _, b := range buckets {
+		// Bucket 0 would hold negative prices, which the schema rules out
+		if b.Bucket < 1 || b.Bucket > len(bounds) {
+			continue
+		}
+		r := PriceRangeFacet{Min: bounds[b.Bucket-1], Count: b.Count}
+		if b.Bucket < len(bounds) {
+			upper := bounds[b.Bucket]
+			r.Max = &upper
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// ratingFacets turns counts per whole star into "N stars & up" facets from
+// 4 stars down, leaving out those matching no product
+func ratingFacets(stars []repository.RatingStarCount) []RatingFacet {
+	facets := []RatingFacet{}
+	for atLeast := 4; atLeast >= 1; atLeast-- {
+		n := 0
+		for _, c := range stars {
+			if c.Stars >= atLeast {
+				n += c.Count
+			}
+		}
+		if n > 0 {
+			facets = append(facets, RatingFacet{MinRating: atLeast, Count: n})
+		}
+	}
+	return facets
+}
+
+// attributeFacets groups value counts, already ordered by key, per key
+func attributeFacets(counts []repository.AttributeValueCount) []AttributeFacet {
+	facets := []AttributeFacet{}
+	for _, c := range counts {
+		if len(facets) == 0 || facets[len(facets)-1].Key != c.Key {
+			facets = append(facets, AttributeFacet{Key: c.Key})
+		}
+		last := &facets[len(facets)-1]
+		last.Values = append(last.Values, AttributeValueFacet{Value: c.Value, Count: c.Count})
+	}
+	return facets
+}
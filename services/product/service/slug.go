@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/text/unicode/norm"
+	"main.go/services/product/repository"
+)
+
+// Slug lengths, matching the slug columns
+const (
+	maxProductSlug  = 500
+	maxCategorySlug = 255
+)
+
+// maxSlugAttempts bounds the retries of an insert whose generated slug was
+// taken concurrently
+const maxSlugAttempts = 5
+
+// slugPattern matches lowercase, hyphen-separated slugs
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// slugLetters transliterates letters that do not decompose into an ASCII
+// letter and a combining mark
+var slugLetters = map[rune]string{
+	'&': " and ", 'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th",
+	'ł': "l", 'ı': "i", 'ħ': "h", 'ŋ': "ng",
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh",
+	'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya", 'і': "i",
+	'ї': "yi", 'є': "ye", 'ґ': "g",
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i",
+	'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s",
+	'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// slugify turns a name into a slug of at most maxLen characters: letters are
+// transliterated to ASCII and lower-cased, and every run of other characters
+// becomes a single hyphen. Names without any usable character yield fallback.
+func slugify(name, fallback string, maxLen int) string {
+	var b strings.Builder
+	hyphen := false
+	write := func(s string) {
+		for _, c := range s {
+			if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' {
+				if hyphen && b.Len() > 0 {
+					b.WriteByte('-')
+				}
+				hyphen = false
+				b.WriteRune(c)
+			} else {
+				hyphen = true
+			}
+		}
+	}
+	for _, r := range name {
+		r = unicode.ToLower(r)
+		if s, ok := slugLetters[r]; ok {
+			write(s)
+			continue
+		}
+		if r < unicode.MaxASCII {
+			write(string(r))
+			continue
+		}
+		// Decompose accented letters and compatibility forms such as "é" or
+		// "ﬁ" and keep their ASCII part
+		decomposed := norm.NFKD.String(string(r))
+		ascii := strings.Map(func(c rune) rune {
+			if c < unicode.MaxASCII {
+				return unicode.ToLower(c)
+			}
+			return -1
+		}, decomposed)
+		if ascii == "" {
+			hyphen = true
+			continue
+		}
+		write(ascii)
+	}
+	slug := truncateSlug(b.String(), maxLen)
+	if slug == "" {
+		return fallback
+	}
+	return slug
+}
+
+// truncateSlug cuts a slug to maxLen characters without leaving a trailing
+// hyphen
+func truncateSlug(slug string, maxLen int) string {
+	if len(slug) > maxLen {
+		slug = slug[:maxLen]
+	}
+	return strings.TrimRight(slug, "-")
+}
+
+// nextSlug returns base when it is not taken, else base with the smallest
+// numeric suffix from 2 that is not, shortening base to stay within maxLen
+func nextSlug(base string, taken map[string]bool, maxLen int) string {
+	if !taken[base] {
+		return base
+	}
+	for n := 2; ; n++ {
+		suffix := "-" + strconv.Itoa(n)
+		slug := truncateSlug(base, maxLen-len(suffix)) + suffix
+		if !taken[slug] {
+			return slug
+		}
+	}
+}
+
+// createWithSlug calls create with base, or base with a numeric suffix when
+// that is already a current or former slug. Each attempt runs in a savepoint
+// of tx; a slug taken concurrently violates the UNIQUE constraint and is
+// retried with the next suffix. It returns the slug used.
+func createWithSlug(ctx context.Context, tx pgx.Tx, slugs *repository.SlugRepository, base string, maxLen int, create func(tx pgx.Tx, slug string) error) (string, error) {
+	used, err := slugs.ListTaken(ctx, base)
+	if err != nil {
+		return "", err
+	}
+	taken := make(map[string]bool, len(used))
+	for _, slug := range used {
+		taken[slug] = true
+	}
+	for range maxSlugAttempts {
+		slug := nextSlug(base, taken, maxLen)
+		err := pgx.BeginFunc(ctx, tx, func(sp pgx.Tx) error {
+			return create(sp, slug)
+		})
+		if !isSlugConflict(err) {
+			return slug, err
+		}
+		taken[slug] = true
+	}
+	return "", fmt.Errorf("%w: could not find a free slug for %q", ErrInvalidState, base)
+}
+
+// freeSlug returns base, or base with a numeric suffix, such that it is
+// neither a current nor a former slug. The record's own slug counts as free.
+func freeSlug(ctx context.Context, slugs *repository.SlugRepository, base, own string, maxLen int) (string, error) {
+	used, err := slugs.ListTaken(ctx, base)
+	if err != nil {
+		return "", err
+	}
+	taken := make(map[string]bool, len(used))
+	for _, slug := range used {
+		taken[slug] = slug != own
+	}
+	return nextSlug(base, taken, maxLen), nil
+}
+
+// changeSlug records that a record moved from one slug to another, so the
+// former one redirects to it
+func changeSlug(ctx context.Context, slugs *repository.SlugRepository, id uuid.UUID, from, to string) error {
+	if from == to {
+		return nil
+	}
+	if err := slugs.Claim(ctx, to); err != nil {
+		return err
+	}
+	return slugs.Retire(ctx, id, from)
+}
+
+// isSlugConflict reports whether err is a violation of a slug UNIQUE
+// constraint
+func isSlugConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && strings.HasSuffix(pgErr.ConstraintName, "_slug_key")
+}
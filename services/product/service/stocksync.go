@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/product/clients"
+	"main.go/services/product/models"
+	"main.go/services/product/repository"
+)
+
+// stockSyncPage is the number of availability changes fetched per request
+const stockSyncPage = 500
+
+// stockSyncOverlap is how far behind the last seen change a sync restarts
+// after a restart, covering inventory transactions that committed late with
+// an earlier timestamp. Re-applying a change is harmless.
+const stockSyncOverlap = time.Minute
+
+// AvailabilityFeed lists stock changes recorded by the inventory service
+type AvailabilityFeed interface {
+	AvailabilityChanges(ctx context.Context, since time.Time, afterID uuid.UUID, limit int) ([]clients.ProductAvailability, error)
+}
+
+// StockSyncService mirrors variant availability from the inventory service,
+// and the product totals derived from it, so searches and bundles can filter
+// on stock without calling it per request
+type StockSyncService struct {
+	pool      *pgxpool.Pool
+	inventory AvailabilityFeed
+
+	mu      sync.Mutex
+	loaded  bool
+	since   time.Time
+	afterID uuid.UUID
+}
+
+// NewStockSyncService creates a new StockSyncService
+func NewStockSyncService(pool *pgxpool.Pool, inventory AvailabilityFeed) *StockSyncService {
+	return &StockSyncService{pool: pool, inventory: inventory}
+}
+
+// Sync pulls every availability change since the previous sync and returns
+// the number of variants updated. Each page is stored together with the
+// refreshed stock of the products it touches. The first sync resumes shortly
+// before the latest change already stored.
+func (s *StockSyncService) Sync(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loaded {
+		latest, _, err := repository.NewStockRepository(s.pool).Latest(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if !latest.IsZero() {
+			s.since = latest.Add(-stockSyncOverlap)
+		}
+		s.loaded = true
+	}
+
+	updated := 0
+	for {
+		changes, err := s.inventory.AvailabilityChanges(ctx, s.since, s.afterID, stockSyncPage)
+		if err != nil {
+			return updated, err
+		}
+		if len(changes) == 0 {
+			return updated, nil
+		}
+		levels := make([]models.VariantStock, len(changes))
+		for i, c := range changes {
+			levels[i] = models.VariantStock{VariantID: c.VariantID, ProductID: c.ProductID, Available: c.Available, UpdatedAt: c.UpdatedAt}
+		}
+		var written []uuid.UUID
+		err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+			stock := repository.NewStockRepository(tx)
+			var err error
+			if written, err = stock.Upsert(ctx, levels); err != nil {
+				return err
+			}
+			if len(written) == 0 {
+				return nil
+			}
+			return stock.RefreshProducts(ctx, written)
+		})
+		if err != nil {
+			return updated, err
+		}
+		updated += len(written)
+		last := changes[len(changes)-1]
+		s.since, s.afterID = last.UpdatedAt, last.VariantID
+		if len(changes) < stockSyncPage {
+			return updated, nil
+		}
+	}
+}
+
+// RunStockSync calls Sync every interval until ctx is done
+func (s *StockSyncService) RunStockSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.Sync(ctx)
+			if err != nil {
+				log.Printf("stock sync failed: %v", err)
+			} else if n > 0 {
+				log.Printf("synced stock of %d variants", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"main.go/services/product/models"
+	"main.go/services/product/repository"
+)
+
+// Variant limits
+const (
+	maxProductOptions = 5
+	maxOptionValues   = 100
+	maxVariants       = 500
+)
+
+// OptionInput defines a product option and its values in display order
+type OptionInput struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// VariantInput holds the fields of a variant. Options must give a value for
+// every option of the product. Position is its index in the product's
+// variant list; a new variant is appended when it is nil.
+type VariantInput struct {
+	SKU            *string           `json:"sku,omitempty"`
+	Options        map[string]string `json:"options"`
+	Price          *float64          `json:"price,omitempty"`
+	CompareAtPrice *float64          `json:"compare_at_price,omitempty"`
+	IsDefault      bool              `json:"is_default"`
+	Position       *int              `json:"position,omitempty"`
+}
+
+// VariantService manages product options and variants
+type VariantService struct {
+	pool *pgxpool.Pool
+}
+
+// NewVariantService creates a new VariantService
+func NewVariantService(pool *pgxpool.Pool) *VariantService {
+	return &VariantService{pool: pool}
+}
+
+// ListOptions returns the options of a product
+func (s *VariantService) ListOptions(ctx context.Context, productID uuid.UUID) ([]models.ProductOption, error) {
+	if _, err := repository.NewProductRepository(s.pool).GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	return repository.NewVariantRepository(s.pool).ListOptions(ctx, productID)
+}
+
+// SetOptions replaces the options of a product. Existing variants take the
+// first value of an added option and lose removed options. Removing a value
+// still used by a variant, or a change that would leave two variants with
+// the same values, is rejected.
+func (s *VariantService) SetOptions(ctx context.Context, productID uuid.UUID, in []OptionInput) ([]models.ProductOption, error) {
+	options, err := normalizeOptions(in)
+	if err != nil {
+		return nil, err
+	}
+
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := repository.NewProductRepository(tx).GetByIDForUpdate(ctx, productID); err != nil {
+			return err
+		}
+		variants := repository.NewVariantRepository(tx)
+		existing, err := variants.ListVariantsForUpdate(ctx, productID)
+		if err != nil {
+			return err
+		}
+
+		seen := map[string]uuid.UUID{}
+		for i := range existing {
+			v := &existing[i]
+			values := map[string]string{}
+			for _, o := range options {
+				value, ok := v.Options[o.Name]
+				if !ok {
+					value = o.Values[0]
+				}
+				if !slices.Contains(o.Values, value) {
+					return fmt.Errorf("%w: variant %s uses value %q of option %q", ErrInvalidState, v.ID, value, o.Name)
+				}
+				values[o.Name] = value
+			}
+			key := optionKey(options, values)
+			if other, ok := seen[key]; ok {
+				return fmt.Errorf("%w: variants %s and %s would have the same options", ErrInvalidState, other, v.ID)
+			}
+			seen[key] = v.ID
+			v.Options = values
+			v.Title = variantTitle(options, values)
+		}
+
+		if err := variants.ReplaceOptions(ctx, productID, options); err != nil {
+			return err
+		}
+		for i := range existing {
+			if err := variants.UpdateVariant(ctx, &existing[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// ListVariants returns the variants of a product with their images
+func (s *VariantService) ListVariants(ctx context.Context, productID uuid.UUID) ([]models.ProductVariant, error) {
+	if _, err := repository.NewProductRepository(s.pool).GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	variants := repository.NewVariantRepository(s.pool)
+	list, err := variants.ListVariants(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	images, err := variants.ListImages(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Images = variantImages(images, list[i].ID)
+	}
+	return list, nil
+}
+
+// GetVariant returns a variant with its images
+func (s *VariantService) GetVariant(ctx context.Context, id uuid.UUID) (*models.ProductVariant, error) {
+	variants := repository.NewVariantRepository(s.pool)
+	v, err := variants.GetVariant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	images, err := variants.ListImages(ctx, v.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	v.Images = variantImages(images, v.ID)
+	return v, nil
+}
+
+// CreateVariant adds a variant to a product
+func (s *VariantService) CreateVariant(ctx context.Context, productID uuid.UUID, in VariantInput) (*models.ProductVariant, error) {
+	v := &models.ProductVariant{ProductID: productID}
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := repository.NewProductRepository(tx).GetByIDForUpdate(ctx, productID); err != nil {
+			return err
+		}
+		variants := repository.NewVariantRepository(tx)
+		existing, err := variants.ListVariantsForUpdate(ctx, productID)
+		if err != nil {
+			return err
+		}
+		if len(existing) >= maxVariants {
+			return fmt.Errorf("%w: a product can have at most %d variants", ErrInvalidState, maxVariants)
+		}
+		v.Position = len(existing)
+		if err := s.apply(ctx, variants, v, existing, in); err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			v.IsDefault = true
+		}
+		if v.IsDefault {
+			if err := variants.ClearDefault(ctx, productID); err != nil {
+				return err
+			}
+		}
+		return variants.CreateVariant(ctx, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// UpdateVariant replaces the fields of a variant. The default flag can only
+// be moved by marking another variant as default.
+func (s *VariantService) UpdateVariant(ctx context.Context, id uuid.UUID, in VariantInput) (*models.ProductVariant, error) {
+	var v *models.ProductVariant
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		variants := repository.NewVariantRepository(tx)
+		current, err := variants.GetVariant(ctx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := repository.NewProductRepository(tx).GetByIDForUpdate(ctx, current.ProductID); err != nil {
+			return err
+		}
+		existing, err := variants.ListVariantsForUpdate(ctx, current.ProductID)
+		if err != nil {
+			return err
+		}
+		if v, err = variants.GetVariant(ctx, id); err != nil {
+			return err
+		}
+		if v.IsDefault && !in.IsDefault {
+			return fmt.Errorf("%w: mark another variant as default instead", ErrValidation)
+		}
+		if err := s.apply(ctx, variants, v, existing, in); err != nil {
+			return err
+		}
+		if v.IsDefault {
+			if err := variants.ClearDefault(ctx, v.ProductID); err != nil {
+				return err
+			}
+		}
+		return variants.UpdateVariant(ctx, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DeleteVariant deletes a variant other than the product's default one that
+// no bundle contains
+func (s *VariantService) DeleteVariant(ctx context.Context, id uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		variants := repository.NewVariantRepository(tx)
+		v, err := variants.GetVariantForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if v.IsDefault {
+			return fmt.Errorf("%w: the default variant cannot be deleted", ErrInvalidState)
+		}
+		inBundle, err := repository.NewBundleRepository(tx).IsVariantComponent(ctx, id)
+		if err != nil {
+			return err
+		}
+		if inBundle {
+			return fmt.Errorf("%w: the variant is a component of a bundle", ErrInvalidState)
+		}
+		return variants.DeleteVariant(ctx, id)
+	})
+}
+
+// SetVariantImages makes the given product images the images of a variant
+func (s *VariantService) SetVariantImages(ctx context.Context, id uuid.UUID, imageIDs []uuid.UUID) (*models.ProductVariant, error) {
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		variants := repository.NewVariantRepository(tx)
+		v, err := variants.GetVariantForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		images, err := variants.ListImages(ctx, v.ProductID)
+		if err != nil {
+			return err
+		}
+		for _, imageID := range imageIDs {
+			if !slices.ContainsFunc(images, func(img models.ProductImage) bool { return img.ID == imageID }) {
+				return fmt.Errorf("%w: image %s does not belong to product %s", ErrValidation, imageID, v.ProductID)
+			}
+		}
+		if imageIDs == nil {
+			imageIDs = []uuid.UUID{}
+		}
+		return variants.AssignImages(ctx, v.ProductID, v.ID, imageIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetVariant(ctx, id)
+}
+
+// apply validates in against the product's options and the other variants
+// and copies it onto v
+func (s *VariantService) apply(ctx context.Context, variants *repository.VariantRepository, v *models.ProductVariant, existing []models.ProductVariant, in VariantInput) error {
+	options, err := variants.ListOptions(ctx, v.ProductID)
+	if err != nil {
+		return err
+	}
+	values := map[string]string{}
+	for name, value := range in.Options {
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	for _, o := range options {
+		value, ok := values[o.Name]
+		if !ok {
+			return fmt.Errorf("%w: a value for option %q is required", ErrValidation, o.Name)
+		}
+		if !slices.Contains(o.Values, value) {
+			return fmt.Errorf("%w: %q is not a value of option %q", ErrValidation, value, o.Name)
+		}
+	}
+	if len(values) != len(options) {
+		return fmt.Errorf("%w: options must only name options defined on the product", ErrValidation)
+	}
+
+	key := optionKey(options, values)
+	for _, other := range existing {
+		if other.ID != v.ID && optionKey(options, other.Options) == key {
+			return fmt.Errorf("%w: variant %s already has these options", ErrInvalidState, other.ID)
+		}
+	}
+
+	if in.SKU != nil {
+		sku := strings.TrimSpace(*in.SKU)
+		if sku == "" || len(sku) > 100 {
+			return fmt.Errorf("%w: sku must be 1 to 100 characters", ErrValidation)
+		}
+		other, err := variants.GetVariantBySKU(ctx, sku)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		if err == nil && other.ID != v.ID {
+			return fmt.Errorf("%w: sku %q is already used by variant %s", ErrInvalidState, sku, other.ID)
+		}
+		in.SKU = &sku
+	}
+	if in.Price != nil && *in.Price < 0 || in.CompareAtPrice != nil && *in.CompareAtPrice < 0 {
+		return fmt.Errorf("%w: prices must not be negative", ErrValidation)
+	}
+
+	v.SKU = in.SKU
+	v.Options = values
+	v.Title = variantTitle(options, values)
+	v.Price = in.Price
+	v.CompareAtPrice = in.CompareAtPrice
+	v.IsDefault = v.IsDefault || in.IsDefault
+	if in.Position != nil {
+		if *in.Position < 0 {
+			return fmt.Errorf("%w: position must not be negative", ErrValidation)
+		}
+		v.Position = *in.Position
+	}
+	return nil
+}
+
+// normalizeOptions validates option definitions and trims their names and
+// values
+func normalizeOptions(in []OptionInput) ([]models.ProductOption, error) {
+	if len(in) > maxProductOptions {
+		return nil, fmt.Errorf("%w: a product can have at most %d options", ErrValidation, maxProductOptions)
+	}
+	options := make([]models.ProductOption, 0, len(in))
+	names := map[string]bool{}
+	for _, o := range in {
+		name := strings.TrimSpace(o.Name)
+		if name == "" || len(name) > 100 {
+			return nil, fmt.Errorf("%w: option names must be 1 to 100 characters", ErrValidation)
+		}
+		if names[strings.ToLower(name)] {
+			return nil, fmt.Errorf("%w: option %q is listed twice", ErrValidation, name)
+		}
+		names[strings.ToLower(name)] = true
+		if len(o.Values) == 0 || len(o.Values) > maxOptionValues {
+			return nil, fmt.Errorf("%w: option %q must have 1 to %d values", ErrValidation, name, maxOptionValues)
+		}
+		values := make([]string, 0, len(o.Values))
+		for _, v := range o.Values {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				return nil, fmt.Errorf("%w: option %q has an empty value", ErrValidation, name)
+			}
+			if slices.Contains(values, v) {
+				return nil, fmt.Errorf("%w: option %q lists %q twice", ErrValidation, name, v)
+			}
+			values = append(values, v)
+		}
+		options = append(options, models.ProductOption{Name: name, Values: values})
+	}
+	return options, nil
+}
+
+// variantTitle joins the option values of a variant in option order, e.g.
+// "M / Red"
+func variantTitle(options []models.ProductOption, values map[string]string) string {
+	parts := make([]string, 0, len(options))
+	for _, o := range options {
+		parts = append(parts, values[o.Name])
+	}
+	return strings.Join(parts, " / ")
+}
+
+// optionKey identifies a combination of option values
+func optionKey(options []models.ProductOption, values map[string]string) string {
+	parts := make([]string, 0, len(options))
+	for _, o := range options {
+		parts = append(parts, o.Name+"\x00"+values[o.Name])
+	}
+	return strings.Join(parts, "\x01")
+}
+
+// variantImages returns the images assigned to a variant
+func variantImages(images []models.ProductImage, variantID uuid.UUID) []models.ProductImage {
+	var out []models.ProductImage
+	for _, img := range images {
+		if img.VariantID != nil && *img.VariantID == variantID {
+			out = append(out, img)
+		}
+	}
+	return out
+}